@@ -379,7 +379,7 @@ func (rc *RestClient) CallAppDController(path, method string, data []byte) ([]by
 	}
 	req, err := http.NewRequest(method, url, body)
 	req.Header.Set("Accept", "application/json, text/plain, */*")
-	if method == "POST" {
+	if method == "POST" || method == "PUT" {
 		req.Header.Set("Content-Type", "application/json")
 	}
 	req.Header.Set("X-CSRF-TOKEN", auth.Token)
@@ -396,7 +396,7 @@ func (rc *RestClient) CallAppDController(path, method string, data []byte) ([]by
 	rc.logger.Debugf("CallAppDController method %s. Response Status: %s", method, resp.Status)
 	b, _ := ioutil.ReadAll(resp.Body)
 	//	fmt.Println("response Body:", string(b))
-	if resp.StatusCode < 200 || resp.StatusCode > 202 {
+	if resp.StatusCode < 200 || resp.StatusCode > 204 {
 		return b, fmt.Errorf("Controller request failed with status %s.", resp.Status)
 	}
 	return b, nil
@@ -465,6 +465,11 @@ func (rc *RestClient) CreateDashboard(templatePath string) ([]byte, error) {
 
 }
 
+//ExportDashboard returns the dashboard in the format accepted by CreateDashboard
+func (rc *RestClient) ExportDashboard(id int) ([]byte, error) {
+	return rc.CallAppDController(fmt.Sprintf("CustomDashboardImportExportServlet?dashboardId=%d", id), "GET", nil)
+}
+
 func (rc *RestClient) MarkNodeHistorical(nodeId int) error {
 	url := fmt.Sprintf("%srest/mark-nodes-historical?application-component-node-ids=%d", rc.getControllerUrl(), nodeId)
 
@@ -487,6 +492,48 @@ func (rc *RestClient) MarkNodeHistorical(nodeId int) error {
 	return nil
 }
 
+//PostCustomEvent creates a custom event on the timeline of the application (name or ID). Tier is optional
+func (rc *RestClient) PostCustomEvent(appName string, tierName string, customType string, severity string, summary string, properties map[string]string) error {
+	params := url.Values{}
+	params.Set("eventtype", "CUSTOM")
+	params.Set("customeventtype", customType)
+	params.Set("severity", severity)
+	params.Set("summary", utils.TruncateString(summary, MAX_FIELD_LENGTH))
+	if tierName != "" {
+		params.Set("tier", tierName)
+	}
+	for k, v := range properties {
+		params.Add("propertynames", k)
+		params.Add("propertyvalues", utils.TruncateString(v, MAX_FIELD_LENGTH))
+	}
+	endpoint := fmt.Sprintf("%srest/applications/%s/events?%s", rc.getControllerUrl(), url.PathEscape(appName), params.Encode())
+
+	req, err := http.NewRequest("POST", endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("Unable to create request for custom event. %v\n", err)
+	}
+	ar := strings.Split(rc.Bag.RestAPICred, ":")
+	if len(ar) != 2 {
+		return fmt.Errorf("Rest API credentials are formatted incorrectly. Must be <username>@<account>:<password>")
+	}
+
+	req.SetBasicAuth(ar[0], ar[1])
+	client := rc.getClient(req)
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Unable to post custom event. %v\n", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 202 {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("Custom event request failed with status %s. %s", resp.Status, string(b))
+	}
+	rc.logger.Debugf("Custom event %s posted to application %s", customType, appName)
+
+	return nil
+}
+
 func (rc *RestClient) GetControllerVersion() ([]byte, error) {
 	url := fmt.Sprintf("%srest/serverstatus", rc.getControllerUrl())
 
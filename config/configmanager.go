@@ -13,6 +13,7 @@ import (
 
 	m "github.com/appdynamics/cluster-agent/models"
 	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
 const CONFIG_FILE = "/opt/appdynamics/config/cluster-agent-config.json"
@@ -36,6 +37,7 @@ type MutexConfigManager struct {
 	Watch              *ConfigWatcher
 	Logger             *log.Logger
 	InstrumentCallback func()
+	UpdateCallbacks    []func()
 }
 
 func NewMutexConfigManager(env *m.AppDBag, l *log.Logger) *MutexConfigManager {
@@ -60,6 +62,12 @@ func (self *MutexConfigManager) SubscribeToInstrumentationUpdates(callback func(
 	self.InstrumentCallback = callback
 }
 
+func (self *MutexConfigManager) SubscribeToConfigUpdates(callback func()) {
+	self.Mutex.Lock()
+	defer self.Mutex.Unlock()
+	self.UpdateCallbacks = append(self.UpdateCallbacks, callback)
+}
+
 func (self *MutexConfigManager) setDefaults(env *m.AppDBag) {
 	//set all secrets passed via env vars
 	self.Conf.RestAPICred = env.RestAPICred
@@ -110,6 +118,10 @@ func (self *MutexConfigManager) Set(conf *m.AppDBag) {
 		conf.NodeID = self.Conf.NodeID
 	}
 
+	if conf.ClusterUID == "" {
+		conf.ClusterUID = self.Conf.ClusterUID
+	}
+
 	self.Conf = conf
 
 	self.validate()
@@ -122,6 +134,65 @@ func (self *MutexConfigManager) validate() {
 	if self.Conf.NSInstrumentRule == nil {
 		self.Conf.NSInstrumentRule = []m.AgentRequest{}
 	}
+	if self.Conf.EventRules == nil {
+		self.Conf.EventRules = []m.EventRule{}
+	}
+	if self.Conf.AdqlSearches == nil {
+		self.Conf.AdqlSearches = []m.AdqlSearchDef{}
+	}
+	if self.Conf.DashboardTemplates == nil {
+		self.Conf.DashboardTemplates = make(map[string]string)
+	}
+	if self.Conf.DashboardCleanup == "" {
+		self.Conf.DashboardCleanup = m.DASHBOARD_CLEANUP_DELETE
+	}
+	if self.Conf.DashboardCleanup != m.DASHBOARD_CLEANUP_DELETE && self.Conf.DashboardCleanup != m.DASHBOARD_CLEANUP_ARCHIVE && self.Conf.DashboardCleanup != m.DASHBOARD_CLEANUP_NONE {
+		self.Logger.WithField("DashboardCleanup", self.Conf.DashboardCleanup).Warn("Invalid dashboard cleanup mode. Stale dashboards will not be removed")
+		self.Conf.DashboardCleanup = m.DASHBOARD_CLEANUP_NONE
+	}
+	if self.Conf.RecommendationWindowHours <= 0 {
+		self.Conf.RecommendationWindowHours = 168
+	}
+	if self.Conf.RecommendationSampleMin <= 0 {
+		self.Conf.RecommendationSampleMin = 5
+	}
+	if self.Conf.RecommendationMinHours > self.Conf.RecommendationWindowHours {
+		self.Logger.WithFields(log.Fields{"RecommendationMinHours": self.Conf.RecommendationMinHours, "RecommendationWindowHours": self.Conf.RecommendationWindowHours}).Warn("Minimum usage history is longer than the window. Using the window")
+		self.Conf.RecommendationMinHours = self.Conf.RecommendationWindowHours
+	}
+	if self.Conf.NodeHeatMap == "" {
+		self.Conf.NodeHeatMap = m.NODE_HEAT_MAP_NONE
+	}
+	if self.Conf.NodeHeatMap != m.NODE_HEAT_MAP_NONE && self.Conf.NodeHeatMap != m.NODE_HEAT_MAP_OVERVIEW && self.Conf.NodeHeatMap != m.NODE_HEAT_MAP_DASHBOARD {
+		self.Logger.WithField("NodeHeatMap", self.Conf.NodeHeatMap).Warn("Invalid node heat map mode. The node heat map is disabled")
+		self.Conf.NodeHeatMap = m.NODE_HEAT_MAP_NONE
+	}
+	if self.Conf.CostPrices == nil {
+		self.Conf.CostPrices = []m.CostPrice{}
+	}
+	if self.Conf.CostLabels == nil {
+		self.Conf.CostLabels = []string{}
+	}
+	if self.Conf.CostAllocation == "" {
+		self.Conf.CostAllocation = m.COST_ALLOCATION_MAX
+	}
+	if self.Conf.CostAllocation != m.COST_ALLOCATION_REQUEST && self.Conf.CostAllocation != m.COST_ALLOCATION_USAGE && self.Conf.CostAllocation != m.COST_ALLOCATION_MAX {
+		self.Logger.WithField("CostAllocation", self.Conf.CostAllocation).Warn("Invalid cost allocation basis. Using max of requests and usage")
+		self.Conf.CostAllocation = m.COST_ALLOCATION_MAX
+	}
+	if self.Conf.CostIdleDistribution == "" {
+		self.Conf.CostIdleDistribution = m.COST_IDLE_NONE
+	}
+	if self.Conf.CostIdleDistribution != m.COST_IDLE_NONE && self.Conf.CostIdleDistribution != m.COST_IDLE_NODE && self.Conf.CostIdleDistribution != m.COST_IDLE_CLUSTER {
+		self.Logger.WithField("CostIdleDistribution", self.Conf.CostIdleDistribution).Warn("Invalid idle cost distribution. Idle cost will be reported separately")
+		self.Conf.CostIdleDistribution = m.COST_IDLE_NONE
+	}
+	if self.Conf.CapacityShapes == nil {
+		self.Conf.CapacityShapes = []m.CapacityShape{}
+	}
+	if self.Conf.NodePoolLabels == nil {
+		self.Conf.NodePoolLabels = []string{}
+	}
 	if self.Conf.InstrumentMatchString == nil {
 		self.Conf.InstrumentMatchString = []string{}
 	}
@@ -158,6 +229,39 @@ func (self *MutexConfigManager) validate() {
 		self.Conf.RemoteBiqHost = host
 		self.Conf.RemoteBiqPort = port
 	}
+	for _, selector := range []string{self.Conf.NsSelector, self.Conf.NodeSelector, self.Conf.NsToInstrumentSelector, self.Conf.NsToDashboardSelector, self.Conf.NodesToDashboardSelector} {
+		if err := utils.ValidateSelector(selector); err != nil {
+			self.Logger.Errorf("%v. Objects will not be matched by this selector", err)
+		}
+	}
+	for _, price := range self.Conf.CostPrices {
+		if err := utils.ValidateSelector(price.NodeSelector); err != nil {
+			self.Logger.Errorf("%v. Nodes will not be priced by this selector", err)
+		}
+		if price.CpuCoreHour < 0 || price.MemGBHour < 0 || price.StorageGBHour < 0 {
+			self.Logger.WithField("NodeSelector", price.NodeSelector).Warn("Negative cost price configured")
+		}
+	}
+	for _, shape := range self.Conf.CapacityShapes {
+		if shape.Workload != "" {
+			if err := utils.ValidateWorkloadSelector(shape.Workload); err != nil {
+				self.Logger.Errorf("%v. Capacity shape %s will be skipped", err, shape.Name)
+			}
+		}
+		for _, q := range []string{shape.Cpu, shape.Memory} {
+			if _, err := resource.ParseQuantity(q); q != "" && err != nil {
+				self.Logger.WithFields(log.Fields{"shape": shape.Name, "quantity": q}).Error("Invalid request of capacity shape. The shape will be skipped")
+			}
+		}
+		if strings.Contains(shape.Name, m.METRIC_SEPARATOR) {
+			self.Logger.WithField("shape", shape.Name).Warn("Capacity shape name contains the metric separator. The metric path will be split")
+		}
+	}
+	for _, selector := range self.Conf.WorkloadsToDashboard {
+		if err := utils.ValidateWorkloadSelector(selector); err != nil {
+			self.Logger.Errorf("%v. Workloads will not be matched by this selector", err)
+		}
+	}
 	self.Conf.EnsureDefaults()
 }
 
@@ -199,6 +303,13 @@ func (self *MutexConfigManager) reconcile(updated *m.AppDBag) {
 		self.Conf.InstrumentationUpdated = false
 		self.Mutex.Unlock()
 	}
+
+	self.Mutex.Lock()
+	callbacks := self.UpdateCallbacks
+	self.Mutex.Unlock()
+	for _, callback := range callbacks {
+		go callback()
+	}
 }
 
 func (self *MutexConfigManager) Get() *m.AppDBag {
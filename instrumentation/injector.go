@@ -191,7 +191,7 @@ func GetAgentRequestsForDeployment(deploy *appsv1.Deployment, bag *m.AppDBag, l
 
 		//if no rules exist for deployment/namespace, check namespace settings
 		if list == nil {
-			if utils.StringInSlice(deploy.Namespace, bag.NsToInstrument) {
+			if utils.NSSelectedForInstrumentation(deploy.Namespace, bag) {
 				global := false
 				if len(bag.InstrumentMatchString) == 0 {
 					//everything in the namespace needs to be instrumented
@@ -20,6 +20,7 @@ import (
 	w "github.com/appdynamics/cluster-agent/workers"
 
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
@@ -64,6 +65,10 @@ func buildParams() Flags {
 	flag.StringVar(&params.Bag.LogSchemaName, "schema-logs", bagDefaults.LogSchemaName, "Log schema name")
 	flag.StringVar(&params.Bag.EpSchemaName, "schema-ep", bagDefaults.EpSchemaName, "Endpoint schema name")
 	flag.StringVar(&params.Bag.JobSchemaName, "schema-jobs", bagDefaults.JobSchemaName, "Jobs schema name")
+	flag.StringVar(&params.Bag.RolloutSchemaName, "schema-rollouts", bagDefaults.RolloutSchemaName, "Rollouts schema name")
+	flag.StringVar(&params.Bag.ChangeSchemaName, "schema-changes", bagDefaults.ChangeSchemaName, "Workload changes schema name")
+	flag.StringVar(&params.Bag.RecommendationSchemaName, "schema-recommendations", bagDefaults.RecommendationSchemaName, "Right-sizing recommendations schema name")
+	flag.StringVar(&params.Bag.CostSchemaName, "schema-costs", bagDefaults.CostSchemaName, "Cost allocation schema name")
 	flag.StringVar(&params.Bag.DashboardTemplatePath, "template-path", getTemplatePath(), "Dashboard template path")
 	flag.StringVar(&params.Bag.DashboardSuffix, "dash-name", getDashboardSuffix(), "Dashboard name")
 	flag.IntVar(&params.Bag.DashboardDelayMin, "dash-delay", getDashboardDelayMin(), "Dashboard delay (min)")
@@ -115,15 +120,19 @@ func buildParams() Flags {
 	var nodeToMonitor string
 	flag.StringVar(&nodeToMonitor, "nodes-to-monitor", getNodesToMonitor(), "List of nodes to monitor")
 	if nodeToMonitor != "" {
-		params.Bag.NsToMonitor = strings.Split(nodeToMonitor, ",")
+		params.Bag.NodesToMonitor = strings.Split(nodeToMonitor, ",")
 	}
 
 	var nodeToMonitorExclude string
 	flag.StringVar(&nodeToMonitorExclude, "nodes-to-monitor-exc", getNodesToMonitorExclude(), "List of nodes to exclude from monitoring")
 	if nodeToMonitorExclude != "" {
-		params.Bag.NsToMonitorExclude = strings.Split(nodeToMonitorExclude, ",")
+		params.Bag.NodesToMonitorExclude = strings.Split(nodeToMonitorExclude, ",")
 	}
 
+	flag.StringVar(&params.Bag.NsSelector, "ns-selector", getNSSelector(), "Label selector of namespaces to monitor, e.g. monitoring=enabled")
+	flag.StringVar(&params.Bag.NodeSelector, "node-selector", getNodeSelector(), "Label selector of nodes to monitor, e.g. node-role!=infra")
+	flag.StringVar(&params.Bag.NsToInstrumentSelector, "ns-to-instrument-selector", getNSToInstrumentSelector(), "Label selector of namespaces to instrument")
+
 	var nsToInstrument string
 	flag.StringVar(&nsToInstrument, "ns-to-instrument", getNSToIntrument(), "List of namespaces to instrument")
 	if nsToInstrument != "" {
@@ -195,9 +204,15 @@ func main() {
 		return
 	}
 
+	metaClient, err := metadata.NewForConfig(config)
+	if err != nil {
+		l.WithField("error", err.Error()).Error("Issues authenticating with the cluster. Terminating...")
+		return
+	}
+
 	var wg sync.WaitGroup
 
-	controller := w.NewController(configManager, clientset, l, config)
+	controller := w.NewController(configManager, clientset, metaClient, l, config)
 	validationErr := controller.ValidateParameters()
 	if validationErr != nil {
 		l.WithField("error", validationErr.Error()).Error("Cluster Agent parameters are invalid. Terminating...")
@@ -470,6 +485,18 @@ func getNodesToMonitorExclude() string {
 	return os.Getenv("NODES_TO_MONITOR_EXC")
 }
 
+func getNSSelector() string {
+	return os.Getenv("NS_SELECTOR")
+}
+
+func getNodeSelector() string {
+	return os.Getenv("NODE_SELECTOR")
+}
+
+func getNSToInstrumentSelector() string {
+	return os.Getenv("NS_TO_INSTRUMENT_SELECTOR")
+}
+
 func getDefaultInstrumentationTech() string {
 	tech := os.Getenv("APPDYNAMICS_INSTRUMENT_TECH")
 	if tech == "" {
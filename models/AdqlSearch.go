@@ -1,9 +1,23 @@
 package models
 
 type AdqlSearch struct {
-	ID         int64               `json:"id"`
-	SearchName string              `json:"searchName"`
-	Query      string              `json:"-"`
-	SchemaName string              `json:"-"`
-	SchemaDef  AppDSchemaInterface `json:"-"`
+	ID          int64               `json:"id"`
+	Name        string              `json:"name"`
+	SearchName  string              `json:"searchName"`
+	AdqlQueries []string            `json:"adqlQueries"`
+	Query       string              `json:"-"`
+	SchemaName  string              `json:"-"`
+	SchemaDef   AppDSchemaInterface `json:"-"`
+}
+
+//AdqlSearchDef defines a saved search for the drill-down of a metric. Query is a text/template
+//with {{.ClusterName}} and {{.SchemaName}} variables, e.g. select * from {{.SchemaName}} where clusterName = '{{.ClusterName}}'
+type AdqlSearchDef struct {
+	MetricPath string `json:"metricPath"` //metric name, e.g. PodFailed, or full metric path
+	SchemaName string `json:"schemaName"`
+	Query      string `json:"query"`
+}
+
+func (sd *AdqlSearchDef) IsValid() bool {
+	return sd.MetricPath != "" && sd.SchemaName != "" && sd.Query != ""
 }
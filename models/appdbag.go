@@ -39,9 +39,16 @@ type AppDBag struct {
 	RqSchemaName                string
 	JobSchemaName               string
 	LogSchemaName               string
+	RolloutSchemaName           string
+	ChangeSchemaName            string
+	RecommendationSchemaName    string
+	CostSchemaName              string
 	DashboardTemplatePath       string
 	DashboardSuffix             string
 	DashboardDelayMin           int
+	DashboardCleanup            string //delete, archive or none
+	DashboardGracePeriodMin     int    //minutes a dashboard stays after it is no longer generated
+	NodeHeatMap                 string //none, overview or dashboard
 	AgentEnvVar                 string
 	AgentLabel                  string
 	AppNameLiteral              string
@@ -72,16 +79,53 @@ type AppDBag struct {
 	InitContainerDir            string
 	MetricsSyncInterval         int // Frequency of metrics pushes to the controller, sec
 	SnapshotSyncInterval        int // Frequency of snapshot pushes to events api, sec
+	InformerResyncInterval      int // Frequency of informer cache resyncs, sec. 0 - no resync
+	MetricsServerTimeout        int // Timeout of requests to metrics-server, sec
+	MetricsMaxAge               int // Max age of the usage samples reported to the controller, sec. 0 - no limit
+	RolloutStallThreshold       int // Time a rollout can stay partially available before it is flagged as stalled, sec. 0 - disabled
+	ChangeEventsToController    bool
+	RecommendationIntervalMin   int    // Frequency of right-sizing recommendations, min. 0 - disabled
+	RecommendationWindowHours   int    // Usage history window, hours
+	RecommendationMinHours      int    // Minimum usage history of a container before it gets a recommendation, hours
+	RecommendationSampleMin     int    // Usage history resolution, min. The peak use within the period is kept
+	RecommendationMargin        int    // Headroom added to the recommended requests and limits, percent
+	RecommendationHistoryPath   string // File where the usage history is persisted. Empty - in memory only
+	CostIntervalMin             int    // Frequency of cost records pushes to the costs schema, min. 0 - metrics only
+	ForwardEventsPerMinute      int    // Max number of k8s events forwarded to an AppD application per minute. 0 - no limit
+	HealthRulesEnabled          bool
+	HealthRulePodFailed         int // Thresholds of the provisioned health rules. 0 - the rule is not provisioned
+	HealthRuleEvictionThreats   int
+	HealthRuleMissingDeps       int
+	HealthRuleNoConnectivity    int
+	HealthRulePodOverconsume    int
 	AgentServerPort             int
 	NetVizPort                  int
 	NsToMonitor                 []string
 	NsToMonitorExclude          []string
 	DeploysToDashboard          []string
+	WorkloadsToDashboard        []string          //kind/namespace/name selectors with wildcards, e.g. DaemonSet/*/fluentd
+	DashboardTemplates          map[string]string //dashboard type (cluster, tier, namespace, node) -> Go template file
+	NsToDashboard               []string
+	NsToDashboardSelector       string
+	NodesToDashboard            []string
+	NodesToDashboardSelector    string
 	NodesToMonitor              []string
 	NodesToMonitorExclude       []string
 	NsToInstrument              []string
 	NsToInstrumentExclude       []string
+	NsSelector                  string //label selector, e.g. monitoring=enabled
+	NodeSelector                string //label selector, e.g. node-role!=infra
+	NsToInstrumentSelector      string
 	NSInstrumentRule            []AgentRequest
+	EventRules                  []EventRule
+	AdqlSearches                []AdqlSearchDef
+	CostPrices                  []CostPrice     //hourly prices per node selector. Empty - cost allocation is disabled
+	CostAllocation              string          //request, usage or max
+	CostIdleDistribution        string          //none, node or cluster
+	CostLabels                  []string        //pod label keys the cost is broken down by, e.g. team
+	CapacityShapes              []CapacityShape //pod shapes the schedulable headroom is reported for
+	NodePoolLabels              []string        //node label keys identifying the node pool, first match wins
+	ForwardEventCategories      []string        //categories, subcategories or reasons of events forwarded to AppD applications
 	InstrumentationMethod       InstrumentationMethod
 	DefaultInstrumentationTech  TechnologyName
 	BiqService                  string
@@ -92,7 +136,8 @@ type AppDBag struct {
 	BiqRequestMem               string
 	BiqRequestCpu               string
 	UniqueHostID                string
-	LogLines                    int //0 - no logging
+	ClusterUID                  string //uid of the kube-system namespace. Identifies the cluster if it gets renamed
+	LogLines                    int    //0 - no logging
 	PodEventNumber              int
 	RemoteBiqProtocol           string
 	RemoteBiqHost               string
@@ -120,6 +165,9 @@ type AgentStatus struct {
 	NodesToMonitorExclude      []string
 	NsToInstrument             []string
 	NsToInstrumentExclude      []string
+	NsSelector                 string
+	NodeSelector               string
+	NsToInstrumentSelector     string
 	NSInstrumentRule           []AgentRequest
 	InstrumentationMethod      InstrumentationMethod
 	DefaultInstrumentationTech TechnologyName
@@ -128,11 +176,14 @@ type AgentStatus struct {
 	AnalyticsAgentImage        string
 	AppDJavaAttachImage        string
 	AppDDotNetAttachImage      string
+	CacheMemory                map[string]int64
+	MetricsStale               bool
+	MetricsLastUpdate          string
 }
 
 func IsUpdatable(fieldName string) bool {
 	arr := []string{"AgentNamespace", "AppName", "TierName", "NodeName", "AppID", "TierID", "NodeID", "Account", "GlobalAccount", "AccessKey", "ControllerUrl",
-		"ControllerPort", "RestAPIUrl", "SSLEnabled", "SystemSSLCert", "AgentSSLCert", "EventKey", "EventServiceUrl", "RestAPICred"}
+		"ControllerPort", "RestAPIUrl", "SSLEnabled", "SystemSSLCert", "AgentSSLCert", "EventKey", "EventServiceUrl", "RestAPICred", "ClusterUID"}
 	for _, s := range arr {
 		if s == fieldName {
 			return false
@@ -153,7 +204,11 @@ func UpdateField(fieldName string, current *reflect.Value, updated *reflect.Valu
 		"NsSchemaName",
 		"RqSchemaName",
 		"JobSchemaName",
-		"LogSchemaName"}
+		"LogSchemaName",
+		"RolloutSchemaName",
+		"ChangeSchemaName",
+		"RecommendationSchemaName",
+		"CostSchemaName"}
 
 	found := false
 	for _, s := range arr {
@@ -232,6 +287,18 @@ func (self *AppDBag) EnsureDefaults() {
 	if self.DaemonSchemaName == "" {
 		self.DaemonSchemaName = bag.DaemonSchemaName
 	}
+	if self.RolloutSchemaName == "" {
+		self.RolloutSchemaName = bag.RolloutSchemaName
+	}
+	if self.ChangeSchemaName == "" {
+		self.ChangeSchemaName = bag.ChangeSchemaName
+	}
+	if self.RecommendationSchemaName == "" {
+		self.RecommendationSchemaName = bag.RecommendationSchemaName
+	}
+	if self.CostSchemaName == "" {
+		self.CostSchemaName = bag.CostSchemaName
+	}
 }
 
 func GetDefaultProperties() *AppDBag {
@@ -245,6 +312,10 @@ func GetDefaultProperties() *AppDBag {
 		EventAPILimit:               100,
 		MetricsSyncInterval:         60,
 		SnapshotSyncInterval:        30,
+		InformerResyncInterval:      600,
+		MetricsServerTimeout:        10,
+		MetricsMaxAge:               180,
+		RolloutStallThreshold:       600,
 		PodSchemaName:               "kube_pod_snapshots",
 		NodeSchemaName:              "kube_node_snapshots",
 		EventSchemaName:             "kube_event_snapshots",
@@ -257,10 +328,23 @@ func GetDefaultProperties() *AppDBag {
 		DeploySchemaName:            "kube_deploy_snapshots",
 		RSSchemaName:                "kube_rs_snapshots",
 		DaemonSchemaName:            "kube_daemon_snapshots",
+		RolloutSchemaName:           "kube_rollouts",
+		ChangeSchemaName:            "kube_changes",
+		RecommendationSchemaName:    "kube_recommendations",
+		CostSchemaName:              "kube_costs",
 		DashboardTemplatePath:       "/opt/appdynamics/templates/cluster-template.json",
 		DashboardSuffix:             "SUMMARY",
 		DashboardDelayMin:           2,
+		DashboardCleanup:            DASHBOARD_CLEANUP_DELETE,
+		DashboardGracePeriodMin:     60,
+		NodeHeatMap:                 NODE_HEAT_MAP_NONE,
 		DeploysToDashboard:          []string{},
+		WorkloadsToDashboard:        []string{},
+		DashboardTemplates:          map[string]string{},
+		NsToDashboard:               []string{},
+		NsToDashboardSelector:       "",
+		NodesToDashboard:            []string{},
+		NodesToDashboardSelector:    "",
 		InstrumentationMethod:       "none",
 		DefaultInstrumentationTech:  "java",
 		BiqService:                  "none",
@@ -293,7 +377,19 @@ func GetDefaultProperties() *AppDBag {
 		NodesToMonitorExclude:       []string{},
 		NsToInstrument:              []string{},
 		NsToInstrumentExclude:       []string{},
+		NsSelector:                  "",
+		NodeSelector:                "",
+		NsToInstrumentSelector:      "",
 		NSInstrumentRule:            []AgentRequest{},
+		EventRules:                  []EventRule{},
+		AdqlSearches:                []AdqlSearchDef{},
+		CostPrices:                  []CostPrice{},
+		CostAllocation:              COST_ALLOCATION_MAX,
+		CostIdleDistribution:        COST_IDLE_NONE,
+		CostLabels:                  []string{},
+		CapacityShapes:              []CapacityShape{CapacityShape{Name: "default", Cpu: "500m", Memory: "512Mi"}},
+		NodePoolLabels:              []string{"cloud.google.com/gke-nodepool", "eks.amazonaws.com/nodegroup", "kubernetes.azure.com/agentpool", "agentpool"},
+		ForwardEventCategories:      []string{},
 		InitRequestMem:              "50",
 		InitRequestCpu:              "0.1",
 		BiqRequestMem:               "600",
@@ -307,6 +403,21 @@ func GetDefaultProperties() *AppDBag {
 		PodEventNumber:              1,
 		LogLevel:                    "info",
 		OverconsumptionThreshold:    80,
+		ChangeEventsToController:    false,
+		RecommendationIntervalMin:   60,
+		RecommendationWindowHours:   168,
+		RecommendationMinHours:      24,
+		RecommendationSampleMin:     5,
+		RecommendationMargin:        15,
+		RecommendationHistoryPath:   "",
+		CostIntervalMin:             60,
+		ForwardEventsPerMinute:      10,
+		HealthRulesEnabled:          true,
+		HealthRulePodFailed:         1,
+		HealthRuleEvictionThreats:   1,
+		HealthRuleMissingDeps:       1,
+		HealthRuleNoConnectivity:    1,
+		HealthRulePodOverconsume:    1,
 		InstrumentationUpdated:      false,
 	}
 
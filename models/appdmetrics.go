@@ -20,6 +20,9 @@ const ALL string = "all"
 const METRIC_SEPARATOR string = "|"
 const METRIC_PATH_NODES string = "Nodes"
 const METRIC_PATH_NAMESPACES string = "Namespaces"
+const METRIC_PATH_ZONES string = "Zones"
+const METRIC_PATH_POOLS string = "Pools"
+const METRIC_PATH_PODS string = "Pods"
 const METRIC_PATH_APPS string = "Deployments"
 const METRIC_PATH_CONT string = "Containers"
 const METRIC_PATH_INSTANCES string = "Instances"
@@ -0,0 +1,169 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fatih/structs"
+	"k8s.io/api/core/v1"
+)
+
+const (
+	METRIC_PATH_CAPACITY string = "Capacity"
+	NODE_POOL_DEFAULT    string = "default"
+)
+
+//CapacityShape is a pod shape to place on the cluster. The requests are taken from the running pods of the workload
+//or given as quantities. Quantities override the requests of the workload
+type CapacityShape struct {
+	Name         string
+	Workload     string            //kind/namespace/name, e.g. Deployment/prod/checkout
+	Cpu          string            //cpu request, e.g. 500m
+	Memory       string            //memory request, e.g. 512Mi
+	NodeSelector map[string]string //overrides the node selector of the workload
+	Tolerations  []v1.Toleration   //added to the tolerations of the workload
+	Replicas     int64             //replicas to place. 0 - as many as fit
+}
+
+//PodShape is the scheduling footprint of a pod: effective requests, node selection and tolerations
+type PodShape struct {
+	Name         string            `json:"name"`
+	Cpu          int64             `json:"cpu"` //millicores
+	Mem          int64             `json:"mem"` //bytes
+	NodeSelector map[string]string `json:"nodeSelector"`
+	Affinity     *v1.NodeSelector  `json:"-"`
+	Tolerations  []v1.Toleration   `json:"-"`
+}
+
+//CapacityPlacement is the number of replicas of a shape placed on a node
+type CapacityPlacement struct {
+	NodeName string `json:"nodeName"`
+	Pool     string `json:"pool"`
+	Replicas int64  `json:"replicas"`
+}
+
+//CapacityFit is the result of placing a shape on the free capacity of the cluster
+type CapacityFit struct {
+	Shape         string              `json:"shape"`
+	Cpu           int64               `json:"cpu"`
+	Mem           int64               `json:"mem"`
+	EligibleNodes int64               `json:"eligibleNodes"`
+	MaxReplicas   int64               `json:"maxReplicas"` //replicas that fit when the shape is placed alone
+	Requested     int64               `json:"requested"`
+	Placed        int64               `json:"placed"`
+	Fits          bool                `json:"fits"`
+	Reason        string              `json:"reason,omitempty"`
+	Placements    []CapacityPlacement `json:"placements,omitempty"`
+}
+
+//CapacityPool is the allocatable capacity and the requests of the schedulable nodes of a node pool
+type CapacityPool struct {
+	Name            string `json:"name"`
+	Nodes           int64  `json:"nodes"`
+	CpuAllocatable  int64  `json:"cpuAllocatable"`
+	CpuRequested    int64  `json:"cpuRequested"`
+	MemAllocatable  int64  `json:"memAllocatable"`
+	MemRequested    int64  `json:"memRequested"`
+	PodsAllocatable int64  `json:"podsAllocatable"`
+	PodsRequested   int64  `json:"podsRequested"`
+}
+
+func (cp *CapacityPool) CpuFree() int64 {
+	return freeOf(cp.CpuAllocatable, cp.CpuRequested)
+}
+
+func (cp *CapacityPool) MemFree() int64 {
+	return freeOf(cp.MemAllocatable, cp.MemRequested)
+}
+
+func (cp *CapacityPool) PodsFree() int64 {
+	return freeOf(cp.PodsAllocatable, cp.PodsRequested)
+}
+
+func freeOf(allocatable int64, requested int64) int64 {
+	if requested > allocatable {
+		return 0
+	}
+	return allocatable - requested
+}
+
+//CapacityReport is the capacity of the node pools and the headroom of the configured shapes per pool
+type CapacityReport struct {
+	Timestamp time.Time                   `json:"timestamp"`
+	Pools     []CapacityPool              `json:"pools"`
+	Headroom  map[string]map[string]int64 `json:"headroom"` //shape -> pool -> replicas that fit
+}
+
+//ClusterCapacityMetrics are the free requests of a node pool. Cpu is in millicores, memory in bytes
+type ClusterCapacityMetrics struct {
+	Path               string
+	Pool               string
+	Nodes              int64
+	CpuAllocatable     int64
+	CpuRequested       int64
+	CpuFree            int64
+	CpuRequestedPct    int64
+	MemoryAllocatable  int64
+	MemoryRequested    int64
+	MemoryFree         int64
+	MemoryRequestedPct int64
+	PodsFree           int64
+}
+
+func (cpm ClusterCapacityMetrics) GetPath() string {
+	return cpm.Path
+}
+
+func (cpm ClusterCapacityMetrics) ShouldExcludeField(fieldName string) bool {
+	if fieldName == "Pool" || fieldName == "Path" {
+		return true
+	}
+	return false
+}
+
+func (cpm ClusterCapacityMetrics) Unwrap() *map[string]interface{} {
+	objMap := structs.Map(cpm)
+	return &objMap
+}
+
+func NewClusterCapacityMetrics(bag *AppDBag, pool *CapacityPool) ClusterCapacityMetrics {
+	p := fmt.Sprintf("%s%s%s%s%s", RootPath, METRIC_PATH_CAPACITY, METRIC_SEPARATOR, pool.Name, METRIC_SEPARATOR)
+	metrics := ClusterCapacityMetrics{Path: p, Pool: pool.Name, Nodes: pool.Nodes, CpuAllocatable: pool.CpuAllocatable, CpuRequested: pool.CpuRequested,
+		CpuFree: pool.CpuFree(), MemoryAllocatable: pool.MemAllocatable, MemoryRequested: pool.MemRequested, MemoryFree: pool.MemFree(), PodsFree: pool.PodsFree()}
+	if pool.CpuAllocatable > 0 {
+		metrics.CpuRequestedPct = pool.CpuRequested * 100 / pool.CpuAllocatable
+	}
+	if pool.MemAllocatable > 0 {
+		metrics.MemoryRequestedPct = pool.MemRequested * 100 / pool.MemAllocatable
+	}
+	return metrics
+}
+
+//ClusterHeadroomMetrics is the number of replicas of a shape that can be scheduled on a node pool
+type ClusterHeadroomMetrics struct {
+	Path                string
+	Pool                string
+	Shape               string
+	SchedulableHeadroom int64
+}
+
+func (cpm ClusterHeadroomMetrics) GetPath() string {
+	return cpm.Path
+}
+
+func (cpm ClusterHeadroomMetrics) ShouldExcludeField(fieldName string) bool {
+	if fieldName == "Pool" || fieldName == "Shape" || fieldName == "Path" {
+		return true
+	}
+	return false
+}
+
+func (cpm ClusterHeadroomMetrics) Unwrap() *map[string]interface{} {
+	objMap := structs.Map(cpm)
+	return &objMap
+}
+
+func NewClusterHeadroomMetrics(bag *AppDBag, pool string, shape string, headroom int64) ClusterHeadroomMetrics {
+	p := fmt.Sprintf("%s%s%s%s%s%s%s", RootPath, METRIC_PATH_CAPACITY, METRIC_SEPARATOR, pool, METRIC_SEPARATOR, shape, METRIC_SEPARATOR)
+	return ClusterHeadroomMetrics{Path: p, Pool: pool, Shape: shape, SchedulableHeadroom: headroom}
+}
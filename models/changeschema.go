@@ -0,0 +1,99 @@
+package models
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/fatih/structs"
+)
+
+const (
+	CHANGE_FIELD_IMAGES     string = "images"
+	CHANGE_FIELD_ENV        string = "env"
+	CHANGE_FIELD_RESOURCES  string = "resources"
+	CHANGE_FIELD_REPLICAS   string = "replicas"
+	CHANGE_FIELD_PROBES     string = "probes"
+	CHANGE_FIELD_VOLUMES    string = "volumes"
+	CHANGE_FIELD_CONTAINERS string = "containers"
+)
+
+type ChangeSchemaDefWrapper struct {
+	Schema ChangeSchemaDef `json:"schema"`
+}
+
+func (sd ChangeSchemaDefWrapper) Unwrap() *map[string]interface{} {
+	objMap := structs.Map(sd)
+	return &objMap
+}
+
+type ChangeSchemaDef struct {
+	ClusterName   string `json:"clusterName"`
+	Namespace     string `json:"namespace"`
+	ObjectKind    string `json:"objectKind"`
+	ObjectName    string `json:"objectName"`
+	ObjectUid     string `json:"objectUid"`
+	Timestamp     string `json:"timestamp"`
+	AppName       string `json:"appName"`
+	TierName      string `json:"tierName"`
+	ChangedFields string `json:"changedFields"`
+	Containers    string `json:"containers"`
+	Images        string `json:"images"`
+	EnvVars       string `json:"envVars"`
+	Resources     string `json:"resources"`
+	Replicas      string `json:"replicas"`
+	Probes        string `json:"probes"`
+	Volumes       string `json:"volumes"`
+	Summary       string `json:"summary"`
+}
+
+func NewChangeSchemaDefWrapper() ChangeSchemaDefWrapper {
+	schema := NewChangeSchemaDef()
+	wrapper := ChangeSchemaDefWrapper{Schema: schema}
+	return wrapper
+}
+
+func NewChangeSchemaDef() ChangeSchemaDef {
+	pdsd := ChangeSchemaDef{ClusterName: "string", Namespace: "string", ObjectKind: "string", ObjectName: "string", ObjectUid: "string",
+		Timestamp: "date", AppName: "string", TierName: "string", ChangedFields: "string", Containers: "string", Images: "string", EnvVars: "string",
+		Resources: "string", Replicas: "string", Probes: "string", Volumes: "string", Summary: "string"}
+	return pdsd
+}
+
+func (sd ChangeSchemaDef) Unwrap() *map[string]interface{} {
+	objMap := structs.Map(sd)
+	return &objMap
+}
+
+//ChangeSchema describes the difference between two versions of a workload spec.
+//Only names of env vars are recorded, never their values
+type ChangeSchema struct {
+	ClusterName   string    `json:"clusterName"`
+	Namespace     string    `json:"namespace"`
+	ObjectKind    string    `json:"objectKind"`
+	ObjectName    string    `json:"objectName"`
+	ObjectUid     string    `json:"objectUid"`
+	Timestamp     time.Time `json:"timestamp"`
+	AppName       string    `json:"appName"`
+	TierName      string    `json:"tierName"`
+	ChangedFields string    `json:"changedFields"`
+	Containers    string    `json:"containers"`
+	Images        string    `json:"images"`
+	EnvVars       string    `json:"envVars"`
+	Resources     string    `json:"resources"`
+	Replicas      string    `json:"replicas"`
+	Probes        string    `json:"probes"`
+	Volumes       string    `json:"volumes"`
+	Summary       string    `json:"summary"`
+}
+
+func (cs *ChangeSchema) Equals(obj *ChangeSchema) bool {
+	return reflect.DeepEqual(*cs, *obj)
+}
+
+func (cs *ChangeSchema) IsEmpty() bool {
+	return cs.ChangedFields == ""
+}
+
+func NewChangeObj() ChangeSchema {
+	return ChangeSchema{Timestamp: time.Now()}
+}
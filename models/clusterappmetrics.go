@@ -7,37 +7,63 @@ import (
 )
 
 type ClusterAppMetrics struct {
-	Path                string
-	Metadata            map[string]AppDMetricMetadata
-	Namespace           string
-	TierName            string
-	PodCount            int64
-	Privileged          int64
-	Evictions           int64
-	PodRestarts         int64
-	PodRunning          int64
-	PodFailed           int64
-	PodPending          int64
-	PendingTime         int64
-	UpTime              int64
-	ContainerCount      int64
-	InitContainerCount  int64
-	RequestCpu          int64
-	RequestMemory       int64
-	LimitCpu            int64
-	LimitMemory         int64
-	UseCpu              int64
-	UseMemory           int64
-	ConsumptionCpu      int64
-	ConsumptionMem      int64
-	NoLimits            int64
-	NoReadinessProbe    int64
-	NoLivenessProbe     int64
-	MissingDependencies int64
-	NoConnectivity      int64
-	Services            []ClusterServiceMetrics
-	QuotasSpec          RQFields
-	QuotasUsed          RQFields
+	Path                      string
+	Metadata                  map[string]AppDMetricMetadata
+	Namespace                 string
+	TierName                  string
+	PodCount                  int64
+	Privileged                int64
+	Evictions                 int64
+	OOMKills                  int64
+	CrashLoops                int64
+	PodRestarts               int64
+	PodRunning                int64
+	PodFailed                 int64
+	PodPending                int64
+	PendingTime               int64
+	PendingInsufficientCpu    int64
+	PendingInsufficientMemory int64
+	PendingTaints             int64
+	PendingNodeAffinity       int64
+	PendingUnboundPVC         int64
+	PendingTooManyPods        int64
+	PendingVolumeAffinity     int64
+	PendingOther              int64
+	SchedulingLatency         int64
+	InitLatency               int64
+	ImagePullLatency          int64
+	ReadyLatency              int64
+	StartupLatency            int64
+	UpTime                    int64
+	ContainerCount            int64
+	InitContainerCount        int64
+	RequestCpu                int64
+	RequestMemory             int64
+	LimitCpu                  int64
+	LimitMemory               int64
+	UseCpu                    int64
+	UseMemory                 int64
+	ConsumptionCpu            int64
+	ConsumptionMem            int64
+	PodOverconsume            int64
+	NoLimits                  int64
+	NoReadinessProbe          int64
+	NoLivenessProbe           int64
+	MissingDependencies       int64
+	NoConnectivity            int64
+	HostNamespaces            int64
+	HostPath                  int64
+	AddedCapabilities         int64
+	RunAsRoot                 int64
+	PrivilegeEscalation       int64
+	WritableRootFs            int64
+	NoSeccomp                 int64
+	PssBaselineViolations     int64
+	PssRestrictedViolations   int64
+	Services                  []ClusterServiceMetrics
+	QuotasSpec                RQFields
+	QuotasUsed                RQFields
+	samples                   latencySamples
 }
 
 type ClusterServiceMetrics struct {
@@ -133,11 +159,15 @@ func NewClusterAppMetrics(bag *AppDBag, podObject *PodSchema) ClusterAppMetrics
 	p := RootPath
 	p = fmt.Sprintf("%s%s%s%s%s%s%s%s%s", p, METRIC_PATH_NAMESPACES, METRIC_SEPARATOR, podObject.Namespace, METRIC_SEPARATOR, METRIC_PATH_APPS, METRIC_SEPARATOR, podObject.Owner, METRIC_SEPARATOR)
 
-	appMetrics := ClusterAppMetrics{Namespace: podObject.Namespace, TierName: podObject.Owner, Privileged: 0, PodCount: 0, Evictions: 0,
+	appMetrics := ClusterAppMetrics{Namespace: podObject.Namespace, TierName: podObject.Owner, Privileged: 0, PodCount: 0, Evictions: 0, OOMKills: 0, CrashLoops: 0,
 		PodRestarts: 0, PodRunning: 0, PodFailed: 0, PodPending: 0, PendingTime: 0, UpTime: 0, ContainerCount: 0, InitContainerCount: 0,
+		PendingInsufficientCpu: 0, PendingInsufficientMemory: 0, PendingTaints: 0, PendingNodeAffinity: 0, PendingUnboundPVC: 0, PendingTooManyPods: 0,
+		PendingVolumeAffinity: 0, PendingOther: 0,
+		SchedulingLatency: 0, InitLatency: 0, ImagePullLatency: 0, ReadyLatency: 0, StartupLatency: 0, samples: latencySamples{},
 		RequestCpu: 0, RequestMemory: 0, LimitCpu: 0, LimitMemory: 0, UseCpu: 0, UseMemory: 0,
-		ConsumptionCpu: 0, ConsumptionMem: 0, NoLimits: 0, NoReadinessProbe: 0, NoLivenessProbe: 0,
-		MissingDependencies: 0, NoConnectivity: 0, QuotasSpec: NewRQFields(), QuotasUsed: NewRQFields(), Path: p}
+		ConsumptionCpu: 0, ConsumptionMem: 0, PodOverconsume: 0, NoLimits: 0, NoReadinessProbe: 0, NoLivenessProbe: 0,
+		MissingDependencies: 0, NoConnectivity: 0, HostNamespaces: 0, HostPath: 0, AddedCapabilities: 0, RunAsRoot: 0, PrivilegeEscalation: 0,
+		WritableRootFs: 0, NoSeccomp: 0, PssBaselineViolations: 0, PssRestrictedViolations: 0, QuotasSpec: NewRQFields(), QuotasUsed: NewRQFields(), Path: p}
 
 	for _, svc := range podObject.Services {
 		svcMetrics := NewClusterServiceMetrics(bag, podObject.Namespace, podObject.Owner, &svc)
@@ -147,6 +177,23 @@ func NewClusterAppMetrics(bag *AppDBag, podObject *PodSchema) ClusterAppMetrics
 	return appMetrics
 }
 
+//AddSecurity counts the Pod Security Standards violations of the container
+func (cpm *ClusterAppMetrics) AddSecurity(c *ContainerSchema) {
+	cpm.HostNamespaces += int64(c.HostNamespaces)
+	cpm.HostPath += int64(c.HostPath)
+	cpm.AddedCapabilities += int64(c.AddedCapabilities)
+	cpm.RunAsRoot += int64(c.RunAsRoot)
+	cpm.PrivilegeEscalation += int64(c.PrivilegeEscalation)
+	cpm.WritableRootFs += int64(c.WritableRootFs)
+	cpm.NoSeccomp += int64(c.NoSeccomp)
+	if c.SecurityLevel == PSS_LEVEL_PRIVILEGED {
+		cpm.PssBaselineViolations++
+	}
+	if c.SecurityLevel != PSS_LEVEL_RESTRICTED {
+		cpm.PssRestrictedViolations++
+	}
+}
+
 func NewClusterServiceMetrics(bag *AppDBag, ns string, tierName string, svcSchema *ServiceSchema) ClusterServiceMetrics {
 	p := RootPath
 	p = fmt.Sprintf("%s%s%s%s%s%s%s%s%s%s%s%s%s", p, METRIC_PATH_NAMESPACES, METRIC_SEPARATOR, ns, METRIC_SEPARATOR, METRIC_PATH_APPS, METRIC_SEPARATOR, tierName, METRIC_SEPARATOR, METRIC_PATH_SERVICES, METRIC_SEPARATOR, svcSchema.Name, METRIC_SEPARATOR)
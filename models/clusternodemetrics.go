@@ -54,6 +54,14 @@ func NewClusterNodeMetrics(bag *AppDBag, node string) ClusterNodeMetrics {
 		UseCpu: 0, UseMemory: 0, Path: p}
 }
 
+//NewClusterNodeGroupMetrics returns the metrics of the nodes of a zone or a node pool
+func NewClusterNodeGroupMetrics(bag *AppDBag, group string, name string) ClusterNodeMetrics {
+	metrics := NewClusterNodeMetrics(bag, ALL)
+	metrics.Nodename = name
+	metrics.Path = fmt.Sprintf("%s%s%s%s%s", RootPath, group, METRIC_SEPARATOR, name, METRIC_SEPARATOR)
+	return metrics
+}
+
 func NewClusterNodeMetricsMetadata(bag *AppDBag, node string) ClusterNodeMetrics {
 	metrics := NewClusterNodeMetrics(bag, node)
 	metrics.Metadata = buildAppMetadata(bag)
@@ -7,48 +7,74 @@ import (
 )
 
 type ClusterPodMetrics struct {
-	Path                string
-	Metadata            map[string]AppDMetricMetadata
-	Namespace           string
-	Nodename            string
-	PodCount            int64
-	NamespaceCount      int64
-	NamespaceNoQuotas   int64
-	ServiceCount        int64
-	EndpointCount       int64
-	EPReadyCount        int64
-	EPNotReadyCount     int64
-	OrphanEndpoint      int64
-	ExtServiceCount     int64
-	Evictions           int64
-	PodRestarts         int64
-	PodRunning          int64
-	PodFailed           int64
-	PodPending          int64
-	PendingTime         int64
-	UpTime              int64
-	ContainerCount      int64
-	InitContainerCount  int64
-	NoLimits            int64
-	NoReadinessProbe    int64
-	NoLivenessProbe     int64
-	Privileged          int64
-	PodStorageRequest   int64
-	PodStorageLimit     int64
-	StorageRequest      int64
-	StorageCapacity     int64
-	RequestCpu          int64
-	RequestMemory       int64
-	LimitCpu            int64
-	LimitMemory         int64
-	UseCpu              int64
-	UseMemory           int64
-	ConsumptionCpu      int64
-	ConsumptionMem      int64
-	MissingDependencies int64
-	NoConnectivity      int64
-	QuotasSpec          RQFields
-	QuotasUsed          RQFields
+	Path                      string
+	Metadata                  map[string]AppDMetricMetadata
+	Namespace                 string
+	Nodename                  string
+	PodCount                  int64
+	NamespaceCount            int64
+	NamespaceNoQuotas         int64
+	ServiceCount              int64
+	EndpointCount             int64
+	EPReadyCount              int64
+	EPNotReadyCount           int64
+	OrphanEndpoint            int64
+	ExtServiceCount           int64
+	Evictions                 int64
+	OOMKills                  int64
+	CrashLoops                int64
+	PodRestarts               int64
+	PodRunning                int64
+	PodFailed                 int64
+	PodPending                int64
+	PendingTime               int64
+	PendingInsufficientCpu    int64
+	PendingInsufficientMemory int64
+	PendingTaints             int64
+	PendingNodeAffinity       int64
+	PendingUnboundPVC         int64
+	PendingTooManyPods        int64
+	PendingVolumeAffinity     int64
+	PendingOther              int64
+	SchedulingLatency         int64
+	InitLatency               int64
+	ImagePullLatency          int64
+	ReadyLatency              int64
+	StartupLatency            int64
+	UpTime                    int64
+	ContainerCount            int64
+	InitContainerCount        int64
+	NoLimits                  int64
+	NoReadinessProbe          int64
+	NoLivenessProbe           int64
+	Privileged                int64
+	HostNamespaces            int64
+	HostPath                  int64
+	AddedCapabilities         int64
+	RunAsRoot                 int64
+	PrivilegeEscalation       int64
+	WritableRootFs            int64
+	NoSeccomp                 int64
+	PssBaselineViolations     int64
+	PssRestrictedViolations   int64
+	PodStorageRequest         int64
+	PodStorageLimit           int64
+	StorageRequest            int64
+	StorageCapacity           int64
+	RequestCpu                int64
+	RequestMemory             int64
+	LimitCpu                  int64
+	LimitMemory               int64
+	UseCpu                    int64
+	UseMemory                 int64
+	ConsumptionCpu            int64
+	ConsumptionMem            int64
+	PodOverconsume            int64
+	MissingDependencies       int64
+	NoConnectivity            int64
+	QuotasSpec                RQFields
+	QuotasUsed                RQFields
+	samples                   latencySamples
 }
 
 func (cpm ClusterPodMetrics) GetPath() string {
@@ -90,12 +116,121 @@ func NewClusterPodMetrics(bag *AppDBag, ns string, node string) ClusterPodMetric
 	} else if ns != "" && ns != ALL {
 		p = fmt.Sprintf("%s%s%s%s%s", p, METRIC_PATH_NAMESPACES, METRIC_SEPARATOR, ns, METRIC_SEPARATOR)
 	}
-	return ClusterPodMetrics{Namespace: ns, Nodename: node, PodCount: 0, Evictions: 0,
+	return ClusterPodMetrics{Namespace: ns, Nodename: node, PodCount: 0, Evictions: 0, OOMKills: 0, CrashLoops: 0,
 		PodRestarts: 0, PodRunning: 0, PodFailed: 0, PodPending: 0, PendingTime: 0, UpTime: 0, ContainerCount: 0, InitContainerCount: 0,
+		PendingInsufficientCpu: 0, PendingInsufficientMemory: 0, PendingTaints: 0, PendingNodeAffinity: 0, PendingUnboundPVC: 0, PendingTooManyPods: 0,
+		PendingVolumeAffinity: 0, PendingOther: 0,
+		SchedulingLatency: 0, InitLatency: 0, ImagePullLatency: 0, ReadyLatency: 0, StartupLatency: 0, samples: latencySamples{},
 		NoLimits: 0, NoReadinessProbe: 0, NoLivenessProbe: 0, Privileged: 0, RequestCpu: 0, RequestMemory: 0, LimitCpu: 0, LimitMemory: 0,
 		UseCpu: 0, UseMemory: 0, PodStorageRequest: 0, PodStorageLimit: 0, StorageRequest: 0, StorageCapacity: 0, NamespaceCount: 0,
 		NamespaceNoQuotas: 0, ServiceCount: 0, EndpointCount: 0, OrphanEndpoint: 0, EPReadyCount: 0, EPNotReadyCount: 0,
-		ExtServiceCount: 0, MissingDependencies: 0, NoConnectivity: 0, ConsumptionCpu: 0, ConsumptionMem: 0, QuotasSpec: NewRQFields(), QuotasUsed: NewRQFields(), Path: p}
+		ExtServiceCount: 0, MissingDependencies: 0, NoConnectivity: 0, ConsumptionCpu: 0, ConsumptionMem: 0, PodOverconsume: 0, QuotasSpec: NewRQFields(), QuotasUsed: NewRQFields(), Path: p}
+}
+
+//ClusterPodGroupMetrics are the pod counts of the nodes of a zone or a node pool. They are posted under the Pods folder of the group,
+//next to the node metrics of the group. Usage, latencies and quotas are not rolled up
+type ClusterPodGroupMetrics struct {
+	Path                    string
+	Group                   string
+	Name                    string
+	PodCount                int64
+	ContainerCount          int64
+	InitContainerCount      int64
+	PodRunning              int64
+	PodPending              int64
+	PodFailed               int64
+	PodRestarts             int64
+	Evictions               int64
+	OOMKills                int64
+	CrashLoops              int64
+	NoLimits                int64
+	NoLivenessProbe         int64
+	NoReadinessProbe        int64
+	Privileged              int64
+	HostNamespaces          int64
+	HostPath                int64
+	AddedCapabilities       int64
+	RunAsRoot               int64
+	PrivilegeEscalation     int64
+	WritableRootFs          int64
+	NoSeccomp               int64
+	PssBaselineViolations   int64
+	PssRestrictedViolations int64
+	PodOverconsume          int64
+	RequestCpu              int64
+	RequestMemory           int64
+	LimitCpu                int64
+	LimitMemory             int64
+}
+
+func (cpm ClusterPodGroupMetrics) GetPath() string {
+	return cpm.Path
+}
+
+func (cpm ClusterPodGroupMetrics) ShouldExcludeField(fieldName string) bool {
+	if fieldName == "Group" || fieldName == "Name" || fieldName == "Path" {
+		return true
+	}
+	return false
+}
+
+func (cpm ClusterPodGroupMetrics) Unwrap() *map[string]interface{} {
+	objMap := structs.Map(cpm)
+	return &objMap
+}
+
+func NewClusterPodGroupMetrics(bag *AppDBag, group string, name string) ClusterPodGroupMetrics {
+	p := fmt.Sprintf("%s%s%s%s%s%s%s", RootPath, group, METRIC_SEPARATOR, name, METRIC_SEPARATOR, METRIC_PATH_PODS, METRIC_SEPARATOR)
+	return ClusterPodGroupMetrics{Path: p, Group: group, Name: name}
+}
+
+//AddCounts adds the counters and totals of the pods of a node
+func (cpm *ClusterPodGroupMetrics) AddCounts(other *ClusterPodMetrics) {
+	cpm.PodCount += other.PodCount
+	cpm.ContainerCount += other.ContainerCount
+	cpm.InitContainerCount += other.InitContainerCount
+	cpm.PodRunning += other.PodRunning
+	cpm.PodPending += other.PodPending
+	cpm.PodFailed += other.PodFailed
+	cpm.PodRestarts += other.PodRestarts
+	cpm.Evictions += other.Evictions
+	cpm.OOMKills += other.OOMKills
+	cpm.CrashLoops += other.CrashLoops
+	cpm.NoLimits += other.NoLimits
+	cpm.NoLivenessProbe += other.NoLivenessProbe
+	cpm.NoReadinessProbe += other.NoReadinessProbe
+	cpm.Privileged += other.Privileged
+	cpm.HostNamespaces += other.HostNamespaces
+	cpm.HostPath += other.HostPath
+	cpm.AddedCapabilities += other.AddedCapabilities
+	cpm.RunAsRoot += other.RunAsRoot
+	cpm.PrivilegeEscalation += other.PrivilegeEscalation
+	cpm.WritableRootFs += other.WritableRootFs
+	cpm.NoSeccomp += other.NoSeccomp
+	cpm.PssBaselineViolations += other.PssBaselineViolations
+	cpm.PssRestrictedViolations += other.PssRestrictedViolations
+	cpm.PodOverconsume += other.PodOverconsume
+	cpm.RequestCpu += other.RequestCpu
+	cpm.RequestMemory += other.RequestMemory
+	cpm.LimitCpu += other.LimitCpu
+	cpm.LimitMemory += other.LimitMemory
+}
+
+//AddSecurity counts the security violations of a container and the containers that do not meet the Pod Security Standards
+func (cpm *ClusterPodMetrics) AddSecurity(c *ContainerSchema) {
+	cpm.HostNamespaces += int64(c.HostNamespaces)
+	cpm.HostPath += int64(c.HostPath)
+	cpm.AddedCapabilities += int64(c.AddedCapabilities)
+	cpm.RunAsRoot += int64(c.RunAsRoot)
+	cpm.PrivilegeEscalation += int64(c.PrivilegeEscalation)
+	cpm.WritableRootFs += int64(c.WritableRootFs)
+	cpm.NoSeccomp += int64(c.NoSeccomp)
+	if c.SecurityLevel == PSS_LEVEL_PRIVILEGED {
+		cpm.PssBaselineViolations++
+	}
+	if c.SecurityLevel != PSS_LEVEL_RESTRICTED {
+		cpm.PssRestrictedViolations++
+	}
 }
 
 func NewClusterPodMetricsMetadata(bag *AppDBag, ns string, node string) ClusterPodMetrics {
@@ -12,39 +12,52 @@ type ContainerSchemaDefWrapper struct {
 }
 
 type ContainerSchemaDef struct {
-	Name              string `json:"name"`
-	Init              string `json:"init"`
-	Namespace         string `json:"namespace"`
-	ClusterName       string `json:"clusterName"`
-	NodeName          string `json:"nodeName"`
-	PodName           string `json:"podName"`
-	PodInitTime       string `json:"podInitTime"`
-	StartTime         string `json:"startTime"`
-	LiveProbes        string `json:"liveProbes"`
-	ReadyProbes       string `json:"readyProbes"`
-	Restarts          string `json:"restarts"`
-	Privileged        string `json:"privileged"`
-	Ports             string `json:"ports"`
-	MemRequest        string `json:"memRequest"`
-	CpuRequest        string `json:"cpuRequest"`
-	CpuLimit          string `json:"cpuLimit"`
-	MemLimit          string `json:"memLimit"`
-	PodStorageRequest string `json:"podStorageRequest"`
-	PodStorageLimit   string `json:"podStorageLimit"`
-	StorageRequest    string `json:"storageRequest"`
-	StorageCapacity   string `json:"storageCapacity"`
-	CpuUse            string `json:"cpuUse"`
-	MemUse            string `json:"memUse"`
-	Image             string `json:"image"`
-	WaitReason        string `json:"waitReason"`
-	TermReason        string `json:"termReason"`
-	TerminationTime   string `json:"terminationTime"`
-	Mounts            string `json:"mounts"`
-	MissingConfigs    string `json:"missingConfigs"`
-	MissingSecrets    string `json:"missingSecrets"`
-	MissingServices   string `json:"missingServices"`
-	ConsumptionCpu    string `json:"consumptionCpu"`
-	ConsumptionMem    string `json:"consumptionMem"`
+	Name                string `json:"name"`
+	Init                string `json:"init"`
+	Namespace           string `json:"namespace"`
+	ClusterName         string `json:"clusterName"`
+	NodeName            string `json:"nodeName"`
+	PodName             string `json:"podName"`
+	PodInitTime         string `json:"podInitTime"`
+	StartTime           string `json:"startTime"`
+	LiveProbes          string `json:"liveProbes"`
+	ReadyProbes         string `json:"readyProbes"`
+	Restarts            string `json:"restarts"`
+	Privileged          string `json:"privileged"`
+	Ports               string `json:"ports"`
+	MemRequest          string `json:"memRequest"`
+	CpuRequest          string `json:"cpuRequest"`
+	CpuLimit            string `json:"cpuLimit"`
+	MemLimit            string `json:"memLimit"`
+	PodStorageRequest   string `json:"podStorageRequest"`
+	PodStorageLimit     string `json:"podStorageLimit"`
+	StorageRequest      string `json:"storageRequest"`
+	StorageCapacity     string `json:"storageCapacity"`
+	CpuUse              string `json:"cpuUse"`
+	MemUse              string `json:"memUse"`
+	Image               string `json:"image"`
+	WaitReason          string `json:"waitReason"`
+	TermReason          string `json:"termReason"`
+	TerminationTime     string `json:"terminationTime"`
+	LastExitCode        string `json:"lastExitCode"`
+	LastSignal          string `json:"lastSignal"`
+	LastTermReason      string `json:"lastTermReason"`
+	LastFinishTime      string `json:"lastFinishTime"`
+	RestartCause        string `json:"restartCause"`
+	Mounts              string `json:"mounts"`
+	MissingConfigs      string `json:"missingConfigs"`
+	MissingSecrets      string `json:"missingSecrets"`
+	MissingServices     string `json:"missingServices"`
+	ConsumptionCpu      string `json:"consumptionCpu"`
+	ConsumptionMem      string `json:"consumptionMem"`
+	HostNamespaces      string `json:"hostNamespaces"`
+	HostPath            string `json:"hostPath"`
+	AddedCapabilities   string `json:"addedCapabilities"`
+	RunAsRoot           string `json:"runAsRoot"`
+	PrivilegeEscalation string `json:"privilegeEscalation"`
+	WritableRootFs      string `json:"writableRootFs"`
+	NoSeccomp           string `json:"noSeccomp"`
+	SecurityLevel       string `json:"securityLevel"`
 }
 
 func (sd ContainerSchemaDef) Unwrap() *map[string]interface{} {
@@ -67,11 +80,25 @@ func NewContainerSchemaDef() ContainerSchemaDef {
 	pdsd := ContainerSchemaDef{Name: "string", Init: "boolean", Namespace: "string", ClusterName: "string", NodeName: "string", PodName: "string", PodInitTime: "date", StartTime: "date", LiveProbes: "integer", ReadyProbes: "integer", Restarts: "integer",
 		Privileged: "integer", Ports: "string", MemRequest: "float", CpuRequest: "float", CpuLimit: "float", MemLimit: "float",
 		ConsumptionCpu: "float", ConsumptionMem: "float", PodStorageRequest: "float", PodStorageLimit: "float", StorageRequest: "float", StorageCapacity: "float", CpuUse: "float", MemUse: "float",
-		Image: "string", WaitReason: "string", TermReason: "string", TerminationTime: "date", Mounts: "string", MissingConfigs: "string",
-		MissingSecrets: "string", MissingServices: "string"}
+		Image: "string", WaitReason: "string", TermReason: "string", TerminationTime: "date",
+		LastExitCode: "integer", LastSignal: "integer", LastTermReason: "string", LastFinishTime: "date", RestartCause: "string", Mounts: "string", MissingConfigs: "string",
+		MissingSecrets: "string", MissingServices: "string", HostNamespaces: "integer", HostPath: "integer", AddedCapabilities: "integer",
+		RunAsRoot: "integer", PrivilegeEscalation: "integer", WritableRootFs: "integer", NoSeccomp: "integer", SecurityLevel: "string"}
 	return pdsd
 }
 
+const (
+	REASON_OOM_KILLED string = "OOMKilled"
+	REASON_CRASH_LOOP string = "CrashLoopBackOff"
+
+	RESTART_CAUSE_OOM        string = "OOMKilled"
+	RESTART_CAUSE_START      string = "StartError"
+	RESTART_CAUSE_COMPLETED  string = "Completed"
+	RESTART_CAUSE_KILLED     string = "Killed"
+	RESTART_CAUSE_TERMINATED string = "Terminated"
+	RESTART_CAUSE_ERROR      string = "Error"
+)
+
 type ContainerPort struct {
 	Name       string
 	PortNumber int32
@@ -107,12 +134,25 @@ type ContainerSchema struct {
 	WaitReason           string          `json:"waitReason"`
 	TermReason           string          `json:"termReason"`
 	TerminationTime      time.Time       `json:"terminationTime"`
+	LastExitCode         int32           `json:"lastExitCode"`
+	LastSignal           int32           `json:"lastSignal"`
+	LastTermReason       string          `json:"lastTermReason"`
+	LastFinishTime       *time.Time      `json:"lastFinishTime"`
+	RestartCause         string          `json:"restartCause"`
 	Mounts               string          `json:"mounts"`
 	MissingConfigs       string          `json:"missingConfigs"`
 	MissingSecrets       string          `json:"missingSecrets"`
 	MissingServices      string          `json:"missingServices"`
 	ConsumptionCpu       float64         `json:"consumptionCpu"`
 	ConsumptionMem       float64         `json:"consumptionMem"`
+	HostNamespaces       int             `json:"hostNamespaces"`      //hostNetwork, hostPID or hostIPC
+	HostPath             int             `json:"hostPath"`            //mounts a hostPath volume
+	AddedCapabilities    int             `json:"addedCapabilities"`   //adds capabilities other than NET_BIND_SERVICE
+	RunAsRoot            int             `json:"runAsRoot"`           //runAsNonRoot not set or runAsUser 0
+	PrivilegeEscalation  int             `json:"privilegeEscalation"` //allowPrivilegeEscalation not set to false
+	WritableRootFs       int             `json:"writableRootFs"`      //readOnlyRootFilesystem not set to true
+	NoSeccomp            int             `json:"noSeccomp"`           //no seccomp profile or Unconfined
+	SecurityLevel        string          `json:"securityLevel"`       //strictest Pod Security Standard the container meets
 	Index                int8            `json:"-"`
 	ContainerPorts       []ContainerPort `json:"-"`
 	LastTerminationTime  *time.Time      `json:"-"`
@@ -150,6 +190,37 @@ func (p ContainerSchema) ToString() string {
 		p.Image, p.WaitReason, p.TermReason, p.TerminationTime.String(), p.Mounts)
 }
 
+//true if the container was terminated by the OOM killer after the given time
+func (p *ContainerSchema) OOMKilledSince(since time.Time) bool {
+	if p.TermReason == REASON_OOM_KILLED && p.TerminationTime.After(since) {
+		return true
+	}
+	return p.LastTermReason == REASON_OOM_KILLED && p.LastFinishTime != nil && p.LastFinishTime.After(since)
+}
+
+func (p *ContainerSchema) IsCrashLooping() bool {
+	return p.WaitReason == REASON_CRASH_LOOP
+}
+
+//classifies the cause of the last restart of the container by the reason and exit code of the last termination
+func ClassifyRestart(reason string, exitCode int32, signal int32) string {
+	switch {
+	case reason == REASON_OOM_KILLED:
+		return RESTART_CAUSE_OOM
+	case reason == "ContainerCannotRun" || reason == "StartError":
+		return RESTART_CAUSE_START
+	case exitCode == 0 && signal == 0:
+		return RESTART_CAUSE_COMPLETED
+	case exitCode == 137 || signal == 9:
+		//SIGKILL. Usually failed liveness probes or eviction
+		return RESTART_CAUSE_KILLED
+	case exitCode == 143 || signal == 15:
+		return RESTART_CAUSE_TERMINATED
+	default:
+		return RESTART_CAUSE_ERROR
+	}
+}
+
 func (p *ContainerSchema) HasMissingDependencies() bool {
 	return p.MissingConfigs != "" || p.MissingSecrets != ""
 }
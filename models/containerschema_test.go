@@ -0,0 +1,55 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClassifyRestart(t *testing.T) {
+	tests := []struct {
+		reason   string
+		exitCode int32
+		signal   int32
+		cause    string
+	}{
+		{REASON_OOM_KILLED, 137, 0, RESTART_CAUSE_OOM},
+		{"ContainerCannotRun", 128, 0, RESTART_CAUSE_START},
+		{"StartError", 128, 0, RESTART_CAUSE_START},
+		{"Completed", 0, 0, RESTART_CAUSE_COMPLETED},
+		{"Error", 137, 0, RESTART_CAUSE_KILLED},
+		{"Error", 0, 9, RESTART_CAUSE_KILLED},
+		{"Error", 143, 0, RESTART_CAUSE_TERMINATED},
+		{"Error", 0, 15, RESTART_CAUSE_TERMINATED},
+		{"Error", 1, 0, RESTART_CAUSE_ERROR},
+		{"", 2, 0, RESTART_CAUSE_ERROR},
+	}
+	for _, tt := range tests {
+		if cause := ClassifyRestart(tt.reason, tt.exitCode, tt.signal); cause != tt.cause {
+			t.Errorf("ClassifyRestart(%q, %d, %d) = %q, want %q", tt.reason, tt.exitCode, tt.signal, cause, tt.cause)
+		}
+	}
+}
+
+func TestOOMKilledSince(t *testing.T) {
+	since := time.Now().Add(-time.Minute)
+	before := since.Add(-time.Minute)
+	after := since.Add(30 * time.Second)
+	tests := []struct {
+		name   string
+		schema ContainerSchema
+		killed bool
+	}{
+		{"running", ContainerSchema{}, false},
+		{"terminated in interval", ContainerSchema{TermReason: REASON_OOM_KILLED, TerminationTime: after}, true},
+		{"terminated before interval", ContainerSchema{TermReason: REASON_OOM_KILLED, TerminationTime: before}, false},
+		{"restarted in interval", ContainerSchema{LastTermReason: REASON_OOM_KILLED, LastFinishTime: &after}, true},
+		{"restarted before interval", ContainerSchema{LastTermReason: REASON_OOM_KILLED, LastFinishTime: &before}, false},
+		{"restarted with error", ContainerSchema{LastTermReason: "Error", LastFinishTime: &after}, false},
+		{"no finish time", ContainerSchema{LastTermReason: REASON_OOM_KILLED}, false},
+	}
+	for _, tt := range tests {
+		if killed := tt.schema.OOMKilledSince(since); killed != tt.killed {
+			t.Errorf("%s: OOMKilledSince = %t, want %t", tt.name, killed, tt.killed)
+		}
+	}
+}
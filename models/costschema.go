@@ -0,0 +1,179 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fatih/structs"
+)
+
+const (
+	COST_ALLOCATION_REQUEST string = "request"
+	COST_ALLOCATION_USAGE   string = "usage"
+	COST_ALLOCATION_MAX     string = "max"
+
+	COST_IDLE_NONE    string = "none"    //idle cost is reported separately
+	COST_IDLE_NODE    string = "node"    //idle cost of a node is shared by its pods
+	COST_IDLE_CLUSTER string = "cluster" //idle cost of the cluster is shared by all pods
+
+	COST_SCOPE_CLUSTER   string = "cluster"
+	COST_SCOPE_NAMESPACE string = "namespace"
+	COST_SCOPE_WORKLOAD  string = "workload"
+	COST_SCOPE_LABEL     string = "label"
+	COST_SCOPE_IDLE      string = "idle"
+
+	HOURS_PER_MONTH float64 = 730
+
+	METRIC_PATH_COST   string = "Cost"
+	METRIC_PATH_LABELS string = "Labels"
+)
+
+//CostPrice is the hourly price of the resources of the nodes matching the selector
+type CostPrice struct {
+	NodeSelector  string  //label selector of nodes. Empty - all nodes
+	CpuCoreHour   float64 //price of a cpu core per hour
+	MemGBHour     float64 //price of a GB of memory per hour
+	StorageGBHour float64 //price of a GB of requested storage per hour
+}
+
+type CostSchemaDefWrapper struct {
+	Schema CostSchemaDef `json:"schema"`
+}
+
+func (sd CostSchemaDefWrapper) Unwrap() *map[string]interface{} {
+	objMap := structs.Map(sd)
+	return &objMap
+}
+
+type CostSchemaDef struct {
+	ClusterName string `json:"clusterName"`
+	Timestamp   string `json:"timestamp"`
+	Scope       string `json:"scope"`
+	Namespace   string `json:"namespace"`
+	OwnerKind   string `json:"ownerKind"`
+	Workload    string `json:"workload"`
+	LabelKey    string `json:"labelKey"`
+	LabelValue  string `json:"labelValue"`
+	Allocation  string `json:"allocation"`
+	Pods        string `json:"pods"`
+	CpuCores    string `json:"cpuCores"`
+	MemGB       string `json:"memGB"`
+	StorageGB   string `json:"storageGB"`
+	CpuCost     string `json:"cpuCost"`
+	MemCost     string `json:"memCost"`
+	StorageCost string `json:"storageCost"`
+	IdleCost    string `json:"idleCost"`
+	TotalCost   string `json:"totalCost"`
+	MonthlyCost string `json:"monthlyCost"`
+	Efficiency  string `json:"efficiency"`
+}
+
+func NewCostSchemaDefWrapper() CostSchemaDefWrapper {
+	schema := NewCostSchemaDef()
+	wrapper := CostSchemaDefWrapper{Schema: schema}
+	return wrapper
+}
+
+func NewCostSchemaDef() CostSchemaDef {
+	pdsd := CostSchemaDef{ClusterName: "string", Timestamp: "date", Scope: "string", Namespace: "string", OwnerKind: "string", Workload: "string",
+		LabelKey: "string", LabelValue: "string", Allocation: "string", Pods: "integer", CpuCores: "float", MemGB: "float", StorageGB: "float",
+		CpuCost: "float", MemCost: "float", StorageCost: "float", IdleCost: "float", TotalCost: "float", MonthlyCost: "float", Efficiency: "float"}
+	return pdsd
+}
+
+func (sd CostSchemaDef) Unwrap() *map[string]interface{} {
+	objMap := structs.Map(sd)
+	return &objMap
+}
+
+//CostSchema is the hourly cost of a namespace, workload or pod label value.
+//Resources are the allocation basis: requests, usage or the max of both
+type CostSchema struct {
+	ClusterName string    `json:"clusterName"`
+	Timestamp   time.Time `json:"timestamp"`
+	Scope       string    `json:"scope"`
+	Namespace   string    `json:"namespace"`
+	OwnerKind   string    `json:"ownerKind"`
+	Workload    string    `json:"workload"`
+	LabelKey    string    `json:"labelKey"`
+	LabelValue  string    `json:"labelValue"`
+	Allocation  string    `json:"allocation"`
+	Pods        int64     `json:"pods"`
+	CpuCores    float64   `json:"cpuCores"`
+	MemGB       float64   `json:"memGB"`
+	StorageGB   float64   `json:"storageGB"`
+	CpuCost     float64   `json:"cpuCost"`
+	MemCost     float64   `json:"memCost"`
+	StorageCost float64   `json:"storageCost"`
+	IdleCost    float64   `json:"idleCost"`
+	TotalCost   float64   `json:"totalCost"`
+	MonthlyCost float64   `json:"monthlyCost"`
+	Efficiency  float64   `json:"efficiency"` //cost of the used resources, percent of the allocated cost
+	usedCost    float64
+}
+
+//Add accumulates the cost of a pod. usedCost is the cost of the resources the pod actually uses
+func (cs *CostSchema) Add(other *CostSchema, usedCost float64) {
+	cs.Pods += other.Pods
+	cs.CpuCores += other.CpuCores
+	cs.MemGB += other.MemGB
+	cs.StorageGB += other.StorageGB
+	cs.CpuCost += other.CpuCost
+	cs.MemCost += other.MemCost
+	cs.StorageCost += other.StorageCost
+	cs.IdleCost += other.IdleCost
+	cs.usedCost += usedCost
+}
+
+//Finalize computes the totals once all pods are added
+func (cs *CostSchema) Finalize() {
+	cs.TotalCost = cs.CpuCost + cs.MemCost + cs.StorageCost + cs.IdleCost
+	cs.MonthlyCost = cs.TotalCost * HOURS_PER_MONTH
+	allocated := cs.CpuCost + cs.MemCost
+	if allocated > 0 {
+		cs.Efficiency = cs.usedCost / allocated * 100
+	}
+}
+
+//ClusterCostMetrics are the monthly costs in cents of the cluster, a namespace or a pod label value
+type ClusterCostMetrics struct {
+	Path           string
+	Namespace      string
+	CostTotal      int64
+	CostCpu        int64
+	CostMemory     int64
+	CostStorage    int64
+	CostIdle       int64
+	CostEfficiency int64
+}
+
+func (cpm ClusterCostMetrics) GetPath() string {
+	return cpm.Path
+}
+
+func (cpm ClusterCostMetrics) ShouldExcludeField(fieldName string) bool {
+	if fieldName == "Namespace" || fieldName == "Path" {
+		return true
+	}
+	return false
+}
+
+func (cpm ClusterCostMetrics) Unwrap() *map[string]interface{} {
+	objMap := structs.Map(cpm)
+	return &objMap
+}
+
+func NewClusterCostMetrics(bag *AppDBag, cost *CostSchema) ClusterCostMetrics {
+	p := RootPath
+	switch cost.Scope {
+	case COST_SCOPE_NAMESPACE:
+		p = fmt.Sprintf("%s%s%s%s%s", p, METRIC_PATH_NAMESPACES, METRIC_SEPARATOR, cost.Namespace, METRIC_SEPARATOR)
+	case COST_SCOPE_LABEL:
+		p = fmt.Sprintf("%s%s%s%s%s%s%s%s%s", p, METRIC_PATH_COST, METRIC_SEPARATOR, METRIC_PATH_LABELS, METRIC_SEPARATOR, cost.LabelKey, METRIC_SEPARATOR, cost.LabelValue, METRIC_SEPARATOR)
+	}
+	cents := func(hourly float64) int64 {
+		return int64(hourly*HOURS_PER_MONTH*100 + 0.5)
+	}
+	return ClusterCostMetrics{Namespace: cost.Namespace, Path: p, CostTotal: cents(cost.TotalCost), CostCpu: cents(cost.CpuCost),
+		CostMemory: cents(cost.MemCost), CostStorage: cents(cost.StorageCost), CostIdle: cents(cost.IdleCost), CostEfficiency: int64(cost.Efficiency + 0.5)}
+}
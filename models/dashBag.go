@@ -6,19 +6,29 @@ import (
 
 type DashboardType string
 
+//node heat map modes
+const (
+	NODE_HEAT_MAP_NONE      string = "none"
+	NODE_HEAT_MAP_OVERVIEW  string = "overview"
+	NODE_HEAT_MAP_DASHBOARD string = "dashboard"
+)
+
 const (
 	Cluster   DashboardType = "cluster"
 	Tier      DashboardType = "tier"
 	Node      DashboardType = "node"
 	Namespace DashboardType = "namespace"
+	NodeHeat  DashboardType = "nodeheat"
 )
 
 type DashboardBag struct {
-	Type          DashboardType //cluster/tier/node/namespace
+	Type          DashboardType //cluster/tier/node/namespace/nodeheat
 	ClusterName   string
 	Namespace     string
 	AppName       string
 	TierName      string
+	NodeName      string
+	OwnerKind     string //kind of the workload of tier dashboards
 	ClusterAppID  int
 	ClusterTierID int
 	ClusterNodeID int
@@ -27,14 +37,27 @@ type DashboardBag struct {
 	NodeID        int
 	Pods          []PodSchema
 	NSMap         map[string]map[string][]HeatNode
+	NodeMap       map[string]NodeHeatNode
 }
 
 func NewDashboardBagTier(ns string, tierName string, pods []PodSchema) DashboardBag {
 	return DashboardBag{Namespace: ns, TierName: tierName, Pods: pods, Type: Tier, NSMap: make(map[string]map[string][]HeatNode)}
 }
 
+func NewDashboardBagNamespace(ns string) DashboardBag {
+	return DashboardBag{Namespace: ns, Pods: []PodSchema{}, Type: Namespace, NSMap: make(map[string]map[string][]HeatNode)}
+}
+
+func NewDashboardBagNode(nodeName string) DashboardBag {
+	return DashboardBag{NodeName: nodeName, Pods: []PodSchema{}, Type: Node, NSMap: make(map[string]map[string][]HeatNode)}
+}
+
 func NewDashboardBagCluster() *DashboardBag {
-	return &(DashboardBag{Type: Cluster, NSMap: make(map[string]map[string][]HeatNode)})
+	return &(DashboardBag{Type: Cluster, NSMap: make(map[string]map[string][]HeatNode), NodeMap: make(map[string]NodeHeatNode)})
+}
+
+func NewDashboardBagNodeHeat(nodeMap map[string]NodeHeatNode) DashboardBag {
+	return DashboardBag{Type: NodeHeat, NSMap: make(map[string]map[string][]HeatNode), NodeMap: nodeMap}
 }
 
 func (db *DashboardBag) AddNode(hn *HeatNode) {
@@ -81,3 +104,50 @@ func (db *DashboardBag) GetNodes() ([]HeatNode, int, int) {
 	}
 	return nodes, numNS, numDeploy
 }
+
+func (db *DashboardBag) AddNodeHeat(nh *NodeHeatNode) {
+	db.NodeMap[nh.Nodename] = *nh
+}
+
+//AddPodToNodeHeat accounts the pod on the tile of its node. Pods of unknown nodes are ignored
+func (db *DashboardBag) AddPodToNodeHeat(podSchema *PodSchema) {
+	nh, ok := db.NodeMap[podSchema.NodeName]
+	if !ok {
+		return
+	}
+	nh.AddPod(podSchema)
+	db.NodeMap[podSchema.NodeName] = nh
+}
+
+//GetNodeHeatNodes returns the node tiles sorted by role and name and the number of roles
+func (db *DashboardBag) GetNodeHeatNodes() ([]NodeHeatNode, int) {
+	nodes := []NodeHeatNode{}
+	roles := make(map[string]bool)
+	for _, nh := range db.NodeMap {
+		nodes = append(nodes, nh)
+		roles[nh.Role] = true
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Role == nodes[j].Role {
+			return nodes[i].Nodename < nodes[j].Nodename
+		}
+		return nodes[i].Role < nodes[j].Role
+	})
+	return nodes, len(roles)
+}
+
+//GetTopConsumers returns up to limit pods of the bag with the highest cpu use
+func (db *DashboardBag) GetTopConsumers(limit int) []PodSchema {
+	pods := make([]PodSchema, len(db.Pods))
+	copy(pods, db.Pods)
+	sort.Slice(pods, func(i, j int) bool {
+		if pods[i].CpuUse == pods[j].CpuUse {
+			return pods[i].MemUse > pods[j].MemUse
+		}
+		return pods[i].CpuUse > pods[j].CpuUse
+	})
+	if len(pods) > limit {
+		pods = pods[:limit]
+	}
+	return pods
+}
@@ -146,6 +146,13 @@ type WidgetTemplate struct {
 type StaticThreshold struct {
 }
 
+//handling of agent dashboards that are no longer generated
+const (
+	DASHBOARD_CLEANUP_DELETE  string = "delete"
+	DASHBOARD_CLEANUP_ARCHIVE string = "archive"
+	DASHBOARD_CLEANUP_NONE    string = "none"
+)
+
 type Dashboard struct {
 	ID                      float64                  `json:"id"`
 	Version                 float64                  `json:"version"`
@@ -52,6 +52,8 @@ type DeploySchemaDef struct {
 	MissScheduled          string `json:"missScheduled"`
 	UpdatedNumberScheduled string `json:"updatedNumberScheduled"`
 	DeploymentType         string `json:"deploymentType"`
+	ReplicaSpread          string `json:"replicaSpread"`
+	SinglePointOfFailure   string `json:"singlePointOfFailure"`
 }
 
 func NewDeploySchemaDefWrapper() DeploySchemaDefWrapper {
@@ -67,7 +69,7 @@ func NewDeploySchemaDef() DeploySchemaDef {
 		MaxSurge: "string", MaxUnavailable: "string", ReplicasAvailable: "integer", ReplicasUnAvailable: "integer",
 		ReplicasUpdated: "integer", CollisionCount: "integer", ReplicasReady: "integer", ReplicasLabeled: "integer",
 		NumberScheduled: "integer", DesiredNumber: "integer", MissScheduled: "integer", UpdatedNumberScheduled: "integer",
-		DeploymentType: "string"}
+		DeploymentType: "string", ReplicaSpread: "string", SinglePointOfFailure: "string"}
 	return pdsd
 }
 
@@ -103,6 +105,8 @@ type DeploySchema struct {
 	MissScheduled          int32     `json:"missScheduled"`
 	UpdatedNumberScheduled int32     `json:"updatedNumberScheduled"`
 	DeploymentType         string    `json:"deploymentType"`
+	ReplicaSpread          string    `json:"replicaSpread"`        //ready replicas per node and zone
+	SinglePointOfFailure   string    `json:"singlePointOfFailure"` //spread issues, comma separated. Empty - none
 }
 
 type DeployObjList struct {
@@ -0,0 +1,56 @@
+package models
+
+const (
+	EVENT_CATEGORY_INFO  string = "info"
+	EVENT_CATEGORY_ERROR string = "error"
+
+	EVENT_SEVERITY_INFO    string = "info"
+	EVENT_SEVERITY_WARNING string = "warning"
+	EVENT_SEVERITY_ERROR   string = "error"
+)
+
+//EventRule assigns category, subcategory and severity to the events it matches.
+//Empty match fields match any value. Reasons match if the event reason is any of the listed values.
+//MessageRegex is a regular expression matched against the event message.
+//Without Severity, the severity follows the event type: warning for Warning events, info otherwise
+type EventRule struct {
+	Reasons         []string
+	Kind            string
+	SourceComponent string
+	MessageRegex    string
+	Category        string
+	SubCategory     string
+	Severity        string
+}
+
+func NewEventRule(category string, subCategory string, messageRegex string, reasons ...string) EventRule {
+	severity := ""
+	if category == EVENT_CATEGORY_ERROR {
+		severity = EVENT_SEVERITY_ERROR
+	}
+	return EventRule{Reasons: reasons, MessageRegex: messageRegex, Category: category, SubCategory: subCategory, Severity: severity}
+}
+
+//rules applied when none of the configured rules match the event. The first matching rule wins
+func DefaultEventRules() []EventRule {
+	return []EventRule{
+		NewEventRule(EVENT_CATEGORY_ERROR, "reduction", "Scaled down", "ScalingReplicaSet"),
+		NewEventRule(EVENT_CATEGORY_ERROR, "reduction", "", "Killing"),
+		NewEventRule(EVENT_CATEGORY_ERROR, "image", "ImagePullBackOff|image", "Failed"),
+		NewEventRule(EVENT_CATEGORY_ERROR, "pod", "", "Failed"),
+		NewEventRule(EVENT_CATEGORY_ERROR, "pod", "container", "BackOff"),
+		NewEventRule(EVENT_CATEGORY_ERROR, "image", "image", "BackOff"),
+		NewEventRule(EVENT_CATEGORY_ERROR, "", "", "BackOff"),
+		NewEventRule(EVENT_CATEGORY_ERROR, "quota", "quota", "FailedCreate"),
+		NewEventRule(EVENT_CATEGORY_ERROR, "pod", "", "FailedCreate"),
+		NewEventRule(EVENT_CATEGORY_ERROR, "image", "", "ErrImageNeverPull", "ImageGCFailed"),
+		NewEventRule(EVENT_CATEGORY_ERROR, "pod", "", "FailedKillPod", "FailedCreatePodContainer", "ContainerGCFailed", "SandboxChanged",
+			"FailedCreatePodSandBox", "FailedPodSandBoxStatus", "Unhealthy"),
+		NewEventRule(EVENT_CATEGORY_ERROR, "storage", "", "FailedAttachVolume", "FailedDetachVolume", "FailedMount", "FailedBinding",
+			"VolumeResizeFailed", "FileSystemResizeFailed", "FailedUnMount", "FailedMapVolume", "FailedUnmapDevice", "UnsupportedMountOption",
+			"InvalidDiskCapacity", "FreeDiskSpaceFailed"),
+		NewEventRule(EVENT_CATEGORY_ERROR, "", "", "NodeAllocatableEnforced", "Preempting", "InspectFailed", "KubeletSetupFailed",
+			"NodeSelectorMismatching", "FailedNodeAllocatableEnforcement", "UnfinishedPreStopHook", "ErrorReconciliationRetryTimeout"),
+		NewEventRule(EVENT_CATEGORY_INFO, "eviction", "", "Evicted"),
+	}
+}
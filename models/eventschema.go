@@ -38,6 +38,7 @@ type EventSchemaDef struct {
 	Type                  string `json:"type"`
 	Category              string `json:"category"`
 	SubCategory           string `json:"subCategory"`
+	Severity              string `json:"severity"`
 	Count                 string `json:"count"`
 	SourceComponent       string `json:"sourceComponent"`
 	SourceHost            string `json:"sourceHost"`
@@ -53,7 +54,7 @@ func NewEventSchemaDefWrapper() EventSchemaDefWrapper {
 func NewEventSchemaDef() EventSchemaDef {
 	pdsd := EventSchemaDef{ObjectKind: "string", ObjectName: "string", ClusterName: "string", ObjectNamespace: "string", ObjectResourceVersion: "string",
 		ObjectUid: "string", LastTimestamp: "date", Message: "string", CreationTimestamp: "date", DeletionTimestamp: "date", GenerateName: "string", Generation: "integer",
-		Name: "string", Namespace: "string", OwnerReferences: "string", ResourceVersion: "string", Category: "string", SubCategory: "string",
+		Name: "string", Namespace: "string", OwnerReferences: "string", ResourceVersion: "string", Category: "string", SubCategory: "string", Severity: "string",
 		SelfLink: "string", Type: "string", Count: "integer", SourceComponent: "string", SourceHost: "string", Reason: "string"}
 	return pdsd
 }
@@ -84,6 +85,7 @@ type EventSchema struct {
 	Type                  string    `json:"type"`
 	Category              string    `json:"category"`
 	SubCategory           string    `json:"subCategory"`
+	Severity              string    `json:"severity"`
 	Count                 int32     `json:"count"`
 	SourceComponent       string    `json:"sourceComponent"`
 	SourceHost            string    `json:"sourceHost"`
@@ -11,8 +11,8 @@ type Utilization struct {
 	CpuUseString string
 	MemUseString string
 	Overconsume  bool
-	CpuGoal      float64
-	MemGoal      float64
+	CpuGoal      float64 //recommended cpu request, millicores
+	MemGoal      float64 //recommended memory request, bytes
 	Restarts     int32
 }
 
@@ -110,8 +110,12 @@ func (hn *HeatNode) GetContainerStatsFormatted() string {
 			if c.Restarts > 0 {
 				restartVal = fmt.Sprintf("Restarts: %d\n", c.Restarts)
 			}
+			goalVal := ""
+			if c.CpuGoal > 0 || c.MemGoal > 0 {
+				goalVal = fmt.Sprintf("Recommended requests: %.0fm, %.0fMi\n", c.CpuGoal, c.MemGoal/(1024*1024))
+			}
 
-			s += fmt.Sprintf("%s:\n%s%s%s", name, restartVal, cpuVal, memVal)
+			s += fmt.Sprintf("%s:\n%s%s%s%s", name, restartVal, cpuVal, memVal, goalVal)
 		}
 	}
 
@@ -146,3 +150,120 @@ func (hn *HeatNode) GetAPMID() (int, bool) {
 	}
 	return 0, false
 }
+
+//node heat map states from best to worst
+const (
+	NODE_HEAT_OK          int = 0
+	NODE_HEAT_BUSY        int = 1 //use or requests above the overconsumption threshold
+	NODE_HEAT_PRESSURE    int = 2 //memory or disk pressure or requests over allocatable
+	NODE_HEAT_UNAVAILABLE int = 3 //not ready or out of disk
+)
+
+type NodeHeatNode struct {
+	Nodename       string
+	Role           string
+	Ready          bool
+	OutOfDisk      bool
+	MemoryPressure bool
+	DiskPressure   bool
+	CpuCapacity    int64
+	MemCapacity    int64
+	CpuAllocatable int64
+	MemAllocatable int64
+	CpuUse         int64
+	MemUse         int64
+	CpuRequests    int64
+	MemRequests    int64
+	Pods           int
+	PodsNotRunning int
+	Restarts       int32
+}
+
+func NewNodeHeatNode(node *NodeSchema) NodeHeatNode {
+	return NodeHeatNode{Nodename: node.NodeName, Role: node.Role, Ready: node.Ready == "true", OutOfDisk: node.OutOfDisk == "true",
+		MemoryPressure: node.MemoryPressure == "true", DiskPressure: node.DiskPressure == "true",
+		CpuCapacity: node.CpuCapacity, MemCapacity: node.MemCapacity, CpuAllocatable: node.CpuAllocations, MemAllocatable: node.MemAllocations,
+		CpuUse: node.CpuUse, MemUse: node.MemUse}
+}
+
+//AddPod accounts the requests of the pods scheduled on the node. Completed pods do not hold resources
+func (nh *NodeHeatNode) AddPod(podSchema *PodSchema) {
+	state := podSchema.GetState()
+	if state == "Succeeded" || state == "Failed" {
+		return
+	}
+	nh.Pods++
+	if state != "Running" {
+		nh.PodsNotRunning++
+	}
+	nh.Restarts += podSchema.PodRestarts
+	nh.CpuRequests += podSchema.CpuRequest
+	nh.MemRequests += podSchema.MemRequest
+}
+
+func nodePercent(val int64, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(val) / float64(total) * 100
+}
+
+func (nh *NodeHeatNode) CpuUsePercent() float64 {
+	return nodePercent(nh.CpuUse, nh.CpuCapacity)
+}
+
+func (nh *NodeHeatNode) MemUsePercent() float64 {
+	return nodePercent(nh.MemUse, nh.MemCapacity)
+}
+
+func (nh *NodeHeatNode) CpuRequestsPercent() float64 {
+	return nodePercent(nh.CpuRequests, nh.CpuAllocatable)
+}
+
+func (nh *NodeHeatNode) MemRequestsPercent() float64 {
+	return nodePercent(nh.MemRequests, nh.MemAllocatable)
+}
+
+//GetHeatState returns the worst state of the node conditions, use and requests, and the reasons of the state
+func (nh *NodeHeatNode) GetHeatState(threshold float64) (int, []string) {
+	state := NODE_HEAT_OK
+	reasons := []string{}
+	raise := func(s int, reason string) {
+		if s > state {
+			state = s
+		}
+		reasons = append(reasons, reason)
+	}
+	if !nh.Ready {
+		raise(NODE_HEAT_UNAVAILABLE, "Not ready")
+	}
+	if nh.OutOfDisk {
+		raise(NODE_HEAT_UNAVAILABLE, "Out of disk")
+	}
+	if nh.MemoryPressure {
+		raise(NODE_HEAT_PRESSURE, "Memory pressure")
+	}
+	if nh.DiskPressure {
+		raise(NODE_HEAT_PRESSURE, "Disk pressure")
+	}
+	if nh.CpuRequestsPercent() > 100 {
+		raise(NODE_HEAT_PRESSURE, "Cpu requests overcommitted")
+	}
+	if nh.MemRequestsPercent() > 100 {
+		raise(NODE_HEAT_PRESSURE, "Mem requests overcommitted")
+	}
+	if threshold > 0 {
+		if nh.CpuUsePercent() >= threshold || nh.MemUsePercent() >= threshold {
+			raise(NODE_HEAT_BUSY, "Use above threshold")
+		}
+		if nh.CpuRequestsPercent() >= threshold || nh.MemRequestsPercent() >= threshold {
+			raise(NODE_HEAT_BUSY, "Requests above threshold")
+		}
+	}
+	return state, reasons
+}
+
+func (nh *NodeHeatNode) GetStatsFormatted() string {
+	return fmt.Sprintf("Cpu: %.0f%s used, %.0f%s requested\nMem: %.0f%s used, %.0f%s requested\nPods: %d (not running: %d)\nRestarts: %d\n",
+		nh.CpuUsePercent(), "%", nh.CpuRequestsPercent(), "%", nh.MemUsePercent(), "%", nh.MemRequestsPercent(), "%", nh.Pods, nh.PodsNotRunning, nh.Restarts)
+}
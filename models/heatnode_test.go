@@ -0,0 +1,95 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNodeHeatNodeAddPod(t *testing.T) {
+	nh := NewNodeHeatNode(&NodeSchema{NodeName: "node-1", Role: "worker", Ready: "true", CpuCapacity: 4000, MemCapacity: 8192,
+		CpuAllocations: 3800, MemAllocations: 8000, CpuUse: 1900, MemUse: 2048})
+
+	nh.AddPod(&PodSchema{Phase: "Running", CpuRequest: 1000, MemRequest: 2000, PodRestarts: 2})
+	nh.AddPod(&PodSchema{Phase: "Pending", CpuRequest: 500, MemRequest: 1000})
+	//completed pods do not hold resources
+	nh.AddPod(&PodSchema{Phase: "Succeeded", CpuRequest: 2000, MemRequest: 4000, PodRestarts: 5})
+	nh.AddPod(&PodSchema{Phase: "Failed", CpuRequest: 2000, MemRequest: 4000})
+
+	if nh.Pods != 2 || nh.PodsNotRunning != 1 || nh.Restarts != 2 || nh.CpuRequests != 1500 || nh.MemRequests != 3000 {
+		t.Errorf("node tile = %+v", nh)
+	}
+	if nh.CpuUsePercent() != 47.5 || nh.MemUsePercent() != 25 || nh.MemRequestsPercent() != 37.5 {
+		t.Errorf("use %.1f%%/%.1f%%, requests %.1f%%/%.1f%%", nh.CpuUsePercent(), nh.MemUsePercent(), nh.CpuRequestsPercent(), nh.MemRequestsPercent())
+	}
+	want := "Cpu: 48% used, 39% requested\nMem: 25% used, 38% requested\nPods: 2 (not running: 1)\nRestarts: 2\n"
+	if stats := nh.GetStatsFormatted(); stats != want {
+		t.Errorf("stats = %q, want %q", stats, want)
+	}
+
+	//nodes without capacity do not divide by zero
+	empty := NodeHeatNode{CpuUse: 100, CpuRequests: 100}
+	if empty.CpuUsePercent() != 0 || empty.CpuRequestsPercent() != 0 {
+		t.Errorf("percent of a node without capacity = %.0f, %.0f", empty.CpuUsePercent(), empty.CpuRequestsPercent())
+	}
+}
+
+func TestNodeHeatState(t *testing.T) {
+	healthy := NodeHeatNode{Ready: true, CpuCapacity: 1000, MemCapacity: 1000, CpuAllocatable: 1000, MemAllocatable: 1000, CpuUse: 300, MemUse: 300, CpuRequests: 300, MemRequests: 300}
+	if state, reasons := healthy.GetHeatState(80); state != NODE_HEAT_OK || len(reasons) != 0 {
+		t.Errorf("healthy node: state %d, reasons %v", state, reasons)
+	}
+
+	busy := healthy
+	busy.MemUse = 850
+	if state, reasons := busy.GetHeatState(80); state != NODE_HEAT_BUSY || strings.Join(reasons, ",") != "Use above threshold" {
+		t.Errorf("busy node: state %d, reasons %v", state, reasons)
+	}
+	//without a threshold only the conditions and overcommitment count
+	if state, _ := busy.GetHeatState(0); state != NODE_HEAT_OK {
+		t.Errorf("busy node without a threshold: state %d", state)
+	}
+
+	overcommitted := healthy
+	overcommitted.CpuRequests = 1200
+	state, reasons := overcommitted.GetHeatState(80)
+	if state != NODE_HEAT_PRESSURE || strings.Join(reasons, ",") != "Cpu requests overcommitted,Requests above threshold" {
+		t.Errorf("overcommitted node: state %d, reasons %v", state, reasons)
+	}
+
+	//the worst state wins and all reasons are reported
+	broken := busy
+	broken.Ready = false
+	broken.DiskPressure = true
+	state, reasons = broken.GetHeatState(80)
+	if state != NODE_HEAT_UNAVAILABLE || strings.Join(reasons, ",") != "Not ready,Disk pressure,Use above threshold" {
+		t.Errorf("node that is not ready: state %d, reasons %v", state, reasons)
+	}
+}
+
+func TestDashboardBagNodeHeat(t *testing.T) {
+	bag := NewDashboardBagCluster()
+	for _, n := range []NodeSchema{{NodeName: "worker-b", Role: "worker"}, {NodeName: "master-1", Role: "master"}, {NodeName: "worker-a", Role: "worker"}} {
+		nh := NewNodeHeatNode(&n)
+		bag.AddNodeHeat(&nh)
+	}
+	bag.AddPodToNodeHeat(&PodSchema{NodeName: "worker-a", Phase: "Running", CpuRequest: 100})
+	bag.AddPodToNodeHeat(&PodSchema{NodeName: "worker-a", Phase: "Running", CpuRequest: 200})
+	//pods of nodes that are not monitored and pods that are not scheduled
+	bag.AddPodToNodeHeat(&PodSchema{NodeName: "edge-1", Phase: "Running"})
+	bag.AddPodToNodeHeat(&PodSchema{Phase: "Pending"})
+
+	nodes, roles := bag.GetNodeHeatNodes()
+	if roles != 2 || len(nodes) != 3 {
+		t.Fatalf("%d tiles of %d roles, want 3 tiles of 2 roles", len(nodes), roles)
+	}
+	names := []string{}
+	for _, nh := range nodes {
+		names = append(names, nh.Nodename)
+	}
+	if strings.Join(names, ",") != "master-1,worker-a,worker-b" {
+		t.Errorf("tiles = %v, want sorted by role and name", names)
+	}
+	if nodes[1].Pods != 2 || nodes[1].CpuRequests != 300 || nodes[2].Pods != 0 {
+		t.Errorf("pods of worker-a %d with requests %d, pods of worker-b %d", nodes[1].Pods, nodes[1].CpuRequests, nodes[2].Pods)
+	}
+}
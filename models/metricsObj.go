@@ -8,45 +8,19 @@ import (
 
 type NodeMetricsObjList struct {
 	Kind  string
-	Items []struct {
-		Metadata struct {
-			Name              string
-			SelfLink          string
-			creationTimestamp string
-		}
-		Timestamp string
-		Window    string
-		Usage     struct {
-			Cpu    string
-			Memory string
-		}
-	}
+	Items []NodeMetricsObj
 }
 
 type PodMetricsObjList struct {
 	Kind  string
-	Items []struct {
-		Metadata struct {
-			Name              string
-			SelfLink          string
-			creationTimestamp string
-		}
-		Timestamp  string
-		Window     string
-		Containers []struct {
-			Name  string
-			Usage struct {
-				Cpu    string
-				Memory string
-			}
-		}
-	}
+	Items []PodMetricsObj
 }
 
 type PodMetricsObj struct {
 	Kind     string
 	Metadata struct {
 		Name              string
+		Namespace         string
 		SelfLink          string
 		creationTimestamp string
 	}
@@ -10,6 +10,78 @@ import (
 	"k8s.io/api/core/v1"
 )
 
+const (
+	NODE_LABEL_ZONE               string = "topology.kubernetes.io/zone"
+	NODE_LABEL_ZONE_BETA          string = "failure-domain.beta.kubernetes.io/zone"
+	NODE_LABEL_REGION             string = "topology.kubernetes.io/region"
+	NODE_LABEL_REGION_BETA        string = "failure-domain.beta.kubernetes.io/region"
+	NODE_LABEL_INSTANCE_TYPE      string = "node.kubernetes.io/instance-type"
+	NODE_LABEL_INSTANCE_TYPE_BETA string = "beta.kubernetes.io/instance-type"
+	NODE_LABEL_ROLE_MASTER        string = "node-role.kubernetes.io/master"
+	NODE_LABEL_ROLE_CONTROL_PLANE string = "node-role.kubernetes.io/control-plane"
+
+	NODE_ROLE_MASTER string = "master"
+	NODE_ROLE_WORKER string = "worker"
+)
+
+//GetNodeZone returns the zone of a node from its labels. Empty if the node has no zone label
+func GetNodeZone(labels map[string]string) string {
+	return firstLabel(labels, NODE_LABEL_ZONE, NODE_LABEL_ZONE_BETA)
+}
+
+func GetNodeRegion(labels map[string]string) string {
+	return firstLabel(labels, NODE_LABEL_REGION, NODE_LABEL_REGION_BETA)
+}
+
+func GetNodeInstanceType(labels map[string]string) string {
+	return firstLabel(labels, NODE_LABEL_INSTANCE_TYPE, NODE_LABEL_INSTANCE_TYPE_BETA)
+}
+
+//GetNodePool returns the value of the first pool label of the node. Nodes without pool labels are in the default pool
+func GetNodePool(labels map[string]string, poolLabels []string) string {
+	if pool := firstLabel(labels, poolLabels...); pool != "" {
+		return pool
+	}
+	return NODE_POOL_DEFAULT
+}
+
+func IsControlPlaneNode(labels map[string]string) bool {
+	_, master := labels[NODE_LABEL_ROLE_MASTER]
+	_, controlPlane := labels[NODE_LABEL_ROLE_CONTROL_PLANE]
+	return master || controlPlane
+}
+
+func firstLabel(labels map[string]string, keys ...string) string {
+	for _, key := range keys {
+		if val, ok := labels[key]; ok && val != "" {
+			return val
+		}
+	}
+	return ""
+}
+
+//ParseProviderID returns the cloud provider and the zone from the provider ID of a node,
+//e.g. aws:///us-east-1a/i-0123 or gce://project/us-central1-a/node-1. The zone is only known for AWS and GCE
+func ParseProviderID(providerID string) (string, string) {
+	parts := strings.SplitN(providerID, "://", 2)
+	if len(parts) != 2 {
+		return "", ""
+	}
+	cloud := parts[0]
+	segments := strings.Split(strings.TrimPrefix(parts[1], "/"), "/")
+	switch cloud {
+	case "aws":
+		if len(segments) == 2 {
+			return cloud, segments[0]
+		}
+	case "gce":
+		if len(segments) == 3 {
+			return cloud, segments[1]
+		}
+	}
+	return cloud, ""
+}
+
 type NodeSchemaDefWrapper struct {
 	Schema NodeSchemaDef `json:"schema"`
 }
@@ -48,6 +120,11 @@ type NodeSchemaDef struct {
 	OutOfDisk       string `json:"outOfDisk"`
 	MemoryPressure  string `json:"memoryPressure"`
 	DiskPressure    string `json:"diskPressure"`
+	Cloud           string `json:"cloud"`
+	Region          string `json:"region"`
+	Zone            string `json:"zone"`
+	InstanceType    string `json:"instanceType"`
+	Pool            string `json:"pool"`
 }
 
 func (sd NodeSchemaDef) Unwrap() *map[string]interface{} {
@@ -66,7 +143,8 @@ func NewNodeSchemaDef() NodeSchemaDef {
 		Addresses: "string", Labels: "string", Role: "string", CpuUse: "float", MemUse: "float", CpuCapacity: "float", MemCapacity: "float", PodCapacity: "integer",
 		CpuAllocations: "float", MemAllocations: "float", PodAllocations: "integer", KubeletPort: "integer", OsArch: "string",
 		KubeletVersion: "string", RuntimeVersion: "string", MachineID: "string", OsName: "string", AttachedVolumes: "string", VolumesInUse: "string",
-		Ready: "string", OutOfDisk: "string", MemoryPressure: "string", DiskPressure: "string"}
+		Ready: "string", OutOfDisk: "string", MemoryPressure: "string", DiskPressure: "string", Cloud: "string", Region: "string", Zone: "string",
+		InstanceType: "string", Pool: "string"}
 	return pdsd
 }
 
@@ -99,6 +177,11 @@ type NodeSchema struct {
 	OutOfDisk       string `json:"outOfDisk"`
 	MemoryPressure  string `json:"memoryPressure"`
 	DiskPressure    string `json:"diskPressure"`
+	Cloud           string `json:"cloud"`
+	Region          string `json:"region"`
+	Zone            string `json:"zone"`
+	InstanceType    string `json:"instanceType"`
+	Pool            string `json:"pool"`
 	TaintsNumber    int    `json:"-"`
 }
 
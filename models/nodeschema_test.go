@@ -0,0 +1,46 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestParseProviderID(t *testing.T) {
+	tests := []struct {
+		providerID string
+		cloud      string
+		zone       string
+	}{
+		{"aws:///us-east-1a/i-0123456789abcdef0", "aws", "us-east-1a"},
+		{"gce://my-project/us-central1-a/gke-node-1", "gce", "us-central1-a"},
+		{"azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/vm-1", "azure", ""},
+		{"aws:///i-0123456789abcdef0", "aws", ""},
+		{"kind://docker/kind/kind-control-plane", "kind", ""},
+		{"", "", ""},
+		{"i-0123456789abcdef0", "", ""},
+	}
+	for _, tt := range tests {
+		if cloud, zone := ParseProviderID(tt.providerID); cloud != tt.cloud || zone != tt.zone {
+			t.Errorf("ParseProviderID(%q) = (%q, %q), want (%q, %q)", tt.providerID, cloud, zone, tt.cloud, tt.zone)
+		}
+	}
+}
+
+func TestGetNodePool(t *testing.T) {
+	poolLabels := []string{"cloud.google.com/gke-nodepool", "eks.amazonaws.com/nodegroup", "agentpool"}
+	tests := []struct {
+		labels map[string]string
+		pool   string
+	}{
+		{map[string]string{"cloud.google.com/gke-nodepool": "default-pool"}, "default-pool"},
+		{map[string]string{"eks.amazonaws.com/nodegroup": "workers", "agentpool": "ignored"}, "workers"},
+		{map[string]string{"agentpool": "nodepool1"}, "nodepool1"},
+		{map[string]string{"agentpool": ""}, NODE_POOL_DEFAULT},
+		{map[string]string{"node.kubernetes.io/instance-type": "m5.large"}, NODE_POOL_DEFAULT},
+		{nil, NODE_POOL_DEFAULT},
+	}
+	for _, tt := range tests {
+		if pool := GetNodePool(tt.labels, poolLabels); pool != tt.pool {
+			t.Errorf("GetNodePool(%v) = %q, want %q", tt.labels, pool, tt.pool)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"strings"
+)
+
+const (
+	PENDING_REASON_INSUFFICIENT_CPU    string = "InsufficientCpu"
+	PENDING_REASON_INSUFFICIENT_MEMORY string = "InsufficientMemory"
+	PENDING_REASON_TAINT               string = "TaintNotTolerated"
+	PENDING_REASON_NODE_AFFINITY       string = "NodeAffinityMismatch"
+	PENDING_REASON_VOLUME_AFFINITY     string = "VolumeNodeAffinityConflict"
+	PENDING_REASON_UNBOUND_PVC         string = "UnboundPVC"
+	PENDING_REASON_TOO_MANY_PODS       string = "TooManyPods"
+	PENDING_REASON_OTHER               string = "Other"
+
+	PENDING_REASONS_SEPARATOR string = ";"
+
+	//since K8s 1.24 the scheduler appends the outcome of preemption to the message, e.g.
+	//"... preemption: 0/5 nodes are available: 1 Preemption is not helpful for scheduling, 4 No preemption victims found for incoming pod."
+	PENDING_PREEMPTION_PREFIX string = "preemption:"
+)
+
+//fragments of the scheduler's FailedScheduling messages, e.g.
+//"0/5 nodes are available: 3 Insufficient memory, 2 node(s) had taints that the pod didn't tolerate."
+//The first matching fragment wins, so specific fragments go ahead of the generic ones
+var pendingReasonPatterns = []struct {
+	Fragment string
+	Reason   string
+}{
+	{"insufficient cpu", PENDING_REASON_INSUFFICIENT_CPU},
+	{"insufficient memory", PENDING_REASON_INSUFFICIENT_MEMORY},
+	{"taint", PENDING_REASON_TAINT},
+	{"didn't match node selector", PENDING_REASON_NODE_AFFINITY},
+	{"didn't match pod's node affinity", PENDING_REASON_NODE_AFFINITY},
+	{"volume node affinity", PENDING_REASON_VOLUME_AFFINITY},
+	{"node affinity", PENDING_REASON_NODE_AFFINITY},
+	{"unbound immediate persistentvolumeclaims", PENDING_REASON_UNBOUND_PVC},
+	{"unbound persistentvolumeclaim", PENDING_REASON_UNBOUND_PVC},
+	{"persistentvolumeclaim", PENDING_REASON_UNBOUND_PVC},
+	{"too many pods", PENDING_REASON_TOO_MANY_PODS},
+}
+
+//ParseSchedulingReasons extracts the distinct structured reasons from the message of a FailedScheduling event
+func ParseSchedulingReasons(message string) []string {
+	reasons := []string{}
+	msg := strings.ToLower(message)
+	if idx := strings.Index(msg, PENDING_PREEMPTION_PREFIX); idx >= 0 {
+		msg = msg[:idx]
+	}
+	if idx := strings.Index(msg, ":"); idx >= 0 {
+		msg = msg[idx+1:]
+	}
+	for _, part := range strings.Split(msg, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		reason := PENDING_REASON_OTHER
+		for _, p := range pendingReasonPatterns {
+			if strings.Contains(part, p.Fragment) {
+				reason = p.Reason
+				break
+			}
+		}
+		found := false
+		for _, r := range reasons {
+			if r == reason {
+				found = true
+				break
+			}
+		}
+		if !found {
+			reasons = append(reasons, reason)
+		}
+	}
+	return reasons
+}
+
+func (ps *PodSchema) HasPendingReason(reason string) bool {
+	if ps.PendingReasons == "" {
+		return false
+	}
+	for _, r := range strings.Split(ps.PendingReasons, PENDING_REASONS_SEPARATOR) {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSchedulingReasons(t *testing.T) {
+	tests := []struct {
+		message string
+		reasons []string
+	}{
+		{"0/5 nodes are available: 3 Insufficient memory, 2 node(s) had taints that the pod didn't tolerate.",
+			[]string{PENDING_REASON_INSUFFICIENT_MEMORY, PENDING_REASON_TAINT}},
+		{"0/3 nodes are available: 1 Insufficient cpu, 2 Insufficient cpu.",
+			[]string{PENDING_REASON_INSUFFICIENT_CPU}},
+		{"0/2 nodes are available: 2 node(s) didn't match node selector.",
+			[]string{PENDING_REASON_NODE_AFFINITY}},
+		{"0/2 nodes are available: 2 node(s) didn't match pod's node affinity/selector.",
+			[]string{PENDING_REASON_NODE_AFFINITY}},
+		{"0/4 nodes are available: 4 node(s) had volume node affinity conflict.",
+			[]string{PENDING_REASON_VOLUME_AFFINITY}},
+		{"pod has unbound immediate PersistentVolumeClaims.",
+			[]string{PENDING_REASON_UNBOUND_PVC}},
+		{"0/1 nodes are available: 1 Too many pods.",
+			[]string{PENDING_REASON_TOO_MANY_PODS}},
+		{"0/1 nodes are available: 1 node(s) were unschedulable.",
+			[]string{PENDING_REASON_OTHER}},
+		{"0/3 nodes are available: 1 node(s) had volume node affinity conflict, 2 node(s) didn't match pod's node affinity/selector.",
+			[]string{PENDING_REASON_VOLUME_AFFINITY, PENDING_REASON_NODE_AFFINITY}},
+		{"", []string{}},
+	}
+	for _, tt := range tests {
+		if reasons := ParseSchedulingReasons(tt.message); !reflect.DeepEqual(reasons, tt.reasons) {
+			t.Errorf("ParseSchedulingReasons(%q) = %v, want %v", tt.message, reasons, tt.reasons)
+		}
+	}
+}
+
+//messages of the scheduler since K8s 1.24, with the outcome of preemption appended
+func TestParseSchedulingReasonsPreemption(t *testing.T) {
+	reasons := ParseSchedulingReasons("0/5 nodes are available: 1 Insufficient cpu, 4 node(s) didn't match Pod's node affinity/selector. " +
+		"preemption: 0/5 nodes are available: 1 No preemption victims found for incoming pod, 4 Preemption is not helpful for scheduling.")
+	if want := []string{PENDING_REASON_INSUFFICIENT_CPU, PENDING_REASON_NODE_AFFINITY}; !reflect.DeepEqual(reasons, want) {
+		t.Errorf("reasons of the preemption message = %v, want %v", reasons, want)
+	}
+
+	reasons = ParseSchedulingReasons("0/3 nodes are available: 1 node(s) had untolerated taint {node-role.kubernetes.io/control-plane: }, " +
+		"2 Insufficient memory. preemption: 0/3 nodes are available: 1 Preemption is not helpful for scheduling, 2 No preemption victims found for incoming pod.")
+	if want := []string{PENDING_REASON_TAINT, PENDING_REASON_INSUFFICIENT_MEMORY}; !reflect.DeepEqual(reasons, want) {
+		t.Errorf("reasons of the untolerated taint message = %v, want %v", reasons, want)
+	}
+
+	reasons = ParseSchedulingReasons("0/6 nodes are available: 6 node(s) had volume node affinity conflict. " +
+		"preemption: 0/6 nodes are available: 6 Preemption is not helpful for scheduling.")
+	if want := []string{PENDING_REASON_VOLUME_AFFINITY}; !reflect.DeepEqual(reasons, want) {
+		t.Errorf("reasons of the volume affinity message = %v, want %v", reasons, want)
+	}
+
+	reasons = ParseSchedulingReasons("0/2 nodes are available: 2 node(s) were unschedulable. preemption: 0/2 nodes are available: 2 Preemption is not helpful for scheduling.")
+	if want := []string{PENDING_REASON_OTHER}; !reflect.DeepEqual(reasons, want) {
+		t.Errorf("reasons of the unschedulable nodes message = %v, want %v", reasons, want)
+	}
+}
+
+func TestHasPendingReason(t *testing.T) {
+	ps := PodSchema{PendingReasons: PENDING_REASON_INSUFFICIENT_CPU + PENDING_REASONS_SEPARATOR + PENDING_REASON_TAINT}
+	tests := []struct {
+		reason string
+		has    bool
+	}{
+		{PENDING_REASON_INSUFFICIENT_CPU, true},
+		{PENDING_REASON_TAINT, true},
+		{PENDING_REASON_INSUFFICIENT_MEMORY, false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if has := ps.HasPendingReason(tt.reason); has != tt.has {
+			t.Errorf("HasPendingReason(%q) = %t, want %t", tt.reason, has, tt.has)
+		}
+	}
+}
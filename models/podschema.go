@@ -63,6 +63,12 @@ type PodSchemaDef struct {
 	TermReasons                   string `json:"termReasons"`
 	RunningStartTime              string `json:"runningStartTime"`
 	TerminationTime               string `json:"terminationTime"`
+	PendingReasons                string `json:"pendingReasons"`
+	SchedulingLatency             string `json:"schedulingLatency"`
+	InitLatency                   string `json:"initLatency"`
+	ImagePullLatency              string `json:"imagePullLatency"`
+	ReadyLatency                  string `json:"readyLatency"`
+	StartupLatency                string `json:"startupLatency"`
 }
 
 func NewPodSchemaDefWrapper() PodSchemaDefWrapper {
@@ -85,7 +91,8 @@ func NewPodSchemaDef() PodSchemaDef {
 		StatusCondition: "string", TypeCondition: "string", LimitsDefined: "boolean", LiveProbes: "integer", ReadyProbes: "integer", PodRestarts: "integer",
 		NumPrivileged: "integer", Ports: "string", MemRequest: "float", CpuRequest: "float", CpuLimit: "float", MemLimit: "float",
 		PodStorageRequest: "float", PodStorageLimit: "float", StorageRequest: "float", StorageCapacity: "float", CpuUse: "float", MemUse: "float",
-		Images: "string", WaitReasons: "string", TermReasons: "string", RunningStartTime: "date", TerminationTime: "date"}
+		Images: "string", WaitReasons: "string", TermReasons: "string", RunningStartTime: "date", TerminationTime: "date", PendingReasons: "string",
+		SchedulingLatency: "integer", InitLatency: "integer", ImagePullLatency: "integer", ReadyLatency: "integer", StartupLatency: "integer"}
 	return pdsd
 }
 
@@ -144,10 +151,18 @@ type PodSchema struct {
 	TermReasons                   string                     `json:"termReasons"`
 	RunningStartTime              *time.Time                 `json:"runningStartTime"`
 	TerminationTime               *time.Time                 `json:"terminationTime"`
+	PendingReasons                string                     `json:"pendingReasons"`
+	SchedulingLatency             int64                      `json:"schedulingLatency"`
+	InitLatency                   int64                      `json:"initLatency"`
+	ImagePullLatency              int64                      `json:"imagePullLatency"`
+	ReadyLatency                  int64                      `json:"readyLatency"`
+	StartupLatency                int64                      `json:"startupLatency"`
 	PendingTime                   int64                      `json:"-"`
 	Containers                    map[string]ContainerSchema `json:"-"`
 	InitContainers                map[string]ContainerSchema `json:"-"`
 	Owner                         string                     `json:"-"`
+	OwnerKind                     string                     `json:"-"` //Deployment, DaemonSet, StatefulSet, ReplicaSet, Job etc.
+	Workload                      string                     `json:"-"` //name of the workload. Differs from the owner for pods of deployments
 	IsEvicted                     bool                       `json:"-"`
 	AppID                         int                        `json:"-"`
 	TierID                        int                        `json:"-"`
@@ -162,6 +177,12 @@ type PodSchema struct {
 	TerminationTimeMillis         int64                      `json:"-"`
 	UpTimeMillis                  int64                      `json:"-"`
 	BreakPointMillis              int64                      `json:"-"` //time when a container exited
+	CreationTimeMillis            int64                      `json:"-"`
+	ScheduledTimeMillis           int64                      `json:"-"`
+	InitializedTimeMillis         int64                      `json:"-"`
+	ReadyTimeMillis               int64                      `json:"-"`
+	ImagePullStartMillis          int64                      `json:"-"`
+	ImagePullEndMillis            int64                      `json:"-"`
 	MissingDependencies           bool                       `json:"-"`
 	NoConnectivity                bool                       `json:"-"`
 	ConsumptionCpu                float64                    `json:"-"`
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"k8s.io/api/core/v1"
+)
+
+const (
+	PSS_LEVEL_PRIVILEGED string = "privileged"
+	PSS_LEVEL_BASELINE   string = "baseline"
+	PSS_LEVEL_RESTRICTED string = "restricted"
+
+	CAPABILITY_ALL              v1.Capability = "ALL"
+	CAPABILITY_NET_BIND_SERVICE v1.Capability = "NET_BIND_SERVICE"
+)
+
+//capabilities the baseline standard allows to add
+var baselineCapabilities = map[v1.Capability]bool{"AUDIT_WRITE": true, "CHOWN": true, "DAC_OVERRIDE": true, "FOWNER": true, "FSETID": true, "KILL": true,
+	"MKNOD": true, "NET_BIND_SERVICE": true, "SETFCAP": true, "SETGID": true, "SETPCAP": true, "SETUID": true, "SYS_CHROOT": true}
+
+//EvaluateSecurity checks the container against the Pod Security Standards and sets the violation flags and the
+//strictest standard the container meets. A writable root filesystem is flagged, but is not part of the standards
+func (cs *ContainerSchema) EvaluateSecurity(pod *v1.Pod, c *v1.Container) {
+	level := PSS_LEVEL_RESTRICTED
+	//baseline violations fail both standards
+	fail := func(l string) {
+		if l == PSS_LEVEL_BASELINE {
+			level = PSS_LEVEL_PRIVILEGED
+		} else if level == PSS_LEVEL_RESTRICTED {
+			level = PSS_LEVEL_BASELINE
+		}
+	}
+
+	psc := pod.Spec.SecurityContext
+	if psc == nil {
+		psc = &v1.PodSecurityContext{}
+	}
+	sc := c.SecurityContext
+	if sc == nil {
+		sc = &v1.SecurityContext{}
+	}
+
+	if sc.Privileged != nil && *sc.Privileged {
+		fail(PSS_LEVEL_BASELINE)
+	}
+
+	if pod.Spec.HostNetwork || pod.Spec.HostPID || pod.Spec.HostIPC {
+		cs.HostNamespaces = 1
+		fail(PSS_LEVEL_BASELINE)
+	}
+
+	hostPaths := make(map[string]bool)
+	for _, v := range pod.Spec.Volumes {
+		if v.HostPath != nil {
+			hostPaths[v.Name] = true
+		}
+	}
+	for _, vm := range c.VolumeMounts {
+		if hostPaths[vm.Name] {
+			cs.HostPath = 1
+			fail(PSS_LEVEL_BASELINE)
+			break
+		}
+	}
+
+	dropsAll := false
+	if sc.Capabilities != nil {
+		for _, capability := range sc.Capabilities.Add {
+			if !baselineCapabilities[capability] {
+				cs.AddedCapabilities = 1
+				fail(PSS_LEVEL_BASELINE)
+			} else if capability != CAPABILITY_NET_BIND_SERVICE {
+				cs.AddedCapabilities = 1
+				fail(PSS_LEVEL_RESTRICTED)
+			}
+		}
+		for _, capability := range sc.Capabilities.Drop {
+			if capability == CAPABILITY_ALL {
+				dropsAll = true
+			}
+		}
+	}
+	if !dropsAll {
+		fail(PSS_LEVEL_RESTRICTED)
+	}
+
+	//the settings of the container override the settings of the pod
+	runAsNonRoot := psc.RunAsNonRoot
+	if sc.RunAsNonRoot != nil {
+		runAsNonRoot = sc.RunAsNonRoot
+	}
+	runAsUser := psc.RunAsUser
+	if sc.RunAsUser != nil {
+		runAsUser = sc.RunAsUser
+	}
+	if runAsNonRoot == nil || !*runAsNonRoot || (runAsUser != nil && *runAsUser == 0) {
+		cs.RunAsRoot = 1
+		fail(PSS_LEVEL_RESTRICTED)
+	}
+
+	if sc.AllowPrivilegeEscalation == nil || *sc.AllowPrivilegeEscalation {
+		cs.PrivilegeEscalation = 1
+		fail(PSS_LEVEL_RESTRICTED)
+	}
+
+	if sc.ReadOnlyRootFilesystem == nil || !*sc.ReadOnlyRootFilesystem {
+		cs.WritableRootFs = 1
+	}
+
+	seccomp := psc.SeccompProfile
+	if sc.SeccompProfile != nil {
+		seccomp = sc.SeccompProfile
+	}
+	if seccomp == nil {
+		cs.NoSeccomp = 1
+		fail(PSS_LEVEL_RESTRICTED)
+	} else if seccomp.Type == v1.SeccompProfileTypeUnconfined {
+		cs.NoSeccomp = 1
+		fail(PSS_LEVEL_BASELINE)
+	}
+
+	cs.SecurityLevel = level
+}
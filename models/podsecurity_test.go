@@ -0,0 +1,86 @@
+package models
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+)
+
+//returns a pod with a single container that meets the restricted standard
+func restrictedPod() (*v1.Pod, *v1.Container) {
+	yes := true
+	no := false
+	c := v1.Container{
+		Name: "app",
+		SecurityContext: &v1.SecurityContext{
+			RunAsNonRoot:             &yes,
+			AllowPrivilegeEscalation: &no,
+			ReadOnlyRootFilesystem:   &yes,
+			Capabilities:             &v1.Capabilities{Drop: []v1.Capability{CAPABILITY_ALL}},
+		},
+	}
+	pod := &v1.Pod{Spec: v1.PodSpec{
+		SecurityContext: &v1.PodSecurityContext{SeccompProfile: &v1.SeccompProfile{Type: v1.SeccompProfileTypeRuntimeDefault}},
+		Containers:      []v1.Container{c},
+	}}
+	return pod, &pod.Spec.Containers[0]
+}
+
+func TestEvaluateSecurity(t *testing.T) {
+	yes := true
+	var root int64 = 0
+	tests := []struct {
+		name   string
+		modify func(pod *v1.Pod, c *v1.Container)
+		level  string
+		flag   func(cs *ContainerSchema) int
+	}{
+		{"restricted", func(pod *v1.Pod, c *v1.Container) {}, PSS_LEVEL_RESTRICTED, nil},
+		{"privileged", func(pod *v1.Pod, c *v1.Container) { c.SecurityContext.Privileged = &yes }, PSS_LEVEL_PRIVILEGED, nil},
+		{"host network", func(pod *v1.Pod, c *v1.Container) { pod.Spec.HostNetwork = true }, PSS_LEVEL_PRIVILEGED,
+			func(cs *ContainerSchema) int { return cs.HostNamespaces }},
+		{"host path", func(pod *v1.Pod, c *v1.Container) {
+			pod.Spec.Volumes = []v1.Volume{{Name: "logs", VolumeSource: v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: "/var/log"}}}}
+			c.VolumeMounts = []v1.VolumeMount{{Name: "logs", MountPath: "/logs"}}
+		}, PSS_LEVEL_PRIVILEGED, func(cs *ContainerSchema) int { return cs.HostPath }},
+		{"host path volume not mounted", func(pod *v1.Pod, c *v1.Container) {
+			pod.Spec.Volumes = []v1.Volume{{Name: "logs", VolumeSource: v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: "/var/log"}}}}
+		}, PSS_LEVEL_RESTRICTED, nil},
+		{"SYS_ADMIN added", func(pod *v1.Pod, c *v1.Container) { c.SecurityContext.Capabilities.Add = []v1.Capability{"SYS_ADMIN"} },
+			PSS_LEVEL_PRIVILEGED, func(cs *ContainerSchema) int { return cs.AddedCapabilities }},
+		{"CHOWN added", func(pod *v1.Pod, c *v1.Container) { c.SecurityContext.Capabilities.Add = []v1.Capability{"CHOWN"} },
+			PSS_LEVEL_BASELINE, func(cs *ContainerSchema) int { return cs.AddedCapabilities }},
+		{"NET_BIND_SERVICE added", func(pod *v1.Pod, c *v1.Container) {
+			c.SecurityContext.Capabilities.Add = []v1.Capability{CAPABILITY_NET_BIND_SERVICE}
+		}, PSS_LEVEL_RESTRICTED, nil},
+		{"capabilities not dropped", func(pod *v1.Pod, c *v1.Container) { c.SecurityContext.Capabilities = nil }, PSS_LEVEL_BASELINE, nil},
+		{"runs as root", func(pod *v1.Pod, c *v1.Container) { c.SecurityContext.RunAsUser = &root }, PSS_LEVEL_BASELINE,
+			func(cs *ContainerSchema) int { return cs.RunAsRoot }},
+		{"non root set on pod", func(pod *v1.Pod, c *v1.Container) {
+			c.SecurityContext.RunAsNonRoot = nil
+			pod.Spec.SecurityContext.RunAsNonRoot = &yes
+		}, PSS_LEVEL_RESTRICTED, nil},
+		{"privilege escalation", func(pod *v1.Pod, c *v1.Container) { c.SecurityContext.AllowPrivilegeEscalation = nil }, PSS_LEVEL_BASELINE,
+			func(cs *ContainerSchema) int { return cs.PrivilegeEscalation }},
+		{"writable root filesystem", func(pod *v1.Pod, c *v1.Container) { c.SecurityContext.ReadOnlyRootFilesystem = nil }, PSS_LEVEL_RESTRICTED,
+			func(cs *ContainerSchema) int { return cs.WritableRootFs }},
+		{"no seccomp", func(pod *v1.Pod, c *v1.Container) { pod.Spec.SecurityContext = nil }, PSS_LEVEL_BASELINE,
+			func(cs *ContainerSchema) int { return cs.NoSeccomp }},
+		{"unconfined seccomp", func(pod *v1.Pod, c *v1.Container) {
+			c.SecurityContext.SeccompProfile = &v1.SeccompProfile{Type: v1.SeccompProfileTypeUnconfined}
+		}, PSS_LEVEL_PRIVILEGED, func(cs *ContainerSchema) int { return cs.NoSeccomp }},
+		{"no security context", func(pod *v1.Pod, c *v1.Container) { c.SecurityContext = nil }, PSS_LEVEL_BASELINE, nil},
+	}
+	for _, tt := range tests {
+		pod, c := restrictedPod()
+		tt.modify(pod, c)
+		cs := ContainerSchema{}
+		cs.EvaluateSecurity(pod, c)
+		if cs.SecurityLevel != tt.level {
+			t.Errorf("%s: SecurityLevel = %q, want %q", tt.name, cs.SecurityLevel, tt.level)
+		}
+		if tt.flag != nil && tt.flag(&cs) != 1 {
+			t.Errorf("%s: violation is not flagged", tt.name)
+		}
+	}
+}
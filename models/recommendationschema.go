@@ -0,0 +1,198 @@
+package models
+
+import (
+	"sort"
+	"time"
+
+	"github.com/fatih/structs"
+)
+
+const (
+	RECOMMENDATION_ACTION_DECREASE string = "decrease"
+	RECOMMENDATION_ACTION_INCREASE string = "increase"
+	RECOMMENDATION_ACTION_KEEP     string = "keep"
+
+	RECOMMENDATION_RISK_NONE        string = "none"
+	RECOMMENDATION_RISK_NO_REQUESTS string = "noRequests"
+	RECOMMENDATION_RISK_UNDER       string = "underProvisioned"
+	RECOMMENDATION_RISK_THROTTLING  string = "cpuThrottling"
+	RECOMMENDATION_RISK_OOM         string = "oomKill"
+)
+
+type RecommendationSchemaDefWrapper struct {
+	Schema RecommendationSchemaDef `json:"schema"`
+}
+
+func (sd RecommendationSchemaDefWrapper) Unwrap() *map[string]interface{} {
+	objMap := structs.Map(sd)
+	return &objMap
+}
+
+type RecommendationSchemaDef struct {
+	ClusterName   string `json:"clusterName"`
+	Namespace     string `json:"namespace"`
+	OwnerKind     string `json:"ownerKind"`
+	Workload      string `json:"workload"`
+	ContainerName string `json:"containerName"`
+	Timestamp     string `json:"timestamp"`
+	Replicas      string `json:"replicas"`
+	Samples       string `json:"samples"`
+	HistoryHours  string `json:"historyHours"`
+	CpuRequest    string `json:"cpuRequest"`
+	CpuLimit      string `json:"cpuLimit"`
+	MemRequest    string `json:"memRequest"`
+	MemLimit      string `json:"memLimit"`
+	CpuP50        string `json:"cpuP50"`
+	CpuP95        string `json:"cpuP95"`
+	CpuMax        string `json:"cpuMax"`
+	MemP50        string `json:"memP50"`
+	MemP95        string `json:"memP95"`
+	MemMax        string `json:"memMax"`
+	RecCpuRequest string `json:"recCpuRequest"`
+	RecCpuLimit   string `json:"recCpuLimit"`
+	RecMemRequest string `json:"recMemRequest"`
+	RecMemLimit   string `json:"recMemLimit"`
+	CpuSavings    string `json:"cpuSavings"`
+	MemSavings    string `json:"memSavings"`
+	Action        string `json:"action"`
+	Risk          string `json:"risk"`
+	Summary       string `json:"summary"`
+}
+
+func NewRecommendationSchemaDefWrapper() RecommendationSchemaDefWrapper {
+	schema := NewRecommendationSchemaDef()
+	wrapper := RecommendationSchemaDefWrapper{Schema: schema}
+	return wrapper
+}
+
+func NewRecommendationSchemaDef() RecommendationSchemaDef {
+	pdsd := RecommendationSchemaDef{ClusterName: "string", Namespace: "string", OwnerKind: "string", Workload: "string", ContainerName: "string",
+		Timestamp: "date", Replicas: "integer", Samples: "integer", HistoryHours: "float", CpuRequest: "integer", CpuLimit: "integer",
+		MemRequest: "integer", MemLimit: "integer", CpuP50: "integer", CpuP95: "integer", CpuMax: "integer", MemP50: "integer", MemP95: "integer",
+		MemMax: "integer", RecCpuRequest: "integer", RecCpuLimit: "integer", RecMemRequest: "integer", RecMemLimit: "integer",
+		CpuSavings: "integer", MemSavings: "integer", Action: "string", Risk: "string", Summary: "string"}
+	return pdsd
+}
+
+func (sd RecommendationSchemaDef) Unwrap() *map[string]interface{} {
+	objMap := structs.Map(sd)
+	return &objMap
+}
+
+//RecommendationSchema holds the recommended requests and limits of a container of a workload.
+//Cpu values are in millicores, memory values in bytes. Savings are per workload (all replicas), negative savings are the resources the workload is missing
+type RecommendationSchema struct {
+	ClusterName   string    `json:"clusterName"`
+	Namespace     string    `json:"namespace"`
+	OwnerKind     string    `json:"ownerKind"`
+	Workload      string    `json:"workload"`
+	ContainerName string    `json:"containerName"`
+	Timestamp     time.Time `json:"timestamp"`
+	Replicas      int64     `json:"replicas"`
+	Samples       int64     `json:"samples"`
+	HistoryHours  float64   `json:"historyHours"`
+	CpuRequest    int64     `json:"cpuRequest"`
+	CpuLimit      int64     `json:"cpuLimit"`
+	MemRequest    int64     `json:"memRequest"`
+	MemLimit      int64     `json:"memLimit"`
+	CpuP50        int64     `json:"cpuP50"`
+	CpuP95        int64     `json:"cpuP95"`
+	CpuMax        int64     `json:"cpuMax"`
+	MemP50        int64     `json:"memP50"`
+	MemP95        int64     `json:"memP95"`
+	MemMax        int64     `json:"memMax"`
+	RecCpuRequest int64     `json:"recCpuRequest"`
+	RecCpuLimit   int64     `json:"recCpuLimit"`
+	RecMemRequest int64     `json:"recMemRequest"`
+	RecMemLimit   int64     `json:"recMemLimit"`
+	CpuSavings    int64     `json:"cpuSavings"`
+	MemSavings    int64     `json:"memSavings"`
+	Action        string    `json:"action"`
+	Risk          string    `json:"risk"`
+	Summary       string    `json:"summary"`
+}
+
+//UsageSample is the peak use of a container within a sampling slot
+type UsageSample struct {
+	Time int64 `json:"t"` //unix seconds
+	Cpu  int64 `json:"c"`
+	Mem  int64 `json:"m"`
+}
+
+//UsageHistory is the rolling usage history of a container of a workload
+type UsageHistory struct {
+	Namespace     string        `json:"namespace"`
+	OwnerKind     string        `json:"ownerKind"`
+	Workload      string        `json:"workload"`
+	ContainerName string        `json:"containerName"`
+	CpuRequest    int64         `json:"cpuRequest"`
+	CpuLimit      int64         `json:"cpuLimit"`
+	MemRequest    int64         `json:"memRequest"`
+	MemLimit      int64         `json:"memLimit"`
+	LastSeen      int64         `json:"lastSeen"`
+	Samples       []UsageSample `json:"samples"`
+}
+
+//AddSample keeps the peak use of the pods of the workload per slot of slotSec seconds
+func (uh *UsageHistory) AddSample(now time.Time, slotSec int64, cpu int64, mem int64) {
+	slot := now.Unix() - now.Unix()%slotSec
+	uh.LastSeen = now.Unix()
+	last := len(uh.Samples) - 1
+	if last >= 0 && uh.Samples[last].Time == slot {
+		if cpu > uh.Samples[last].Cpu {
+			uh.Samples[last].Cpu = cpu
+		}
+		if mem > uh.Samples[last].Mem {
+			uh.Samples[last].Mem = mem
+		}
+		return
+	}
+	uh.Samples = append(uh.Samples, UsageSample{Time: slot, Cpu: cpu, Mem: mem})
+}
+
+//Prune drops the samples older than the window
+func (uh *UsageHistory) Prune(now time.Time, window time.Duration) {
+	cutoff := now.Add(-window).Unix()
+	i := 0
+	for i < len(uh.Samples) && uh.Samples[i].Time < cutoff {
+		i++
+	}
+	if i > 0 {
+		uh.Samples = append([]UsageSample{}, uh.Samples[i:]...)
+	}
+}
+
+//Duration is the time covered by the samples
+func (uh *UsageHistory) Duration() time.Duration {
+	if len(uh.Samples) < 2 {
+		return 0
+	}
+	return time.Duration(uh.Samples[len(uh.Samples)-1].Time-uh.Samples[0].Time) * time.Second
+}
+
+//Percentiles returns the cpu and memory use at the percentile (0-100)
+func (uh *UsageHistory) Percentiles(p float64) (int64, int64) {
+	cpu := make([]int64, len(uh.Samples))
+	mem := make([]int64, len(uh.Samples))
+	for i, s := range uh.Samples {
+		cpu[i] = s.Cpu
+		mem[i] = s.Mem
+	}
+	return percentile(cpu, p), percentile(mem, p)
+}
+
+//nearest-rank percentile
+func percentile(vals []int64, p float64) int64 {
+	if len(vals) == 0 {
+		return 0
+	}
+	sort.Slice(vals, func(i, j int) bool { return vals[i] < vals[j] })
+	rank := int(p/100*float64(len(vals))+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(vals) {
+		rank = len(vals) - 1
+	}
+	return vals[rank]
+}
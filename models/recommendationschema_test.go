@@ -0,0 +1,55 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUsagePercentiles(t *testing.T) {
+	uh := UsageHistory{}
+	for i, cpu := range []int64{50, 10, 40, 20, 30} {
+		uh.Samples = append(uh.Samples, UsageSample{Time: int64(i * 300), Cpu: cpu, Mem: cpu * 1000})
+	}
+	for p, want := range map[float64]int64{0: 10, 20: 10, 30: 20, 50: 30, 95: 50, 100: 50} {
+		cpu, mem := uh.Percentiles(p)
+		if cpu != want || mem != want*1000 {
+			t.Errorf("p%.0f = %d/%d, want %d/%d", p, cpu, mem, want, want*1000)
+		}
+	}
+	//the samples keep their order
+	if uh.Samples[0].Cpu != 50 || uh.Samples[4].Cpu != 30 {
+		t.Errorf("percentiles reordered the samples: %v", uh.Samples)
+	}
+
+	empty := UsageHistory{}
+	if cpu, mem := empty.Percentiles(95); cpu != 0 || mem != 0 {
+		t.Errorf("percentile of an empty history = %d/%d", cpu, mem)
+	}
+}
+
+func TestUsageHistorySamples(t *testing.T) {
+	start := time.Unix(1600000200, 0)
+	uh := UsageHistory{}
+
+	//pods of the workload in the same slot keep the peak of each resource
+	uh.AddSample(start, 300, 100, 5000)
+	uh.AddSample(start.Add(time.Minute), 300, 300, 1000)
+	uh.AddSample(start.Add(2*time.Minute), 300, 200, 2000)
+	if len(uh.Samples) != 1 || uh.Samples[0] != (UsageSample{Time: 1600000200, Cpu: 300, Mem: 5000}) {
+		t.Fatalf("samples of one slot = %v", uh.Samples)
+	}
+	if uh.LastSeen != start.Add(2*time.Minute).Unix() {
+		t.Errorf("last seen = %d", uh.LastSeen)
+	}
+
+	uh.AddSample(start.Add(5*time.Minute), 300, 50, 500)
+	uh.AddSample(start.Add(time.Hour), 300, 60, 600)
+	if len(uh.Samples) != 3 || uh.Duration() != time.Hour {
+		t.Errorf("%d samples over %v, want 3 over 1h", len(uh.Samples), uh.Duration())
+	}
+
+	uh.Prune(start.Add(time.Hour), 30*time.Minute)
+	if len(uh.Samples) != 1 || uh.Samples[0].Cpu != 60 || uh.Duration() != 0 {
+		t.Errorf("samples after pruning = %v", uh.Samples)
+	}
+}
@@ -0,0 +1,114 @@
+package models
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/fatih/structs"
+)
+
+const (
+	ROLLOUT_STATUS_IN_PROGRESS string = "InProgress"
+	ROLLOUT_STATUS_COMPLETE    string = "Complete"
+	ROLLOUT_STATUS_FAILED      string = "Failed"
+	ROLLOUT_STATUS_SUPERSEDED  string = "Superseded"
+
+	ROLLOUT_REASON_DEADLINE_EXCEEDED string = "ProgressDeadlineExceeded"
+	ROLLOUT_REASON_STALLED           string = "PartiallyAvailable"
+)
+
+type RolloutSchemaDefWrapper struct {
+	Schema RolloutSchemaDef `json:"schema"`
+}
+
+func (sd RolloutSchemaDefWrapper) Unwrap() *map[string]interface{} {
+	objMap := structs.Map(sd)
+	return &objMap
+}
+
+type RolloutSchemaDef struct {
+	Name              string `json:"name"`
+	ClusterName       string `json:"clusterName"`
+	Namespace         string `json:"namespace"`
+	ObjectUid         string `json:"objectUid"`
+	Revision          string `json:"revision"`
+	TemplateHash      string `json:"templateHash"`
+	StartTime         string `json:"startTime"`
+	EndTime           string `json:"endTime"`
+	Duration          string `json:"duration"`
+	OldImages         string `json:"oldImages"`
+	NewImages         string `json:"newImages"`
+	Status            string `json:"status"`
+	Stalled           string `json:"stalled"`
+	Reason            string `json:"reason"`
+	Message           string `json:"message"`
+	Replicas          string `json:"replicas"`
+	ReplicasUpdated   string `json:"replicasUpdated"`
+	ReplicasAvailable string `json:"replicasAvailable"`
+}
+
+func NewRolloutSchemaDefWrapper() RolloutSchemaDefWrapper {
+	schema := NewRolloutSchemaDef()
+	wrapper := RolloutSchemaDefWrapper{Schema: schema}
+	return wrapper
+}
+
+func NewRolloutSchemaDef() RolloutSchemaDef {
+	pdsd := RolloutSchemaDef{Name: "string", ClusterName: "string", Namespace: "string", ObjectUid: "string", Revision: "string", TemplateHash: "string",
+		StartTime: "date", EndTime: "date", Duration: "integer", OldImages: "string", NewImages: "string", Status: "string", Stalled: "boolean",
+		Reason: "string", Message: "string", Replicas: "integer", ReplicasUpdated: "integer", ReplicasAvailable: "integer"}
+	return pdsd
+}
+
+func (sd RolloutSchemaDef) Unwrap() *map[string]interface{} {
+	objMap := structs.Map(sd)
+	return &objMap
+}
+
+type RolloutSchema struct {
+	Name              string     `json:"name"`
+	ClusterName       string     `json:"clusterName"`
+	Namespace         string     `json:"namespace"`
+	ObjectUid         string     `json:"objectUid"`
+	Revision          string     `json:"revision"`
+	TemplateHash      string     `json:"templateHash"`
+	StartTime         time.Time  `json:"startTime"`
+	EndTime           *time.Time `json:"endTime"`
+	Duration          int64      `json:"duration"`
+	OldImages         string     `json:"oldImages"`
+	NewImages         string     `json:"newImages"`
+	Status            string     `json:"status"`
+	Stalled           bool       `json:"stalled"`
+	Reason            string     `json:"reason"`
+	Message           string     `json:"message"`
+	Replicas          int32      `json:"replicas"`
+	ReplicasUpdated   int32      `json:"replicasUpdated"`
+	ReplicasAvailable int32      `json:"replicasAvailable"`
+}
+
+func (rs *RolloutSchema) Equals(obj *RolloutSchema) bool {
+	return reflect.DeepEqual(*rs, *obj)
+}
+
+func (rs *RolloutSchema) IsInProgress() bool {
+	return rs.Status == ROLLOUT_STATUS_IN_PROGRESS
+}
+
+//true if the rollout failed after the given time
+func (rs *RolloutSchema) FailedSince(since time.Time) bool {
+	return rs.Status == ROLLOUT_STATUS_FAILED && rs.EndTime != nil && rs.EndTime.After(since)
+}
+
+//Finish closes the rollout with the given status and computes its duration (ms)
+func (rs *RolloutSchema) Finish(status string, reason string, message string) {
+	now := time.Now()
+	rs.Status = status
+	rs.Reason = reason
+	rs.Message = message
+	rs.EndTime = &now
+	rs.Duration = (now.UnixNano() - rs.StartTime.UnixNano()) / 1000000
+}
+
+func NewRolloutObj() RolloutSchema {
+	return RolloutSchema{Status: ROLLOUT_STATUS_IN_PROGRESS, Duration: 0, Replicas: 0, ReplicasUpdated: 0, ReplicasAvailable: 0}
+}
@@ -0,0 +1,172 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fatih/structs"
+)
+
+const (
+	SPOF_SINGLE_NODE    string = "singleNode"          //all ready replicas run on one node
+	SPOF_SINGLE_ZONE    string = "singleZone"          //all ready replicas run in one zone of a multi-zone cluster
+	SPOF_NO_CONSTRAINTS string = "noSpreadConstraints" //no pod anti-affinity and no topology spread constraints
+
+	METRIC_PATH_STATEFULSETS string = "StatefulSets"
+)
+
+//WorkloadSpread is the distribution of the replicas of a Deployment or StatefulSet across nodes and zones
+type WorkloadSpread struct {
+	Namespace      string           `json:"namespace"`
+	OwnerKind      string           `json:"ownerKind"`
+	Workload       string           `json:"workload"`
+	Replicas       int64            `json:"replicas"`
+	ReplicasReady  int64            `json:"replicasReady"`
+	Nodes          map[string]int64 `json:"nodes"` //ready replicas per node
+	Zones          map[string]int64 `json:"zones"` //ready replicas per zone
+	HasConstraints bool             `json:"hasConstraints"`
+	Issues         []string         `json:"issues"`
+}
+
+func NewWorkloadSpread(namespace string, kind string, workload string) WorkloadSpread {
+	return WorkloadSpread{Namespace: namespace, OwnerKind: kind, Workload: workload, Nodes: make(map[string]int64), Zones: make(map[string]int64), Issues: []string{}}
+}
+
+//Evaluate flags the workloads with more than one replica that can be taken down by the loss of a node or a zone
+func (ws *WorkloadSpread) Evaluate(clusterZones int) {
+	ws.Issues = []string{}
+	if ws.Replicas < 2 {
+		return
+	}
+	if ws.ReplicasReady > 1 && len(ws.Nodes) == 1 {
+		ws.Issues = append(ws.Issues, SPOF_SINGLE_NODE)
+	}
+	if ws.ReplicasReady > 1 && clusterZones > 1 && len(ws.Zones) == 1 {
+		ws.Issues = append(ws.Issues, SPOF_SINGLE_ZONE)
+	}
+	if !ws.HasConstraints {
+		ws.Issues = append(ws.Issues, SPOF_NO_CONSTRAINTS)
+	}
+}
+
+func (ws *WorkloadSpread) IsSinglePointOfFailure() bool {
+	return len(ws.Issues) > 0
+}
+
+func (ws *WorkloadSpread) HasIssue(issue string) bool {
+	for _, i := range ws.Issues {
+		if i == issue {
+			return true
+		}
+	}
+	return false
+}
+
+//GetSpreadFormatted returns the distribution in the format nodes: node-1=2,node-2=1; zones: zone-a=3
+func (ws *WorkloadSpread) GetSpreadFormatted() string {
+	return fmt.Sprintf("nodes: %s; zones: %s", formatCounts(ws.Nodes), formatCounts(ws.Zones))
+}
+
+func formatCounts(counts map[string]int64) string {
+	keys := []string{}
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = fmt.Sprintf("%s=%d", k, counts[k])
+	}
+	return strings.Join(pairs, ",")
+}
+
+//ClusterSpreadMetrics are the spread metrics of a Deployment or StatefulSet
+type ClusterSpreadMetrics struct {
+	Path                 string
+	Namespace            string
+	Workload             string
+	SinglePointOfFailure int64
+	SpreadReplicasReady  int64
+	SpreadNodes          int64
+	SpreadZones          int64
+}
+
+func (cpm ClusterSpreadMetrics) GetPath() string {
+	return cpm.Path
+}
+
+func (cpm ClusterSpreadMetrics) ShouldExcludeField(fieldName string) bool {
+	if fieldName == "Namespace" || fieldName == "Workload" || fieldName == "Path" {
+		return true
+	}
+	return false
+}
+
+func (cpm ClusterSpreadMetrics) Unwrap() *map[string]interface{} {
+	objMap := structs.Map(cpm)
+	return &objMap
+}
+
+func NewClusterSpreadMetrics(bag *AppDBag, spread *WorkloadSpread) ClusterSpreadMetrics {
+	kindPath := METRIC_PATH_APPS
+	if spread.OwnerKind == "StatefulSet" {
+		kindPath = METRIC_PATH_STATEFULSETS
+	}
+	p := fmt.Sprintf("%s%s%s%s%s%s%s%s%s", RootPath, METRIC_PATH_NAMESPACES, METRIC_SEPARATOR, spread.Namespace, METRIC_SEPARATOR, kindPath, METRIC_SEPARATOR, spread.Workload, METRIC_SEPARATOR)
+	metrics := ClusterSpreadMetrics{Path: p, Namespace: spread.Namespace, Workload: spread.Workload, SpreadReplicasReady: spread.ReplicasReady,
+		SpreadNodes: int64(len(spread.Nodes)), SpreadZones: int64(len(spread.Zones))}
+	if spread.IsSinglePointOfFailure() {
+		metrics.SinglePointOfFailure = 1
+	}
+	return metrics
+}
+
+//ClusterSpofMetrics are the number of workloads of the cluster or of a namespace flagged as single points of failure
+type ClusterSpofMetrics struct {
+	Path                    string
+	Namespace               string
+	SinglePointOfFailure    int64
+	SpofSingleNode          int64
+	SpofSingleZone          int64
+	SpofNoSpreadConstraints int64
+}
+
+func (cpm ClusterSpofMetrics) GetPath() string {
+	return cpm.Path
+}
+
+func (cpm ClusterSpofMetrics) ShouldExcludeField(fieldName string) bool {
+	if fieldName == "Namespace" || fieldName == "Path" {
+		return true
+	}
+	return false
+}
+
+func (cpm ClusterSpofMetrics) Unwrap() *map[string]interface{} {
+	objMap := structs.Map(cpm)
+	return &objMap
+}
+
+func NewClusterSpofMetrics(bag *AppDBag, ns string) ClusterSpofMetrics {
+	p := RootPath
+	if ns != "" && ns != ALL {
+		p = fmt.Sprintf("%s%s%s%s%s", p, METRIC_PATH_NAMESPACES, METRIC_SEPARATOR, ns, METRIC_SEPARATOR)
+	}
+	return ClusterSpofMetrics{Namespace: ns, Path: p}
+}
+
+func (cpm *ClusterSpofMetrics) Add(spread *WorkloadSpread) {
+	if spread.IsSinglePointOfFailure() {
+		cpm.SinglePointOfFailure++
+	}
+	if spread.HasIssue(SPOF_SINGLE_NODE) {
+		cpm.SpofSingleNode++
+	}
+	if spread.HasIssue(SPOF_SINGLE_ZONE) {
+		cpm.SpofSingleZone++
+	}
+	if spread.HasIssue(SPOF_NO_CONSTRAINTS) {
+		cpm.SpofNoSpreadConstraints++
+	}
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWorkloadSpreadEvaluate(t *testing.T) {
+	tests := []struct {
+		name         string
+		replicas     int64
+		ready        int64
+		nodes        map[string]int64
+		zones        map[string]int64
+		constrained  bool
+		clusterZones int
+		issues       string
+	}{
+		{"spread across nodes and zones", 3, 3, map[string]int64{"node-1": 2, "node-2": 1}, map[string]int64{"zone-a": 2, "zone-b": 1}, true, 2, ""},
+		{"single replica", 1, 1, map[string]int64{"node-1": 1}, map[string]int64{"zone-a": 1}, false, 2, ""},
+		{"single node", 3, 3, map[string]int64{"node-1": 3}, map[string]int64{"zone-a": 3}, true, 2, "singleNode,singleZone"},
+		{"single zone of a single zone cluster", 2, 2, map[string]int64{"node-1": 1, "node-2": 1}, map[string]int64{"zone-a": 2}, true, 1, ""},
+		{"single zone", 2, 2, map[string]int64{"node-1": 1, "node-2": 1}, map[string]int64{"zone-a": 2}, true, 3, "singleZone"},
+		//one ready replica is not a spread problem, the missing constraints still are
+		{"one ready replica", 3, 1, map[string]int64{"node-1": 1}, map[string]int64{"zone-a": 1}, false, 2, "noSpreadConstraints"},
+		{"no constraints", 2, 2, map[string]int64{"node-1": 1, "node-2": 1}, map[string]int64{}, false, 0, "noSpreadConstraints"},
+	}
+	for _, tt := range tests {
+		ws := NewWorkloadSpread("shop", "Deployment", "api")
+		ws.Replicas, ws.ReplicasReady, ws.Nodes, ws.Zones, ws.HasConstraints = tt.replicas, tt.ready, tt.nodes, tt.zones, tt.constrained
+		ws.Issues = []string{SPOF_NO_CONSTRAINTS}
+		ws.Evaluate(tt.clusterZones)
+		if issues := strings.Join(ws.Issues, ","); issues != tt.issues {
+			t.Errorf("%s: issues = %q, want %q", tt.name, issues, tt.issues)
+		}
+		if ws.IsSinglePointOfFailure() != (tt.issues != "") {
+			t.Errorf("%s: single point of failure = %t", tt.name, ws.IsSinglePointOfFailure())
+		}
+	}
+}
+
+func TestWorkloadSpreadMetrics(t *testing.T) {
+	ws := NewWorkloadSpread("data", "StatefulSet", "db")
+	ws.Replicas, ws.ReplicasReady = 3, 3
+	ws.Nodes["node-2"], ws.Nodes["node-1"] = 1, 2
+	ws.Zones["zone-a"] = 3
+	ws.Evaluate(2)
+	if spread := ws.GetSpreadFormatted(); spread != "nodes: node-1=2,node-2=1; zones: zone-a=3" {
+		t.Errorf("spread = %q", spread)
+	}
+
+	metrics := NewClusterSpreadMetrics(&AppDBag{}, &ws)
+	if !strings.HasSuffix(metrics.Path, "|Namespaces|data|StatefulSets|db|") || metrics.SinglePointOfFailure != 1 ||
+		metrics.SpreadNodes != 2 || metrics.SpreadZones != 1 || metrics.SpreadReplicasReady != 3 {
+		t.Errorf("spread metrics = %+v", metrics)
+	}
+
+	summary := NewClusterSpofMetrics(&AppDBag{}, "data")
+	summary.Add(&ws)
+	healthy := NewWorkloadSpread("data", "Deployment", "api")
+	summary.Add(&healthy)
+	if summary.SinglePointOfFailure != 1 || summary.SpofSingleZone != 1 || summary.SpofSingleNode != 0 || summary.SpofNoSpreadConstraints != 1 {
+		t.Errorf("single points of failure of the namespace = %+v", summary)
+	}
+	if all := NewClusterSpofMetrics(&AppDBag{}, ALL); all.Path != RootPath {
+		t.Errorf("path of the cluster summary = %q", all.Path)
+	}
+}
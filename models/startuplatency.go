@@ -0,0 +1,115 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	LATENCY_SCHEDULING string = "SchedulingLatency"
+	LATENCY_INIT       string = "InitLatency"
+	LATENCY_IMAGE_PULL string = "ImagePullLatency"
+	LATENCY_READY      string = "ReadyLatency"
+	LATENCY_STARTUP    string = "StartupLatency"
+)
+
+//number of pods that contributed to each average latency
+type latencySamples map[string]int64
+
+//stage of the startup of a pod, from and to are timestamps in ms
+type startupStage struct {
+	Name string
+	From int64
+	To   int64
+}
+
+//CalculateStartupLatencies derives the startup latencies (ms) of the pod from the recorded timestamps.
+//Latencies of stages the pod has not reached yet remain 0
+func (ps *PodSchema) CalculateStartupLatencies() {
+	for name, val := range ps.GetStartupLatencies() {
+		switch name {
+		case LATENCY_SCHEDULING:
+			ps.SchedulingLatency = val
+		case LATENCY_INIT:
+			ps.InitLatency = val
+		case LATENCY_IMAGE_PULL:
+			ps.ImagePullLatency = val
+		case LATENCY_READY:
+			ps.ReadyLatency = val
+		case LATENCY_STARTUP:
+			ps.StartupLatency = val
+		}
+	}
+}
+
+//GetStartupLatencies returns the latencies of the startup stages the pod has completed
+func (ps *PodSchema) GetStartupLatencies() map[string]int64 {
+	return ps.GetStartupLatenciesSince(time.Time{})
+}
+
+//GetStartupLatenciesSince returns the latencies of the startup stages the pod completed after the given time
+func (ps *PodSchema) GetStartupLatenciesSince(since time.Time) map[string]int64 {
+	sinceMillis := int64(0)
+	if !since.IsZero() {
+		sinceMillis = since.UnixNano() / int64(time.Millisecond)
+	}
+	latencies := make(map[string]int64)
+	for _, stage := range ps.startupStages() {
+		if stage.From > 0 && stage.To >= stage.From && stage.To >= sinceMillis {
+			latencies[stage.Name] = stage.To - stage.From
+		}
+	}
+	return latencies
+}
+
+func (ps *PodSchema) startupStages() []startupStage {
+	return []startupStage{
+		{LATENCY_SCHEDULING, ps.CreationTimeMillis, ps.ScheduledTimeMillis},
+		{LATENCY_INIT, ps.ScheduledTimeMillis, ps.InitializedTimeMillis},
+		{LATENCY_IMAGE_PULL, ps.ImagePullStartMillis, ps.ImagePullEndMillis},
+		{LATENCY_READY, ps.RunningStartTimeMillis, ps.ReadyTimeMillis},
+		{LATENCY_STARTUP, ps.CreationTimeMillis, ps.ReadyTimeMillis},
+	}
+}
+
+//running average of the latency across the pods that reached the stage
+func updateLatencyAverage(avg *int64, samples latencySamples, name string, value int64) {
+	count := samples[name] + 1
+	samples[name] = count
+	*avg = *avg + (value-*avg)/count
+}
+
+//AddStartupLatencies adds the latencies of the stages the pod completed after since to the averages,
+//so that each pod contributes in the metrics interval it started in
+func (cpm *ClusterPodMetrics) AddStartupLatencies(podObject *PodSchema, since time.Time) {
+	for name, val := range podObject.GetStartupLatenciesSince(since) {
+		switch name {
+		case LATENCY_SCHEDULING:
+			updateLatencyAverage(&cpm.SchedulingLatency, cpm.samples, name, val)
+		case LATENCY_INIT:
+			updateLatencyAverage(&cpm.InitLatency, cpm.samples, name, val)
+		case LATENCY_IMAGE_PULL:
+			updateLatencyAverage(&cpm.ImagePullLatency, cpm.samples, name, val)
+		case LATENCY_READY:
+			updateLatencyAverage(&cpm.ReadyLatency, cpm.samples, name, val)
+		case LATENCY_STARTUP:
+			updateLatencyAverage(&cpm.StartupLatency, cpm.samples, name, val)
+		}
+	}
+}
+
+func (cpm *ClusterAppMetrics) AddStartupLatencies(podObject *PodSchema, since time.Time) {
+	for name, val := range podObject.GetStartupLatenciesSince(since) {
+		switch name {
+		case LATENCY_SCHEDULING:
+			updateLatencyAverage(&cpm.SchedulingLatency, cpm.samples, name, val)
+		case LATENCY_INIT:
+			updateLatencyAverage(&cpm.InitLatency, cpm.samples, name, val)
+		case LATENCY_IMAGE_PULL:
+			updateLatencyAverage(&cpm.ImagePullLatency, cpm.samples, name, val)
+		case LATENCY_READY:
+			updateLatencyAverage(&cpm.ReadyLatency, cpm.samples, name, val)
+		case LATENCY_STARTUP:
+			updateLatencyAverage(&cpm.StartupLatency, cpm.samples, name, val)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAddStartupLatencies(t *testing.T) {
+	now := time.Now()
+	millis := func(ago time.Duration) int64 {
+		return now.Add(-ago).UnixNano() / int64(time.Millisecond)
+	}
+	//started 10 sec ago, within the interval
+	recent := PodSchema{CreationTimeMillis: millis(40 * time.Second), ScheduledTimeMillis: millis(38 * time.Second),
+		RunningStartTimeMillis: millis(30 * time.Second), ReadyTimeMillis: millis(10 * time.Second)}
+	//ready for an hour, reported again in every snapshot
+	old := PodSchema{CreationTimeMillis: millis(time.Hour + 20*time.Second), ScheduledTimeMillis: millis(time.Hour + 10*time.Second),
+		RunningStartTimeMillis: millis(time.Hour + 4*time.Second), ReadyTimeMillis: millis(time.Hour)}
+	//scheduled within the interval, not ready yet
+	starting := PodSchema{CreationTimeMillis: millis(2 * time.Minute), ScheduledTimeMillis: millis(50 * time.Second)}
+
+	since := now.Add(-time.Minute)
+	cpm := NewClusterPodMetrics(&AppDBag{}, ALL, ALL)
+	for _, p := range []*PodSchema{&recent, &old, &starting} {
+		cpm.AddStartupLatencies(p, since)
+	}
+	if cpm.StartupLatency != 30000 || cpm.ReadyLatency != 20000 {
+		t.Errorf("startup latency = %d, ready latency = %d, want 30000 and 20000 of the recent pod only", cpm.StartupLatency, cpm.ReadyLatency)
+	}
+	//(2000 + 70000) / 2
+	if cpm.SchedulingLatency != 36000 {
+		t.Errorf("scheduling latency = %d, want 36000", cpm.SchedulingLatency)
+	}
+
+	app := NewClusterAppMetrics(&AppDBag{}, &recent)
+	app.AddStartupLatencies(&old, since)
+	if app.StartupLatency != 0 || app.SchedulingLatency != 0 {
+		t.Errorf("tier latencies of the pod started an hour ago = %d, %d, want 0", app.StartupLatency, app.SchedulingLatency)
+	}
+
+	if latencies := old.GetStartupLatencies(); latencies[LATENCY_STARTUP] != 20000 || len(latencies) != 3 {
+		t.Errorf("GetStartupLatencies = %v, want the scheduling, ready and startup latencies", latencies)
+	}
+}
@@ -0,0 +1,152 @@
+package utils
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+//labels of namespaces and nodes, populated by the namespace watcher and the node informer
+var lockLabels = sync.RWMutex{}
+var namespaceLabels = make(map[string]labels.Set)
+var nodeLabels = make(map[string]labels.Set)
+
+var lockSelectors = sync.RWMutex{}
+var selectorCache = make(map[string]labels.Selector)
+var selectorErrors = make(map[string]error)
+
+//returns true if the labels of the namespace are new or different from the ones on record
+func UpdateNamespaceLabels(name string, l map[string]string) bool {
+	return updateLabels(namespaceLabels, name, l)
+}
+
+func DeleteNamespaceLabels(name string) {
+	lockLabels.Lock()
+	defer lockLabels.Unlock()
+	delete(namespaceLabels, name)
+}
+
+//returns true if the labels of the node are new or different from the ones on record
+func UpdateNodeLabels(name string, l map[string]string) bool {
+	return updateLabels(nodeLabels, name, l)
+}
+
+func DeleteNodeLabels(name string) {
+	lockLabels.Lock()
+	defer lockLabels.Unlock()
+	delete(nodeLabels, name)
+}
+
+//GetNodeLabels returns the labels of the node on record
+func GetNodeLabels(name string) map[string]string {
+	lockLabels.RLock()
+	defer lockLabels.RUnlock()
+	l := make(map[string]string)
+	for k, v := range nodeLabels[name] {
+		l[k] = v
+	}
+	return l
+}
+
+func updateLabels(store map[string]labels.Set, name string, l map[string]string) bool {
+	set := labels.Set{}
+	for k, v := range l {
+		set[k] = v
+	}
+	lockLabels.Lock()
+	defer lockLabels.Unlock()
+	existing, ok := store[name]
+	if ok && labels.Equals(existing, set) {
+		return false
+	}
+	store[name] = set
+	return true
+}
+
+func ValidateSelector(selector string) error {
+	_, err := parseSelector(selector)
+	return err
+}
+
+func parseSelector(selector string) (labels.Selector, error) {
+	lockSelectors.RLock()
+	s, ok := selectorCache[selector]
+	err, invalid := selectorErrors[selector]
+	lockSelectors.RUnlock()
+	if ok {
+		return s, nil
+	}
+	if invalid {
+		return nil, err
+	}
+	s, err = labels.Parse(selector)
+	lockSelectors.Lock()
+	defer lockSelectors.Unlock()
+	if err != nil {
+		err = fmt.Errorf("Label selector %s is invalid. %v", selector, err)
+		selectorErrors[selector] = err
+		return nil, err
+	}
+	selectorCache[selector] = s
+	return s, nil
+}
+
+//objects without labels on record do not match a non-empty selector.
+//Invalid selectors match nothing. They are reported once, when the config is validated
+func selectorMatches(selector string, store map[string]labels.Set, name string) bool {
+	if selector == "" {
+		return true
+	}
+	s, err := parseSelector(selector)
+	if err != nil {
+		return false
+	}
+	lockLabels.RLock()
+	defer lockLabels.RUnlock()
+	set, ok := store[name]
+	if !ok {
+		return false
+	}
+	return s.Matches(set)
+}
+
+func NSMatchesSelector(ns string, selector string) bool {
+	return selectorMatches(selector, namespaceLabels, ns)
+}
+
+func NodeMatchesSelector(name string, selector string) bool {
+	return selectorMatches(selector, nodeLabels, name)
+}
+
+//workload selectors have the format kind/namespace/name. Each part may contain wildcards, e.g. DaemonSet/*/fluentd-*
+func ValidateWorkloadSelector(selector string) error {
+	parts := strings.Split(selector, "/")
+	if len(parts) != 3 {
+		return fmt.Errorf("Workload selector %s is invalid. Use this format: kind/namespace/name", selector)
+	}
+	for _, p := range parts {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("Workload selector %s is invalid. %v", selector, err)
+		}
+	}
+	return nil
+}
+
+func WorkloadMatchesSelectors(kind string, ns string, name string, selectors []string) bool {
+	for _, selector := range selectors {
+		parts := strings.Split(selector, "/")
+		if len(parts) != 3 {
+			continue
+		}
+		kindOk, _ := path.Match(strings.ToLower(parts[0]), strings.ToLower(kind))
+		nsOk, _ := path.Match(parts[1], ns)
+		nameOk, _ := path.Match(parts[2], name)
+		if kindOk && nsOk && nameOk {
+			return true
+		}
+	}
+	return false
+}
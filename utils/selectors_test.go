@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"testing"
+)
+
+func TestValidateSelector(t *testing.T) {
+	tests := []struct {
+		selector string
+		valid    bool
+	}{
+		{"", true},
+		{"team=payments", true},
+		{"team in (payments,orders),env!=dev", true},
+		{"!canary", true},
+		{"team=", true},
+		{"team==payments==orders", false},
+		{"team in payments", false},
+		{"=payments", false},
+	}
+	for _, tt := range tests {
+		err := ValidateSelector(tt.selector)
+		if (err == nil) != tt.valid {
+			t.Errorf("ValidateSelector(%q) error = %v, valid %t", tt.selector, err, tt.valid)
+		}
+	}
+}
+
+func TestUpdateNamespaceLabels(t *testing.T) {
+	defer DeleteNamespaceLabels("test-update")
+	tests := []struct {
+		labels  map[string]string
+		changed bool
+	}{
+		{map[string]string{"team": "payments"}, true},
+		{map[string]string{"team": "payments"}, false},
+		{map[string]string{"team": "orders"}, true},
+		{map[string]string{"team": "orders", "env": "prod"}, true},
+		{map[string]string{}, true},
+		{nil, false},
+	}
+	for i, tt := range tests {
+		if changed := UpdateNamespaceLabels("test-update", tt.labels); changed != tt.changed {
+			t.Errorf("step %d: UpdateNamespaceLabels(%v) = %t, want %t", i, tt.labels, changed, tt.changed)
+		}
+	}
+}
+
+func TestNSMatchesSelector(t *testing.T) {
+	UpdateNamespaceLabels("test-payments", map[string]string{"team": "payments", "env": "prod"})
+	UpdateNamespaceLabels("test-orders", map[string]string{"team": "orders", "env": "dev"})
+	defer DeleteNamespaceLabels("test-payments")
+	defer DeleteNamespaceLabels("test-orders")
+
+	tests := []struct {
+		ns       string
+		selector string
+		matches  bool
+	}{
+		{"test-payments", "", true},
+		{"test-unknown", "", true},
+		{"test-payments", "team=payments", true},
+		{"test-orders", "team=payments", false},
+		{"test-payments", "team in (payments,orders),env=prod", true},
+		{"test-orders", "team in (payments,orders),env=prod", false},
+		{"test-orders", "env!=prod", true},
+		{"test-payments", "canary", false},
+		{"test-payments", "!canary", true},
+		{"test-unknown", "team=payments", false},
+		{"test-unknown", "!canary", false},
+		{"test-payments", "team in payments", false},
+	}
+	for _, tt := range tests {
+		if matches := NSMatchesSelector(tt.ns, tt.selector); matches != tt.matches {
+			t.Errorf("NSMatchesSelector(%q, %q) = %t, want %t", tt.ns, tt.selector, matches, tt.matches)
+		}
+	}
+}
+
+func TestNodeMatchesSelector(t *testing.T) {
+	UpdateNodeLabels("test-node-1", map[string]string{"node-role.kubernetes.io/worker": "", "pool": "gpu"})
+	defer DeleteNodeLabels("test-node-1")
+
+	tests := []struct {
+		node     string
+		selector string
+		matches  bool
+	}{
+		{"test-node-1", "node-role.kubernetes.io/worker", true},
+		{"test-node-1", "pool=gpu", true},
+		{"test-node-1", "pool=cpu", false},
+		{"test-node-2", "pool=gpu", false},
+		{"test-node-2", "", true},
+	}
+	for _, tt := range tests {
+		if matches := NodeMatchesSelector(tt.node, tt.selector); matches != tt.matches {
+			t.Errorf("NodeMatchesSelector(%q, %q) = %t, want %t", tt.node, tt.selector, matches, tt.matches)
+		}
+	}
+}
+
+func TestDeleteNodeLabels(t *testing.T) {
+	UpdateNodeLabels("test-node-deleted", map[string]string{"pool": "gpu"})
+	DeleteNodeLabels("test-node-deleted")
+	if NodeMatchesSelector("test-node-deleted", "pool=gpu") {
+		t.Errorf("deleted node matches the selector")
+	}
+	if l := GetNodeLabels("test-node-deleted"); len(l) != 0 {
+		t.Errorf("GetNodeLabels of deleted node = %v, want empty", l)
+	}
+}
@@ -40,6 +40,11 @@ func GetKey(namespace, podName string) string {
 	return fmt.Sprintf("%s/%s", namespace, podName)
 }
 
+//KeyNamespace returns the namespace of a key built by GetKey or by the keys of configMaps, secrets, services and endpoints
+func KeyNamespace(key string) string {
+	return strings.SplitN(key, "/", 2)[0]
+}
+
 func GetConfigMapKey(cm *v1.ConfigMap) string {
 	return fmt.Sprintf("%s/%s", cm.Namespace, cm.Name)
 }
@@ -115,7 +120,18 @@ func MapContainsNocase(m map[string]interface{}, key string) (interface{}, bool)
 func NSQualifiesForMonitoring(ns string, bag *m.AppDBag) bool {
 	return (len(bag.NsToMonitor) == 0 ||
 		StringInSlice(ns, bag.NsToMonitor)) &&
-		!StringInSlice(ns, bag.NsToMonitorExclude)
+		!StringInSlice(ns, bag.NsToMonitorExclude) &&
+		NSMatchesSelector(ns, bag.NsSelector)
+}
+
+func NSSelectedForInstrumentation(ns string, bag *m.AppDBag) bool {
+	return StringInSlice(ns, bag.NsToInstrument) ||
+		(bag.NsToInstrumentSelector != "" && NSMatchesSelector(ns, bag.NsToInstrumentSelector))
+}
+
+func NSSelectedForDashboard(ns string, bag *m.AppDBag) bool {
+	return StringInSlice(ns, bag.NsToDashboard) ||
+		(bag.NsToDashboardSelector != "" && NSMatchesSelector(ns, bag.NsToDashboardSelector))
 }
 
 func IsSystemNamespace(namespace string) bool {
@@ -128,7 +144,13 @@ func IsSystemNamespace(namespace string) bool {
 func NodeQualifiesForMonitoring(name string, bag *m.AppDBag) bool {
 	return (len(bag.NodesToMonitor) == 0 ||
 		StringInSlice(name, bag.NodesToMonitor)) &&
-		!StringInSlice(name, bag.NodesToMonitorExclude)
+		!StringInSlice(name, bag.NodesToMonitorExclude) &&
+		NodeMatchesSelector(name, bag.NodeSelector)
+}
+
+func NodeSelectedForDashboard(name string, bag *m.AppDBag) bool {
+	return StringInSlice(name, bag.NodesToDashboard) ||
+		(bag.NodesToDashboardSelector != "" && NodeMatchesSelector(name, bag.NodesToDashboardSelector))
 }
 
 func FormatDuration(sinceNanosec int64) string {
@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"testing"
+)
+
+func TestKeyNamespace(t *testing.T) {
+	tests := []struct {
+		key       string
+		namespace string
+	}{
+		{GetKey("payments", "api-7d9f"), "payments"},
+		{"payments/api/v1", "payments"},
+		{"/api", ""},
+		{"payments", "payments"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if namespace := KeyNamespace(tt.key); namespace != tt.namespace {
+			t.Errorf("KeyNamespace(%q) = %q, want %q", tt.key, namespace, tt.namespace)
+		}
+	}
+}
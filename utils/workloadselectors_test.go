@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"testing"
+)
+
+func TestValidateWorkloadSelector(t *testing.T) {
+	tests := []struct {
+		selector string
+		valid    bool
+	}{
+		{"DaemonSet/*/fluentd-*", true},
+		{"Deployment/payments/api", true},
+		{"*/*/*", true},
+		{"Deployment/payments", false},
+		{"Deployment/payments/api/v1", false},
+		{"Deployment/[/api", false},
+	}
+	for _, tt := range tests {
+		err := ValidateWorkloadSelector(tt.selector)
+		if (err == nil) != tt.valid {
+			t.Errorf("ValidateWorkloadSelector(%q) error = %v, valid %t", tt.selector, err, tt.valid)
+		}
+	}
+}
+
+func TestWorkloadMatchesSelectors(t *testing.T) {
+	selectors := []string{"DaemonSet/*/fluentd-*", "deployment/payments/api", "StatefulSet/kafka/*", "invalid"}
+	tests := []struct {
+		kind    string
+		ns      string
+		name    string
+		matches bool
+	}{
+		{"DaemonSet", "logging", "fluentd-es", true},
+		{"DaemonSet", "logging", "filebeat", false},
+		{"Deployment", "payments", "api", true},
+		{"Deployment", "payments", "api-v2", false},
+		{"Deployment", "orders", "api", false},
+		{"StatefulSet", "kafka", "broker", true},
+		{"statefulset", "kafka", "zookeeper", true},
+		{"Deployment", "kafka", "broker", false},
+	}
+	for _, tt := range tests {
+		if matches := WorkloadMatchesSelectors(tt.kind, tt.ns, tt.name, selectors); matches != tt.matches {
+			t.Errorf("WorkloadMatchesSelectors(%q, %q, %q) = %t, want %t", tt.kind, tt.ns, tt.name, matches, tt.matches)
+		}
+	}
+	if WorkloadMatchesSelectors("Deployment", "payments", "api", nil) {
+		t.Errorf("WorkloadMatchesSelectors without selectors = true, want false")
+	}
+}
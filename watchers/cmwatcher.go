@@ -5,37 +5,35 @@ import (
 	"time"
 
 	"k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/watch"
-
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	log "github.com/sirupsen/logrus"
 
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 
 	"github.com/appdynamics/cluster-agent/config"
 	"github.com/appdynamics/cluster-agent/utils"
 )
 
 type ConfigWatcher struct {
-	Client      *kubernetes.Clientset
-	LockConfigs *sync.RWMutex
-	CMCache     map[string]v1.ConfigMap
-	ConfManager *config.MutexConfigManager
-	Listener    *WatchListener
-	UpdateDelay bool
-	Logger      *log.Logger
+	Client          *kubernetes.Clientset
+	LockConfigs     *sync.RWMutex
+	CMCache         map[string]v1.ConfigMap
+	ConfManager     *config.MutexConfigManager
+	InformerManager *InformerManager
+	Listener        *WatchListener
+	UpdateDelay     bool
+	Logger          *log.Logger
 }
 
-func NewConfigWatcher(client *kubernetes.Clientset, cm *config.MutexConfigManager, cache *map[string]v1.ConfigMap, listener WatchListener, l *log.Logger, lock *sync.RWMutex) *ConfigWatcher {
-	sw := ConfigWatcher{Client: client, CMCache: *cache, ConfManager: cm, Listener: &listener, Logger: l, LockConfigs: lock}
+func NewConfigWatcher(client *kubernetes.Clientset, cm *config.MutexConfigManager, im *InformerManager, cache *map[string]v1.ConfigMap, listener WatchListener, l *log.Logger, lock *sync.RWMutex) *ConfigWatcher {
+	sw := ConfigWatcher{Client: client, InformerManager: im, CMCache: *cache, ConfManager: cm, Listener: &listener, Logger: l, LockConfigs: lock}
 	sw.UpdateDelay = true
+	im.SubscribeToNamespacePurge(sw.purgeNamespaces)
 	return &sw
 }
 
-func (pw ConfigWatcher) WatchConfigs() {
-	api := pw.Client.CoreV1()
-	listOptions := metav1.ListOptions{}
+func (pw ConfigWatcher) WatchConfigs(stopCh <-chan struct{}) {
 	pw.Logger.Info("Starting Config Watcher...")
 	bag := (*pw.ConfManager).Get()
 	dashTimer := time.NewTimer(time.Second * time.Duration(bag.SnapshotSyncInterval))
@@ -45,35 +43,27 @@ func (pw ConfigWatcher) WatchConfigs() {
 		pw.Logger.Info("ConfigMap Update delay lifted.")
 	}()
 
-	watcher, err := api.ConfigMaps(metav1.NamespaceAll).Watch(listOptions)
-	if err != nil {
-		pw.Logger.WithField("error", err).Error("Issues when setting up Config watcher. Aborting...")
-	} else {
-
-		ch := watcher.ResultChan()
-
-		for ev := range ch {
-			cm, ok := ev.Object.(*v1.ConfigMap)
-			if !ok {
-				pw.Logger.Warn("Expected ConfigMap, but received an object of an unknown type.")
-				continue
-			}
-			switch ev.Type {
-			case watch.Added:
+	i := pw.InformerManager.NewInformer("configmaps", pw.InformerManager.ConfigMapInformer, cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if cm, ok := ConfigMapFromMetadata(obj); ok {
 				pw.onNewConfig(cm)
-				break
-
-			case watch.Deleted:
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if cm, ok := ConfigMapFromMetadata(DeletedObject(obj)); ok {
 				pw.onDeleteConfig(cm)
-				break
-
-			case watch.Modified:
+			}
+		},
+		UpdateFunc: func(oldObj interface{}, newObj interface{}) {
+			if IsResync(oldObj, newObj) {
+				return
+			}
+			if cm, ok := ConfigMapFromMetadata(newObj); ok {
 				pw.onUpdateConfig(cm)
-				break
 			}
-
-		}
-	}
+		},
+	})
+	i.Run(stopCh)
 	pw.Logger.Info("Exiting Config watcher.")
 }
 
@@ -135,3 +125,14 @@ func (pw ConfigWatcher) CloneMap() map[string]v1.ConfigMap {
 	}
 	return m
 }
+
+//drops the config maps of the namespaces that are no longer monitored
+func (pw ConfigWatcher) purgeNamespaces(keep func(namespace string) bool) {
+	pw.LockConfigs.Lock()
+	defer pw.LockConfigs.Unlock()
+	for key := range pw.CMCache {
+		if !keep(utils.KeyNamespace(key)) {
+			delete(pw.CMCache, key)
+		}
+	}
+}
@@ -4,31 +4,33 @@ import (
 	"sync"
 
 	"k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/watch"
 
 	log "github.com/sirupsen/logrus"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 
 	"github.com/appdynamics/cluster-agent/config"
 	"github.com/appdynamics/cluster-agent/utils"
 )
 
 type EndpointWatcher struct {
-	Client        *kubernetes.Clientset
-	LockEP        *sync.RWMutex
-	EndpointCache map[string]v1.Endpoints
-	UpdatedCache  map[string]v1.Endpoints
-	ConfManager   *config.MutexConfigManager
-	Logger        *log.Logger
+	Client          *kubernetes.Clientset
+	LockEP          *sync.RWMutex
+	EndpointCache   map[string]v1.Endpoints
+	UpdatedCache    map[string]v1.Endpoints
+	ConfManager     *config.MutexConfigManager
+	InformerManager *InformerManager
+	Logger          *log.Logger
 }
 
 var lockUpdated = sync.RWMutex{}
 
-func NewEndpointWatcher(client *kubernetes.Clientset, cm *config.MutexConfigManager, cache *map[string]v1.Endpoints, l *log.Logger, lock *sync.RWMutex) *EndpointWatcher {
-	epw := EndpointWatcher{Client: client, EndpointCache: *cache, UpdatedCache: make(map[string]v1.Endpoints),
+func NewEndpointWatcher(client *kubernetes.Clientset, cm *config.MutexConfigManager, im *InformerManager, cache *map[string]v1.Endpoints, l *log.Logger, lock *sync.RWMutex) *EndpointWatcher {
+	epw := EndpointWatcher{Client: client, InformerManager: im, EndpointCache: *cache, UpdatedCache: make(map[string]v1.Endpoints),
 		ConfManager: cm, Logger: l, LockEP: lock}
+	im.SubscribeToNamespacePurge(epw.purgeNamespaces)
 	return &epw
 }
 
@@ -38,40 +40,32 @@ func (pw *EndpointWatcher) qualifies(p *v1.Endpoints) bool {
 }
 
 //end points
-func (pw EndpointWatcher) WatchEndpoints() {
-	api := pw.Client.CoreV1()
-	listOptions := metav1.ListOptions{}
+func (pw EndpointWatcher) WatchEndpoints(stopCh <-chan struct{}) {
 	pw.Logger.Info("Starting Endpoint Watcher...")
 
-	watcher, err := api.Endpoints(metav1.NamespaceAll).Watch(listOptions)
-	if err != nil {
-		pw.Logger.WithField("error", err).Error("Issues when setting up Endpoint watcher. Aborting...")
-	} else {
-
-		ch := watcher.ResultChan()
-
-		for ev := range ch {
-			ep, ok := ev.Object.(*v1.Endpoints)
-			if !ok {
-				pw.Logger.Warn("Expected endpoints, but received an object of an unknown type. ")
-				continue
-			}
-			switch ev.Type {
-			case watch.Added:
+	i := pw.InformerManager.NewInformer("endpoints", func(factory informers.SharedInformerFactory, namespace string) cache.SharedIndexInformer {
+		return factory.Core().V1().Endpoints().Informer()
+	}, cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if ep, ok := obj.(*v1.Endpoints); ok {
 				pw.onNewEndpoint(ep)
-				break
-
-			case watch.Deleted:
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if ep, ok := DeletedObject(obj).(*v1.Endpoints); ok {
 				pw.onDeleteEndpoint(ep)
-				break
-
-			case watch.Modified:
+			}
+		},
+		UpdateFunc: func(oldObj interface{}, newObj interface{}) {
+			if IsResync(oldObj, newObj) {
+				return
+			}
+			if ep, ok := newObj.(*v1.Endpoints); ok {
 				pw.onUpdateEndpoint(ep)
-				break
 			}
-
-		}
-	}
+		},
+	})
+	i.Run(stopCh)
 	pw.Logger.Info("Exiting endpoint watcher.")
 }
 
@@ -144,3 +138,23 @@ func (pw EndpointWatcher) ResetUpdatedCache() {
 	defer lockUpdated.Unlock()
 	pw.UpdatedCache = make(map[string]v1.Endpoints)
 }
+
+//drops the endpoints of the namespaces that are no longer monitored
+func (pw EndpointWatcher) purgeNamespaces(keep func(namespace string) bool) {
+	pw.LockEP.Lock()
+	for key := range pw.EndpointCache {
+		if !keep(utils.KeyNamespace(key)) {
+			delete(pw.EndpointCache, key)
+		}
+	}
+	pw.LockEP.Unlock()
+
+	//the updated cache has its own lock, see updateChanged
+	lockUpdated.Lock()
+	for key := range pw.UpdatedCache {
+		if !keep(utils.KeyNamespace(key)) {
+			delete(pw.UpdatedCache, key)
+		}
+	}
+	lockUpdated.Unlock()
+}
@@ -0,0 +1,65 @@
+package watchers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"testing"
+
+	"github.com/appdynamics/cluster-agent/config"
+	m "github.com/appdynamics/cluster-agent/models"
+	"github.com/appdynamics/cluster-agent/utils"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestConfManager(bag *m.AppDBag) (*config.MutexConfigManager, *log.Logger) {
+	l := log.New()
+	l.Out = ioutil.Discard
+	return &config.MutexConfigManager{Conf: bag, Mutex: &sync.Mutex{}, Logger: l}, l
+}
+
+//purges run on config updates while the informers keep delivering endpoint updates. Run with -race
+func TestEndpointPurgeDuringUpdates(t *testing.T) {
+	cm, l := newTestConfManager(&m.AppDBag{})
+	im := NewInformerManager(nil, nil, cm, l)
+	cache := make(map[string]v1.Endpoints)
+	epw := NewEndpointWatcher(nil, cm, im, &cache, l, &sync.RWMutex{})
+
+	keep := func(namespace string) bool {
+		return namespace == "kept"
+	}
+	var wg sync.WaitGroup
+	for _, ns := range []string{"kept", "dropped"} {
+		wg.Add(1)
+		go func(ns string) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				epw.onUpdateEndpoint(&v1.Endpoints{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: fmt.Sprintf("svc-%d", i%20)}})
+			}
+		}(ns)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			epw.purgeNamespaces(keep)
+		}
+	}()
+	wg.Wait()
+
+	epw.purgeNamespaces(keep)
+	if n := len(epw.CloneMap()); n != 20 {
+		t.Errorf("endpoints after purge = %d, want 20", n)
+	}
+	updated := epw.GetUpdated()
+	if len(updated) != 20 {
+		t.Errorf("updated endpoints after purge = %d, want 20", len(updated))
+	}
+	for key := range updated {
+		if utils.KeyNamespace(key) != "kept" {
+			t.Errorf("updated endpoint %s of a purged namespace", key)
+		}
+	}
+}
@@ -0,0 +1,482 @@
+package watchers
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/appdynamics/cluster-agent/config"
+	"github.com/appdynamics/cluster-agent/utils"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/tools/cache"
+)
+
+//key of the factory of the cluster-scoped informers. Namespace names cannot contain underscores
+const CLUSTER_SCOPE string = "_cluster"
+
+//builds an informer of a particular resource type from the factory of a namespace. namespace is metav1.NamespaceAll for the cluster-wide factory
+type InformerBuilder func(factory informers.SharedInformerFactory, namespace string) cache.SharedIndexInformer
+
+//InformerManager maintains the shared informer factories of the agent.
+//Cluster-scoped resources are served by a factory of their own. Unless NsToMonitor is set, namespaced resources are served
+//by a single cluster-wide factory. When NsToMonitor is set, namespaced resources are served by a factory per monitored namespace.
+//Namespace factories are added and removed as the list of namespaces changes in the config.
+//Informers relist and rewatch on their own when the API server closes the watch or the resource version expires
+type InformerManager struct {
+	Client         *kubernetes.Clientset
+	MetadataClient metadata.Interface
+	ConfManager    *config.MutexConfigManager
+	Logger         *log.Logger
+	lock           *sync.RWMutex
+	factories      map[string]informers.SharedInformerFactory
+	stopChannels   map[string]chan struct{}
+	informers      []*NamespacedInformer
+	clusterWide    bool
+	stopCh         <-chan struct{}
+	lockListeners  *sync.RWMutex
+	nsListeners    []func(namespace string)
+	nodeListeners  []func(node string)
+	purgeListeners []func(keep func(namespace string) bool)
+}
+
+func NewInformerManager(client *kubernetes.Clientset, metaClient metadata.Interface, cm *config.MutexConfigManager, l *log.Logger) *InformerManager {
+	im := InformerManager{Client: client, MetadataClient: metaClient, ConfManager: cm, Logger: l, lock: &sync.RWMutex{},
+		factories: make(map[string]informers.SharedInformerFactory), stopChannels: make(map[string]chan struct{}), informers: []*NamespacedInformer{},
+		lockListeners: &sync.RWMutex{}, nsListeners: []func(string){}, nodeListeners: []func(string){},
+		purgeListeners: []func(func(string) bool){}}
+	cm.SubscribeToConfigUpdates(im.reconcile)
+	return &im
+}
+
+//SubscribeToNamespaceLabels registers a listener, which re-evaluates the selection of the objects of a namespace
+//when the labels of the namespace change
+func (im *InformerManager) SubscribeToNamespaceLabels(listener func(namespace string)) {
+	im.lockListeners.Lock()
+	defer im.lockListeners.Unlock()
+	im.nsListeners = append(im.nsListeners, listener)
+}
+
+//SubscribeToNodeLabels registers a listener, which re-evaluates the selection of the objects of a node
+//when the labels of the node change
+func (im *InformerManager) SubscribeToNodeLabels(listener func(node string)) {
+	im.lockListeners.Lock()
+	defer im.lockListeners.Unlock()
+	im.nodeListeners = append(im.nodeListeners, listener)
+}
+
+//SubscribeToNamespacePurge registers a listener, which drops the state of the namespaces no longer served by the informers.
+//keep returns true for the namespaces that are still monitored
+func (im *InformerManager) SubscribeToNamespacePurge(listener func(keep func(namespace string) bool)) {
+	im.lockListeners.Lock()
+	defer im.lockListeners.Unlock()
+	im.purgeListeners = append(im.purgeListeners, listener)
+}
+
+func (im *InformerManager) NamespaceLabelsChanged(namespace string) {
+	im.lockListeners.RLock()
+	listeners := im.nsListeners
+	im.lockListeners.RUnlock()
+	for _, listener := range listeners {
+		listener(namespace)
+	}
+}
+
+func (im *InformerManager) NodeLabelsChanged(node string) {
+	im.lockListeners.RLock()
+	listeners := im.nodeListeners
+	im.lockListeners.RUnlock()
+	for _, listener := range listeners {
+		listener(node)
+	}
+}
+
+//creates an informer of a namespaced resource type, which spans all monitored namespaces
+func (im *InformerManager) NewInformer(name string, builder InformerBuilder, handler cache.ResourceEventHandler) *NamespacedInformer {
+	ni := NamespacedInformer{Name: name, manager: im, builder: builder, handler: handler, lock: &sync.RWMutex{}, informers: make(map[string]cache.SharedIndexInformer)}
+	return &ni
+}
+
+//creates an informer of a cluster-scoped resource type, e.g. nodes or namespaces
+func (im *InformerManager) NewClusterInformer(name string, builder InformerBuilder, handler cache.ResourceEventHandler) *NamespacedInformer {
+	ni := im.NewInformer(name, builder, handler)
+	ni.clusterScoped = true
+	return ni
+}
+
+//namespaces to build factories for. metav1.NamespaceAll when no namespaces are listed explicitly
+func (im *InformerManager) namespaces() []string {
+	bag := (*im.ConfManager).Get()
+	list := []string{}
+	for _, ns := range bag.NsToMonitor {
+		if ns != "" && !utils.StringInSlice(ns, list) && !utils.StringInSlice(ns, bag.NsToMonitorExclude) {
+			list = append(list, ns)
+		}
+	}
+	if len(list) == 0 {
+		list = append(list, metav1.NamespaceAll)
+	}
+	return list
+}
+
+//factories the informer belongs to. Must be called under the manager lock
+func (im *InformerManager) scopesOf(ni *NamespacedInformer) []string {
+	if ni.clusterScoped {
+		return []string{CLUSTER_SCOPE}
+	}
+	list := []string{}
+	for scope, _ := range im.factories {
+		if scope != CLUSTER_SCOPE {
+			list = append(list, scope)
+		}
+	}
+	return list
+}
+
+//informers of the cluster-scoped factory are keyed by metav1.NamespaceAll
+func informerKey(scope string) string {
+	if scope == CLUSTER_SCOPE {
+		return metav1.NamespaceAll
+	}
+	return scope
+}
+
+func (im *InformerManager) start(ni *NamespacedInformer, stopCh <-chan struct{}) {
+	im.lock.Lock()
+	defer im.lock.Unlock()
+	if im.stopCh == nil {
+		im.stopCh = stopCh
+		go func() {
+			<-stopCh
+			im.stopAll()
+		}()
+		im.addFactory(CLUSTER_SCOPE)
+		desired := im.namespaces()
+		im.clusterWide = len(desired) == 1 && desired[0] == metav1.NamespaceAll
+		for _, ns := range desired {
+			im.addFactory(ns)
+		}
+	}
+	im.informers = append(im.informers, ni)
+	for _, scope := range im.scopesOf(ni) {
+		ni.attach(informerKey(scope), im.factories[scope])
+		im.factories[scope].Start(im.stopChannels[scope])
+	}
+}
+
+//must be called under the manager lock
+func (im *InformerManager) addFactory(scope string) {
+	bag := (*im.ConfManager).Get()
+	switch scope {
+	case CLUSTER_SCOPE:
+		im.Logger.Info("Starting informers of cluster-scoped resources")
+	case metav1.NamespaceAll:
+		im.Logger.Info("Starting cluster-wide informers")
+	default:
+		im.Logger.Infof("Starting informers for namespace %s", scope)
+	}
+	resync := time.Duration(bag.InformerResyncInterval) * time.Second
+	factory := informers.NewSharedInformerFactoryWithOptions(im.Client, resync, informers.WithNamespace(informerKey(scope)))
+	im.factories[scope] = factory
+	im.stopChannels[scope] = make(chan struct{})
+}
+
+//must be called under the manager lock
+func (im *InformerManager) removeFactory(scope string) {
+	switch scope {
+	case CLUSTER_SCOPE:
+		im.Logger.Info("Stopping informers of cluster-scoped resources")
+	case metav1.NamespaceAll:
+		im.Logger.Info("Stopping cluster-wide informers")
+	default:
+		im.Logger.Infof("Stopping informers for namespace %s", scope)
+	}
+	if ch, ok := im.stopChannels[scope]; ok {
+		close(ch)
+	}
+	delete(im.stopChannels, scope)
+	delete(im.factories, scope)
+	for _, ni := range im.informers {
+		if ni.clusterScoped == (scope == CLUSTER_SCOPE) {
+			ni.detach(informerKey(scope))
+		}
+	}
+}
+
+//attaches the informers of namespaced resources to a new factory. Must be called under the manager lock
+func (im *InformerManager) attachAll(ns string) {
+	factory := im.factories[ns]
+	for _, ni := range im.informers {
+		if !ni.clusterScoped {
+			ni.attach(ns, factory)
+		}
+	}
+	factory.Start(im.stopChannels[ns])
+}
+
+func (im *InformerManager) stopAll() {
+	im.lock.Lock()
+	defer im.lock.Unlock()
+	for scope, _ := range im.factories {
+		im.removeFactory(scope)
+	}
+}
+
+//adds and removes namespace factories when the list of monitored namespaces changes.
+//The state of the namespaces that are no longer monitored is purged by the subscribed workers and watchers
+func (im *InformerManager) reconcile() {
+	keep, purge := im.reconcileFactories()
+	if !purge {
+		return
+	}
+	im.lockListeners.RLock()
+	listeners := im.purgeListeners
+	im.lockListeners.RUnlock()
+	for _, listener := range listeners {
+		listener(keep)
+	}
+}
+
+//returns the filter of the namespaces still monitored and true if factories of namespaces were removed
+func (im *InformerManager) reconcileFactories() (func(namespace string) bool, bool) {
+	im.lock.Lock()
+	defer im.lock.Unlock()
+	if im.stopCh == nil {
+		//not started yet
+		return nil, false
+	}
+	desired := im.namespaces()
+	clusterWide := len(desired) == 1 && desired[0] == metav1.NamespaceAll
+	if clusterWide == im.clusterWide && clusterWide {
+		return nil, false
+	}
+
+	//the factory of the cluster-scoped resources keeps running, so that nodes and namespaces are not replayed
+	removed := false
+	for scope, _ := range im.factories {
+		if scope != CLUSTER_SCOPE && !utils.StringInSlice(scope, desired) {
+			im.removeFactory(scope)
+			removed = true
+		}
+	}
+	im.clusterWide = clusterWide
+	for _, ns := range desired {
+		if _, ok := im.factories[ns]; ok {
+			continue
+		}
+		im.addFactory(ns)
+		im.attachAll(ns)
+	}
+	if clusterWide || !removed {
+		return nil, false
+	}
+	return func(namespace string) bool {
+		return utils.StringInSlice(namespace, desired)
+	}, true
+}
+
+//approximate size in bytes of the objects held by the cache of each informer
+func (im *InformerManager) MemoryEstimate() map[string]int64 {
+	im.lock.RLock()
+	defer im.lock.RUnlock()
+	estimate := make(map[string]int64)
+	for _, ni := range im.informers {
+		estimate[ni.Name] += ni.memoryEstimate()
+	}
+	return estimate
+}
+
+//NamespacedInformer is a set of informers of the same resource type, one per monitored namespace.
+//It exposes the subset of cache.SharedIndexInformer used by the workers
+type NamespacedInformer struct {
+	Name          string
+	manager       *InformerManager
+	builder       InformerBuilder
+	handler       cache.ResourceEventHandler
+	lock          *sync.RWMutex
+	informers     map[string]cache.SharedIndexInformer
+	clusterScoped bool
+}
+
+func (ni *NamespacedInformer) attach(ns string, factory informers.SharedInformerFactory) {
+	ni.lock.Lock()
+	defer ni.lock.Unlock()
+	if _, ok := ni.informers[ns]; ok {
+		return
+	}
+	i := ni.builder(factory, ns)
+	i.AddEventHandler(ni.handler)
+	ni.informers[ns] = i
+}
+
+func (ni *NamespacedInformer) detach(ns string) {
+	ni.lock.Lock()
+	defer ni.lock.Unlock()
+	delete(ni.informers, ns)
+}
+
+//starts the informers and blocks until the stop channel is closed
+func (ni *NamespacedInformer) Run(stopCh <-chan struct{}) {
+	ni.manager.start(ni, stopCh)
+	<-stopCh
+}
+
+func (ni *NamespacedInformer) HasSynced() bool {
+	ni.lock.RLock()
+	defer ni.lock.RUnlock()
+	if len(ni.informers) == 0 {
+		return false
+	}
+	for _, i := range ni.informers {
+		if !i.HasSynced() {
+			return false
+		}
+	}
+	return true
+}
+
+func (ni *NamespacedInformer) GetStore() cache.Store {
+	return &namespacedStore{ni}
+}
+
+func (ni *NamespacedInformer) storeFor(ns string) cache.Store {
+	ni.lock.RLock()
+	defer ni.lock.RUnlock()
+	if i, ok := ni.informers[metav1.NamespaceAll]; ok {
+		return i.GetStore()
+	}
+	if i, ok := ni.informers[ns]; ok {
+		return i.GetStore()
+	}
+	return nil
+}
+
+func (ni *NamespacedInformer) stores() []cache.Store {
+	ni.lock.RLock()
+	defer ni.lock.RUnlock()
+	list := []cache.Store{}
+	for _, i := range ni.informers {
+		list = append(list, i.GetStore())
+	}
+	return list
+}
+
+//sums the serialized size of the cached objects. Objects of the core API types implement Size()
+func (ni *NamespacedInformer) memoryEstimate() int64 {
+	var total int64 = 0
+	for _, store := range ni.stores() {
+		for _, obj := range store.List() {
+			if sized, ok := obj.(interface{ Size() int }); ok {
+				total += int64(sized.Size())
+			}
+		}
+	}
+	return total
+}
+
+//namespacedStore routes store operations to the store of the object namespace
+type namespacedStore struct {
+	ni *NamespacedInformer
+}
+
+func (s *namespacedStore) objectStore(obj interface{}) (cache.Store, error) {
+	metaObj, err := meta.Accessor(DeletedObject(obj))
+	if err != nil {
+		return nil, err
+	}
+	store := s.ni.storeFor(metaObj.GetNamespace())
+	if store == nil {
+		return nil, fmt.Errorf("Namespace %s is not monitored", metaObj.GetNamespace())
+	}
+	return store, nil
+}
+
+func (s *namespacedStore) Add(obj interface{}) error {
+	store, err := s.objectStore(obj)
+	if err != nil {
+		return err
+	}
+	return store.Add(obj)
+}
+
+func (s *namespacedStore) Update(obj interface{}) error {
+	store, err := s.objectStore(obj)
+	if err != nil {
+		return err
+	}
+	return store.Update(obj)
+}
+
+func (s *namespacedStore) Delete(obj interface{}) error {
+	store, err := s.objectStore(obj)
+	if err != nil {
+		return err
+	}
+	return store.Delete(obj)
+}
+
+func (s *namespacedStore) List() []interface{} {
+	list := []interface{}{}
+	for _, store := range s.ni.stores() {
+		list = append(list, store.List()...)
+	}
+	return list
+}
+
+func (s *namespacedStore) ListKeys() []string {
+	list := []string{}
+	for _, store := range s.ni.stores() {
+		list = append(list, store.ListKeys()...)
+	}
+	return list
+}
+
+func (s *namespacedStore) Get(obj interface{}) (item interface{}, exists bool, err error) {
+	store, err := s.objectStore(obj)
+	if err != nil {
+		return nil, false, nil
+	}
+	return store.Get(obj)
+}
+
+func (s *namespacedStore) GetByKey(key string) (item interface{}, exists bool, err error) {
+	ns, _, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return nil, false, err
+	}
+	store := s.ni.storeFor(ns)
+	if store == nil {
+		return nil, false, nil
+	}
+	return store.GetByKey(key)
+}
+
+func (s *namespacedStore) Replace(list []interface{}, resourceVersion string) error {
+	return fmt.Errorf("Replace is not supported by the store of %s informer", s.ni.Name)
+}
+
+func (s *namespacedStore) Resync() error {
+	return nil
+}
+
+//unwraps objects of delete notifications which missed the final state of the object
+func DeletedObject(obj interface{}) interface{} {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		return tombstone.Obj
+	}
+	return obj
+}
+
+//periodic resyncs deliver updates with unchanged objects
+func IsResync(oldObj interface{}, newObj interface{}) bool {
+	oldMeta, errOld := meta.Accessor(oldObj)
+	newMeta, errNew := meta.Accessor(newObj)
+	if errOld != nil || errNew != nil {
+		return false
+	}
+	return oldMeta.GetResourceVersion() == newMeta.GetResourceVersion()
+}
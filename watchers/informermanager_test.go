@@ -0,0 +1,219 @@
+package watchers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	m "github.com/appdynamics/cluster-agent/models"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+)
+
+//API server with no objects. Watches stay open until the informers stop
+func newEmptyAPIServer() *httptest.Server {
+	kinds := map[string]string{"pods": "PodList", "nodes": "NodeList"}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("watch") == "true" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+			return
+		}
+		for resource, kind := range kinds {
+			if len(r.URL.Path) > len(resource) && r.URL.Path[len(r.URL.Path)-len(resource):] == resource {
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprintf(w, `{"kind": "%s", "apiVersion": "v1", "metadata": {"resourceVersion": "1"}, "items": []}`, kind)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	}))
+}
+
+func newTestInformerManager(t *testing.T, bag *m.AppDBag) *InformerManager {
+	srv := newEmptyAPIServer()
+	t.Cleanup(srv.Close)
+	client, err := kubernetes.NewForConfig(&rest.Config{Host: srv.URL})
+	if err != nil {
+		t.Fatalf("Unable to create the client: %v", err)
+	}
+	cm, l := newTestConfManager(bag)
+	return NewInformerManager(client, nil, cm, l)
+}
+
+func (im *InformerManager) scopes() []string {
+	im.lock.RLock()
+	defer im.lock.RUnlock()
+	list := []string{}
+	for scope := range im.factories {
+		list = append(list, scope)
+	}
+	sort.Strings(list)
+	return list
+}
+
+func (ni *NamespacedInformer) keys() []string {
+	ni.lock.RLock()
+	defer ni.lock.RUnlock()
+	list := []string{}
+	for key := range ni.informers {
+		list = append(list, key)
+	}
+	sort.Strings(list)
+	return list
+}
+
+func TestReconcileFactories(t *testing.T) {
+	bag := &m.AppDBag{NsToMonitor: []string{"shop", "cart"}}
+	im := newTestInformerManager(t, bag)
+	var purged func(namespace string) bool
+	purges := 0
+	im.SubscribeToNamespacePurge(func(keep func(namespace string) bool) {
+		purged = keep
+		purges++
+	})
+
+	pods := im.NewInformer("pods", func(factory informers.SharedInformerFactory, namespace string) cache.SharedIndexInformer {
+		return factory.Core().V1().Pods().Informer()
+	}, cache.ResourceEventHandlerFuncs{})
+	nodes := im.NewClusterInformer("nodes", func(factory informers.SharedInformerFactory, namespace string) cache.SharedIndexInformer {
+		return factory.Core().V1().Nodes().Informer()
+	}, cache.ResourceEventHandlerFuncs{})
+
+	//config updates before the informers start are ignored
+	im.reconcile()
+	if scopes := im.scopes(); len(scopes) != 0 {
+		t.Fatalf("factories before start = %v, want none", scopes)
+	}
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	im.start(pods, stopCh)
+	im.start(nodes, stopCh)
+	if scopes := fmt.Sprint(im.scopes()); scopes != "[_cluster cart shop]" {
+		t.Errorf("factories = %s, want [_cluster cart shop]", scopes)
+	}
+	if keys := fmt.Sprint(pods.keys()); keys != "[cart shop]" {
+		t.Errorf("pod informers = %s, want [cart shop]", keys)
+	}
+	nodeInformer := nodes.informers[""]
+
+	//cart is no longer monitored, web is added
+	bag.NsToMonitor = []string{"shop", "web"}
+	im.reconcile()
+	if scopes := fmt.Sprint(im.scopes()); scopes != "[_cluster shop web]" {
+		t.Errorf("factories after the update = %s, want [_cluster shop web]", scopes)
+	}
+	if keys := fmt.Sprint(pods.keys()); keys != "[shop web]" {
+		t.Errorf("pod informers after the update = %s, want [shop web]", keys)
+	}
+	if purges != 1 || purged == nil || !purged("shop") || !purged("web") || purged("cart") {
+		t.Errorf("purge of cart was not requested, purges %d", purges)
+	}
+
+	//switch to all namespaces. Nothing to purge, cluster-scoped informers keep running
+	bag.NsToMonitor = []string{}
+	im.reconcile()
+	if scopes := fmt.Sprint(im.scopes()); scopes != "[ _cluster]" {
+		t.Errorf("factories in cluster-wide mode = %q, want [ _cluster]", scopes)
+	}
+	if keys := pods.keys(); len(keys) != 1 || keys[0] != "" {
+		t.Errorf("pod informers in cluster-wide mode = %q, want the cluster-wide informer", keys)
+	}
+	if purges != 1 {
+		t.Errorf("purges after the switch to all namespaces = %d, want 1", purges)
+	}
+	if nodes.informers[""] != nodeInformer {
+		t.Errorf("node informer was replaced on the switch to all namespaces")
+	}
+
+	//excluded namespaces are not served
+	bag.NsToMonitor = []string{"shop", "cart"}
+	bag.NsToMonitorExclude = []string{"cart"}
+	im.reconcile()
+	if scopes := fmt.Sprint(im.scopes()); scopes != "[_cluster shop]" {
+		t.Errorf("factories with an excluded namespace = %s, want [_cluster shop]", scopes)
+	}
+	if purges != 2 || purged("cart") || !purged("shop") {
+		t.Errorf("purge on the switch back to namespaces: purges %d", purges)
+	}
+	if store := pods.storeFor("cart"); store != nil {
+		t.Errorf("store of the excluded namespace is served")
+	}
+}
+
+func TestNamespacedStore(t *testing.T) {
+	im := newTestInformerManager(t, &m.AppDBag{NsToMonitor: []string{"shop", "cart"}})
+	pods := im.NewInformer("pods", func(factory informers.SharedInformerFactory, namespace string) cache.SharedIndexInformer {
+		return factory.Core().V1().Pods().Informer()
+	}, cache.ResourceEventHandlerFuncs{})
+	if pods.HasSynced() {
+		t.Errorf("informer without factories reports synced")
+	}
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	im.start(pods, stopCh)
+	if !cache.WaitForCacheSync(stopCh, pods.HasSynced) {
+		t.Fatalf("informers of the namespaces did not sync")
+	}
+
+	store := pods.GetStore()
+	for _, p := range []*v1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "api-1", Namespace: "shop"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "api-2", Namespace: "shop"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "cart-1", Namespace: "cart"}},
+	} {
+		if err := store.Add(p); err != nil {
+			t.Fatalf("Add %s/%s: %v", p.Namespace, p.Name, err)
+		}
+	}
+	if err := store.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "web"}}); err == nil {
+		t.Errorf("Add to a namespace that is not monitored succeeded")
+	}
+
+	if obj, ok, err := store.GetByKey("cart/cart-1"); err != nil || !ok || obj.(*v1.Pod).Name != "cart-1" {
+		t.Errorf("GetByKey cart/cart-1 = %v, %t, %v", obj, ok, err)
+	}
+	if _, ok, _ := store.GetByKey("web/web-1"); ok {
+		t.Errorf("GetByKey found a pod of a namespace that is not monitored")
+	}
+	keys := store.ListKeys()
+	sort.Strings(keys)
+	if fmt.Sprint(keys) != "[cart/cart-1 shop/api-1 shop/api-2]" {
+		t.Errorf("ListKeys = %v", keys)
+	}
+
+	//delete notifications of objects whose final state was missed carry a tombstone
+	tombstone := cache.DeletedFinalStateUnknown{Key: "shop/api-2", Obj: &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "api-2", Namespace: "shop"}}}
+	if err := store.Delete(tombstone); err != nil {
+		t.Errorf("Delete of the tombstone: %v", err)
+	}
+	if len(store.List()) != 2 {
+		t.Errorf("List after delete = %d pods, want 2", len(store.List()))
+	}
+}
+
+func TestIsResync(t *testing.T) {
+	old := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "api-1", ResourceVersion: "10"}}
+	same := old.DeepCopy()
+	changed := old.DeepCopy()
+	changed.ResourceVersion = "11"
+	if !IsResync(old, same) {
+		t.Errorf("update with the same resource version is not a resync")
+	}
+	if IsResync(old, changed) {
+		t.Errorf("update with a new resource version is a resync")
+	}
+	if IsResync("api-1", same) {
+		t.Errorf("update of an object without metadata is a resync")
+	}
+}
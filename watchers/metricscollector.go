@@ -0,0 +1,181 @@
+package watchers
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/appdynamics/cluster-agent/config"
+	m "github.com/appdynamics/cluster-agent/models"
+	"github.com/appdynamics/cluster-agent/utils"
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const METRICS_API_PATH string = "apis/metrics.k8s.io/v1beta1"
+
+//MetricsCollector fetches the usage of all pods and nodes from metrics-server once per metrics cycle.
+//The snapshot is shared by the pod and node workers, which would otherwise make a request per object.
+//When metrics-server is unavailable, the last known snapshot is kept and marked as stale
+type MetricsCollector struct {
+	Client      *kubernetes.Clientset
+	ConfManager *config.MutexConfigManager
+	Logger      *log.Logger
+	lock        *sync.RWMutex
+	pods        map[string]m.PodMetricsObj
+	nodes       map[string]m.NodeMetricsObj
+	lastAttempt time.Time
+	lastSuccess time.Time
+	lastError   error
+}
+
+func NewMetricsCollector(client *kubernetes.Clientset, cm *config.MutexConfigManager, l *log.Logger) *MetricsCollector {
+	mc := MetricsCollector{Client: client, ConfManager: cm, Logger: l, lock: &sync.RWMutex{},
+		pods: make(map[string]m.PodMetricsObj), nodes: make(map[string]m.NodeMetricsObj)}
+	return &mc
+}
+
+//refreshes the snapshot unless it was already refreshed in the current metrics cycle.
+//The requests are made without the lock. Readers keep the previous snapshot until the new one is swapped in
+func (mc *MetricsCollector) Refresh() {
+	bag := (*mc.ConfManager).Get()
+	cycle := time.Duration(bag.MetricsSyncInterval) * time.Second
+	mc.lock.Lock()
+	if time.Since(mc.lastAttempt) < cycle/2 {
+		mc.lock.Unlock()
+		return
+	}
+	attempt := time.Now()
+	mc.lastAttempt = attempt
+	mc.lock.Unlock()
+
+	timeout := time.Duration(bag.MetricsServerTimeout) * time.Second
+	nodes, errNodes := mc.fetchNodes(timeout)
+	pods, errPods := mc.fetchPods(timeout, bag)
+
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	if errNodes != nil || errPods != nil {
+		mc.lastError = errNodes
+		if mc.lastError == nil {
+			mc.lastError = errPods
+		}
+		mc.Logger.WithFields(log.Fields{"error": mc.lastError, "lastUpdate": mc.lastSuccess}).Warn("Metrics server is unavailable. Usage metrics are stale")
+		return
+	}
+	mc.nodes = nodes
+	mc.pods = pods
+	mc.lastSuccess = attempt
+	mc.lastError = nil
+	mc.Logger.WithFields(log.Fields{"pods": len(pods), "nodes": len(nodes)}).Debug("Metrics snapshot refreshed")
+}
+
+//list of namespaces to request pod metrics for. metav1.NamespaceAll unless namespaces are listed explicitly
+func (mc *MetricsCollector) namespaces(bag *m.AppDBag) []string {
+	list := []string{}
+	for _, ns := range bag.NsToMonitor {
+		if ns != "" && !utils.StringInSlice(ns, list) && !utils.StringInSlice(ns, bag.NsToMonitorExclude) {
+			list = append(list, ns)
+		}
+	}
+	if len(list) == 0 {
+		list = append(list, metav1.NamespaceAll)
+	}
+	return list
+}
+
+func (mc *MetricsCollector) fetchPods(timeout time.Duration, bag *m.AppDBag) (map[string]m.PodMetricsObj, error) {
+	pods := make(map[string]m.PodMetricsObj)
+	for _, ns := range mc.namespaces(bag) {
+		path := fmt.Sprintf("%s/pods", METRICS_API_PATH)
+		if ns != metav1.NamespaceAll {
+			path = fmt.Sprintf("%s/namespaces/%s/pods", METRICS_API_PATH, ns)
+		}
+		var list m.PodMetricsObjList
+		if err := mc.get(path, timeout, &list); err != nil {
+			return nil, err
+		}
+		for _, item := range list.Items {
+			pods[utils.GetKey(item.Metadata.Namespace, item.Metadata.Name)] = item
+		}
+	}
+	return pods, nil
+}
+
+func (mc *MetricsCollector) fetchNodes(timeout time.Duration) (map[string]m.NodeMetricsObj, error) {
+	nodes := make(map[string]m.NodeMetricsObj)
+	var list m.NodeMetricsObjList
+	if err := mc.get(fmt.Sprintf("%s/nodes", METRICS_API_PATH), timeout, &list); err != nil {
+		return nil, err
+	}
+	for _, item := range list.Items {
+		nodes[item.Metadata.Name] = item
+	}
+	return nodes, nil
+}
+
+func (mc *MetricsCollector) get(path string, timeout time.Duration, obj interface{}) error {
+	req := mc.Client.RESTClient().Get().AbsPath(path)
+	if timeout > 0 {
+		req = req.Timeout(timeout)
+	}
+	data, err := req.DoRaw()
+	if err != nil {
+		return fmt.Errorf("Issues when requesting metrics with path [%s]: %v", path, err)
+	}
+	if err = json.Unmarshal(data, obj); err != nil {
+		return fmt.Errorf("Unmarshal issues with metrics of path [%s]: %v", path, err)
+	}
+	return nil
+}
+
+//usage of the pod from the latest snapshot. nil if metrics-server has not reported the pod or the sample is older than MetricsMaxAge
+func (mc *MetricsCollector) GetPodMetrics(namespace string, podName string) *m.PodMetricsObj {
+	maxAge := time.Duration((*mc.ConfManager).Get().MetricsMaxAge) * time.Second
+	mc.lock.RLock()
+	defer mc.lock.RUnlock()
+	if obj, ok := mc.pods[utils.GetKey(namespace, podName)]; ok && mc.isFresh(obj.Timestamp, maxAge) {
+		return &obj
+	}
+	return nil
+}
+
+//usage of the node from the latest snapshot. nil if metrics-server has not reported the node or the sample is older than MetricsMaxAge
+func (mc *MetricsCollector) GetNodeMetrics(nodeName string) *m.NodeMetricsObj {
+	maxAge := time.Duration((*mc.ConfManager).Get().MetricsMaxAge) * time.Second
+	mc.lock.RLock()
+	defer mc.lock.RUnlock()
+	if obj, ok := mc.nodes[nodeName]; ok && mc.isFresh(obj.Timestamp, maxAge) {
+		return &obj
+	}
+	return nil
+}
+
+//age of a sample is measured from its timestamp, or from the refresh when metrics-server did not set one.
+//Must be called under the lock
+func (mc *MetricsCollector) isFresh(timestamp string, maxAge time.Duration) bool {
+	if maxAge <= 0 {
+		return true
+	}
+	sampled := mc.lastSuccess
+	if t, err := time.Parse(time.RFC3339, timestamp); err == nil {
+		sampled = t
+	}
+	return time.Since(sampled) <= maxAge
+}
+
+//true when the last request to metrics-server failed
+func (mc *MetricsCollector) IsStale() bool {
+	mc.lock.RLock()
+	defer mc.lock.RUnlock()
+	return mc.lastError != nil
+}
+
+//time of the last successful refresh. Zero if metrics-server has never responded
+func (mc *MetricsCollector) LastUpdate() time.Time {
+	mc.lock.RLock()
+	defer mc.lock.RUnlock()
+	return mc.lastSuccess
+}
@@ -0,0 +1,137 @@
+package watchers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/appdynamics/cluster-agent/config"
+	m "github.com/appdynamics/cluster-agent/models"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func TestIsFresh(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name        string
+		timestamp   string
+		lastSuccess time.Time
+		maxAge      time.Duration
+		fresh       bool
+	}{
+		{"recent sample", now.Add(-time.Minute).Format(time.RFC3339), now, 3 * time.Minute, true},
+		{"old sample", now.Add(-5 * time.Minute).Format(time.RFC3339), now, 3 * time.Minute, false},
+		{"no limit", now.Add(-time.Hour).Format(time.RFC3339), now, 0, true},
+		{"no timestamp, recent refresh", "", now.Add(-time.Minute), 3 * time.Minute, true},
+		{"no timestamp, old refresh", "", now.Add(-5 * time.Minute), 3 * time.Minute, false},
+		{"invalid timestamp", "yesterday", now.Add(-5 * time.Minute), 3 * time.Minute, false},
+	}
+	for _, tt := range tests {
+		mc := MetricsCollector{lastSuccess: tt.lastSuccess}
+		if fresh := mc.isFresh(tt.timestamp, tt.maxAge); fresh != tt.fresh {
+			t.Errorf("%s: isFresh = %t, want %t", tt.name, fresh, tt.fresh)
+		}
+	}
+}
+
+//metrics-server stub. Responds with the usage of one node and one pod until it is told to fail
+type metricsServerStub struct {
+	lock     sync.Mutex
+	failing  bool
+	requests int
+}
+
+func (s *metricsServerStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.requests++
+	if s.failing {
+		http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/" + METRICS_API_PATH + "/nodes":
+		fmt.Fprint(w, `{"kind": "NodeMetricsList", "items": [{"metadata": {"name": "node-1"}, "usage": {"cpu": "250m", "memory": "1Gi"}}]}`)
+	case "/" + METRICS_API_PATH + "/pods", "/" + METRICS_API_PATH + "/namespaces/shop/pods":
+		fmt.Fprint(w, `{"kind": "PodMetricsList", "items": [{"metadata": {"name": "api-1", "namespace": "shop"}, "containers": []}]}`)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *metricsServerStub) fail(failing bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.failing = failing
+}
+
+func newTestMetricsCollector(client *kubernetes.Clientset, bag *m.AppDBag) *MetricsCollector {
+	l := log.New()
+	l.Out = ioutil.Discard
+	return NewMetricsCollector(client, &config.MutexConfigManager{Conf: bag, Mutex: &sync.Mutex{}, Logger: l}, l)
+}
+
+func TestMetricsCollectorRefresh(t *testing.T) {
+	stub := &metricsServerStub{}
+	srv := httptest.NewServer(stub)
+	defer srv.Close()
+	client, err := kubernetes.NewForConfig(&rest.Config{Host: srv.URL})
+	if err != nil {
+		t.Fatalf("Unable to create the client: %v", err)
+	}
+	mc := newTestMetricsCollector(client, &m.AppDBag{NsToMonitor: []string{"shop"}})
+
+	mc.Refresh()
+	if mc.IsStale() || mc.LastUpdate().IsZero() {
+		t.Fatalf("snapshot after a successful refresh: stale %t, last update %v", mc.IsStale(), mc.LastUpdate())
+	}
+	if mc.GetPodMetrics("shop", "api-1") == nil || mc.GetNodeMetrics("node-1") == nil {
+		t.Fatalf("usage of the pod and the node is missing from the snapshot")
+	}
+	lastUpdate := mc.LastUpdate()
+
+	//metrics-server goes down. The previous snapshot is kept and marked as stale
+	stub.fail(true)
+	mc.Refresh()
+	if !mc.IsStale() {
+		t.Errorf("snapshot is not stale after a failed refresh")
+	}
+	if !mc.LastUpdate().Equal(lastUpdate) {
+		t.Errorf("last update moved to %v after a failed refresh, want %v", mc.LastUpdate(), lastUpdate)
+	}
+	if mc.GetPodMetrics("shop", "api-1") == nil || mc.GetNodeMetrics("node-1") == nil {
+		t.Errorf("failed refresh dropped the previous snapshot")
+	}
+
+	//recovery
+	stub.fail(false)
+	mc.Refresh()
+	if mc.IsStale() || !mc.LastUpdate().After(lastUpdate) {
+		t.Errorf("snapshot after recovery: stale %t, last update %v", mc.IsStale(), mc.LastUpdate())
+	}
+}
+
+func TestMetricsCollectorRefreshOncePerCycle(t *testing.T) {
+	stub := &metricsServerStub{}
+	srv := httptest.NewServer(stub)
+	defer srv.Close()
+	client, err := kubernetes.NewForConfig(&rest.Config{Host: srv.URL})
+	if err != nil {
+		t.Fatalf("Unable to create the client: %v", err)
+	}
+	mc := newTestMetricsCollector(client, &m.AppDBag{MetricsSyncInterval: 60})
+
+	//the pod and node workers both refresh in the same cycle
+	mc.Refresh()
+	mc.Refresh()
+	if stub.requests != 2 {
+		t.Errorf("requests to metrics-server = %d, want 2 (nodes and pods of all namespaces)", stub.requests)
+	}
+}
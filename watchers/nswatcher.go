@@ -4,12 +4,12 @@ import (
 	"sync"
 
 	"k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/watch"
 
 	"github.com/appdynamics/cluster-agent/config"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 
 	m "github.com/appdynamics/cluster-agent/models"
 	"github.com/appdynamics/cluster-agent/utils"
@@ -17,63 +17,65 @@ import (
 )
 
 type NSWatcher struct {
-	Client      *kubernetes.Clientset
-	LockNS      *sync.RWMutex
-	NSCache     map[string]m.NsSchema
-	ConfManager *config.MutexConfigManager
-	Logger      *log.Logger
+	Client          *kubernetes.Clientset
+	LockNS          *sync.RWMutex
+	NSCache         map[string]m.NsSchema
+	ConfManager     *config.MutexConfigManager
+	InformerManager *InformerManager
+	Listener        *WatchListener
+	Logger          *log.Logger
 }
 
-func NewNSWatcher(client *kubernetes.Clientset, cm *config.MutexConfigManager, cache *map[string]m.NsSchema, l *log.Logger, lock *sync.RWMutex) *NSWatcher {
-	epw := NSWatcher{Client: client, NSCache: *cache, ConfManager: cm, Logger: l, LockNS: lock}
+func NewNSWatcher(client *kubernetes.Clientset, cm *config.MutexConfigManager, im *InformerManager, cache *map[string]m.NsSchema, listener WatchListener, l *log.Logger, lock *sync.RWMutex) *NSWatcher {
+	epw := NSWatcher{Client: client, InformerManager: im, NSCache: *cache, ConfManager: cm, Listener: &listener, Logger: l, LockNS: lock}
 	return &epw
 }
 
 func (pw *NSWatcher) qualifies(p *v1.Namespace) bool {
-	return (len((*pw.ConfManager).Get().NsToMonitor) == 0 ||
-		utils.StringInSlice(p.Name, (*pw.ConfManager).Get().NsToMonitor)) &&
-		!utils.StringInSlice(p.Name, (*pw.ConfManager).Get().NsToMonitorExclude)
+	return utils.NSQualifiesForMonitoring(p.Name, (*pw.ConfManager).Get())
 }
 
 //quotas
-func (pw NSWatcher) WatchNamespaces() {
-	api := pw.Client.CoreV1()
-	listOptions := metav1.ListOptions{}
+func (pw NSWatcher) WatchNamespaces(stopCh <-chan struct{}) {
 	pw.Logger.Info("Starting Namespace Watcher...")
 
-	watcher, err := api.Namespaces().Watch(listOptions)
-	if err != nil {
-		pw.Logger.WithField("error", err).Error("Issues when setting up Namespace watcher. Aborting...")
-	} else {
-
-		ch := watcher.ResultChan()
-
-		for ev := range ch {
-			ns, ok := ev.Object.(*v1.Namespace)
-			if !ok {
-				pw.Logger.Warn("Expected Namespace, but received an object of an unknown type. ")
-				continue
-			}
-			switch ev.Type {
-			case watch.Added:
+	i := pw.InformerManager.NewClusterInformer("namespaces", func(factory informers.SharedInformerFactory, namespace string) cache.SharedIndexInformer {
+		return factory.Core().V1().Namespaces().Informer()
+	}, cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if ns, ok := obj.(*v1.Namespace); ok {
 				pw.onNewNamespace(ns)
-				break
-
-			case watch.Deleted:
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if ns, ok := DeletedObject(obj).(*v1.Namespace); ok {
 				pw.onDeleteNamespace(ns)
-				break
-
-			case watch.Modified:
+			}
+		},
+		UpdateFunc: func(oldObj interface{}, newObj interface{}) {
+			if IsResync(oldObj, newObj) {
+				return
+			}
+			if ns, ok := newObj.(*v1.Namespace); ok {
 				pw.onUpdateNamespace(ns)
-				break
 			}
+		},
+	})
+	i.Run(stopCh)
+	pw.Logger.Info("Exiting Namespace watcher...")
+}
 
-		}
+//label selectors are re-evaluated when namespace labels change.
+//the workers subscribed to the informer manager re-queue the objects of the namespace
+func (pw NSWatcher) notifyListener(ns *v1.Namespace) {
+	if utils.UpdateNamespaceLabels(ns.Name, ns.Labels) && (*pw.ConfManager).Get().NsSelector != "" {
+		pw.Logger.Debugf("Labels of namespace %s changed. Re-evaluating the namespace selector\n", ns.Name)
+		pw.InformerManager.NamespaceLabelsChanged(ns.Name)
 	}
-	pw.Logger.Info("Exiting Namespace watcher...")
 }
 
 func (pw NSWatcher) onNewNamespace(ns *v1.Namespace) {
+	pw.notifyListener(ns)
 	if !pw.qualifies(ns) {
 		return
 	}
@@ -82,9 +84,11 @@ func (pw NSWatcher) onNewNamespace(ns *v1.Namespace) {
 }
 
 func (pw NSWatcher) onDeleteNamespace(ns *v1.Namespace) {
-	if !pw.qualifies(ns) {
-		return
-	}
+	utils.DeleteNamespaceLabels(ns.Name)
+	pw.removeFromMap(ns)
+}
+
+func (pw NSWatcher) removeFromMap(ns *v1.Namespace) {
 	key := ns.Name
 	pw.LockNS.RLock()
 	_, ok := pw.NSCache[key]
@@ -98,7 +102,10 @@ func (pw NSWatcher) onDeleteNamespace(ns *v1.Namespace) {
 }
 
 func (pw NSWatcher) onUpdateNamespace(ns *v1.Namespace) {
+	pw.notifyListener(ns)
 	if !pw.qualifies(ns) {
+		//the namespace may no longer match the selector
+		pw.removeFromMap(ns)
 		return
 	}
 	pw.updateMap(ns)
@@ -5,69 +5,60 @@ import (
 
 	"github.com/appdynamics/cluster-agent/config"
 	"k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/watch"
-
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 
 	"github.com/appdynamics/cluster-agent/utils"
 )
 
 type PVCWatcher struct {
-	Client      *kubernetes.Clientset
-	LockPVC     *sync.RWMutex
-	PVCCache    map[string]v1.PersistentVolumeClaim
-	ConfManager *config.MutexConfigManager
-	Logger      *log.Logger
+	Client          *kubernetes.Clientset
+	LockPVC         *sync.RWMutex
+	PVCCache        map[string]v1.PersistentVolumeClaim
+	ConfManager     *config.MutexConfigManager
+	InformerManager *InformerManager
+	Logger          *log.Logger
 }
 
-func NewPVCWatcher(client *kubernetes.Clientset, cm *config.MutexConfigManager, cache *map[string]v1.PersistentVolumeClaim, l *log.Logger, lock *sync.RWMutex) *PVCWatcher {
-	epw := PVCWatcher{Client: client, PVCCache: *cache, ConfManager: cm, Logger: l, LockPVC: lock}
+func NewPVCWatcher(client *kubernetes.Clientset, cm *config.MutexConfigManager, im *InformerManager, cache *map[string]v1.PersistentVolumeClaim, l *log.Logger, lock *sync.RWMutex) *PVCWatcher {
+	epw := PVCWatcher{Client: client, InformerManager: im, PVCCache: *cache, ConfManager: cm, Logger: l, LockPVC: lock}
+	im.SubscribeToNamespacePurge(epw.purgeNamespaces)
 	return &epw
 }
 
 func (pw *PVCWatcher) qualifies(p *v1.PersistentVolumeClaim) bool {
-	return (len((*pw.ConfManager).Get().NsToMonitor) == 0 ||
-		utils.StringInSlice(p.Namespace, (*pw.ConfManager).Get().NsToMonitor)) &&
-		!utils.StringInSlice(p.Namespace, (*pw.ConfManager).Get().NsToMonitorExclude)
+	return utils.NSQualifiesForMonitoring(p.Namespace, (*pw.ConfManager).Get())
 }
 
-func (pw PVCWatcher) WatchPVC() {
-	api := pw.Client.CoreV1()
-	listOptions := metav1.ListOptions{}
+func (pw PVCWatcher) WatchPVC(stopCh <-chan struct{}) {
 	pw.Logger.Info("Starting Persistent Volume Claim Watcher...")
 
-	watcher, err := api.PersistentVolumeClaims(metav1.NamespaceAll).Watch(listOptions)
-	if err != nil {
-		pw.Logger.WithField("error", err).Error("Issues when setting up PVC watcher. Aborting...")
-	} else {
-
-		ch := watcher.ResultChan()
-
-		for ev := range ch {
-			pvc, ok := ev.Object.(*v1.PersistentVolumeClaim)
-			if !ok {
-				pw.Logger.Warn("Expected PVC, but received an object of an unknown type.")
-				continue
-			}
-			switch ev.Type {
-			case watch.Added:
+	i := pw.InformerManager.NewInformer("persistentvolumeclaims", func(factory informers.SharedInformerFactory, namespace string) cache.SharedIndexInformer {
+		return factory.Core().V1().PersistentVolumeClaims().Informer()
+	}, cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if pvc, ok := obj.(*v1.PersistentVolumeClaim); ok {
 				pw.onNewPVC(pvc)
-				break
-
-			case watch.Deleted:
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if pvc, ok := DeletedObject(obj).(*v1.PersistentVolumeClaim); ok {
 				pw.onDeletePVC(pvc)
-				break
-
-			case watch.Modified:
+			}
+		},
+		UpdateFunc: func(oldObj interface{}, newObj interface{}) {
+			if IsResync(oldObj, newObj) {
+				return
+			}
+			if pvc, ok := newObj.(*v1.PersistentVolumeClaim); ok {
 				pw.onUpdatePVC(pvc)
-				break
 			}
-
-		}
-	}
+		},
+	})
+	i.Run(stopCh)
 	pw.Logger.Info("Exiting PVC watcher.")
 }
 
@@ -119,3 +110,14 @@ func (pw PVCWatcher) CloneMap() map[string]v1.PersistentVolumeClaim {
 
 	return m
 }
+
+//drops the persistent volume claims of the namespaces that are no longer monitored
+func (pw PVCWatcher) purgeNamespaces(keep func(namespace string) bool) {
+	pw.LockPVC.Lock()
+	defer pw.LockPVC.Unlock()
+	for key := range pw.PVCCache {
+		if !keep(utils.KeyNamespace(key)) {
+			delete(pw.PVCCache, key)
+		}
+	}
+}
@@ -8,12 +8,12 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/watch"
 
 	"github.com/appdynamics/cluster-agent/config"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 
 	app "github.com/appdynamics/cluster-agent/appd"
 	m "github.com/appdynamics/cluster-agent/models"
@@ -21,23 +21,23 @@ import (
 )
 
 type RQWatcher struct {
-	Client       *kubernetes.Clientset
-	LockRQ       *sync.RWMutex
-	RQCache      map[string]v1.ResourceQuota
-	ConfManager  *config.MutexConfigManager
-	UpdatedCache map[string]m.RqSchema
-	Logger       *log.Logger
+	Client          *kubernetes.Clientset
+	LockRQ          *sync.RWMutex
+	RQCache         map[string]v1.ResourceQuota
+	ConfManager     *config.MutexConfigManager
+	InformerManager *InformerManager
+	UpdatedCache    map[string]m.RqSchema
+	Logger          *log.Logger
 }
 
-func NewRQWatcher(client *kubernetes.Clientset, cm *config.MutexConfigManager, cache *map[string]v1.ResourceQuota, l *log.Logger, lock *sync.RWMutex) *RQWatcher {
-	epw := RQWatcher{Client: client, RQCache: *cache, ConfManager: cm, UpdatedCache: make(map[string]m.RqSchema), Logger: l, LockRQ: lock}
+func NewRQWatcher(client *kubernetes.Clientset, cm *config.MutexConfigManager, im *InformerManager, cache *map[string]v1.ResourceQuota, l *log.Logger, lock *sync.RWMutex) *RQWatcher {
+	epw := RQWatcher{Client: client, InformerManager: im, RQCache: *cache, ConfManager: cm, UpdatedCache: make(map[string]m.RqSchema), Logger: l, LockRQ: lock}
+	im.SubscribeToNamespacePurge(epw.purgeNamespaces)
 	return &epw
 }
 
 func (pw *RQWatcher) qualifies(p *v1.ResourceQuota) bool {
-	return (len((*pw.ConfManager).Get().NsToMonitor) == 0 ||
-		utils.StringInSlice(p.Namespace, (*pw.ConfManager).Get().NsToMonitor)) &&
-		!utils.StringInSlice(p.Namespace, (*pw.ConfManager).Get().NsToMonitorExclude)
+	return utils.NSQualifiesForMonitoring(p.Namespace, (*pw.ConfManager).Get())
 }
 
 func (pw *RQWatcher) startEventQueueWorker(stopCh <-chan struct{}) {
@@ -58,43 +58,34 @@ func (pw *RQWatcher) eventQueueTicker(stop <-chan struct{}, ticker *time.Ticker)
 }
 
 //quotas
-func (pw RQWatcher) WatchResourceQuotas() {
-	api := pw.Client.CoreV1()
-	listOptions := metav1.ListOptions{}
+func (pw RQWatcher) WatchResourceQuotas(stopCh <-chan struct{}) {
 	pw.Logger.Info("Starting Quota Watcher...")
 
-	stop := make(chan struct{})
-	go pw.startEventQueueWorker(stop)
+	go pw.startEventQueueWorker(stopCh)
 
-	watcher, err := api.ResourceQuotas(metav1.NamespaceAll).Watch(listOptions)
-	if err != nil {
-		pw.Logger.WithField("error", err).Error("Issues when setting up Quota watcher. Aborting...")
-	} else {
-
-		ch := watcher.ResultChan()
-
-		for ev := range ch {
-			rq, ok := ev.Object.(*v1.ResourceQuota)
-			if !ok {
-				pw.Logger.Warn("Expected ResourceQuota, but received an object of an unknown type. ")
-				continue
-			}
-			switch ev.Type {
-			case watch.Added:
+	i := pw.InformerManager.NewInformer("resourcequotas", func(factory informers.SharedInformerFactory, namespace string) cache.SharedIndexInformer {
+		return factory.Core().V1().ResourceQuotas().Informer()
+	}, cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if rq, ok := obj.(*v1.ResourceQuota); ok {
 				pw.onNewResourceQuota(rq)
-				break
-
-			case watch.Deleted:
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if rq, ok := DeletedObject(obj).(*v1.ResourceQuota); ok {
 				pw.onDeleteResourceQuota(rq)
-				break
-
-			case watch.Modified:
+			}
+		},
+		UpdateFunc: func(oldObj interface{}, newObj interface{}) {
+			if IsResync(oldObj, newObj) {
+				return
+			}
+			if rq, ok := newObj.(*v1.ResourceQuota); ok {
 				pw.onUpdateResourceQuota(rq)
-				break
 			}
-
-		}
-	}
+		},
+	})
+	i.Run(stopCh)
 	pw.Logger.Info("Exiting quota watcher.")
 }
 
@@ -194,3 +185,19 @@ func (pw *RQWatcher) postRQBatchRecords(objList *[]m.RqSchema) {
 	}
 
 }
+
+//drops the resource quotas of the namespaces that are no longer monitored
+func (pw RQWatcher) purgeNamespaces(keep func(namespace string) bool) {
+	pw.LockRQ.Lock()
+	defer pw.LockRQ.Unlock()
+	for key := range pw.RQCache {
+		if !keep(utils.KeyNamespace(key)) {
+			delete(pw.RQCache, key)
+		}
+	}
+	for key := range pw.UpdatedCache {
+		if !keep(utils.KeyNamespace(key)) {
+			delete(pw.UpdatedCache, key)
+		}
+	}
+}
@@ -5,37 +5,35 @@ import (
 	"time"
 
 	"k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/watch"
 
 	log "github.com/sirupsen/logrus"
 
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 
 	"github.com/appdynamics/cluster-agent/config"
 	"github.com/appdynamics/cluster-agent/utils"
 )
 
 type SecretWathcer struct {
-	Client      *kubernetes.Clientset
-	LockSecrets *sync.RWMutex
-	SecretCache map[string]v1.Secret
-	ConfManager *config.MutexConfigManager
-	Listener    *WatchListener
-	UpdateDelay bool
-	Logger      *log.Logger
+	Client          *kubernetes.Clientset
+	LockSecrets     *sync.RWMutex
+	SecretCache     map[string]v1.Secret
+	ConfManager     *config.MutexConfigManager
+	InformerManager *InformerManager
+	Listener        *WatchListener
+	UpdateDelay     bool
+	Logger          *log.Logger
 }
 
-func NewSecretWathcer(client *kubernetes.Clientset, secret *config.MutexConfigManager, cache *map[string]v1.Secret, listener WatchListener, l *log.Logger, lock *sync.RWMutex) *SecretWathcer {
-	sw := SecretWathcer{Client: client, SecretCache: *cache, ConfManager: secret, Listener: &listener, Logger: l, LockSecrets: lock}
+func NewSecretWathcer(client *kubernetes.Clientset, secret *config.MutexConfigManager, im *InformerManager, cache *map[string]v1.Secret, listener WatchListener, l *log.Logger, lock *sync.RWMutex) *SecretWathcer {
+	sw := SecretWathcer{Client: client, InformerManager: im, SecretCache: *cache, ConfManager: secret, Listener: &listener, Logger: l, LockSecrets: lock}
 	sw.UpdateDelay = true
+	im.SubscribeToNamespacePurge(sw.purgeNamespaces)
 	return &sw
 }
 
-func (pw SecretWathcer) WatchSecrets() {
-	api := pw.Client.CoreV1()
-	listOptions := metav1.ListOptions{}
+func (pw SecretWathcer) WatchSecrets(stopCh <-chan struct{}) {
 	pw.Logger.Info("Starting Secrets Watcher...")
 
 	bag := (*pw.ConfManager).Get()
@@ -46,35 +44,27 @@ func (pw SecretWathcer) WatchSecrets() {
 		pw.Logger.Info("Secret Update Delay lifted.")
 	}()
 
-	watcher, err := api.Secrets(metav1.NamespaceAll).Watch(listOptions)
-	if err != nil {
-		pw.Logger.WithField("error", err).Error("Issues when setting up Secret watcher. Aborting...")
-	} else {
-
-		ch := watcher.ResultChan()
-
-		for ev := range ch {
-			secret, ok := ev.Object.(*v1.Secret)
-			if !ok {
-				pw.Logger.Warn("Expected Secrets, but received an object of an unknown type. ")
-				continue
-			}
-			switch ev.Type {
-			case watch.Added:
+	i := pw.InformerManager.NewInformer("secrets", pw.InformerManager.SecretInformer, cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if secret, ok := SecretFromMetadata(obj); ok {
 				pw.onNewConfig(secret)
-				break
-
-			case watch.Deleted:
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if secret, ok := SecretFromMetadata(DeletedObject(obj)); ok {
 				pw.onDeleteConfig(secret)
-				break
-
-			case watch.Modified:
+			}
+		},
+		UpdateFunc: func(oldObj interface{}, newObj interface{}) {
+			if IsResync(oldObj, newObj) {
+				return
+			}
+			if secret, ok := SecretFromMetadata(newObj); ok {
 				pw.onUpdateConfig(secret)
-				break
 			}
-
-		}
-	}
+		},
+	})
+	i.Run(stopCh)
 	pw.Logger.Info("Exiting secret watcher.")
 }
 
@@ -134,3 +124,14 @@ func (pw SecretWathcer) CloneMap() map[string]v1.Secret {
 	}
 	return m
 }
+
+//drops the secrets of the namespaces that are no longer monitored
+func (pw SecretWathcer) purgeNamespaces(keep func(namespace string) bool) {
+	pw.LockSecrets.Lock()
+	defer pw.LockSecrets.Unlock()
+	for key := range pw.SecretCache {
+		if !keep(utils.KeyNamespace(key)) {
+			delete(pw.SecretCache, key)
+		}
+	}
+}
@@ -8,12 +8,12 @@ import (
 	"strings"
 
 	"k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/watch"
 
 	log "github.com/sirupsen/logrus"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 
 	"github.com/appdynamics/cluster-agent/config"
 	m "github.com/appdynamics/cluster-agent/models"
@@ -21,24 +21,24 @@ import (
 )
 
 type ServiceWatcher struct {
-	Client       *kubernetes.Clientset
-	LockServices *sync.RWMutex
-	SvcCache     map[string]m.ServiceSchema
-	ConfManager  *config.MutexConfigManager
-	Listener     *WatchListener
-	UpdateDelay  bool
-	Logger       *log.Logger
+	Client          *kubernetes.Clientset
+	LockServices    *sync.RWMutex
+	SvcCache        map[string]m.ServiceSchema
+	ConfManager     *config.MutexConfigManager
+	InformerManager *InformerManager
+	Listener        *WatchListener
+	UpdateDelay     bool
+	Logger          *log.Logger
 }
 
-func NewServiceWatcher(client *kubernetes.Clientset, cm *config.MutexConfigManager, cache *map[string]m.ServiceSchema, listener WatchListener, l *log.Logger, lock *sync.RWMutex) *ServiceWatcher {
-	sw := ServiceWatcher{Client: client, SvcCache: *cache, ConfManager: cm, Listener: &listener, Logger: l, LockServices: lock}
+func NewServiceWatcher(client *kubernetes.Clientset, cm *config.MutexConfigManager, im *InformerManager, cache *map[string]m.ServiceSchema, listener WatchListener, l *log.Logger, lock *sync.RWMutex) *ServiceWatcher {
+	sw := ServiceWatcher{Client: client, InformerManager: im, SvcCache: *cache, ConfManager: cm, Listener: &listener, Logger: l, LockServices: lock}
 	sw.UpdateDelay = true
+	im.SubscribeToNamespacePurge(sw.purgeNamespaces)
 	return &sw
 }
 
-func (pw ServiceWatcher) WatchServices() {
-	api := pw.Client.CoreV1()
-	listOptions := metav1.ListOptions{}
+func (pw ServiceWatcher) WatchServices(stopCh <-chan struct{}) {
 	pw.Logger.Info("Starting Service Watcher...")
 
 	bag := (*pw.ConfManager).Get()
@@ -49,35 +49,29 @@ func (pw ServiceWatcher) WatchServices() {
 		pw.Logger.Info("Service  Update delay lifted.")
 	}()
 
-	watcher, err := api.Services(metav1.NamespaceAll).Watch(listOptions)
-	if err != nil {
-		pw.Logger.WithField("error", err).Error("Issues when setting up Service watcher. Aborting...")
-	} else {
-
-		ch := watcher.ResultChan()
-
-		for ev := range ch {
-			svc, ok := ev.Object.(*v1.Service)
-			if !ok {
-				pw.Logger.Warn("Expected Service, but received an object of an unknown type. ")
-				continue
-			}
-			switch ev.Type {
-			case watch.Added:
+	i := pw.InformerManager.NewInformer("services", func(factory informers.SharedInformerFactory, namespace string) cache.SharedIndexInformer {
+		return factory.Core().V1().Services().Informer()
+	}, cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if svc, ok := obj.(*v1.Service); ok {
 				pw.onNewService(svc)
-				break
-
-			case watch.Deleted:
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if svc, ok := DeletedObject(obj).(*v1.Service); ok {
 				pw.onDeleteService(svc)
-				break
-
-			case watch.Modified:
+			}
+		},
+		UpdateFunc: func(oldObj interface{}, newObj interface{}) {
+			if IsResync(oldObj, newObj) {
+				return
+			}
+			if svc, ok := newObj.(*v1.Service); ok {
 				pw.onUpdateService(svc)
-				break
 			}
-
-		}
-	}
+		},
+	})
+	i.Run(stopCh)
 	pw.Logger.Info("Exiting Service watcher.")
 }
 
@@ -177,3 +171,14 @@ func (pw *ServiceWatcher) findExternalService(headlessName string) (*m.ServiceSc
 	}
 	return nil, ""
 }
+
+//drops the services of the namespaces that are no longer monitored
+func (pw ServiceWatcher) purgeNamespaces(keep func(namespace string) bool) {
+	pw.LockServices.Lock()
+	defer pw.LockServices.Unlock()
+	for key := range pw.SvcCache {
+		if !keep(utils.KeyNamespace(key)) {
+			delete(pw.SvcCache, key)
+		}
+	}
+}
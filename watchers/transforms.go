@@ -0,0 +1,208 @@
+package watchers
+
+import (
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+)
+
+const LAST_APPLIED_ANNOTATION string = "kubectl.kubernetes.io/last-applied-configuration"
+
+//set on replica sets, which were cached without the pod template
+const TEMPLATE_STRIPPED_ANNOTATION string = "appdynamics.com/template-stripped"
+
+var (
+	CONFIGMAP_RESOURCE = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	SECRET_RESOURCE    = schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+)
+
+//keys of the metadata-only informers in the factories. Factories key informers by the type of the exemplar
+type configMapMetadata struct{ metav1.PartialObjectMetadata }
+type secretMetadata struct{ metav1.PartialObjectMetadata }
+
+//modifies objects in place before they are cached by informers
+type TransformFunc func(obj runtime.Object)
+
+//wraps a ListerWatcher and applies the transform to listed and watched objects
+type transformingListWatch struct {
+	lw        cache.ListerWatcher
+	transform TransformFunc
+}
+
+func (t *transformingListWatch) List(options metav1.ListOptions) (runtime.Object, error) {
+	list, err := t.lw.List(options)
+	if err != nil {
+		return nil, err
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		t.transform(item)
+	}
+	//items of typed lists are extracted by reference. Set them back in case of copies
+	if err = meta.SetList(list, items); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (t *transformingListWatch) Watch(options metav1.ListOptions) (watch.Interface, error) {
+	w, err := t.lw.Watch(options)
+	if err != nil {
+		return nil, err
+	}
+	return watch.Filter(w, func(in watch.Event) (watch.Event, bool) {
+		if in.Type != watch.Error && in.Object != nil {
+			t.transform(in.Object)
+		}
+		return in, true
+	}), nil
+}
+
+//builds an informer of the factory, which applies the transform to the objects before they are cached
+func NewTransformedInformer(factory informers.SharedInformerFactory, namespace string, exemplar runtime.Object, resource string,
+	restClient func(client kubernetes.Interface) rest.Interface, transform TransformFunc) cache.SharedIndexInformer {
+	return factory.InformerFor(exemplar, func(client kubernetes.Interface, resync time.Duration) cache.SharedIndexInformer {
+		lw := cache.NewListWatchFromClient(restClient(client), resource, namespace, fields.Everything())
+		return cache.NewSharedIndexInformer(&transformingListWatch{lw: lw, transform: transform}, exemplar, resync,
+			cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	})
+}
+
+//builds a metadata-only informer of the factory. Only the object metadata is listed, watched and cached
+func NewMetadataInformer(factory informers.SharedInformerFactory, namespace string, key runtime.Object, resource schema.GroupVersionResource,
+	client metadata.Interface) cache.SharedIndexInformer {
+	return factory.InformerFor(key, func(_ kubernetes.Interface, resync time.Duration) cache.SharedIndexInformer {
+		lw := &cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				return client.Resource(resource).Namespace(namespace).List(options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				return client.Resource(resource).Namespace(namespace).Watch(options)
+			},
+		}
+		return cache.NewSharedIndexInformer(&transformingListWatch{lw: lw, transform: StripObjectMeta}, &metav1.PartialObjectMetadata{}, resync,
+			cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	})
+}
+
+func coreClient(client kubernetes.Interface) rest.Interface {
+	return client.CoreV1().RESTClient()
+}
+
+func appsClient(client kubernetes.Interface) rest.Interface {
+	return client.AppsV1().RESTClient()
+}
+
+//drops managed fields and the last applied configuration, which are not used by the agent
+func StripObjectMeta(obj runtime.Object) {
+	metaObj, err := meta.Accessor(obj)
+	if err != nil {
+		return
+	}
+	metaObj.SetManagedFields(nil)
+	annotations := metaObj.GetAnnotations()
+	if _, ok := annotations[LAST_APPLIED_ANNOTATION]; ok {
+		delete(annotations, LAST_APPLIED_ANNOTATION)
+		metaObj.SetAnnotations(annotations)
+	}
+}
+
+func StripPod(obj runtime.Object) {
+	StripObjectMeta(obj)
+}
+
+func StripEvent(obj runtime.Object) {
+	StripObjectMeta(obj)
+}
+
+//the list of images cached on the node is the largest part of the node status
+func StripNode(obj runtime.Object) {
+	StripObjectMeta(obj)
+	if node, ok := obj.(*v1.Node); ok {
+		node.Status.Images = nil
+	}
+}
+
+//old revisions of deployments are kept only for owner and replica counts. Replica sets without a deployment keep the template,
+//as they are the only source of their spec
+func StripReplicaSet(obj runtime.Object) {
+	StripObjectMeta(obj)
+	if rs, ok := obj.(*appsv1.ReplicaSet); ok {
+		if rs.Status.Replicas == 0 && (rs.Spec.Replicas == nil || *rs.Spec.Replicas == 0) && ownedByDeployment(rs) {
+			rs.Spec.Template = v1.PodTemplateSpec{}
+			if rs.Annotations == nil {
+				rs.Annotations = make(map[string]string)
+			}
+			rs.Annotations[TEMPLATE_STRIPPED_ANNOTATION] = "true"
+		}
+	}
+}
+
+func ownedByDeployment(rs *appsv1.ReplicaSet) bool {
+	for _, owner := range rs.OwnerReferences {
+		if owner.Kind == "Deployment" {
+			return true
+		}
+	}
+	return false
+}
+
+//IsTemplateStripped returns true if the replica set was cached without the pod template
+func IsTemplateStripped(rs *appsv1.ReplicaSet) bool {
+	_, ok := rs.Annotations[TEMPLATE_STRIPPED_ANNOTATION]
+	return ok
+}
+
+//the agent tracks existence of config maps and secrets. Only the metadata is cached
+func ConfigMapFromMetadata(obj interface{}) (*v1.ConfigMap, bool) {
+	if m, ok := obj.(*metav1.PartialObjectMetadata); ok {
+		return &v1.ConfigMap{ObjectMeta: m.ObjectMeta}, true
+	}
+	return nil, false
+}
+
+func SecretFromMetadata(obj interface{}) (*v1.Secret, bool) {
+	if m, ok := obj.(*metav1.PartialObjectMetadata); ok {
+		return &v1.Secret{ObjectMeta: m.ObjectMeta}, true
+	}
+	return nil, false
+}
+
+func PodInformer(factory informers.SharedInformerFactory, namespace string) cache.SharedIndexInformer {
+	return NewTransformedInformer(factory, namespace, &v1.Pod{}, "pods", coreClient, StripPod)
+}
+
+func EventInformer(factory informers.SharedInformerFactory, namespace string) cache.SharedIndexInformer {
+	return NewTransformedInformer(factory, namespace, &v1.Event{}, "events", coreClient, StripEvent)
+}
+
+func NodeInformer(factory informers.SharedInformerFactory, namespace string) cache.SharedIndexInformer {
+	return NewTransformedInformer(factory, namespace, &v1.Node{}, "nodes", coreClient, StripNode)
+}
+
+func ReplicaSetInformer(factory informers.SharedInformerFactory, namespace string) cache.SharedIndexInformer {
+	return NewTransformedInformer(factory, namespace, &appsv1.ReplicaSet{}, "replicasets", appsClient, StripReplicaSet)
+}
+
+func (im *InformerManager) ConfigMapInformer(factory informers.SharedInformerFactory, namespace string) cache.SharedIndexInformer {
+	return NewMetadataInformer(factory, namespace, &configMapMetadata{}, CONFIGMAP_RESOURCE, im.MetadataClient)
+}
+
+func (im *InformerManager) SecretInformer(factory informers.SharedInformerFactory, namespace string) cache.SharedIndexInformer {
+	return NewMetadataInformer(factory, namespace, &secretMetadata{}, SECRET_RESOURCE, im.MetadataClient)
+}
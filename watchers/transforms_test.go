@@ -0,0 +1,58 @@
+package watchers
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestStripReplicaSet(t *testing.T) {
+	deployment := []metav1.OwnerReference{{Kind: "Deployment", Name: "api"}}
+	var zero int32 = 0
+	var one int32 = 1
+	tests := []struct {
+		name     string
+		owners   []metav1.OwnerReference
+		replicas *int32
+		status   int32
+		stripped bool
+	}{
+		{"old revision", deployment, &zero, 0, true},
+		{"old revision without replicas", deployment, nil, 0, true},
+		{"current revision", deployment, &one, 1, false},
+		{"scaling down", deployment, &zero, 1, false},
+		{"standalone", nil, &zero, 0, false},
+	}
+	for _, tt := range tests {
+		rs := appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "api-5d8f", OwnerReferences: tt.owners,
+			Annotations: map[string]string{LAST_APPLIED_ANNOTATION: "{}"}}}
+		rs.Spec.Replicas = tt.replicas
+		rs.Status.Replicas = tt.status
+		rs.Spec.Template.Spec.Containers = []v1.Container{{Name: "app", Image: "api:1"}}
+		StripReplicaSet(&rs)
+		if IsTemplateStripped(&rs) != tt.stripped || (len(rs.Spec.Template.Spec.Containers) == 0) != tt.stripped {
+			t.Errorf("%s: stripped = %t with %d containers, want %t", tt.name, IsTemplateStripped(&rs), len(rs.Spec.Template.Spec.Containers), tt.stripped)
+		}
+		if _, ok := rs.Annotations[LAST_APPLIED_ANNOTATION]; ok {
+			t.Errorf("%s: last applied configuration is not stripped", tt.name)
+		}
+	}
+}
+
+func TestFromMetadata(t *testing.T) {
+	meta := &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "payments"}}
+	if cm, ok := ConfigMapFromMetadata(meta); !ok || cm.Name != "app-config" || cm.Namespace != "payments" {
+		t.Errorf("ConfigMapFromMetadata = %v, %t", cm, ok)
+	}
+	if secret, ok := SecretFromMetadata(meta); !ok || secret.Name != "app-config" || secret.Namespace != "payments" {
+		t.Errorf("SecretFromMetadata = %v, %t", secret, ok)
+	}
+	if _, ok := ConfigMapFromMetadata(&v1.ConfigMap{}); ok {
+		t.Errorf("ConfigMapFromMetadata of a typed object = true, want false")
+	}
+	if _, ok := SecretFromMetadata("payments/app-config"); ok {
+		t.Errorf("SecretFromMetadata of a key = true, want false")
+	}
+}
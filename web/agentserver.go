@@ -5,9 +5,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -15,16 +19,39 @@ import (
 	"github.com/appdynamics/cluster-agent/config"
 	m "github.com/appdynamics/cluster-agent/models"
 	"github.com/appdynamics/cluster-agent/version"
+	"github.com/appdynamics/cluster-agent/watchers"
 	"github.com/gorilla/mux"
 )
 
+//DashboardManager moves the agent dashboards between controllers
+type DashboardManager interface {
+	ExportDashboards() ([]json.RawMessage, error)
+	ImportDashboards(dashboards []json.RawMessage) ([]string, []string, error)
+}
+
+//RecommendationProvider returns the right-sizing recommendations of the containers of workloads
+type RecommendationProvider interface {
+	GetRecommendations(namespace string) []m.RecommendationSchema
+}
+
+//CapacityProvider returns the capacity of the node pools and places pod shapes on the free capacity
+type CapacityProvider interface {
+	GetCapacityReport() m.CapacityReport
+	Simulate(shapes []m.CapacityShape) ([]m.CapacityFit, error)
+}
+
 type AgentWebServer struct {
-	ConfigManager *config.MutexConfigManager
-	Logger        *log.Logger
+	ConfigManager    *config.MutexConfigManager
+	InformerManager  *watchers.InformerManager
+	MetricsCollector *watchers.MetricsCollector
+	Dashboards       DashboardManager
+	Recommendations  RecommendationProvider
+	Capacity         CapacityProvider
+	Logger           *log.Logger
 }
 
-func NewAgentWebServer(c *config.MutexConfigManager, l *log.Logger) *AgentWebServer {
-	aws := AgentWebServer{ConfigManager: c, Logger: l}
+func NewAgentWebServer(c *config.MutexConfigManager, im *watchers.InformerManager, mc *watchers.MetricsCollector, l *log.Logger) *AgentWebServer {
+	aws := AgentWebServer{ConfigManager: c, InformerManager: im, MetricsCollector: mc, Logger: l}
 	return &aws
 }
 
@@ -33,6 +60,10 @@ func (ws *AgentWebServer) RunServer() {
 	r := mux.NewRouter()
 	r.HandleFunc("/version", ws.getVersion)
 	r.HandleFunc("/status", ws.getStatus)
+	r.HandleFunc("/dashboards/export", ws.exportDashboards)
+	r.HandleFunc("/dashboards/import", ws.importDashboards)
+	r.HandleFunc("/recommendations", ws.getRecommendations)
+	r.HandleFunc("/capacity", ws.getCapacity)
 	addr := fmt.Sprintf(":%d", bag.AgentServerPort)
 	server := &http.Server{Addr: addr, Handler: r}
 
@@ -74,11 +105,17 @@ func (ws *AgentWebServer) getStatus(w http.ResponseWriter, req *http.Request) {
 		statusObj := m.AgentStatus{}
 		statusObj.Version = version.Version
 		statusObj.NsToMonitor = bag.NsToMonitor
+		statusObj.NsToMonitorExclude = bag.NsToMonitorExclude
 		statusObj.NodesToMonitor = bag.NodesToMonitor
+		statusObj.NodesToMonitorExclude = bag.NodesToMonitorExclude
+		statusObj.NsSelector = bag.NsSelector
+		statusObj.NodeSelector = bag.NodeSelector
 
 		statusObj.InstrumentationMethod = bag.InstrumentationMethod
 		statusObj.DefaultInstrumentationTech = bag.DefaultInstrumentationTech
 		statusObj.NsToInstrument = bag.NsToInstrument
+		statusObj.NsToInstrumentExclude = bag.NsToInstrumentExclude
+		statusObj.NsToInstrumentSelector = bag.NsToInstrumentSelector
 		statusObj.NSInstrumentRule = bag.NSInstrumentRule
 		statusObj.AnalyticsAgentImage = bag.AnalyticsAgentImage
 		statusObj.AppDJavaAttachImage = bag.AppDJavaAttachImage
@@ -91,6 +128,15 @@ func (ws *AgentWebServer) getStatus(w http.ResponseWriter, req *http.Request) {
 		statusObj.LogLines = bag.LogLines
 		statusObj.MetricsSyncInterval = bag.MetricsSyncInterval
 		statusObj.SnapshotSyncInterval = bag.SnapshotSyncInterval
+		if ws.InformerManager != nil {
+			statusObj.CacheMemory = ws.InformerManager.MemoryEstimate()
+		}
+		if ws.MetricsCollector != nil {
+			statusObj.MetricsStale = ws.MetricsCollector.IsStale()
+			if lastUpdate := ws.MetricsCollector.LastUpdate(); !lastUpdate.IsZero() {
+				statusObj.MetricsLastUpdate = lastUpdate.Format(time.RFC3339)
+			}
+		}
 
 		result, _ := json.Marshal(statusObj)
 		io.WriteString(w, string(result))
@@ -98,3 +144,150 @@ func (ws *AgentWebServer) getStatus(w http.ResponseWriter, req *http.Request) {
 		http.Error(w, "Only GET is supported", 404)
 	}
 }
+
+//returns the agent dashboards. The response can be posted to /dashboards/import of an agent connected to another controller
+func (ws *AgentWebServer) exportDashboards(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" {
+		http.Error(w, "Only GET is supported", 404)
+		return
+	}
+	if ws.Dashboards == nil {
+		http.Error(w, "Dashboards are not available", 503)
+		return
+	}
+	exported, err := ws.Dashboards.ExportDashboards()
+	if err != nil {
+		ws.Logger.WithField("error", err).Error("Unable to export dashboards")
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	result, _ := json.Marshal(exported)
+	io.WriteString(w, string(result))
+}
+
+//imports the posted list of exported dashboards. Without a body, the dashboards of the export directory are imported.
+//The import creates dashboards in the controller and is accepted only from localhost, e.g. through kubectl port-forward
+func (ws *AgentWebServer) importDashboards(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" {
+		http.Error(w, "Only POST is supported", 404)
+		return
+	}
+	if !isLocalRequest(req) {
+		ws.Logger.WithField("remote", req.RemoteAddr).Warn("Rejected dashboard import from a remote address")
+		http.Error(w, "Dashboard import is only accepted from localhost", 403)
+		return
+	}
+	if ws.Dashboards == nil {
+		http.Error(w, "Dashboards are not available", 503)
+		return
+	}
+	dashboards := []json.RawMessage{}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	if len(body) > 0 {
+		err = json.Unmarshal(body, &dashboards)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Expected a list of exported dashboards. %v", err), 400)
+			return
+		}
+	}
+	imported, skipped, err := ws.Dashboards.ImportDashboards(dashboards)
+	if err != nil {
+		ws.Logger.WithField("error", err).Error("Unable to import dashboards")
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	result, _ := json.Marshal(map[string][]string{"imported": imported, "skipped": skipped})
+	io.WriteString(w, string(result))
+}
+
+func isLocalRequest(req *http.Request) bool {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+//returns the last right-sizing recommendations. The namespace parameter filters the recommendations of one namespace
+func (ws *AgentWebServer) getRecommendations(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" {
+		http.Error(w, "Only GET is supported", 404)
+		return
+	}
+	if ws.Recommendations == nil {
+		http.Error(w, "Recommendations are not available", 503)
+		return
+	}
+	recs := ws.Recommendations.GetRecommendations(req.URL.Query().Get("namespace"))
+	w.Header().Set("Content-Type", "application/json")
+	result, _ := json.Marshal(recs)
+	io.WriteString(w, string(result))
+}
+
+//without parameters, returns the capacity of the node pools and the headroom of the configured shapes.
+//GET with workload or cpu and memory parameters places one shape, POST places the posted list of shapes
+func (ws *AgentWebServer) getCapacity(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" && req.Method != "POST" {
+		http.Error(w, "Only GET and POST are supported", 404)
+		return
+	}
+	if ws.Capacity == nil {
+		http.Error(w, "Capacity analysis is not available", 503)
+		return
+	}
+	var result []byte
+	shapes := []m.CapacityShape{}
+	q := req.URL.Query()
+	if req.Method == "POST" {
+		body, err := ioutil.ReadAll(req.Body)
+		if err == nil {
+			err = json.Unmarshal(body, &shapes)
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Expected a list of capacity shapes. %v", err), 400)
+			return
+		}
+	} else if q.Get("workload") != "" || q.Get("cpu") != "" || q.Get("memory") != "" {
+		shape := m.CapacityShape{Workload: q.Get("workload"), Cpu: q.Get("cpu"), Memory: q.Get("memory")}
+		if replicas := q.Get("replicas"); replicas != "" {
+			val, err := strconv.ParseInt(replicas, 10, 64)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Replicas %s is invalid", replicas), 400)
+				return
+			}
+			shape.Replicas = val
+		}
+		//node selector in the format key=value,key=value
+		if selector := q.Get("nodeSelector"); selector != "" {
+			shape.NodeSelector = make(map[string]string)
+			for _, pair := range strings.Split(selector, ",") {
+				kv := strings.SplitN(pair, "=", 2)
+				if len(kv) != 2 {
+					http.Error(w, fmt.Sprintf("Node selector %s is invalid. Use this format: key=value,key=value", selector), 400)
+					return
+				}
+				shape.NodeSelector[kv[0]] = kv[1]
+			}
+		}
+		shapes = append(shapes, shape)
+	}
+	if len(shapes) == 0 {
+		result, _ = json.Marshal(ws.Capacity.GetCapacityReport())
+	} else {
+		fits, err := ws.Capacity.Simulate(shapes)
+		if err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		result, _ = json.Marshal(fits)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	io.WriteString(w, string(result))
+}
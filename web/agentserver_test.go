@@ -0,0 +1,112 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//dashboardManagerStub records the imported dashboards
+type dashboardManagerStub struct {
+	imported []json.RawMessage
+	calls    int
+	err      error
+}
+
+func (s *dashboardManagerStub) ExportDashboards() ([]json.RawMessage, error) {
+	return []json.RawMessage{json.RawMessage(`{"name": "cluster-shop"}`)}, s.err
+}
+
+func (s *dashboardManagerStub) ImportDashboards(dashboards []json.RawMessage) ([]string, []string, error) {
+	s.calls++
+	s.imported = dashboards
+	return []string{"cluster-shop"}, []string{}, s.err
+}
+
+func newTestWebServer(dashboards DashboardManager) *AgentWebServer {
+	l := log.New()
+	l.Out = ioutil.Discard
+	return &AgentWebServer{Dashboards: dashboards, Logger: l}
+}
+
+func importRequest(remote string, body string) *http.Request {
+	req := httptest.NewRequest("POST", "/dashboards/import", strings.NewReader(body))
+	req.RemoteAddr = remote
+	return req
+}
+
+func TestIsLocalRequest(t *testing.T) {
+	for _, remote := range []string{"127.0.0.1:51234", "[::1]:51234", "127.0.0.53:80"} {
+		if !isLocalRequest(importRequest(remote, "")) {
+			t.Errorf("%s is not local", remote)
+		}
+	}
+	for _, remote := range []string{"10.2.0.15:51234", "[fe80::1]:51234", "localhost:51234", "127.0.0.1", ""} {
+		if isLocalRequest(importRequest(remote, "")) {
+			t.Errorf("%s is local", remote)
+		}
+	}
+}
+
+func TestImportDashboards(t *testing.T) {
+	stub := &dashboardManagerStub{}
+	ws := newTestWebServer(stub)
+
+	//a pod of the cluster calls the agent service
+	w := httptest.NewRecorder()
+	ws.importDashboards(w, importRequest("10.2.0.15:51234", `[{"name": "cluster-shop"}]`))
+	if w.Code != http.StatusForbidden || stub.calls != 0 {
+		t.Errorf("remote import: status %d, imports %d", w.Code, stub.calls)
+	}
+
+	//kubectl port-forward
+	w = httptest.NewRecorder()
+	ws.importDashboards(w, importRequest("127.0.0.1:51234", `[{"name": "cluster-shop"}, {"name": "cluster-api"}]`))
+	if w.Code != http.StatusOK || len(stub.imported) != 2 {
+		t.Fatalf("local import: status %d, imported %d dashboards", w.Code, len(stub.imported))
+	}
+	var result map[string][]string
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil || len(result["imported"]) != 1 || result["skipped"] == nil {
+		t.Errorf("import result = %s, %v", w.Body.String(), err)
+	}
+
+	//without a body the export directory is imported
+	w = httptest.NewRecorder()
+	ws.importDashboards(w, importRequest("[::1]:51234", ""))
+	if w.Code != http.StatusOK || len(stub.imported) != 0 {
+		t.Errorf("import of the export directory: status %d, dashboards %d", w.Code, len(stub.imported))
+	}
+
+	w = httptest.NewRecorder()
+	ws.importDashboards(w, importRequest("127.0.0.1:51234", `{"name": "cluster-shop"}`))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("import of a single object: status %d, want 400", w.Code)
+	}
+
+	stub.err = errors.New("controller is down")
+	w = httptest.NewRecorder()
+	ws.importDashboards(w, importRequest("127.0.0.1:51234", ""))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("failed import: status %d, want 500", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/dashboards/import", nil)
+	req.RemoteAddr = "127.0.0.1:51234"
+	ws.importDashboards(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("GET import: status %d, want 404", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	newTestWebServer(nil).importDashboards(w, importRequest("127.0.0.1:51234", ""))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("import without dashboards: status %d, want 503", w.Code)
+	}
+}
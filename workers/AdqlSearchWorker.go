@@ -1,10 +1,15 @@
 package workers
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
+	"text/template"
 
 	log "github.com/sirupsen/logrus"
 
@@ -17,8 +22,16 @@ import (
 const (
 	DRILL_DOWN_URL_TEMPLATE string = "%s#/location=ANALYTICS_ADQL_SEARCH&searchId=%d"
 	BASE_PATH               string = "Application Infrastructure Performance|%s|Custom Metrics|Cluster Stats|"
+	ADQL_SEARCHES_TEMPLATE  string = "/adql-searches.json"
+	ADQL_SEARCH_MARKER      string = "appd-cluster-agent"
 )
 
+type adqlQueryVars struct {
+	ClusterName string
+	SchemaName  string
+	Bag         *m.AppDBag
+}
+
 type AdqlSearchWorker struct {
 	Bag         *m.AppDBag
 	SearchCache map[string]m.AdqlSearch
@@ -60,19 +73,27 @@ func (aw *AdqlSearchWorker) GetSearch(metricPath string) string {
 	return search
 }
 
-func (aw *AdqlSearchWorker) CacheSearches() error {
+func (aw *AdqlSearchWorker) loadSearches() ([]m.AdqlSearch, error) {
 	rc := app.NewRestClient(aw.Bag, aw.Logger)
 	data, err := rc.CallAppDController("restui/analyticsSavedSearches/getAllAnalyticsSavedSearches", "GET", nil)
 
 	if err != nil {
 		fmt.Printf("Unable to get the list of saved searches. %v\n", err)
-		return err
+		return nil, err
 	}
 	var list []m.AdqlSearch
 	e := json.Unmarshal(data, &list)
 	if e != nil {
 		fmt.Printf("Unable to deserialize the list of saved searches. %v\n", e)
-		return e
+		return nil, e
+	}
+	return list, nil
+}
+
+func (aw *AdqlSearchWorker) CacheSearches() error {
+	list, err := aw.loadSearches()
+	if err != nil {
+		return err
 	}
 
 	for _, searchObj := range list {
@@ -85,26 +106,51 @@ func (aw *AdqlSearchWorker) CacheSearches() error {
 	return nil
 }
 
-func (aw *AdqlSearchWorker) CreateSearch(searchObj *m.AdqlSearch) (*m.AdqlSearch, error) {
-	name := uuid.New().String()
+//the internal name of the search identifies the searches created by the agent for this cluster
+func (aw *AdqlSearchWorker) newSearchName() string {
+	return fmt.Sprintf("%s-%s-%s", ADQL_SEARCH_MARKER, aw.Bag.ClusterUID, uuid.New().String())
+}
+
+func (aw *AdqlSearchWorker) isOwnSearch(searchObj *m.AdqlSearch) bool {
+	return aw.Bag.ClusterUID != "" && strings.HasPrefix(searchObj.Name, fmt.Sprintf("%s-%s-", ADQL_SEARCH_MARKER, aw.Bag.ClusterUID))
+}
+
+//searches created by older versions of the agent have a bare uuid as the internal name
+func (aw *AdqlSearchWorker) isLegacySearch(searchObj *m.AdqlSearch) bool {
+	_, err := uuid.Parse(searchObj.Name)
+	return err == nil && strings.HasPrefix(searchObj.SearchName, aw.buildFullMetricName(""))
+}
 
+func (aw *AdqlSearchWorker) buildSearchBody(searchObj *m.AdqlSearch, id int64, name string) []byte {
 	cols := []string{}
 
-	val := reflect.ValueOf(searchObj.SchemaDef)
-	for i := 0; i < val.Type().NumField(); i++ {
-		t := val.Type().Field(i)
-		jsonTag := t.Tag.Get("json")
+	if searchObj.SchemaDef != nil {
+		val := reflect.ValueOf(searchObj.SchemaDef)
+		for i := 0; i < val.Type().NumField(); i++ {
+			t := val.Type().Field(i)
+			jsonTag := t.Tag.Get("json")
 
-		if jsonTag != "" && jsonTag != "-" {
-			wrap := fmt.Sprintf("\"%s\"", jsonTag)
-			cols = append(cols, wrap)
+			if jsonTag != "" && jsonTag != "-" {
+				wrap := fmt.Sprintf("\"%s\"", jsonTag)
+				cols = append(cols, wrap)
 
+			}
 		}
 	}
 
-	jsonStr := fmt.Sprintf(`{"name": "%s", "adqlQueries": ["%s"], "searchType": "SINGLE", "searchMode": "ADVANCED", "viewMode": "DATA", "visualization": "TABLE", "selectedFields": [%s], "widgets": [], "searchName": "%s"}`, name, searchObj.Query, strings.Join(cols, ","), searchObj.SearchName)
-	aw.Logger.WithField("payload", jsonStr).Debug("Create search body.")
-	body := []byte(jsonStr)
+	query, _ := json.Marshal(searchObj.Query)
+	idField := ""
+	if id > 0 {
+		idField = fmt.Sprintf(`"id": %d, `, id)
+	}
+	jsonStr := fmt.Sprintf(`{%s"name": "%s", "adqlQueries": [%s], "searchType": "SINGLE", "searchMode": "ADVANCED", "viewMode": "DATA", "visualization": "TABLE", "selectedFields": [%s], "widgets": [], "searchName": "%s"}`, idField, name, query, strings.Join(cols, ","), searchObj.SearchName)
+	aw.Logger.WithField("payload", jsonStr).Debug("Search body.")
+	return []byte(jsonStr)
+}
+
+func (aw *AdqlSearchWorker) CreateSearch(searchObj *m.AdqlSearch) (*m.AdqlSearch, error) {
+	name := aw.newSearchName()
+	body := aw.buildSearchBody(searchObj, 0, name)
 
 	rc := app.NewRestClient(aw.Bag, aw.Logger)
 	data, errSave := rc.CallAppDController("restui/analyticsSavedSearches/createAnalyticsSavedSearch", "POST", body)
@@ -138,7 +184,159 @@ func (aw *AdqlSearchWorker) DeleteSearch(searchID int) error {
 	return nil
 }
 
+func (aw *AdqlSearchWorker) UpdateSearch(existing *m.AdqlSearch, searchObj *m.AdqlSearch) error {
+	name := existing.Name
+	if !aw.isOwnSearch(existing) {
+		name = aw.newSearchName()
+	}
+	body := aw.buildSearchBody(searchObj, existing.ID, name)
+
+	rc := app.NewRestClient(aw.Bag, aw.Logger)
+	_, errSave := rc.CallAppDController("restui/analyticsSavedSearches/updateAnalyticsSavedSearch", "POST", body)
+	if errSave != nil {
+		return fmt.Errorf("Unable to update search %d. %v\n", existing.ID, errSave)
+	}
+
+	return nil
+}
+
+//Reconcile brings the saved searches of the cluster in line with the search definitions. Searches with changed queries are updated in place,
+//so that the drill-downs of the dashboards keep working. Searches for metrics that are no longer defined are deleted.
+//Searches of the cluster saved under a different cluster name are renamed or deleted
+func (aw *AdqlSearchWorker) Reconcile() error {
+	list, err := aw.loadSearches()
+	if err != nil {
+		return err
+	}
+
+	defs := make(map[string]m.AdqlSearch)
+	for _, searchObj := range aw.getQueryMap() {
+		defs[searchObj.SearchName] = searchObj
+	}
+
+	current := make(map[string]bool)
+	renamed := []m.AdqlSearch{}
+	for _, existing := range list {
+		if strings.HasPrefix(existing.SearchName, aw.buildFullMetricName("")) && (aw.isOwnSearch(&existing) || aw.isLegacySearch(&existing)) {
+			current[existing.SearchName] = true
+			def, ok := defs[existing.SearchName]
+			if !ok {
+				aw.Logger.WithField("search", existing.SearchName).Info("Metric of the saved search is no longer defined. Deleting...")
+				if err := aw.DeleteSearch(int(existing.ID)); err != nil {
+					aw.Logger.WithField("error", err).Warn("Unable to delete saved search")
+				}
+				continue
+			}
+			if len(existing.AdqlQueries) != 1 || existing.AdqlQueries[0] != def.Query || !aw.isOwnSearch(&existing) {
+				aw.Logger.WithField("search", existing.SearchName).Info("Updating saved search")
+				if err := aw.UpdateSearch(&existing, &def); err != nil {
+					aw.Logger.WithField("error", err).Warn("Unable to update saved search")
+				}
+			}
+		} else if aw.isOwnSearch(&existing) {
+			renamed = append(renamed, existing)
+		}
+	}
+
+	for _, existing := range renamed {
+		metricName := existing.SearchName
+		if i := strings.LastIndex(metricName, ". "); i >= 0 {
+			metricName = metricName[i+2:]
+		}
+		def, ok := defs[aw.buildFullMetricName(metricName)]
+		if ok && !current[def.SearchName] {
+			aw.Logger.WithFields(log.Fields{"search": existing.SearchName, "cluster": aw.Bag.AppName}).Info("Cluster was renamed. Moving saved search")
+			if err := aw.UpdateSearch(&existing, &def); err != nil {
+				aw.Logger.WithField("error", err).Warn("Unable to update saved search")
+			}
+			current[def.SearchName] = true
+		} else {
+			aw.Logger.WithField("search", existing.SearchName).Info("Saved search of the renamed cluster is obsolete. Deleting...")
+			if err := aw.DeleteSearch(int(existing.ID)); err != nil {
+				aw.Logger.WithField("error", err).Warn("Unable to delete saved search")
+			}
+		}
+	}
+	return nil
+}
+
+//getQueryMap returns the built-in searches merged with the searches from the template file and the config.
+//Definitions from the config override the template and the template overrides the built-in searches of the same metric
+//Fingerprint identifies the desired state of the saved searches: the definitions, the cluster and the controller
+func (aw *AdqlSearchWorker) Fingerprint() string {
+	data, err := json.Marshal(aw.getQueryMap())
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%s|%s|%s", aw.Bag.RestAPIUrl, aw.Bag.ClusterUID, string(data))
+}
+
 func (aw *AdqlSearchWorker) getQueryMap() map[string]m.AdqlSearch {
+	queryMap := aw.getDefaultQueryMap()
+	defs := append(aw.loadSearchTemplates(), aw.Bag.AdqlSearches...)
+	for _, def := range defs {
+		if !def.IsValid() {
+			aw.Logger.WithField("metric", def.MetricPath).Warn("Saved search definition requires metricPath, schemaName and query. Skipping")
+			continue
+		}
+		query, err := aw.renderQuery(&def)
+		if err != nil {
+			aw.Logger.WithFields(log.Fields{"metric": def.MetricPath, "error": err}).Warn("Invalid query template of saved search. Skipping")
+			continue
+		}
+		path := def.MetricPath
+		if !strings.Contains(path, "|") {
+			path = BASE_PATH + path
+		}
+		arr := strings.Split(path, "|")
+		queryMap[path] = m.AdqlSearch{SchemaDef: aw.schemaDefByName(def.SchemaName), SearchName: aw.buildFullMetricName(arr[len(arr)-1]),
+			SchemaName: def.SchemaName, Query: query}
+	}
+	return queryMap
+}
+
+func (aw *AdqlSearchWorker) loadSearchTemplates() []m.AdqlSearchDef {
+	defs := []m.AdqlSearchDef{}
+	templateFile := filepath.Dir(aw.Bag.DashboardTemplatePath) + ADQL_SEARCHES_TEMPLATE
+	data, err := ioutil.ReadFile(templateFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			aw.Logger.WithFields(log.Fields{"file": templateFile, "error": err}).Warn("Unable to read saved search templates")
+		}
+		return defs
+	}
+	err = json.Unmarshal(data, &defs)
+	if err != nil {
+		aw.Logger.WithFields(log.Fields{"file": templateFile, "error": err}).Warn("Unable to deserialize saved search templates")
+		return []m.AdqlSearchDef{}
+	}
+	return defs
+}
+
+func (aw *AdqlSearchWorker) renderQuery(def *m.AdqlSearchDef) (string, error) {
+	t, err := template.New(def.MetricPath).Parse(def.Query)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	err = t.Execute(&buf, adqlQueryVars{ClusterName: aw.Bag.AppName, SchemaName: def.SchemaName, Bag: aw.Bag})
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+//columns of the search are taken from the definition of the schema. Searches of unknown schemas show the default columns
+func (aw *AdqlSearchWorker) schemaDefByName(schemaName string) m.AppDSchemaInterface {
+	defs := map[string]m.AppDSchemaInterface{aw.Bag.PodSchemaName: m.PodSchemaDef{}, aw.Bag.NodeSchemaName: m.NodeSchemaDef{},
+		aw.Bag.DeploySchemaName: m.DeploySchemaDef{}, aw.Bag.EventSchemaName: m.EventSchemaDef{}, aw.Bag.ContainerSchemaName: m.ContainerSchemaDef{},
+		aw.Bag.EpSchemaName: m.EpSchemaDef{}, aw.Bag.NsSchemaName: m.NsSchemaDef{}, aw.Bag.RolloutSchemaName: m.RolloutSchemaDef{},
+		aw.Bag.ChangeSchemaName: m.ChangeSchemaDef{}, aw.Bag.RecommendationSchemaName: m.RecommendationSchemaDef{},
+		aw.Bag.CostSchemaName: m.CostSchemaDef{}}
+	return defs[schemaName]
+}
+
+func (aw *AdqlSearchWorker) getDefaultQueryMap() map[string]m.AdqlSearch {
 	var queryMap = map[string]m.AdqlSearch{
 		BASE_PATH + "EventError": m.AdqlSearch{SchemaDef: m.EventSchemaDef{}, SearchName: fmt.Sprintf("%s. EventError", aw.Bag.AppName), SchemaName: aw.Bag.EventSchemaName,
 			Query: fmt.Sprintf("select * from %s where clusterName = '%s' and category = 'error' ORDER BY creationTimestamp DESC", aw.Bag.EventSchemaName, aw.Bag.AppName)},
@@ -156,6 +354,10 @@ func (aw *AdqlSearchWorker) getQueryMap() map[string]m.AdqlSearch {
 			Query: fmt.Sprintf("SELECT * FROM %s where clusterName = '%s' and  reason = 'Evicted'  ORDER BY pickupTimestamp DESC", aw.Bag.PodSchemaName, aw.Bag.AppName)},
 		BASE_PATH + "PodRestarts": m.AdqlSearch{SchemaDef: m.PodSchemaDef{}, SearchName: aw.buildFullMetricName("PodRestarts"), SchemaName: aw.Bag.PodSchemaName,
 			Query: fmt.Sprintf("SELECT * FROM %s where clusterName = '%s' and podRestarts > 0 ORDER BY pickupTimestamp DESC", aw.Bag.PodSchemaName, aw.Bag.AppName)},
+		BASE_PATH + "OOMKills": m.AdqlSearch{SchemaDef: m.ContainerSchemaDef{}, SearchName: aw.buildFullMetricName("OOMKills"), SchemaName: aw.Bag.ContainerSchemaName,
+			Query: fmt.Sprintf("select * from %s where clusterName = '%s' and (lastTermReason = 'OOMKilled' OR termReason = 'OOMKilled') ORDER BY lastFinishTime DESC", aw.Bag.ContainerSchemaName, aw.Bag.AppName)},
+		BASE_PATH + "CrashLoops": m.AdqlSearch{SchemaDef: m.ContainerSchemaDef{}, SearchName: aw.buildFullMetricName("CrashLoops"), SchemaName: aw.Bag.ContainerSchemaName,
+			Query: fmt.Sprintf("select * from %s where clusterName = '%s' and waitReason = 'CrashLoopBackOff' ORDER BY lastFinishTime DESC", aw.Bag.ContainerSchemaName, aw.Bag.AppName)},
 		BASE_PATH + "MemoryPressureNodes": m.AdqlSearch{SchemaDef: m.NodeSchemaDef{}, SearchName: aw.buildFullMetricName("MemoryPressureNodes"), SchemaName: aw.Bag.NodeSchemaName,
 			Query: fmt.Sprintf("SELECT * FROM %s where clusterName = '%s' and  memoryPressure = true ORDER BY pickupTimestamp DESC", aw.Bag.NodeSchemaName, aw.Bag.AppName)},
 		BASE_PATH + "DiskPressureNodes": m.AdqlSearch{SchemaDef: m.NodeSchemaDef{}, SearchName: aw.buildFullMetricName("DiskPressureNodes"), SchemaName: aw.Bag.NodeSchemaName,
@@ -184,6 +386,24 @@ func (aw *AdqlSearchWorker) getQueryMap() map[string]m.AdqlSearch {
 			Query: fmt.Sprintf("select * from %s where clusterName = '%s' and phase = 'Running'  and liveProbes = 0 ORDER by namespace, name", aw.Bag.PodSchemaName, aw.Bag.AppName)},
 		BASE_PATH + "Privileged": m.AdqlSearch{SchemaDef: m.PodSchemaDef{}, SearchName: fmt.Sprintf("%s. Privileged", aw.Bag.AppName), SchemaName: aw.Bag.PodSchemaName,
 			Query: fmt.Sprintf("select * from %s where clusterName = '%s' and phase = 'Running'  AND numPrivileged > 0 ORDER by namespace, name", aw.Bag.PodSchemaName, aw.Bag.AppName)},
+		BASE_PATH + "HostNamespaces": m.AdqlSearch{SchemaDef: m.ContainerSchemaDef{}, SearchName: aw.buildFullMetricName("HostNamespaces"), SchemaName: aw.Bag.ContainerSchemaName,
+			Query: fmt.Sprintf("select * from %s where clusterName = '%s' and hostNamespaces = 1 ORDER by namespace, podName", aw.Bag.ContainerSchemaName, aw.Bag.AppName)},
+		BASE_PATH + "HostPath": m.AdqlSearch{SchemaDef: m.ContainerSchemaDef{}, SearchName: aw.buildFullMetricName("HostPath"), SchemaName: aw.Bag.ContainerSchemaName,
+			Query: fmt.Sprintf("select * from %s where clusterName = '%s' and hostPath = 1 ORDER by namespace, podName", aw.Bag.ContainerSchemaName, aw.Bag.AppName)},
+		BASE_PATH + "AddedCapabilities": m.AdqlSearch{SchemaDef: m.ContainerSchemaDef{}, SearchName: aw.buildFullMetricName("AddedCapabilities"), SchemaName: aw.Bag.ContainerSchemaName,
+			Query: fmt.Sprintf("select * from %s where clusterName = '%s' and addedCapabilities = 1 ORDER by namespace, podName", aw.Bag.ContainerSchemaName, aw.Bag.AppName)},
+		BASE_PATH + "RunAsRoot": m.AdqlSearch{SchemaDef: m.ContainerSchemaDef{}, SearchName: aw.buildFullMetricName("RunAsRoot"), SchemaName: aw.Bag.ContainerSchemaName,
+			Query: fmt.Sprintf("select * from %s where clusterName = '%s' and runAsRoot = 1 ORDER by namespace, podName", aw.Bag.ContainerSchemaName, aw.Bag.AppName)},
+		BASE_PATH + "PrivilegeEscalation": m.AdqlSearch{SchemaDef: m.ContainerSchemaDef{}, SearchName: aw.buildFullMetricName("PrivilegeEscalation"), SchemaName: aw.Bag.ContainerSchemaName,
+			Query: fmt.Sprintf("select * from %s where clusterName = '%s' and privilegeEscalation = 1 ORDER by namespace, podName", aw.Bag.ContainerSchemaName, aw.Bag.AppName)},
+		BASE_PATH + "WritableRootFs": m.AdqlSearch{SchemaDef: m.ContainerSchemaDef{}, SearchName: aw.buildFullMetricName("WritableRootFs"), SchemaName: aw.Bag.ContainerSchemaName,
+			Query: fmt.Sprintf("select * from %s where clusterName = '%s' and writableRootFs = 1 ORDER by namespace, podName", aw.Bag.ContainerSchemaName, aw.Bag.AppName)},
+		BASE_PATH + "NoSeccomp": m.AdqlSearch{SchemaDef: m.ContainerSchemaDef{}, SearchName: aw.buildFullMetricName("NoSeccomp"), SchemaName: aw.Bag.ContainerSchemaName,
+			Query: fmt.Sprintf("select * from %s where clusterName = '%s' and noSeccomp = 1 ORDER by namespace, podName", aw.Bag.ContainerSchemaName, aw.Bag.AppName)},
+		BASE_PATH + "PssBaselineViolations": m.AdqlSearch{SchemaDef: m.ContainerSchemaDef{}, SearchName: aw.buildFullMetricName("PssBaselineViolations"), SchemaName: aw.Bag.ContainerSchemaName,
+			Query: fmt.Sprintf("select * from %s where clusterName = '%s' and securityLevel = '%s' ORDER by namespace, podName", aw.Bag.ContainerSchemaName, aw.Bag.AppName, m.PSS_LEVEL_PRIVILEGED)},
+		BASE_PATH + "PssRestrictedViolations": m.AdqlSearch{SchemaDef: m.ContainerSchemaDef{}, SearchName: aw.buildFullMetricName("PssRestrictedViolations"), SchemaName: aw.Bag.ContainerSchemaName,
+			Query: fmt.Sprintf("select * from %s where clusterName = '%s' and securityLevel != '%s' ORDER by namespace, podName", aw.Bag.ContainerSchemaName, aw.Bag.AppName, m.PSS_LEVEL_RESTRICTED)},
 		BASE_PATH + "JobCount": m.AdqlSearch{SchemaDef: m.PodSchemaDef{}, SearchName: fmt.Sprintf("%s. JobCount", aw.Bag.AppName), SchemaName: aw.Bag.JobSchemaName,
 			Query: fmt.Sprintf("select * from %s where clusterName = '%s' ORDER BY startTime DESC", aw.Bag.JobSchemaName, aw.Bag.AppName)},
 		BASE_PATH + "JobFailedCount": m.AdqlSearch{SchemaDef: m.PodSchemaDef{}, SearchName: fmt.Sprintf("%s. JobFailedCount", aw.Bag.AppName), SchemaName: aw.Bag.JobSchemaName,
@@ -200,6 +420,12 @@ func (aw *AdqlSearchWorker) getQueryMap() map[string]m.AdqlSearch {
 			Query: fmt.Sprintf("select * from %s where clusterName = '%s' and deploymentType = '%s' ORDER by namespace, name", aw.Bag.DeploySchemaName, aw.Bag.AppName, m.DEPLOYMENT_TYPE_RS)},
 		BASE_PATH + "DaemonCount": m.AdqlSearch{SchemaDef: m.DeploySchemaDef{}, SearchName: fmt.Sprintf("%s. DaemonCount", aw.Bag.AppName), SchemaName: aw.Bag.DeploySchemaName,
 			Query: fmt.Sprintf("select * from %s where clusterName = '%s' and deploymentType = '%s' ORDER by namespace, name", aw.Bag.DeploySchemaName, aw.Bag.AppName, m.DEPLOYMENT_TYPE_DS)},
+		BASE_PATH + "RolloutsInProgress": m.AdqlSearch{SchemaDef: m.RolloutSchemaDef{}, SearchName: aw.buildFullMetricName("RolloutsInProgress"), SchemaName: aw.Bag.RolloutSchemaName,
+			Query: fmt.Sprintf("select * from %s where clusterName = '%s' and status = '%s' ORDER BY startTime DESC", aw.Bag.RolloutSchemaName, aw.Bag.AppName, m.ROLLOUT_STATUS_IN_PROGRESS)},
+		BASE_PATH + "RolloutsFailed": m.AdqlSearch{SchemaDef: m.RolloutSchemaDef{}, SearchName: aw.buildFullMetricName("RolloutsFailed"), SchemaName: aw.Bag.RolloutSchemaName,
+			Query: fmt.Sprintf("select * from %s where clusterName = '%s' and status = '%s' ORDER BY endTime DESC", aw.Bag.RolloutSchemaName, aw.Bag.AppName, m.ROLLOUT_STATUS_FAILED)},
+		BASE_PATH + "RolloutsStalled": m.AdqlSearch{SchemaDef: m.RolloutSchemaDef{}, SearchName: aw.buildFullMetricName("RolloutsStalled"), SchemaName: aw.Bag.RolloutSchemaName,
+			Query: fmt.Sprintf("select * from %s where clusterName = '%s' and status = '%s' and stalled = true ORDER BY startTime DESC", aw.Bag.RolloutSchemaName, aw.Bag.AppName, m.ROLLOUT_STATUS_IN_PROGRESS)},
 		BASE_PATH + "NamespaceNoQuotas": m.AdqlSearch{SchemaDef: m.NsSchemaDef{}, SearchName: fmt.Sprintf("%s. NamespaceNoQuotas", aw.Bag.AppName), SchemaName: aw.Bag.NsSchemaName,
 			Query: fmt.Sprintf("select * from %s where clusterName = '%s' and quotas = 0 ORDER by name", aw.Bag.NsSchemaName, aw.Bag.AppName)},
 		BASE_PATH + "NamespaceCount": m.AdqlSearch{SchemaDef: m.NsSchemaDef{}, SearchName: fmt.Sprintf("%s. NamespaceCount", aw.Bag.AppName), SchemaName: aw.Bag.NsSchemaName,
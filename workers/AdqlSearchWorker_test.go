@@ -0,0 +1,158 @@
+package workers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/appdynamics/cluster-agent/config"
+	m "github.com/appdynamics/cluster-agent/models"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	SEARCHES_LIST   string = "GET restui/analyticsSavedSearches/getAllAnalyticsSavedSearches"
+	SEARCHES_UPDATE string = "POST restui/analyticsSavedSearches/updateAnalyticsSavedSearch"
+	SEARCHES_DELETE string = "POST restui/analyticsSavedSearches/deleteAnalyticsSavedSearches"
+)
+
+func newTestSearchBag() *m.AppDBag {
+	bag := m.GetDefaultProperties()
+	bag.AppName = "cluster"
+	bag.ClusterUID = "uid-1"
+	bag.AppID = 7
+	bag.DashboardTemplatePath = "../templates/cluster-template.json"
+	bag.AdqlSearches = []m.AdqlSearchDef{
+		{MetricPath: "PodFailed", SchemaName: bag.PodSchemaName, Query: "select * from {{.SchemaName}} where clusterName = '{{.ClusterName}}' and phase = 'Failed'"},
+		{MetricPath: "BigPods", SchemaName: bag.PodSchemaName, Query: "select * from {{.SchemaName}} where clusterName = '{{.ClusterName}}' and cpuRequest > 4000"},
+		{MetricPath: "NoSchema", Query: "select * from kube_pod_snapshots"},
+	}
+	return bag
+}
+
+func TestAdqlSearchQueryMap(t *testing.T) {
+	l := log.New()
+	l.Out = ioutil.Discard
+	aw := AdqlSearchWorker{Bag: newTestSearchBag(), SearchCache: make(map[string]m.AdqlSearch), Logger: l}
+	queries := aw.getQueryMap()
+
+	failed := queries[BASE_PATH+"PodFailed"]
+	if failed.Query != "select * from kube_pod_snapshots where clusterName = 'cluster' and phase = 'Failed'" || failed.SearchName != "cluster. PodFailed" {
+		t.Errorf("config did not override the built-in PodFailed search: %q", failed.Query)
+	}
+	if _, ok := failed.SchemaDef.(m.PodSchemaDef); !ok {
+		t.Errorf("columns of the PodFailed search are not taken from the pod schema")
+	}
+	if _, ok := queries[BASE_PATH+"BigPods"]; !ok {
+		t.Errorf("search of a new metric is missing")
+	}
+	if _, ok := queries[BASE_PATH+"NoSchema"]; ok {
+		t.Errorf("search without a schema was accepted")
+	}
+
+	aw.Bag.AdqlSearches = append(aw.Bag.AdqlSearches, m.AdqlSearchDef{MetricPath: "Broken", SchemaName: "x", Query: "select {{.Missing"})
+	if _, ok := aw.getQueryMap()[BASE_PATH+"Broken"]; ok {
+		t.Errorf("search with an invalid template was accepted")
+	}
+}
+
+func TestAdqlSearchReconcile(t *testing.T) {
+	l := log.New()
+	l.Out = ioutil.Discard
+	bag := newTestSearchBag()
+	stub := newControllerStub(t, bag)
+	aw := AdqlSearchWorker{Bag: bag, SearchCache: make(map[string]m.AdqlSearch), Logger: l}
+	bigPods := aw.getQueryMap()[BASE_PATH+"BigPods"].Query
+
+	existing := []m.AdqlSearch{
+		//stale query
+		{ID: 1, Name: "appd-cluster-agent-uid-1-a", SearchName: "cluster. PodFailed", AdqlQueries: []string{"select * from kube_pod_snapshots"}},
+		//up to date
+		{ID: 2, Name: "appd-cluster-agent-uid-1-b", SearchName: "cluster. BigPods", AdqlQueries: []string{bigPods}},
+		//metric no longer defined
+		{ID: 3, Name: "appd-cluster-agent-uid-1-c", SearchName: "cluster. Retired", AdqlQueries: []string{"select 1"}},
+		//created by an older agent, taken over under the new internal name
+		{ID: 4, Name: "0b5e1d1c-2d9e-4a4e-9a57-3b9f3f1c8a11", SearchName: "cluster. PodPending", AdqlQueries: []string{"select 2"}},
+		//saved before the cluster was renamed
+		{ID: 5, Name: "appd-cluster-agent-uid-1-d", SearchName: "old. PodRunning", AdqlQueries: []string{"select 3"}},
+		{ID: 6, Name: "appd-cluster-agent-uid-1-e", SearchName: "old. Retired", AdqlQueries: []string{"select 4"}},
+		//another cluster reporting to the same controller
+		{ID: 7, Name: "appd-cluster-agent-uid-2-f", SearchName: "other. PodFailed", AdqlQueries: []string{"select 5"}},
+		//saved by a user
+		{ID: 8, Name: "my search", SearchName: "cluster. My pods", AdqlQueries: []string{"select 6"}},
+	}
+	data, _ := json.Marshal(existing)
+	stub.responses[SEARCHES_LIST] = string(data)
+
+	if err := aw.Reconcile(); err != nil {
+		t.Fatalf("Reconcile error = %v", err)
+	}
+
+	updated := map[int64]m.AdqlSearch{}
+	for _, body := range stub.bodies[SEARCHES_UPDATE] {
+		var s m.AdqlSearch
+		if err := json.Unmarshal([]byte(body), &s); err != nil {
+			t.Fatalf("Unable to read the update %s: %v", body, err)
+		}
+		updated[s.ID] = s
+	}
+	if len(updated) != 3 {
+		t.Errorf("updated searches = %v, want 1, 4 and 5", updated)
+	}
+	if s := updated[1]; s.Name != "appd-cluster-agent-uid-1-a" || len(s.AdqlQueries) != 1 || !strings.Contains(s.AdqlQueries[0], "phase = 'Failed'") {
+		t.Errorf("update of the stale search = %+v", s)
+	}
+	if s := updated[4]; !strings.HasPrefix(s.Name, "appd-cluster-agent-uid-1-") || s.SearchName != "cluster. PodPending" {
+		t.Errorf("update of the legacy search = %+v", s)
+	}
+	if s := updated[5]; s.Name != "appd-cluster-agent-uid-1-d" || s.SearchName != "cluster. PodRunning" {
+		t.Errorf("move of the search of the renamed cluster = %+v", s)
+	}
+	if deleted := fmt.Sprint(stub.bodies[SEARCHES_DELETE]); deleted != "[[3] [6]]" {
+		t.Errorf("deleted searches = %s, want [[3] [6]]", deleted)
+	}
+}
+
+func TestReconcileSearchesOnSettingsChange(t *testing.T) {
+	l := log.New()
+	l.Out = ioutil.Discard
+	bag := newTestSearchBag()
+	bag.AppID = 0
+	stub := newControllerStub(t, bag)
+	stub.responses[SEARCHES_LIST] = "[]"
+	cm := config.MutexConfigManager{Conf: bag, Mutex: &sync.Mutex{}, Logger: l}
+	c := MainController{ConfManager: &cm, Logger: l, lockSearches: &sync.Mutex{}}
+
+	c.reconcileSearches()
+	if requests := stub.takeRequests(); len(requests) != 0 {
+		t.Errorf("requests before the application is registered = %v", requests)
+	}
+
+	bag.AppID = 7
+	c.reconcileSearches()
+	if requests := stub.takeRequests(); len(requests) == 0 {
+		t.Errorf("searches were not reconciled after the application was registered")
+	}
+
+	//config updates that do not touch the searches
+	bag.MetricsSyncInterval = 30
+	c.reconcileSearches()
+	requests := stub.takeRequests()
+	for _, r := range requests {
+		if r != SEARCHES_LIST {
+			t.Errorf("request without search changes: %s", r)
+		}
+	}
+	if len(requests) != 1 {
+		t.Errorf("requests without search changes = %v, want the list cached by the worker", requests)
+	}
+
+	bag.AppName = "renamed"
+	c.reconcileSearches()
+	if requests := stub.takeRequests(); len(requests) != 2 {
+		t.Errorf("requests after the cluster was renamed = %v, want the list loaded twice", requests)
+	}
+}
@@ -0,0 +1,478 @@
+package workers
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/appdynamics/cluster-agent/config"
+	m "github.com/appdynamics/cluster-agent/models"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const CAPACITY_MAX_REPLICAS int64 = 10000
+
+//requests of the pods scheduled on a node
+type nodeRequests struct {
+	Cpu  int64
+	Mem  int64
+	Pods int64
+}
+
+//free capacity of a schedulable node
+type capacityNode struct {
+	Name           string
+	Pool           string
+	Labels         map[string]string
+	Taints         []v1.Taint
+	CpuAllocatable int64
+	MemAllocatable int64
+	CpuFree        int64
+	MemFree        int64
+	PodsFree       int64
+}
+
+//CapacityAnalyzer simulates the placement of pod shapes on the allocatable resources of the nodes minus the requests of the pods
+type CapacityAnalyzer struct {
+	ConfManager   *config.MutexConfigManager
+	Logger        *log.Logger
+	lock          *sync.RWMutex
+	cycleRequests map[string]nodeRequests
+	cycleShapes   map[string]m.PodShape
+	nodes         []capacityNode
+	shapes        map[string]m.PodShape //workload key -> shape of its pods
+	report        m.CapacityReport
+}
+
+func NewCapacityAnalyzer(cm *config.MutexConfigManager, l *log.Logger) *CapacityAnalyzer {
+	return &CapacityAnalyzer{ConfManager: cm, Logger: l, lock: &sync.RWMutex{}, cycleRequests: make(map[string]nodeRequests),
+		cycleShapes: make(map[string]m.PodShape), nodes: []capacityNode{}, shapes: make(map[string]m.PodShape),
+		report: m.CapacityReport{Pools: []m.CapacityPool{}, Headroom: make(map[string]map[string]int64)}}
+}
+
+//RecordPod adds the requests of a scheduled pod to its node and keeps the shape of the pods of the workload
+func (ca *CapacityAnalyzer) RecordPod(p *v1.Pod) {
+	if p.Status.Phase == v1.PodSucceeded || p.Status.Phase == v1.PodFailed {
+		return
+	}
+	shape := podShapeOf(p)
+	kind, workload := podWorkload(p, p.Name)
+
+	ca.lock.Lock()
+	defer ca.lock.Unlock()
+	if p.Spec.NodeName != "" {
+		r := ca.cycleRequests[p.Spec.NodeName]
+		r.Cpu += shape.Cpu
+		r.Mem += shape.Mem
+		r.Pods++
+		ca.cycleRequests[p.Spec.NodeName] = r
+	}
+	if kind != "Pod" {
+		key := capacityShapeKey(kind, p.Namespace, workload)
+		if _, ok := ca.cycleShapes[key]; !ok {
+			shape.Name = fmt.Sprintf("%s/%s/%s", kind, p.Namespace, workload)
+			ca.cycleShapes[key] = shape
+		}
+	}
+}
+
+func capacityShapeKey(kind string, namespace string, workload string) string {
+	return workloadKey(namespace, strings.ToLower(kind), workload)
+}
+
+//effective requests of a pod as seen by the scheduler: the sum of the containers or the largest init container
+func podShapeOf(p *v1.Pod) m.PodShape {
+	shape := m.PodShape{NodeSelector: p.Spec.NodeSelector, Tolerations: p.Spec.Tolerations}
+	for _, c := range p.Spec.Containers {
+		shape.Cpu += c.Resources.Requests.Cpu().MilliValue()
+		shape.Mem += c.Resources.Requests.Memory().Value()
+	}
+	for _, c := range p.Spec.InitContainers {
+		if cpu := c.Resources.Requests.Cpu().MilliValue(); cpu > shape.Cpu {
+			shape.Cpu = cpu
+		}
+		if mem := c.Resources.Requests.Memory().Value(); mem > shape.Mem {
+			shape.Mem = mem
+		}
+	}
+	if p.Spec.Affinity != nil && p.Spec.Affinity.NodeAffinity != nil {
+		shape.Affinity = p.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	}
+	return shape
+}
+
+//EndCycle takes the snapshot of the free capacity of the nodes and returns the capacity metrics of the node pools
+//and the headroom of the configured shapes
+func (ca *CapacityAnalyzer) EndCycle(nodesWorker *NodesWorker) []m.AppDMetricInterface {
+	bag := (*ca.ConfManager).Get()
+	ca.lock.Lock()
+	requests := ca.cycleRequests
+	ca.shapes = ca.cycleShapes
+	ca.cycleRequests = make(map[string]nodeRequests)
+	ca.cycleShapes = make(map[string]m.PodShape)
+	ca.lock.Unlock()
+
+	if nodesWorker == nil {
+		return nil
+	}
+	nodes := []capacityNode{}
+	pools := make(map[string]*m.CapacityPool)
+	all := &m.CapacityPool{Name: m.ALL}
+	for _, n := range nodesWorker.GetNodeObjects() {
+		if !nodeSchedulable(n) {
+			continue
+		}
+		cn := newCapacityNode(n, requests[n.Name], bag)
+		nodes = append(nodes, cn)
+		pool, ok := pools[cn.Pool]
+		if !ok {
+			pool = &m.CapacityPool{Name: cn.Pool}
+			pools[cn.Pool] = pool
+		}
+		for _, p := range []*m.CapacityPool{pool, all} {
+			p.Nodes++
+			p.CpuAllocatable += n.Status.Allocatable.Cpu().MilliValue()
+			p.MemAllocatable += n.Status.Allocatable.Memory().Value()
+			p.PodsAllocatable += n.Status.Allocatable.Pods().Value()
+			p.CpuRequested += requests[n.Name].Cpu
+			p.MemRequested += requests[n.Name].Mem
+			p.PodsRequested += requests[n.Name].Pods
+		}
+	}
+
+	report := m.CapacityReport{Timestamp: time.Now(), Pools: []m.CapacityPool{*all}, Headroom: make(map[string]map[string]int64)}
+	names := []string{}
+	for name := range pools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		report.Pools = append(report.Pools, *pools[name])
+	}
+
+	metrics := []m.AppDMetricInterface{}
+	for i := range report.Pools {
+		metrics = append(metrics, m.NewClusterCapacityMetrics(bag, &report.Pools[i]))
+	}
+	for _, cs := range bag.CapacityShapes {
+		shape, err := ca.resolveShape(cs)
+		if err != nil {
+			ca.Logger.WithField("shape", cs.Name).Debugf("Skipping capacity shape. %v", err)
+			continue
+		}
+		headroom := map[string]int64{m.ALL: 0}
+		for _, name := range names {
+			headroom[name] = 0
+		}
+		for i := range nodes {
+			if nodeEligible(&nodes[i], &shape) {
+				count := fitCount(&nodes[i], &shape)
+				headroom[nodes[i].Pool] += count
+				headroom[m.ALL] += count
+			}
+		}
+		report.Headroom[shape.Name] = headroom
+		for pool, count := range headroom {
+			metrics = append(metrics, m.NewClusterHeadroomMetrics(bag, pool, shape.Name, count))
+		}
+	}
+
+	ca.lock.Lock()
+	ca.nodes = nodes
+	ca.report = report
+	ca.lock.Unlock()
+	return metrics
+}
+
+func nodeSchedulable(n *v1.Node) bool {
+	if n.Spec.Unschedulable {
+		return false
+	}
+	for _, cond := range n.Status.Conditions {
+		if cond.Type == v1.NodeReady {
+			return cond.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func newCapacityNode(n *v1.Node, r nodeRequests, bag *m.AppDBag) capacityNode {
+	cn := capacityNode{Name: n.Name, Pool: m.GetNodePool(n.Labels, bag.NodePoolLabels), Labels: n.Labels, Taints: n.Spec.Taints,
+		CpuAllocatable: n.Status.Allocatable.Cpu().MilliValue(), MemAllocatable: n.Status.Allocatable.Memory().Value()}
+	cn.CpuFree = cn.CpuAllocatable - r.Cpu
+	cn.MemFree = cn.MemAllocatable - r.Mem
+	cn.PodsFree = n.Status.Allocatable.Pods().Value() - r.Pods
+	return cn
+}
+
+//nodeEligible checks the node selector, the required node affinity and the NoSchedule and NoExecute taints
+func nodeEligible(node *capacityNode, shape *m.PodShape) bool {
+	for k, v := range shape.NodeSelector {
+		if node.Labels[k] != v {
+			return false
+		}
+	}
+	if shape.Affinity != nil && len(shape.Affinity.NodeSelectorTerms) > 0 {
+		matched := false
+		for _, term := range shape.Affinity.NodeSelectorTerms {
+			if termMatches(term, node.Labels) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	for _, taint := range node.Taints {
+		if taint.Effect == v1.TaintEffectPreferNoSchedule {
+			continue
+		}
+		tolerated := false
+		for _, t := range shape.Tolerations {
+			if toleratesTaint(&t, &taint) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			return false
+		}
+	}
+	return true
+}
+
+func termMatches(term v1.NodeSelectorTerm, labels map[string]string) bool {
+	for _, req := range term.MatchExpressions {
+		val, ok := labels[req.Key]
+		switch req.Operator {
+		case v1.NodeSelectorOpIn:
+			if !ok || !containsValue(req.Values, val) {
+				return false
+			}
+		case v1.NodeSelectorOpNotIn:
+			if ok && containsValue(req.Values, val) {
+				return false
+			}
+		case v1.NodeSelectorOpExists:
+			if !ok {
+				return false
+			}
+		case v1.NodeSelectorOpDoesNotExist:
+			if ok {
+				return false
+			}
+		case v1.NodeSelectorOpGt, v1.NodeSelectorOpLt:
+			if !ok || len(req.Values) != 1 {
+				return false
+			}
+			actual, err1 := strconv.ParseInt(val, 10, 64)
+			expected, err2 := strconv.ParseInt(req.Values[0], 10, 64)
+			if err1 != nil || err2 != nil {
+				return false
+			}
+			if (req.Operator == v1.NodeSelectorOpGt && actual <= expected) || (req.Operator == v1.NodeSelectorOpLt && actual >= expected) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func containsValue(values []string, val string) bool {
+	for _, v := range values {
+		if v == val {
+			return true
+		}
+	}
+	return false
+}
+
+//an empty key with the Exists operator tolerates all taints
+func toleratesTaint(t *v1.Toleration, taint *v1.Taint) bool {
+	if t.Effect != "" && t.Effect != taint.Effect {
+		return false
+	}
+	if t.Key == "" {
+		return t.Operator == v1.TolerationOpExists
+	}
+	if t.Key != taint.Key {
+		return false
+	}
+	switch t.Operator {
+	case v1.TolerationOpExists:
+		return true
+	case "", v1.TolerationOpEqual:
+		return t.Value == taint.Value
+	}
+	return false
+}
+
+//number of replicas of the shape that fit into the free capacity of the node
+func fitCount(node *capacityNode, shape *m.PodShape) int64 {
+	count := node.PodsFree
+	if shape.Cpu > 0 && node.CpuFree/shape.Cpu < count {
+		count = node.CpuFree / shape.Cpu
+	}
+	if shape.Mem > 0 && node.MemFree/shape.Mem < count {
+		count = node.MemFree / shape.Mem
+	}
+	if count < 0 {
+		return 0
+	}
+	return count
+}
+
+//resolveShape takes the requests, node selection and tolerations of the workload and applies the overrides of the shape
+func (ca *CapacityAnalyzer) resolveShape(cs m.CapacityShape) (m.PodShape, error) {
+	shape := m.PodShape{Name: cs.Name}
+	if cs.Workload != "" {
+		parts := strings.Split(cs.Workload, "/")
+		if len(parts) != 3 {
+			return shape, fmt.Errorf("Workload %s is invalid. Use this format: kind/namespace/name", cs.Workload)
+		}
+		ca.lock.RLock()
+		template, ok := ca.shapes[capacityShapeKey(parts[0], parts[1], parts[2])]
+		ca.lock.RUnlock()
+		if !ok {
+			return shape, fmt.Errorf("Workload %s has no running pods", cs.Workload)
+		}
+		shape = template
+		shape.Tolerations = append([]v1.Toleration{}, template.Tolerations...)
+		if cs.Name != "" {
+			shape.Name = cs.Name
+		}
+	}
+	if cs.Cpu != "" {
+		q, err := resource.ParseQuantity(cs.Cpu)
+		if err != nil {
+			return shape, fmt.Errorf("Cpu request %s is invalid. %v", cs.Cpu, err)
+		}
+		shape.Cpu = q.MilliValue()
+	}
+	if cs.Memory != "" {
+		q, err := resource.ParseQuantity(cs.Memory)
+		if err != nil {
+			return shape, fmt.Errorf("Memory request %s is invalid. %v", cs.Memory, err)
+		}
+		shape.Mem = q.Value()
+	}
+	if cs.Workload == "" && shape.Cpu <= 0 && shape.Mem <= 0 {
+		return shape, fmt.Errorf("Shape %s has neither a workload nor requests", cs.Name)
+	}
+	if cs.NodeSelector != nil {
+		shape.NodeSelector = cs.NodeSelector
+	}
+	shape.Tolerations = append(shape.Tolerations, cs.Tolerations...)
+	if shape.Name == "" {
+		shape.Name = fmt.Sprintf("cpu:%dm;mem:%d", shape.Cpu, shape.Mem)
+	}
+	return shape, nil
+}
+
+//Simulate places the replicas of the shapes on the free capacity of the last snapshot. The largest shapes are placed first,
+//each replica on the eligible node it leaves with the least free capacity. Shapes without replicas only report how many replicas fit
+func (ca *CapacityAnalyzer) Simulate(list []m.CapacityShape) ([]m.CapacityFit, error) {
+	shapes := make([]m.PodShape, len(list))
+	for i, cs := range list {
+		shape, err := ca.resolveShape(cs)
+		if err != nil {
+			return nil, err
+		}
+		if cs.Replicas < 0 || cs.Replicas > CAPACITY_MAX_REPLICAS {
+			return nil, fmt.Errorf("Replicas of shape %s must be between 0 and %d", shape.Name, CAPACITY_MAX_REPLICAS)
+		}
+		shapes[i] = shape
+	}
+
+	ca.lock.RLock()
+	nodes := make([]capacityNode, len(ca.nodes))
+	copy(nodes, ca.nodes)
+	ca.lock.RUnlock()
+
+	fits := make([]m.CapacityFit, len(shapes))
+	for i := range shapes {
+		fits[i] = m.CapacityFit{Shape: shapes[i].Name, Cpu: shapes[i].Cpu, Mem: shapes[i].Mem, Requested: list[i].Replicas, Placements: []m.CapacityPlacement{}}
+		for n := range nodes {
+			if nodeEligible(&nodes[n], &shapes[i]) {
+				fits[i].EligibleNodes++
+				fits[i].MaxReplicas += fitCount(&nodes[n], &shapes[i])
+			}
+		}
+	}
+
+	order := make([]int, len(shapes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		sa, sb := shapes[order[a]], shapes[order[b]]
+		if sa.Cpu != sb.Cpu {
+			return sa.Cpu > sb.Cpu
+		}
+		return sa.Mem > sb.Mem
+	})
+	for _, i := range order {
+		placed := make(map[int]int64)
+		for fits[i].Placed < fits[i].Requested {
+			best := -1
+			var bestScore float64
+			for n := range nodes {
+				if !nodeEligible(&nodes[n], &shapes[i]) || fitCount(&nodes[n], &shapes[i]) == 0 {
+					continue
+				}
+				score := remainingShare(&nodes[n], &shapes[i])
+				if best < 0 || score < bestScore {
+					best, bestScore = n, score
+				}
+			}
+			if best < 0 {
+				break
+			}
+			nodes[best].CpuFree -= shapes[i].Cpu
+			nodes[best].MemFree -= shapes[i].Mem
+			nodes[best].PodsFree--
+			placed[best]++
+			fits[i].Placed++
+		}
+		for n, count := range placed {
+			fits[i].Placements = append(fits[i].Placements, m.CapacityPlacement{NodeName: nodes[n].Name, Pool: nodes[n].Pool, Replicas: count})
+		}
+		sort.Slice(fits[i].Placements, func(a, b int) bool { return fits[i].Placements[a].NodeName < fits[i].Placements[b].NodeName })
+		fits[i].Fits = fits[i].Placed == fits[i].Requested && (fits[i].Requested > 0 || fits[i].MaxReplicas > 0)
+		if !fits[i].Fits {
+			if fits[i].EligibleNodes == 0 {
+				fits[i].Reason = "No schedulable node matches the node selector, the node affinity and the tolerations"
+			} else {
+				fits[i].Reason = "Not enough free cpu, memory or pod capacity on the eligible nodes"
+			}
+		}
+	}
+	return fits, nil
+}
+
+//share of the allocatable resources of the node left after placing the shape
+func remainingShare(node *capacityNode, shape *m.PodShape) float64 {
+	var share float64
+	if node.CpuAllocatable > 0 {
+		share += float64(node.CpuFree-shape.Cpu) / float64(node.CpuAllocatable)
+	}
+	if node.MemAllocatable > 0 {
+		share += float64(node.MemFree-shape.Mem) / float64(node.MemAllocatable)
+	}
+	return share
+}
+
+//GetCapacityReport returns the capacity of the node pools and the headroom of the configured shapes of the last snapshot
+func (ca *CapacityAnalyzer) GetCapacityReport() m.CapacityReport {
+	ca.lock.RLock()
+	defer ca.lock.RUnlock()
+	return ca.report
+}
@@ -0,0 +1,181 @@
+package workers
+
+import (
+	"io/ioutil"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/appdynamics/cluster-agent/config"
+	m "github.com/appdynamics/cluster-agent/models"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const GI int64 = 1024 * 1024 * 1024
+
+func newTestCapacityAnalyzer(nodes ...capacityNode) *CapacityAnalyzer {
+	l := log.New()
+	l.Out = ioutil.Discard
+	cm := config.MutexConfigManager{Conf: &m.AppDBag{}, Mutex: &sync.Mutex{}, Logger: l}
+	ca := NewCapacityAnalyzer(&cm, l)
+	ca.nodes = nodes
+	return ca
+}
+
+func TestFitCount(t *testing.T) {
+	node := capacityNode{CpuFree: 2000, MemFree: 4 * GI, PodsFree: 10}
+	tests := []struct {
+		cpu   int64
+		mem   int64
+		count int64
+	}{
+		{500, 0, 4},
+		{0, GI, 4},
+		{500, 2 * GI, 2},
+		{100, 0, 10},
+		{0, 0, 10},
+		{3000, 0, 0},
+	}
+	for _, tt := range tests {
+		if count := fitCount(&node, &m.PodShape{Cpu: tt.cpu, Mem: tt.mem}); count != tt.count {
+			t.Errorf("fitCount(cpu %d, mem %d) = %d, want %d", tt.cpu, tt.mem, count, tt.count)
+		}
+	}
+	overcommitted := capacityNode{CpuFree: -100, MemFree: GI, PodsFree: 10}
+	if count := fitCount(&overcommitted, &m.PodShape{Cpu: 100}); count != 0 {
+		t.Errorf("fitCount on overcommitted node = %d, want 0", count)
+	}
+}
+
+func TestNodeEligible(t *testing.T) {
+	node := capacityNode{Labels: map[string]string{"zone": "a", "disk": "ssd", "cores": "16"},
+		Taints: []v1.Taint{{Key: "dedicated", Value: "db", Effect: v1.TaintEffectNoSchedule}, {Key: "spot", Effect: v1.TaintEffectPreferNoSchedule}}}
+	dedicated := []v1.Toleration{{Key: "dedicated", Operator: v1.TolerationOpEqual, Value: "db"}}
+	affinity := func(reqs ...v1.NodeSelectorRequirement) *v1.NodeSelector {
+		return &v1.NodeSelector{NodeSelectorTerms: []v1.NodeSelectorTerm{{MatchExpressions: reqs}}}
+	}
+	tests := []struct {
+		name     string
+		shape    m.PodShape
+		eligible bool
+	}{
+		{"tolerated", m.PodShape{Tolerations: dedicated}, true},
+		{"taint not tolerated", m.PodShape{}, false},
+		{"wrong taint value", m.PodShape{Tolerations: []v1.Toleration{{Key: "dedicated", Value: "web"}}}, false},
+		{"exists toleration", m.PodShape{Tolerations: []v1.Toleration{{Key: "dedicated", Operator: v1.TolerationOpExists}}}, true},
+		{"wildcard toleration", m.PodShape{Tolerations: []v1.Toleration{{Operator: v1.TolerationOpExists}}}, true},
+		{"other effect", m.PodShape{Tolerations: []v1.Toleration{{Key: "dedicated", Value: "db", Effect: v1.TaintEffectNoExecute}}}, false},
+		{"node selector", m.PodShape{NodeSelector: map[string]string{"zone": "a"}, Tolerations: dedicated}, true},
+		{"node selector mismatch", m.PodShape{NodeSelector: map[string]string{"zone": "b"}, Tolerations: dedicated}, false},
+		{"affinity in", m.PodShape{Affinity: affinity(v1.NodeSelectorRequirement{Key: "zone", Operator: v1.NodeSelectorOpIn, Values: []string{"a", "b"}}),
+			Tolerations: dedicated}, true},
+		{"affinity not in", m.PodShape{Affinity: affinity(v1.NodeSelectorRequirement{Key: "disk", Operator: v1.NodeSelectorOpNotIn, Values: []string{"ssd"}}),
+			Tolerations: dedicated}, false},
+		{"affinity does not exist", m.PodShape{Affinity: affinity(v1.NodeSelectorRequirement{Key: "gpu", Operator: v1.NodeSelectorOpDoesNotExist}),
+			Tolerations: dedicated}, true},
+		{"affinity gt", m.PodShape{Affinity: affinity(v1.NodeSelectorRequirement{Key: "cores", Operator: v1.NodeSelectorOpGt, Values: []string{"8"}}),
+			Tolerations: dedicated}, true},
+		{"affinity lt", m.PodShape{Affinity: affinity(v1.NodeSelectorRequirement{Key: "cores", Operator: v1.NodeSelectorOpLt, Values: []string{"8"}}),
+			Tolerations: dedicated}, false},
+		{"second affinity term", m.PodShape{Affinity: &v1.NodeSelector{NodeSelectorTerms: []v1.NodeSelectorTerm{
+			{MatchExpressions: []v1.NodeSelectorRequirement{{Key: "gpu", Operator: v1.NodeSelectorOpExists}}},
+			{MatchExpressions: []v1.NodeSelectorRequirement{{Key: "disk", Operator: v1.NodeSelectorOpExists}}}}},
+			Tolerations: dedicated}, true},
+	}
+	for _, tt := range tests {
+		if eligible := nodeEligible(&node, &tt.shape); eligible != tt.eligible {
+			t.Errorf("%s: nodeEligible = %t, want %t", tt.name, eligible, tt.eligible)
+		}
+	}
+}
+
+func TestPodShapeOf(t *testing.T) {
+	requests := func(cpu string, mem string) v1.ResourceRequirements {
+		return v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu), v1.ResourceMemory: resource.MustParse(mem)}}
+	}
+	p := v1.Pod{Spec: v1.PodSpec{
+		Containers:     []v1.Container{{Name: "app", Resources: requests("250m", "256Mi")}, {Name: "proxy", Resources: requests("250m", "128Mi")}},
+		InitContainers: []v1.Container{{Name: "migrate", Resources: requests("1", "128Mi")}},
+	}}
+	shape := podShapeOf(&p)
+	if shape.Cpu != 1000 || shape.Mem != 384*1024*1024 {
+		t.Errorf("podShapeOf = (cpu %d, mem %d), want (cpu 1000, mem %d)", shape.Cpu, shape.Mem, 384*1024*1024)
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	newNode := func(name string, pool string, cpuAllocatable int64, cpuFree int64) capacityNode {
+		return capacityNode{Name: name, Pool: pool, Labels: map[string]string{"pool": pool}, CpuAllocatable: cpuAllocatable, CpuFree: cpuFree,
+			MemAllocatable: 8 * GI, MemFree: 8 * GI, PodsFree: 110}
+	}
+	gpu := newNode("gpu-1", "gpu", 8000, 8000)
+	gpu.Taints = []v1.Taint{{Key: "gpu", Value: "true", Effect: v1.TaintEffectNoSchedule}}
+	ca := newTestCapacityAnalyzer(newNode("node-1", "default", 4000, 4000), newNode("node-2", "default", 4000, 1000), gpu)
+
+	fits, err := ca.Simulate([]m.CapacityShape{
+		{Name: "small", Cpu: "500m", Replicas: 3},
+		{Name: "gpu", Cpu: "2", NodeSelector: map[string]string{"pool": "gpu"},
+			Tolerations: []v1.Toleration{{Key: "gpu", Operator: v1.TolerationOpExists}}, Replicas: 5},
+		{Name: "nowhere", Cpu: "100m", NodeSelector: map[string]string{"pool": "arm"}, Replicas: 1},
+		{Name: "probe", Cpu: "1"},
+	})
+	if err != nil {
+		t.Fatalf("Simulate error = %v", err)
+	}
+	tests := []struct {
+		fit        m.CapacityFit
+		placements []m.CapacityPlacement
+	}{
+		//best fit fills the fuller node-2 before node-1
+		{m.CapacityFit{Shape: "small", Cpu: 500, EligibleNodes: 2, MaxReplicas: 10, Requested: 3, Placed: 3, Fits: true},
+			[]m.CapacityPlacement{{NodeName: "node-1", Pool: "default", Replicas: 1}, {NodeName: "node-2", Pool: "default", Replicas: 2}}},
+		{m.CapacityFit{Shape: "gpu", Cpu: 2000, EligibleNodes: 1, MaxReplicas: 4, Requested: 5, Placed: 4,
+			Reason: "Not enough free cpu, memory or pod capacity on the eligible nodes"},
+			[]m.CapacityPlacement{{NodeName: "gpu-1", Pool: "gpu", Replicas: 4}}},
+		{m.CapacityFit{Shape: "nowhere", Cpu: 100, Requested: 1,
+			Reason: "No schedulable node matches the node selector, the node affinity and the tolerations"}, []m.CapacityPlacement{}},
+		{m.CapacityFit{Shape: "probe", Cpu: 1000, EligibleNodes: 2, MaxReplicas: 5, Fits: true}, []m.CapacityPlacement{}},
+	}
+	for i, tt := range tests {
+		tt.fit.Placements = tt.placements
+		if !reflect.DeepEqual(fits[i], tt.fit) {
+			t.Errorf("Simulate shape %s = %+v, want %+v", tt.fit.Shape, fits[i], tt.fit)
+		}
+	}
+	if ca.nodes[1].CpuFree != 1000 {
+		t.Errorf("Simulate changed the snapshot of the nodes")
+	}
+}
+
+func TestSimulateErrors(t *testing.T) {
+	ca := newTestCapacityAnalyzer()
+	p := v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "checkout-5d8f-abcde", Namespace: "prod", Labels: map[string]string{"pod-template-hash": "5d8f"},
+		OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "checkout-5d8f"}}}}
+	ca.RecordPod(&p)
+	ca.EndCycle(nil)
+
+	tests := []struct {
+		name  string
+		shape m.CapacityShape
+		valid bool
+	}{
+		{"requests", m.CapacityShape{Cpu: "500m", Memory: "1Gi"}, true},
+		{"recorded workload", m.CapacityShape{Workload: "Deployment/prod/checkout"}, true},
+		{"workload without pods", m.CapacityShape{Workload: "Deployment/prod/cart"}, false},
+		{"invalid workload", m.CapacityShape{Workload: "prod/checkout"}, false},
+		{"invalid cpu", m.CapacityShape{Cpu: "half"}, false},
+		{"invalid memory", m.CapacityShape{Memory: "1Gb"}, false},
+		{"no requests", m.CapacityShape{Name: "empty"}, false},
+		{"negative replicas", m.CapacityShape{Cpu: "1", Replicas: -1}, false},
+		{"too many replicas", m.CapacityShape{Cpu: "1", Replicas: CAPACITY_MAX_REPLICAS + 1}, false},
+	}
+	for _, tt := range tests {
+		_, err := ca.Simulate([]m.CapacityShape{tt.shape})
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: Simulate error = %v, valid %t", tt.name, err, tt.valid)
+		}
+	}
+}
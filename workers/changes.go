@@ -0,0 +1,354 @@
+package workers
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	app "github.com/appdynamics/cluster-agent/appd"
+	"github.com/appdynamics/cluster-agent/config"
+	m "github.com/appdynamics/cluster-agent/models"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const CHANGE_CUSTOM_EVENT_TYPE string = "K8sWorkloadChange"
+
+//ChangeRecorder computes the differences between the old and the new specs of workloads
+//and publishes them to the changes schema and, optionally, as custom events of the matching AppD applications
+type ChangeRecorder struct {
+	ConfManager *config.MutexConfigManager
+	Logger      *log.Logger
+	lock        *sync.Mutex
+	queue       []m.ChangeSchema
+}
+
+func NewChangeRecorder(cm *config.MutexConfigManager, l *log.Logger) *ChangeRecorder {
+	return &ChangeRecorder{ConfManager: cm, Logger: l, lock: &sync.Mutex{}, queue: []m.ChangeSchema{}}
+}
+
+func (cr *ChangeRecorder) Observe(stopCh <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+	bag := (*cr.ConfManager).Get()
+	ticker := time.NewTicker(time.Duration(bag.SnapshotSyncInterval) * time.Second)
+	for {
+		select {
+		case <-ticker.C:
+			cr.flushQueue()
+		case <-stopCh:
+			ticker.Stop()
+			return
+		}
+	}
+}
+
+//RecordChange queues the diff of the pod templates and replicas of a workload. Nothing is recorded if none of the tracked fields changed
+func (cr *ChangeRecorder) RecordChange(kind string, obj metav1.Object, oldTemplate *v1.PodTemplateSpec, newTemplate *v1.PodTemplateSpec, oldReplicas *int32, newReplicas *int32) {
+	bag := (*cr.ConfManager).Get()
+
+	change := m.NewChangeObj()
+	change.ObjectKind = kind
+	change.ObjectName = obj.GetName()
+	change.Namespace = obj.GetNamespace()
+	change.ObjectUid = string(obj.GetUID())
+	if obj.GetClusterName() != "" {
+		change.ClusterName = obj.GetClusterName()
+	} else {
+		change.ClusterName = bag.AppName
+	}
+	change.AppName, change.TierName = workloadApp(bag, obj, newTemplate)
+
+	fields := []string{}
+	summary := []string{}
+	add := func(field string, diff []string, target *string) {
+		if len(diff) > 0 {
+			*target = strings.Join(diff, ";")
+			fields = append(fields, field)
+			summary = append(summary, fmt.Sprintf("%s: %s", field, *target))
+		}
+	}
+
+	if oldTemplate != nil && newTemplate != nil {
+		add(m.CHANGE_FIELD_CONTAINERS, diffContainers(oldTemplate, newTemplate), &change.Containers)
+		add(m.CHANGE_FIELD_IMAGES, diffImages(oldTemplate, newTemplate), &change.Images)
+		add(m.CHANGE_FIELD_ENV, diffEnvNames(oldTemplate, newTemplate), &change.EnvVars)
+		add(m.CHANGE_FIELD_RESOURCES, diffResources(oldTemplate, newTemplate), &change.Resources)
+		add(m.CHANGE_FIELD_PROBES, diffProbes(oldTemplate, newTemplate), &change.Probes)
+		add(m.CHANGE_FIELD_VOLUMES, diffVolumes(oldTemplate, newTemplate), &change.Volumes)
+	}
+	if oldReplicas != nil && newReplicas != nil && *oldReplicas != *newReplicas {
+		add(m.CHANGE_FIELD_REPLICAS, []string{fmt.Sprintf("%d -> %d", *oldReplicas, *newReplicas)}, &change.Replicas)
+	}
+
+	if len(fields) == 0 {
+		return
+	}
+	change.ChangedFields = strings.Join(fields, ";")
+	change.Summary = fmt.Sprintf("%s %s/%s changed. %s", kind, change.Namespace, change.ObjectName, strings.Join(summary, ". "))
+	cr.Logger.WithFields(log.Fields{"kind": kind, "namespace": change.Namespace, "name": change.ObjectName, "fields": change.ChangedFields}).Info("Workload spec changed")
+
+	cr.lock.Lock()
+	cr.queue = append(cr.queue, change)
+	cr.lock.Unlock()
+}
+
+func (cr *ChangeRecorder) flushQueue() {
+	cr.lock.Lock()
+	objList := cr.queue
+	cr.queue = []m.ChangeSchema{}
+	cr.lock.Unlock()
+
+	if len(objList) == 0 {
+		return
+	}
+
+	bag := (*cr.ConfManager).Get()
+	cr.Logger.Infof("Flushing %d change records\n", len(objList))
+	if bag.ChangeEventsToController {
+		cr.postCustomEvents(objList)
+	}
+	for len(objList) > 0 {
+		limit := len(objList)
+		if bag.EventAPILimit > 0 && limit > bag.EventAPILimit {
+			limit = bag.EventAPILimit
+		}
+		batch := objList[:limit]
+		cr.postChangeRecords(&batch)
+		objList = objList[limit:]
+	}
+}
+
+func (cr *ChangeRecorder) postChangeRecords(objList *[]m.ChangeSchema) {
+	bag := (*cr.ConfManager).Get()
+	rc := app.NewRestClient(bag, cr.Logger)
+
+	schemaDefObj := m.NewChangeSchemaDefWrapper()
+
+	err := rc.EnsureSchema(bag.ChangeSchemaName, &schemaDefObj)
+	if err != nil {
+		cr.Logger.Errorf("Issues when ensuring %s schema. %v\n", bag.ChangeSchemaName, err)
+	} else {
+		data, err := json.Marshal(objList)
+		if err != nil {
+			cr.Logger.Errorf("Problems when serializing array of change schemas. %v", err)
+		}
+		rc.PostAppDEvents(bag.ChangeSchemaName, data)
+	}
+}
+
+//changes of workloads that belong to an AppD application show up on the timeline of the application
+func (cr *ChangeRecorder) postCustomEvents(objList []m.ChangeSchema) {
+	bag := (*cr.ConfManager).Get()
+	rc := app.NewRestClient(bag, cr.Logger)
+	for _, change := range objList {
+		if change.AppName == "" {
+			continue
+		}
+		props := map[string]string{"namespace": change.Namespace, "kind": change.ObjectKind, "name": change.ObjectName, "changedFields": change.ChangedFields}
+		err := rc.PostCustomEvent(change.AppName, change.TierName, CHANGE_CUSTOM_EVENT_TYPE, "INFO", change.Summary, props)
+		if err != nil {
+			cr.Logger.WithFields(log.Fields{"app": change.AppName, "error": err}).Warn("Unable to post workload change as custom event")
+		}
+	}
+}
+
+//the AppD application and tier of the workload are taken from the labels configured with AppDAppLabel and AppDTierLabel
+func workloadApp(bag *m.AppDBag, obj metav1.Object, template *v1.PodTemplateSpec) (string, string) {
+	appName := obj.GetLabels()[bag.AppDAppLabel]
+	tierName := obj.GetLabels()[bag.AppDTierLabel]
+	if template != nil {
+		if appName == "" {
+			appName = template.Labels[bag.AppDAppLabel]
+		}
+		if tierName == "" {
+			tierName = template.Labels[bag.AppDTierLabel]
+		}
+	}
+	if appName != "" && tierName == "" {
+		tierName = obj.GetName()
+	}
+	return appName, tierName
+}
+
+func containerMap(template *v1.PodTemplateSpec) map[string]v1.Container {
+	containers := make(map[string]v1.Container)
+	for _, c := range template.Spec.InitContainers {
+		containers[c.Name] = c
+	}
+	for _, c := range template.Spec.Containers {
+		containers[c.Name] = c
+	}
+	return containers
+}
+
+//names of the containers present in both templates, sorted for stable output
+func commonContainers(oldMap map[string]v1.Container, newMap map[string]v1.Container) []string {
+	names := []string{}
+	for name := range newMap {
+		if _, ok := oldMap[name]; ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func diffContainers(oldTemplate *v1.PodTemplateSpec, newTemplate *v1.PodTemplateSpec) []string {
+	oldMap := containerMap(oldTemplate)
+	newMap := containerMap(newTemplate)
+	diff := []string{}
+	for name := range newMap {
+		if _, ok := oldMap[name]; !ok {
+			diff = append(diff, "+"+name)
+		}
+	}
+	for name := range oldMap {
+		if _, ok := newMap[name]; !ok {
+			diff = append(diff, "-"+name)
+		}
+	}
+	sort.Strings(diff)
+	return diff
+}
+
+func diffImages(oldTemplate *v1.PodTemplateSpec, newTemplate *v1.PodTemplateSpec) []string {
+	oldMap := containerMap(oldTemplate)
+	newMap := containerMap(newTemplate)
+	diff := []string{}
+	for _, name := range commonContainers(oldMap, newMap) {
+		if oldMap[name].Image != newMap[name].Image {
+			diff = append(diff, fmt.Sprintf("%s: %s -> %s", name, oldMap[name].Image, newMap[name].Image))
+		}
+	}
+	return diff
+}
+
+func diffEnvNames(oldTemplate *v1.PodTemplateSpec, newTemplate *v1.PodTemplateSpec) []string {
+	oldMap := containerMap(oldTemplate)
+	newMap := containerMap(newTemplate)
+	diff := []string{}
+	for _, name := range commonContainers(oldMap, newMap) {
+		oldEnv := envByName(oldMap[name])
+		newEnv := envByName(newMap[name])
+		changes := []string{}
+		for envName, env := range newEnv {
+			if oldVal, ok := oldEnv[envName]; !ok {
+				changes = append(changes, "+"+envName)
+			} else if !reflect.DeepEqual(oldVal, env) {
+				//the value changed. Values are never recorded
+				changes = append(changes, "~"+envName)
+			}
+		}
+		for envName := range oldEnv {
+			if _, ok := newEnv[envName]; !ok {
+				changes = append(changes, "-"+envName)
+			}
+		}
+		if len(changes) > 0 {
+			sort.Strings(changes)
+			diff = append(diff, fmt.Sprintf("%s: %s", name, strings.Join(changes, ",")))
+		}
+	}
+	return diff
+}
+
+func envByName(c v1.Container) map[string]v1.EnvVar {
+	env := make(map[string]v1.EnvVar)
+	for _, e := range c.Env {
+		env[e.Name] = e
+	}
+	return env
+}
+
+func diffResources(oldTemplate *v1.PodTemplateSpec, newTemplate *v1.PodTemplateSpec) []string {
+	oldMap := containerMap(oldTemplate)
+	newMap := containerMap(newTemplate)
+	diff := []string{}
+	for _, name := range commonContainers(oldMap, newMap) {
+		oldRes := oldMap[name].Resources
+		newRes := newMap[name].Resources
+		changes := []string{}
+		changes = append(changes, diffResourceList("requests", oldRes.Requests, newRes.Requests)...)
+		changes = append(changes, diffResourceList("limits", oldRes.Limits, newRes.Limits)...)
+		if len(changes) > 0 {
+			diff = append(diff, fmt.Sprintf("%s: %s", name, strings.Join(changes, ",")))
+		}
+	}
+	return diff
+}
+
+func diffResourceList(prefix string, oldList v1.ResourceList, newList v1.ResourceList) []string {
+	names := map[string]bool{}
+	for k := range oldList {
+		names[string(k)] = true
+	}
+	for k := range newList {
+		names[string(k)] = true
+	}
+	changes := []string{}
+	for k := range names {
+		oldQ, oldOk := oldList[v1.ResourceName(k)]
+		newQ, newOk := newList[v1.ResourceName(k)]
+		oldVal := "none"
+		newVal := "none"
+		if oldOk {
+			oldVal = oldQ.String()
+		}
+		if newOk {
+			newVal = newQ.String()
+		}
+		if oldVal != newVal {
+			changes = append(changes, fmt.Sprintf("%s.%s %s -> %s", prefix, k, oldVal, newVal))
+		}
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+func diffProbes(oldTemplate *v1.PodTemplateSpec, newTemplate *v1.PodTemplateSpec) []string {
+	oldMap := containerMap(oldTemplate)
+	newMap := containerMap(newTemplate)
+	diff := []string{}
+	for _, name := range commonContainers(oldMap, newMap) {
+		changes := []string{}
+		if !reflect.DeepEqual(oldMap[name].LivenessProbe, newMap[name].LivenessProbe) {
+			changes = append(changes, "liveness")
+		}
+		if !reflect.DeepEqual(oldMap[name].ReadinessProbe, newMap[name].ReadinessProbe) {
+			changes = append(changes, "readiness")
+		}
+		if len(changes) > 0 {
+			diff = append(diff, fmt.Sprintf("%s: %s", name, strings.Join(changes, ",")))
+		}
+	}
+	return diff
+}
+
+func diffVolumes(oldTemplate *v1.PodTemplateSpec, newTemplate *v1.PodTemplateSpec) []string {
+	oldVols := make(map[string]v1.Volume)
+	for _, v := range oldTemplate.Spec.Volumes {
+		oldVols[v.Name] = v
+	}
+	diff := []string{}
+	newVols := make(map[string]bool)
+	for _, v := range newTemplate.Spec.Volumes {
+		newVols[v.Name] = true
+		if oldVol, ok := oldVols[v.Name]; !ok {
+			diff = append(diff, "+"+v.Name)
+		} else if !reflect.DeepEqual(oldVol, v) {
+			diff = append(diff, "~"+v.Name)
+		}
+	}
+	for name := range oldVols {
+		if !newVols[name] {
+			diff = append(diff, "-"+name)
+		}
+	}
+	sort.Strings(diff)
+	return diff
+}
@@ -0,0 +1,185 @@
+package workers
+
+import (
+	"reflect"
+	"testing"
+
+	m "github.com/appdynamics/cluster-agent/models"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testTemplate(containers ...v1.Container) *v1.PodTemplateSpec {
+	return &v1.PodTemplateSpec{Spec: v1.PodSpec{Containers: containers}}
+}
+
+func TestDiffContainers(t *testing.T) {
+	tests := []struct {
+		name string
+		old  *v1.PodTemplateSpec
+		new  *v1.PodTemplateSpec
+		diff []string
+	}{
+		{"unchanged", testTemplate(v1.Container{Name: "app"}), testTemplate(v1.Container{Name: "app"}), []string{}},
+		{"added", testTemplate(v1.Container{Name: "app"}), testTemplate(v1.Container{Name: "app"}, v1.Container{Name: "sidecar"}), []string{"+sidecar"}},
+		{"replaced", testTemplate(v1.Container{Name: "app"}, v1.Container{Name: "proxy"}), testTemplate(v1.Container{Name: "app"}, v1.Container{Name: "sidecar"}),
+			[]string{"+sidecar", "-proxy"}},
+		{"init container", testTemplate(v1.Container{Name: "app"}),
+			&v1.PodTemplateSpec{Spec: v1.PodSpec{InitContainers: []v1.Container{{Name: "init"}}, Containers: []v1.Container{{Name: "app"}}}}, []string{"+init"}},
+	}
+	for _, tt := range tests {
+		if diff := diffContainers(tt.old, tt.new); !reflect.DeepEqual(diff, tt.diff) {
+			t.Errorf("%s: diffContainers = %v, want %v", tt.name, diff, tt.diff)
+		}
+	}
+}
+
+func TestDiffImages(t *testing.T) {
+	tests := []struct {
+		name string
+		old  *v1.PodTemplateSpec
+		new  *v1.PodTemplateSpec
+		diff []string
+	}{
+		{"unchanged", testTemplate(v1.Container{Name: "app", Image: "app:1"}), testTemplate(v1.Container{Name: "app", Image: "app:1"}), []string{}},
+		{"changed", testTemplate(v1.Container{Name: "app", Image: "app:1"}, v1.Container{Name: "proxy", Image: "envoy:1"}),
+			testTemplate(v1.Container{Name: "app", Image: "app:2"}, v1.Container{Name: "proxy", Image: "envoy:2"}),
+			[]string{"app: app:1 -> app:2", "proxy: envoy:1 -> envoy:2"}},
+		{"new container ignored", testTemplate(v1.Container{Name: "app", Image: "app:1"}),
+			testTemplate(v1.Container{Name: "app", Image: "app:1"}, v1.Container{Name: "proxy", Image: "envoy:1"}), []string{}},
+	}
+	for _, tt := range tests {
+		if diff := diffImages(tt.old, tt.new); !reflect.DeepEqual(diff, tt.diff) {
+			t.Errorf("%s: diffImages = %v, want %v", tt.name, diff, tt.diff)
+		}
+	}
+}
+
+func TestDiffEnvNames(t *testing.T) {
+	env := func(vars ...v1.EnvVar) v1.Container {
+		return v1.Container{Name: "app", Env: vars}
+	}
+	tests := []struct {
+		name string
+		old  *v1.PodTemplateSpec
+		new  *v1.PodTemplateSpec
+		diff []string
+	}{
+		{"unchanged", testTemplate(env(v1.EnvVar{Name: "A", Value: "1"})), testTemplate(env(v1.EnvVar{Name: "A", Value: "1"})), []string{}},
+		{"added, changed and removed",
+			testTemplate(env(v1.EnvVar{Name: "A", Value: "1"}, v1.EnvVar{Name: "B", Value: "secret"})),
+			testTemplate(env(v1.EnvVar{Name: "B", Value: "other"}, v1.EnvVar{Name: "C", Value: "3"})),
+			[]string{"app: +C,-A,~B"}},
+		{"value source changed", testTemplate(env(v1.EnvVar{Name: "A", Value: "1"})),
+			testTemplate(env(v1.EnvVar{Name: "A", ValueFrom: &v1.EnvVarSource{FieldRef: &v1.ObjectFieldSelector{FieldPath: "metadata.name"}}})),
+			[]string{"app: ~A"}},
+	}
+	for _, tt := range tests {
+		if diff := diffEnvNames(tt.old, tt.new); !reflect.DeepEqual(diff, tt.diff) {
+			t.Errorf("%s: diffEnvNames = %v, want %v", tt.name, diff, tt.diff)
+		}
+	}
+}
+
+func TestDiffResources(t *testing.T) {
+	res := func(requests v1.ResourceList, limits v1.ResourceList) v1.Container {
+		return v1.Container{Name: "app", Resources: v1.ResourceRequirements{Requests: requests, Limits: limits}}
+	}
+	cpu := func(q string) v1.ResourceList {
+		return v1.ResourceList{v1.ResourceCPU: resource.MustParse(q)}
+	}
+	tests := []struct {
+		name string
+		old  *v1.PodTemplateSpec
+		new  *v1.PodTemplateSpec
+		diff []string
+	}{
+		{"unchanged", testTemplate(res(cpu("100m"), nil)), testTemplate(res(cpu("0.1"), nil)), []string{}},
+		{"request changed", testTemplate(res(cpu("100m"), nil)), testTemplate(res(cpu("200m"), nil)),
+			[]string{"app: requests.cpu 100m -> 200m"}},
+		{"limit added", testTemplate(res(cpu("100m"), nil)), testTemplate(res(cpu("100m"), cpu("1"))),
+			[]string{"app: limits.cpu none -> 1"}},
+		{"request removed", testTemplate(res(v1.ResourceList{v1.ResourceCPU: resource.MustParse("100m"), v1.ResourceMemory: resource.MustParse("64Mi")}, nil)),
+			testTemplate(res(cpu("100m"), nil)), []string{"app: requests.memory 64Mi -> none"}},
+	}
+	for _, tt := range tests {
+		if diff := diffResources(tt.old, tt.new); !reflect.DeepEqual(diff, tt.diff) {
+			t.Errorf("%s: diffResources = %v, want %v", tt.name, diff, tt.diff)
+		}
+	}
+}
+
+func TestDiffProbes(t *testing.T) {
+	probe := func(path string) *v1.Probe {
+		p := v1.Probe{}
+		p.HTTPGet = &v1.HTTPGetAction{Path: path}
+		return &p
+	}
+	tests := []struct {
+		name string
+		old  *v1.PodTemplateSpec
+		new  *v1.PodTemplateSpec
+		diff []string
+	}{
+		{"unchanged", testTemplate(v1.Container{Name: "app", LivenessProbe: probe("/live")}),
+			testTemplate(v1.Container{Name: "app", LivenessProbe: probe("/live")}), []string{}},
+		{"liveness changed", testTemplate(v1.Container{Name: "app", LivenessProbe: probe("/live")}),
+			testTemplate(v1.Container{Name: "app", LivenessProbe: probe("/healthz")}), []string{"app: liveness"}},
+		{"both changed", testTemplate(v1.Container{Name: "app", LivenessProbe: probe("/live")}),
+			testTemplate(v1.Container{Name: "app", ReadinessProbe: probe("/ready")}), []string{"app: liveness,readiness"}},
+	}
+	for _, tt := range tests {
+		if diff := diffProbes(tt.old, tt.new); !reflect.DeepEqual(diff, tt.diff) {
+			t.Errorf("%s: diffProbes = %v, want %v", tt.name, diff, tt.diff)
+		}
+	}
+}
+
+func TestDiffVolumes(t *testing.T) {
+	volumes := func(vols ...v1.Volume) *v1.PodTemplateSpec {
+		return &v1.PodTemplateSpec{Spec: v1.PodSpec{Volumes: vols}}
+	}
+	configMap := func(name string, cm string) v1.Volume {
+		return v1.Volume{Name: name, VolumeSource: v1.VolumeSource{ConfigMap: &v1.ConfigMapVolumeSource{LocalObjectReference: v1.LocalObjectReference{Name: cm}}}}
+	}
+	tests := []struct {
+		name string
+		old  *v1.PodTemplateSpec
+		new  *v1.PodTemplateSpec
+		diff []string
+	}{
+		{"unchanged", volumes(configMap("config", "app-v1")), volumes(configMap("config", "app-v1")), []string{}},
+		{"changed", volumes(configMap("config", "app-v1")), volumes(configMap("config", "app-v2")), []string{"~config"}},
+		{"added and removed", volumes(configMap("config", "app-v1"), configMap("old", "old")),
+			volumes(configMap("config", "app-v1"), configMap("new", "new")), []string{"+new", "-old"}},
+	}
+	for _, tt := range tests {
+		if diff := diffVolumes(tt.old, tt.new); !reflect.DeepEqual(diff, tt.diff) {
+			t.Errorf("%s: diffVolumes = %v, want %v", tt.name, diff, tt.diff)
+		}
+	}
+}
+
+func TestWorkloadApp(t *testing.T) {
+	bag := &m.AppDBag{AppDAppLabel: "appd-app", AppDTierLabel: "appd-tier"}
+	tests := []struct {
+		name     string
+		labels   map[string]string
+		template *v1.PodTemplateSpec
+		appName  string
+		tierName string
+	}{
+		{"workload labels", map[string]string{"appd-app": "shop", "appd-tier": "cart"}, nil, "shop", "cart"},
+		{"template labels", nil, &v1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"appd-app": "shop", "appd-tier": "web"}}},
+			"shop", "web"},
+		{"tier defaults to workload name", map[string]string{"appd-app": "shop"}, nil, "shop", "api"},
+		{"no app", map[string]string{"appd-tier": "cart"}, nil, "", "cart"},
+	}
+	for _, tt := range tests {
+		obj := &metav1.ObjectMeta{Name: "api", Labels: tt.labels}
+		if appName, tierName := workloadApp(bag, obj, tt.template); appName != tt.appName || tierName != tt.tierName {
+			t.Errorf("%s: workloadApp = (%q, %q), want (%q, %q)", tt.name, appName, tierName, tt.appName, tt.tierName)
+		}
+	}
+}
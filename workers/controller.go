@@ -13,6 +13,7 @@ import (
 	"github.com/appdynamics/cluster-agent/config"
 	m "github.com/appdynamics/cluster-agent/models"
 	"github.com/appdynamics/cluster-agent/utils"
+	w "github.com/appdynamics/cluster-agent/watchers"
 	"github.com/appdynamics/cluster-agent/web"
 	"k8s.io/api/core/v1"
 
@@ -20,6 +21,7 @@ import (
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
 	"k8s.io/client-go/rest"
 )
 
@@ -29,17 +31,31 @@ const (
 )
 
 type MainController struct {
-	ConfManager    *config.MutexConfigManager
-	K8sClient      *kubernetes.Clientset
-	Logger         *log.Logger
-	K8sConfig      *rest.Config
-	PodsWorker     *PodWorker
-	NodesWorker    *NodesWorker
-	AppdController *app.ControllerClient
+	ConfManager      *config.MutexConfigManager
+	K8sClient        *kubernetes.Clientset
+	Logger           *log.Logger
+	K8sConfig        *rest.Config
+	PodsWorker       *PodWorker
+	NodesWorker      *NodesWorker
+	AppdController   *app.ControllerClient
+	InformerManager  *w.InformerManager
+	MetricsCollector *w.MetricsCollector
+	ChangeRecorder   *ChangeRecorder
+	HealthRuleWorker *HealthRuleWorker
+	Recommender      *Recommender
+	CostAllocator    *CostAllocator
+	Capacity         *CapacityAnalyzer
+	Spread           *SpreadChecker
+	lockSearches     *sync.Mutex
+	searchSettings   string
 }
 
-func NewController(cm *config.MutexConfigManager, client *kubernetes.Clientset, l *log.Logger, config *rest.Config) MainController {
-	return MainController{ConfManager: cm, K8sClient: client, Logger: l, K8sConfig: config}
+func NewController(cm *config.MutexConfigManager, client *kubernetes.Clientset, metaClient metadata.Interface, l *log.Logger, config *rest.Config) MainController {
+	return MainController{ConfManager: cm, K8sClient: client, Logger: l, K8sConfig: config, InformerManager: w.NewInformerManager(client, metaClient, cm, l),
+		MetricsCollector: w.NewMetricsCollector(client, cm, l), ChangeRecorder: NewChangeRecorder(cm, l),
+		HealthRuleWorker: NewHealthRuleWorker(cm, l), Recommender: NewRecommender(cm, l),
+		CostAllocator: NewCostAllocator(cm, l), Capacity: NewCapacityAnalyzer(cm, l),
+		Spread: NewSpreadChecker(cm, l), lockSearches: &sync.Mutex{}}
 }
 
 func (c *MainController) ValidateParameters() error {
@@ -134,6 +150,11 @@ func (c *MainController) ValidateParameters() error {
 		bag.RemoteBiqHost = host
 		bag.RemoteBiqPort = port
 	}
+	if ns, errNs := c.K8sClient.CoreV1().Namespaces().Get("kube-system", metav1.GetOptions{}); errNs == nil {
+		bag.ClusterUID = string(ns.GetUID())
+	} else {
+		c.Logger.WithField("error", errNs).Warn("Unable to determine cluster uid. Renames of the cluster will not be detected")
+	}
 	c.ConfManager.Set(bag)
 	c.Logger.WithFields(log.Fields{"accessKey": bag.AccessKey, "global account": bag.GlobalAccount}).Debug("Account info")
 	appdC, errInitSdk := app.NewControllerClient(c.ConfManager, c.Logger)
@@ -146,7 +167,10 @@ func (c *MainController) ValidateParameters() error {
 
 func (c *MainController) Run(stopCh <-chan struct{}, wg *sync.WaitGroup) {
 
-	ws := web.NewAgentWebServer(c.ConfManager, c.Logger)
+	ws := web.NewAgentWebServer(c.ConfManager, c.InformerManager, c.MetricsCollector, c.Logger)
+	ws.Dashboards = NewDashboardExporter(c.ConfManager, c.Logger)
+	ws.Recommendations = c.Recommender
+	ws.Capacity = c.Capacity
 	wg.Add(1)
 	go ws.RunServer()
 
@@ -156,6 +180,21 @@ func (c *MainController) Run(stopCh <-chan struct{}, wg *sync.WaitGroup) {
 		go c.startAppIDUpdater(stopCh)
 	}
 
+	wg.Add(1)
+	go c.ChangeRecorder.Observe(stopCh, wg)
+
+	wg.Add(1)
+	go c.HealthRuleWorker.Observe(stopCh, wg)
+
+	wg.Add(1)
+	go c.Recommender.Observe(stopCh, wg)
+
+	//until the application is registered, the searches are reconciled by the app ID updater
+	if bag.AppID != 0 {
+		go c.reconcileSearches()
+	}
+	c.ConfManager.SubscribeToConfigUpdates(c.reconcileSearches)
+
 	wg.Add(3)
 	go c.startNodeWorker(stopCh, c.K8sClient, wg, c.AppdController)
 
@@ -173,6 +212,28 @@ func (c *MainController) Run(stopCh <-chan struct{}, wg *sync.WaitGroup) {
 
 }
 
+//saved searches follow the changes of the search definitions and of the cluster name in the config.
+//Config updates that do not change the searches are skipped
+func (c *MainController) reconcileSearches() {
+	bag := (*c.ConfManager).Get()
+	if bag.AppID == 0 {
+		return
+	}
+	aw := NewAdqlSearchWorker(bag, c.Logger)
+	settings := aw.Fingerprint()
+
+	c.lockSearches.Lock()
+	defer c.lockSearches.Unlock()
+	if settings != "" && settings == c.searchSettings {
+		return
+	}
+	if err := aw.Reconcile(); err != nil {
+		c.Logger.WithField("error", err).Error("Unable to reconcile saved searches")
+		return
+	}
+	c.searchSettings = settings
+}
+
 func (c *MainController) startAppIDUpdater(stopCh <-chan struct{}) {
 	bag := (*c.ConfManager).Get()
 	c.appAppIDTicker(stopCh, time.NewTicker(time.Duration(bag.SnapshotSyncInterval)*time.Second))
@@ -196,6 +257,7 @@ func (c *MainController) appAppIDTicker(stop <-chan struct{}, ticker *time.Ticke
 				updated := (*c.ConfManager).Get()
 				c.Logger.Infof("Agent Application ID in the internal cache: %d %d %d", updated.AppID, updated.TierID, updated.NodeID)
 				ticker.Stop()
+				c.reconcileSearches()
 			}
 		case <-stop:
 			ticker.Stop()
@@ -207,7 +269,7 @@ func (c *MainController) appAppIDTicker(stop <-chan struct{}, ticker *time.Ticke
 func (c *MainController) startNodeWorker(stopCh <-chan struct{}, client *kubernetes.Clientset, wg *sync.WaitGroup, appdController *app.ControllerClient) {
 	c.Logger.Info("Starting Nodes worker...")
 	defer wg.Done()
-	nw := NewNodesWorker(client, c.ConfManager, appdController, c.Logger)
+	nw := NewNodesWorker(client, c.ConfManager, c.InformerManager, c.MetricsCollector, appdController, c.Logger)
 	c.NodesWorker = &nw
 	go c.startPodsWorker(stopCh, client, wg, appdController)
 	nw.Observe(stopCh, wg)
@@ -218,7 +280,10 @@ func (c *MainController) startNodeWorker(stopCh <-chan struct{}, client *kuberne
 func (c *MainController) startDeployWorker(stopCh <-chan struct{}, client *kubernetes.Clientset, wg *sync.WaitGroup, appdController *app.ControllerClient) {
 	c.Logger.Info("Starting Deployment worker...")
 	defer wg.Done()
-	pw := NewDeployWorker(client, c.ConfManager, appdController, c.Logger)
+	pw := NewDeployWorker(client, c.ConfManager, c.InformerManager, appdController, c.ChangeRecorder, c.Logger)
+	pw.Spread = c.Spread
+	c.InformerManager.SubscribeToNamespaceLabels(pw.NamespaceLabelsChanged)
+	c.InformerManager.SubscribeToNamespacePurge(pw.purgeNamespaces)
 	pw.Observe(stopCh, wg)
 	<-stopCh
 }
@@ -226,7 +291,8 @@ func (c *MainController) startDeployWorker(stopCh <-chan struct{}, client *kuber
 func (c *MainController) startDaemonWorker(stopCh <-chan struct{}, client *kubernetes.Clientset, wg *sync.WaitGroup, appdController *app.ControllerClient) {
 	c.Logger.Info("Starting Daemon worker...")
 	defer wg.Done()
-	pw := NewDaemonWorker(client, c.ConfManager, appdController, c.Logger)
+	pw := NewDaemonWorker(client, c.ConfManager, c.InformerManager, appdController, c.ChangeRecorder, c.Logger)
+	c.InformerManager.SubscribeToNamespaceLabels(pw.NamespaceLabelsChanged)
 	pw.Observe(stopCh, wg)
 	<-stopCh
 }
@@ -234,7 +300,8 @@ func (c *MainController) startDaemonWorker(stopCh <-chan struct{}, client *kuber
 func (c *MainController) startRsWorker(stopCh <-chan struct{}, client *kubernetes.Clientset, wg *sync.WaitGroup, appdController *app.ControllerClient) {
 	c.Logger.Info("Starting ReplicaSet worker...")
 	defer wg.Done()
-	pw := NewRsWorker(client, c.ConfManager, appdController, c.Logger)
+	pw := NewRsWorker(client, c.ConfManager, c.InformerManager, appdController, c.ChangeRecorder, c.Logger)
+	c.InformerManager.SubscribeToNamespaceLabels(pw.NamespaceLabelsChanged)
 	pw.Observe(stopCh, wg)
 	<-stopCh
 }
@@ -242,7 +309,7 @@ func (c *MainController) startRsWorker(stopCh <-chan struct{}, client *kubernete
 func (c *MainController) startEventsWorker(stopCh <-chan struct{}, client *kubernetes.Clientset, wg *sync.WaitGroup, appdController *app.ControllerClient) {
 	c.Logger.Info("Starting Events worker...")
 	defer wg.Done()
-	ew := NewEventWorker(client, c.ConfManager, appdController, c.PodsWorker, c.Logger)
+	ew := NewEventWorker(client, c.ConfManager, c.InformerManager, appdController, c.PodsWorker, c.Logger)
 	ew.Observe(stopCh, wg)
 	<-stopCh
 }
@@ -250,7 +317,8 @@ func (c *MainController) startEventsWorker(stopCh <-chan struct{}, client *kuber
 func (c *MainController) startJobsWorker(stopCh <-chan struct{}, client *kubernetes.Clientset, wg *sync.WaitGroup, appdController *app.ControllerClient) {
 	c.Logger.Info("Starting Jobs worker...")
 	defer wg.Done()
-	ew := NewJobsWorker(client, c.ConfManager, appdController, c.K8sConfig, c.Logger)
+	ew := NewJobsWorker(client, c.ConfManager, c.InformerManager, appdController, c.K8sConfig, c.Logger)
+	c.InformerManager.SubscribeToNamespaceLabels(ew.NamespaceLabelsChanged)
 	ew.Observe(stopCh, wg)
 	<-stopCh
 }
@@ -258,8 +326,15 @@ func (c *MainController) startJobsWorker(stopCh <-chan struct{}, client *kuberne
 func (c *MainController) startPodsWorker(stopCh <-chan struct{}, client *kubernetes.Clientset, wg *sync.WaitGroup, appdController *app.ControllerClient) {
 	c.Logger.Info("Starting Pods worker...")
 	defer wg.Done()
-	pw := NewPodWorker(client, c.ConfManager, appdController, c.K8sConfig, c.Logger, c.NodesWorker)
+	pw := NewPodWorker(client, c.ConfManager, c.InformerManager, c.MetricsCollector, appdController, c.K8sConfig, c.Logger, c.NodesWorker)
+	pw.Recommender = c.Recommender
+	pw.CostAllocator = c.CostAllocator
+	pw.CapacityAnalyzer = c.Capacity
+	pw.SpreadChecker = c.Spread
 	c.PodsWorker = &pw
+	c.InformerManager.SubscribeToNamespaceLabels(pw.CacheUpdated)
+	c.InformerManager.SubscribeToNamespacePurge(pw.purgeNamespaces)
+	c.InformerManager.SubscribeToNodeLabels(pw.NodeLabelsChanged)
 	go c.startEventsWorker(stopCh, c.K8sClient, wg, appdController)
 	c.PodsWorker.Observe(stopCh, wg)
 	<-stopCh
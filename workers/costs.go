@@ -0,0 +1,289 @@
+package workers
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	app "github.com/appdynamics/cluster-agent/appd"
+	"github.com/appdynamics/cluster-agent/config"
+	m "github.com/appdynamics/cluster-agent/models"
+	"github.com/appdynamics/cluster-agent/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	BYTES_PER_GB     float64 = 1024 * 1024 * 1024
+	COST_LABEL_NONE  string  = "none"
+	COST_UNKNOWN_POD string  = "Pod"
+)
+
+//resources of a pod in the current metrics cycle
+type costPod struct {
+	Namespace  string
+	OwnerKind  string
+	Workload   string
+	NodeName   string
+	Labels     map[string]string
+	CpuRequest int64 //millicores
+	MemRequest int64 //bytes
+	CpuUse     int64
+	MemUse     int64
+	Storage    int64 //bytes
+}
+
+//CostAllocator allocates the cost of the nodes and of the requested storage to namespaces, workloads and pod labels
+type CostAllocator struct {
+	ConfManager   *config.MutexConfigManager
+	Logger        *log.Logger
+	lock          *sync.Mutex
+	pods          []costPod
+	lastPublished time.Time
+}
+
+func NewCostAllocator(cm *config.MutexConfigManager, l *log.Logger) *CostAllocator {
+	return &CostAllocator{ConfManager: cm, Logger: l, lock: &sync.Mutex{}, pods: []costPod{}}
+}
+
+//RecordPod adds a pod scheduled on a node to the current cycle. Completed pods do not hold resources
+func (ca *CostAllocator) RecordPod(podSchema *m.PodSchema) {
+	bag := (*ca.ConfManager).Get()
+	if len(bag.CostPrices) == 0 || podSchema.NodeName == "" {
+		return
+	}
+	state := podSchema.GetState()
+	if state == "Succeeded" || state == "Failed" {
+		return
+	}
+	kind, workload := podSchema.OwnerKind, podSchema.Workload
+	if workload == "" {
+		kind, workload = COST_UNKNOWN_POD, podSchema.Name
+	}
+	//storage requests are kept in thousandths of bytes
+	p := costPod{Namespace: podSchema.Namespace, OwnerKind: kind, Workload: workload, NodeName: podSchema.NodeName,
+		Labels: parseLabels(podSchema.Labels), CpuRequest: podSchema.CpuRequest, MemRequest: podSchema.MemRequest,
+		CpuUse: podSchema.CpuUse, MemUse: podSchema.MemUse, Storage: (podSchema.StorageRequest + podSchema.PodStorageRequest) / 1000}
+	ca.lock.Lock()
+	ca.pods = append(ca.pods, p)
+	ca.lock.Unlock()
+}
+
+//labels of pod schemas are serialized as key:value;
+func parseLabels(s string) map[string]string {
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(s, ";") {
+		kv := strings.SplitN(pair, ":", 2)
+		if len(kv) == 2 {
+			labels[kv[0]] = kv[1]
+		}
+	}
+	return labels
+}
+
+//priceFor returns the first price with a selector matching the node. Prices without selector match all nodes
+func (ca *CostAllocator) priceFor(nodeName string, bag *m.AppDBag) (m.CostPrice, bool) {
+	for _, price := range bag.CostPrices {
+		if price.NodeSelector == "" || utils.NodeMatchesSelector(nodeName, price.NodeSelector) {
+			return price, true
+		}
+	}
+	return m.CostPrice{}, false
+}
+
+func allocationBasis(bag *m.AppDBag, request int64, use int64) int64 {
+	switch bag.CostAllocation {
+	case m.COST_ALLOCATION_REQUEST:
+		return request
+	case m.COST_ALLOCATION_USAGE:
+		return use
+	default:
+		if use > request {
+			return use
+		}
+		return request
+	}
+}
+
+//EndCycle allocates the costs of the pods of the cycle and returns the cost metrics.
+//The cost records are published to the costs schema once per CostIntervalMin
+func (ca *CostAllocator) EndCycle(nodes *NodesWorker) []m.ClusterCostMetrics {
+	ca.lock.Lock()
+	pods := ca.pods
+	ca.pods = []costPod{}
+	ca.lock.Unlock()
+
+	bag := (*ca.ConfManager).Get()
+	if len(bag.CostPrices) == 0 || nodes == nil {
+		return nil
+	}
+	costs := ca.allocate(pods, nodes, bag)
+
+	metrics := []m.ClusterCostMetrics{}
+	for i := range costs {
+		if costs[i].Scope == m.COST_SCOPE_CLUSTER || costs[i].Scope == m.COST_SCOPE_NAMESPACE || costs[i].Scope == m.COST_SCOPE_LABEL {
+			metrics = append(metrics, m.NewClusterCostMetrics(bag, &costs[i]))
+		}
+	}
+
+	if bag.CostIntervalMin > 0 && time.Since(ca.lastPublished) >= time.Duration(bag.CostIntervalMin)*time.Minute {
+		ca.lastPublished = time.Now()
+		go ca.postCostRecords(costs)
+	}
+	return metrics
+}
+
+func (ca *CostAllocator) allocate(pods []costPod, nodes *NodesWorker, bag *m.AppDBag) []m.CostSchema {
+	now := time.Now()
+	newCost := func(scope string) *m.CostSchema {
+		return &m.CostSchema{ClusterName: bag.AppName, Timestamp: now, Scope: scope, Allocation: bag.CostAllocation}
+	}
+
+	//cost of the node capacity
+	nodeCost := make(map[string]float64)
+	nodePrice := make(map[string]m.CostPrice)
+	for _, name := range nodes.GetKnownNodes() {
+		price, ok := ca.priceFor(name, bag)
+		if !ok {
+			continue
+		}
+		node := nodes.GetNodeData(name)
+		nodePrice[name] = price
+		nodeCost[name] = float64(node.CpuCapacity)/1000*price.CpuCoreHour + float64(node.MemCapacity)/BYTES_PER_GB*price.MemGBHour
+	}
+
+	podCosts := make([]m.CostSchema, len(pods))
+	usedCosts := make([]float64, len(pods))
+	nodeAllocated := make(map[string]float64)
+	var clusterAllocated float64
+	for i, p := range pods {
+		price, ok := nodePrice[p.NodeName]
+		if !ok {
+			continue
+		}
+		cpu := float64(allocationBasis(bag, p.CpuRequest, p.CpuUse)) / 1000
+		mem := float64(allocationBasis(bag, p.MemRequest, p.MemUse)) / BYTES_PER_GB
+		storage := float64(p.Storage) / BYTES_PER_GB
+		pc := m.CostSchema{Pods: 1, CpuCores: cpu, MemGB: mem, StorageGB: storage,
+			CpuCost: cpu * price.CpuCoreHour, MemCost: mem * price.MemGBHour, StorageCost: storage * price.StorageGBHour}
+		podCosts[i] = pc
+		usedCosts[i] = float64(p.CpuUse)/1000*price.CpuCoreHour + float64(p.MemUse)/BYTES_PER_GB*price.MemGBHour
+		nodeAllocated[p.NodeName] += pc.CpuCost + pc.MemCost
+		clusterAllocated += pc.CpuCost + pc.MemCost
+	}
+
+	//capacity not allocated to pods
+	nodeIdle := make(map[string]float64)
+	var clusterIdle float64
+	for name, cost := range nodeCost {
+		if idle := cost - nodeAllocated[name]; idle > 0 {
+			nodeIdle[name] = idle
+			clusterIdle += idle
+		}
+	}
+	undistributed := clusterIdle
+	if bag.CostIdleDistribution != m.COST_IDLE_NONE {
+		for i, p := range pods {
+			share := podCosts[i].CpuCost + podCosts[i].MemCost
+			if bag.CostIdleDistribution == m.COST_IDLE_NODE && nodeAllocated[p.NodeName] > 0 {
+				podCosts[i].IdleCost = nodeIdle[p.NodeName] * share / nodeAllocated[p.NodeName]
+			} else if bag.CostIdleDistribution == m.COST_IDLE_CLUSTER && clusterAllocated > 0 {
+				podCosts[i].IdleCost = clusterIdle * share / clusterAllocated
+			}
+			undistributed -= podCosts[i].IdleCost
+		}
+	}
+
+	cluster := newCost(m.COST_SCOPE_CLUSTER)
+	namespaces := make(map[string]*m.CostSchema)
+	workloads := make(map[string]*m.CostSchema)
+	labels := make(map[string]*m.CostSchema)
+	for i, p := range pods {
+		if podCosts[i].Pods == 0 {
+			continue
+		}
+		cluster.Add(&podCosts[i], usedCosts[i])
+
+		ns, ok := namespaces[p.Namespace]
+		if !ok {
+			ns = newCost(m.COST_SCOPE_NAMESPACE)
+			ns.Namespace = p.Namespace
+			namespaces[p.Namespace] = ns
+		}
+		ns.Add(&podCosts[i], usedCosts[i])
+
+		wkey := workloadKey(p.Namespace, p.OwnerKind, p.Workload)
+		w, ok := workloads[wkey]
+		if !ok {
+			w = newCost(m.COST_SCOPE_WORKLOAD)
+			w.Namespace, w.OwnerKind, w.Workload = p.Namespace, p.OwnerKind, p.Workload
+			workloads[wkey] = w
+		}
+		w.Add(&podCosts[i], usedCosts[i])
+
+		for _, key := range bag.CostLabels {
+			val, ok := p.Labels[key]
+			if !ok || val == "" {
+				val = COST_LABEL_NONE
+			}
+			lkey := key + "=" + val
+			l, ok := labels[lkey]
+			if !ok {
+				l = newCost(m.COST_SCOPE_LABEL)
+				l.LabelKey, l.LabelValue = key, val
+				labels[lkey] = l
+			}
+			l.Add(&podCosts[i], usedCosts[i])
+		}
+	}
+
+	costs := []m.CostSchema{}
+	if undistributed > 0.000001 {
+		idle := newCost(m.COST_SCOPE_IDLE)
+		idle.IdleCost = undistributed
+		idle.Finalize()
+		cluster.IdleCost += undistributed
+		costs = append(costs, *idle)
+	}
+	cluster.Finalize()
+	costs = append(costs, *cluster)
+	for _, group := range []map[string]*m.CostSchema{namespaces, workloads, labels} {
+		keys := []string{}
+		for k := range group {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			group[k].Finalize()
+			costs = append(costs, *group[k])
+		}
+	}
+	ca.Logger.WithFields(log.Fields{"pods": len(pods), "hourlyCost": cluster.TotalCost, "idleCost": cluster.IdleCost}).Debug("Allocated cluster costs")
+	return costs
+}
+
+func (ca *CostAllocator) postCostRecords(objList []m.CostSchema) {
+	bag := (*ca.ConfManager).Get()
+	rc := app.NewRestClient(bag, ca.Logger)
+
+	schemaDefObj := m.NewCostSchemaDefWrapper()
+	err := rc.EnsureSchema(bag.CostSchemaName, &schemaDefObj)
+	if err != nil {
+		ca.Logger.Errorf("Issues when ensuring %s schema. %v\n", bag.CostSchemaName, err)
+		return
+	}
+	for len(objList) > 0 {
+		limit := len(objList)
+		if bag.EventAPILimit > 0 && limit > bag.EventAPILimit {
+			limit = bag.EventAPILimit
+		}
+		data, err := json.Marshal(objList[:limit])
+		if err != nil {
+			ca.Logger.Errorf("Problems when serializing array of cost schemas. %v", err)
+		} else {
+			rc.PostAppDEvents(bag.CostSchemaName, data)
+		}
+		objList = objList[limit:]
+	}
+}
@@ -0,0 +1,206 @@
+package workers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/appdynamics/cluster-agent/config"
+	m "github.com/appdynamics/cluster-agent/models"
+	"github.com/appdynamics/cluster-agent/utils"
+	w "github.com/appdynamics/cluster-agent/watchers"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+)
+
+//nodes worker with the informer store synced from an API server, which lists the nodes and keeps the watches open
+func newTestNodesWorker(t *testing.T, cm *config.MutexConfigManager, nodes ...m.NodeSchema) *NodesWorker {
+	items := []string{}
+	for _, n := range nodes {
+		items = append(items, fmt.Sprintf(`{"metadata": {"name": "%s"}}`, n.NodeName))
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("watch") == "true" {
+			rw.WriteHeader(http.StatusOK)
+			rw.(http.Flusher).Flush()
+			<-r.Context().Done()
+			return
+		}
+		fmt.Fprintf(rw, `{"kind": "NodeList", "apiVersion": "v1", "metadata": {"resourceVersion": "1"}, "items": [%s]}`, strings.Join(items, ","))
+	}))
+	client, err := kubernetes.NewForConfig(&rest.Config{Host: srv.URL})
+	if err != nil {
+		t.Fatalf("Unable to create the client: %v", err)
+	}
+	im := w.NewInformerManager(client, nil, cm, cm.Logger)
+	nw := &NodesWorker{ConfigManager: cm, CapacityMap: make(map[string]m.NodeSchema), Logger: cm.Logger}
+	nw.informer = im.NewClusterInformer("nodes", w.NodeInformer, cache.ResourceEventHandlerFuncs{})
+	lockCapacityMap.Lock()
+	for _, n := range nodes {
+		nw.CapacityMap[n.NodeName] = n
+	}
+	lockCapacityMap.Unlock()
+
+	stopCh := make(chan struct{})
+	t.Cleanup(func() {
+		close(stopCh)
+		srv.Close()
+	})
+	go nw.informer.Run(stopCh)
+	if !cache.WaitForCacheSync(stopCh, nw.informer.HasSynced) {
+		t.Fatalf("Node informer did not sync")
+	}
+	return nw
+}
+
+//cost of the scope with the name, e.g. namespace shop or label team=web
+func findCost(costs []m.CostSchema, scope string, name string) *m.CostSchema {
+	for i, c := range costs {
+		key := ""
+		switch scope {
+		case m.COST_SCOPE_NAMESPACE:
+			key = c.Namespace
+		case m.COST_SCOPE_WORKLOAD:
+			key = workloadKey(c.Namespace, c.OwnerKind, c.Workload)
+		case m.COST_SCOPE_LABEL:
+			key = c.LabelKey + "=" + c.LabelValue
+		}
+		if c.Scope == scope && key == name {
+			return &costs[i]
+		}
+	}
+	return nil
+}
+
+func costEquals(a float64, b float64) bool {
+	return math.Abs(a-b) < 0.0001
+}
+
+func TestCostAllocate(t *testing.T) {
+	l := log.New()
+	l.Out = ioutil.Discard
+	gb := int64(BYTES_PER_GB)
+	bag := &m.AppDBag{AppName: "cluster", CostLabels: []string{"team"}, CostAllocation: m.COST_ALLOCATION_REQUEST, CostIdleDistribution: m.COST_IDLE_NONE,
+		CostPrices: []m.CostPrice{{NodeSelector: "pool=gpu", CpuCoreHour: 2, MemGBHour: 1}, {CpuCoreHour: 1, MemGBHour: 0.5, StorageGBHour: 0.1}}}
+	cm := &config.MutexConfigManager{Conf: bag, Mutex: &sync.Mutex{}, Logger: l}
+	utils.UpdateNodeLabels("node-b", map[string]string{"pool": "gpu"})
+	defer utils.DeleteNodeLabels("node-b")
+	//the capacity of both nodes costs 8 per hour
+	nodes := newTestNodesWorker(t, cm, m.NodeSchema{NodeName: "node-a", CpuCapacity: 4000, MemCapacity: 8 * gb},
+		m.NodeSchema{NodeName: "node-b", CpuCapacity: 2000, MemCapacity: 4 * gb})
+
+	pods := []costPod{
+		{Namespace: "shop", OwnerKind: "Deployment", Workload: "api", NodeName: "node-a", Labels: map[string]string{"team": "web"},
+			CpuRequest: 1000, MemRequest: 2 * gb, CpuUse: 500, MemUse: 3 * gb, Storage: 10 * gb},
+		{Namespace: "shop", OwnerKind: "Deployment", Workload: "worker", NodeName: "node-b", Labels: map[string]string{},
+			CpuRequest: 500, MemRequest: gb, CpuUse: 1000, MemUse: gb / 2},
+		{Namespace: "data", OwnerKind: "StatefulSet", Workload: "db", NodeName: "node-a", Labels: map[string]string{"team": "data"},
+			CpuRequest: 1000, MemRequest: 2 * gb, CpuUse: 1000, MemUse: gb},
+		//the node is gone
+		{Namespace: "data", OwnerKind: "Pod", Workload: "orphan", NodeName: "node-gone", CpuRequest: 4000, MemRequest: 8 * gb},
+	}
+	ca := NewCostAllocator(cm, l)
+
+	costs := ca.allocate(pods, nodes, bag)
+	scopes := []string{}
+	for _, c := range costs {
+		scopes = append(scopes, c.Scope)
+	}
+	if strings.Join(scopes, ",") != "idle,cluster,namespace,namespace,workload,workload,workload,label,label,label" {
+		t.Fatalf("cost scopes = %v", scopes)
+	}
+	cluster := findCost(costs, m.COST_SCOPE_CLUSTER, "")
+	if cluster.Pods != 3 || !costEquals(cluster.CpuCost, 3) || !costEquals(cluster.MemCost, 3) || !costEquals(cluster.StorageCost, 1) ||
+		!costEquals(cluster.IdleCost, 10) || !costEquals(cluster.TotalCost, 17) {
+		t.Errorf("cluster cost by requests = %+v", cluster)
+	}
+	if idle := costs[0]; !costEquals(idle.IdleCost, 10) || idle.Allocation != m.COST_ALLOCATION_REQUEST {
+		t.Errorf("idle cost = %+v", idle)
+	}
+	//the worker uses more than it requests
+	shop := findCost(costs, m.COST_SCOPE_NAMESPACE, "shop")
+	if shop.Pods != 2 || !costEquals(shop.TotalCost, 5) || !costEquals(shop.Efficiency, 112.5) || shop.IdleCost != 0 {
+		t.Errorf("shop cost by requests = %+v", shop)
+	}
+	if worker := findCost(costs, m.COST_SCOPE_WORKLOAD, "shop/Deployment/worker"); worker == nil || !costEquals(worker.CpuCost, 1) || !costEquals(worker.MemCost, 1) {
+		t.Errorf("worker cost at the gpu pool prices = %+v", worker)
+	}
+	if none := findCost(costs, m.COST_SCOPE_LABEL, "team=none"); none == nil || none.Pods != 1 || none.Workload != "" {
+		t.Errorf("cost of pods without the label = %+v", none)
+	}
+	if web := findCost(costs, m.COST_SCOPE_LABEL, "team=web"); web == nil || !costEquals(web.TotalCost, 3) {
+		t.Errorf("cost of team web = %+v", web)
+	}
+
+	//usage and the larger of requests and usage
+	bag.CostAllocation = m.COST_ALLOCATION_USAGE
+	costs = ca.allocate(pods, nodes, bag)
+	if shop := findCost(costs, m.COST_SCOPE_NAMESPACE, "shop"); !costEquals(shop.CpuCost+shop.MemCost, 4.5) || !costEquals(shop.Efficiency, 100) {
+		t.Errorf("shop cost by usage = %+v", shop)
+	}
+	bag.CostAllocation = m.COST_ALLOCATION_MAX
+	costs = ca.allocate(pods, nodes, bag)
+	if shop := findCost(costs, m.COST_SCOPE_NAMESPACE, "shop"); !costEquals(shop.CpuCost+shop.MemCost, 5.5) {
+		t.Errorf("shop cost by the larger of requests and usage = %+v", shop)
+	}
+	if data := findCost(costs, m.COST_SCOPE_NAMESPACE, "data"); !costEquals(data.CpuCost+data.MemCost, 2) || !costEquals(data.Efficiency, 75) {
+		t.Errorf("data cost by the larger of requests and usage = %+v", data)
+	}
+}
+
+func TestCostIdleDistribution(t *testing.T) {
+	l := log.New()
+	l.Out = ioutil.Discard
+	gb := int64(BYTES_PER_GB)
+	bag := &m.AppDBag{AppName: "cluster", CostAllocation: m.COST_ALLOCATION_REQUEST, CostPrices: []m.CostPrice{{CpuCoreHour: 1, MemGBHour: 0.5}}}
+	cm := &config.MutexConfigManager{Conf: bag, Mutex: &sync.Mutex{}, Logger: l}
+	//node-a costs 8 and node-b 4 per hour
+	nodes := newTestNodesWorker(t, cm, m.NodeSchema{NodeName: "node-a", CpuCapacity: 4000, MemCapacity: 8 * gb},
+		m.NodeSchema{NodeName: "node-b", CpuCapacity: 2000, MemCapacity: 4 * gb}, m.NodeSchema{NodeName: "node-empty", CpuCapacity: 1000})
+	pods := []costPod{
+		{Namespace: "shop", OwnerKind: "Deployment", Workload: "api", NodeName: "node-a", CpuRequest: 1000, MemRequest: 2 * gb},
+		{Namespace: "shop", OwnerKind: "Deployment", Workload: "api", NodeName: "node-a", CpuRequest: 1000, MemRequest: 2 * gb},
+		{Namespace: "data", OwnerKind: "StatefulSet", Workload: "db", NodeName: "node-b", CpuRequest: 2000},
+	}
+	ca := NewCostAllocator(cm, l)
+
+	//the idle cost of a node is shared by its pods. Nodes without pods stay idle
+	bag.CostIdleDistribution = m.COST_IDLE_NODE
+	costs := ca.allocate(pods, nodes, bag)
+	if costs[0].Scope != m.COST_SCOPE_IDLE || !costEquals(costs[0].IdleCost, 1) {
+		t.Errorf("undistributed idle cost = %+v, want the cost of the empty node", costs[0])
+	}
+	if shop := findCost(costs, m.COST_SCOPE_NAMESPACE, "shop"); !costEquals(shop.IdleCost, 4) || !costEquals(shop.TotalCost, 8) {
+		t.Errorf("shop cost with node idle distribution = %+v", shop)
+	}
+	if data := findCost(costs, m.COST_SCOPE_NAMESPACE, "data"); !costEquals(data.IdleCost, 2) || !costEquals(data.TotalCost, 4) {
+		t.Errorf("data cost with node idle distribution = %+v", data)
+	}
+	if cluster := findCost(costs, m.COST_SCOPE_CLUSTER, ""); !costEquals(cluster.IdleCost, 7) || !costEquals(cluster.TotalCost, 13) {
+		t.Errorf("cluster cost with node idle distribution = %+v", cluster)
+	}
+
+	//the idle cost of the cluster is shared by all pods in proportion to their cost
+	bag.CostIdleDistribution = m.COST_IDLE_CLUSTER
+	costs = ca.allocate(pods, nodes, bag)
+	if costs[0].Scope != m.COST_SCOPE_CLUSTER {
+		t.Errorf("idle cost is not fully distributed: %+v", costs[0])
+	}
+	if shop := findCost(costs, m.COST_SCOPE_NAMESPACE, "shop"); !costEquals(shop.IdleCost, 14.0/3) {
+		t.Errorf("shop idle cost with cluster distribution = %+v", shop)
+	}
+	if api := findCost(costs, m.COST_SCOPE_WORKLOAD, "shop/Deployment/api"); !costEquals(api.TotalCost, 4+14.0/3) {
+		t.Errorf("api cost with cluster distribution = %+v", api)
+	}
+	if data := findCost(costs, m.COST_SCOPE_NAMESPACE, "data"); !costEquals(data.IdleCost, 7.0/3) {
+		t.Errorf("data idle cost with cluster distribution = %+v", data)
+	}
+}
@@ -12,11 +12,10 @@ import (
 
 	m "github.com/appdynamics/cluster-agent/models"
 	"github.com/appdynamics/cluster-agent/utils"
+	w "github.com/appdynamics/cluster-agent/watchers"
 	appsv1 "k8s.io/api/apps/v1"
 
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 
@@ -24,7 +23,7 @@ import (
 )
 
 type DaemonWorker struct {
-	informer       cache.SharedIndexInformer
+	informer       *w.NamespacedInformer
 	Client         *kubernetes.Clientset
 	ConfigManager  *config.MutexConfigManager
 	SummaryMap     map[string]m.ClusterDaemonMetrics
@@ -32,33 +31,22 @@ type DaemonWorker struct {
 	AppdController *app.ControllerClient
 	PendingCache   []string
 	FailedCache    map[string]m.AttachStatus
+	Changes        *ChangeRecorder
 	Logger         *log.Logger
 }
 
-func NewDaemonWorker(client *kubernetes.Clientset, cm *config.MutexConfigManager, controller *app.ControllerClient, l *log.Logger) DaemonWorker {
+func NewDaemonWorker(client *kubernetes.Clientset, cm *config.MutexConfigManager, im *w.InformerManager, controller *app.ControllerClient, changes *ChangeRecorder, l *log.Logger) DaemonWorker {
 	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
 	dw := DaemonWorker{Client: client, ConfigManager: cm, SummaryMap: make(map[string]m.ClusterDaemonMetrics), WQ: queue,
-		AppdController: controller, PendingCache: []string{}, FailedCache: make(map[string]m.AttachStatus), Logger: l}
-	dw.initDaemonInformer(client)
+		AppdController: controller, PendingCache: []string{}, FailedCache: make(map[string]m.AttachStatus), Changes: changes, Logger: l}
+	dw.initDaemonInformer(im)
 	return dw
 }
 
-func (nw *DaemonWorker) initDaemonInformer(client *kubernetes.Clientset) cache.SharedIndexInformer {
-	i := cache.NewSharedIndexInformer(
-		&cache.ListWatch{
-			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
-				return client.AppsV1().DaemonSets(metav1.NamespaceAll).List(options)
-			},
-			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
-				return client.AppsV1().DaemonSets(metav1.NamespaceAll).Watch(options)
-			},
-		},
-		&appsv1.DaemonSet{},
-		0,
-		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
-	)
-
-	i.AddEventHandler(cache.ResourceEventHandlerFuncs{
+func (nw *DaemonWorker) initDaemonInformer(im *w.InformerManager) *w.NamespacedInformer {
+	i := im.NewInformer("daemonsets", func(factory informers.SharedInformerFactory, namespace string) cache.SharedIndexInformer {
+		return factory.Apps().V1().DaemonSets().Informer()
+	}, cache.ResourceEventHandlerFuncs{
 		AddFunc:    nw.onNewDaemonSet,
 		DeleteFunc: nw.onDeleteDaemonSet,
 		UpdateFunc: nw.onUpdateDaemonSet,
@@ -84,9 +72,18 @@ func (dw *DaemonWorker) Observe(stopCh <-chan struct{}, wg *sync.WaitGroup) {
 }
 
 func (pw *DaemonWorker) qualifies(p *appsv1.DaemonSet) bool {
-	return (len((*pw.ConfigManager).Get().NsToMonitor) == 0 ||
-		utils.StringInSlice(p.Namespace, (*pw.ConfigManager).Get().NsToMonitor)) &&
-		!utils.StringInSlice(p.Namespace, (*pw.ConfigManager).Get().NsToMonitorExclude)
+	return utils.NSQualifiesForMonitoring(p.Namespace, (*pw.ConfigManager).Get())
+}
+
+//re-queues the daemon sets of the namespace when the namespace selector may select or deselect the namespace
+func (dw *DaemonWorker) NamespaceLabelsChanged(namespace string) {
+	for _, obj := range dw.informer.GetStore().List() {
+		daemonObj := obj.(*appsv1.DaemonSet)
+		if daemonObj.Namespace == namespace && dw.qualifies(daemonObj) {
+			record, _ := dw.processObject(daemonObj, nil)
+			dw.WQ.Add(&record)
+		}
+	}
 }
 
 func (dw *DaemonWorker) onNewDaemonSet(obj interface{}) {
@@ -118,6 +115,8 @@ func (dw *DaemonWorker) onUpdateDaemonSet(objOld interface{}, objNew interface{}
 	DaemonRecord, _ := dw.processObject(DaemonObj, nil)
 	dw.WQ.Add(&DaemonRecord)
 
+	DaemonOldObj := objOld.(*appsv1.DaemonSet)
+	dw.Changes.RecordChange("DaemonSet", DaemonObj, &DaemonOldObj.Spec.Template, &DaemonObj.Spec.Template, nil, nil)
 }
 
 func (pw *DaemonWorker) startMetricsWorker(stopCh <-chan struct{}) {
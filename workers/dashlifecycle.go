@@ -0,0 +1,260 @@
+package workers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	app "github.com/appdynamics/cluster-agent/appd"
+	"github.com/appdynamics/cluster-agent/config"
+	m "github.com/appdynamics/cluster-agent/models"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DASHBOARD_OWNER_MARKER string = "Generated by appd-cluster-agent"
+	DASHBOARD_EXPORT_DIR   string = "/export"
+	DASHBOARD_ARCHIVE_DIR  string = "/archive"
+)
+
+//agent dashboards that are no longer generated. Dashboard id -> time when the dashboard became stale
+var lockStaleDashboards = sync.RWMutex{}
+var staleDashboards = make(map[float64]time.Time)
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+//owner tag of any cluster
+var ownerTagPattern = regexp.MustCompile(regexp.QuoteMeta(DASHBOARD_OWNER_MARKER) + `\. Cluster [^\n]*`)
+
+//the owner tag identifies the dashboards generated by the agent of this cluster
+func (dw *DashboardWorker) ownerTag() string {
+	cluster := dw.Bag.ClusterUID
+	if cluster == "" {
+		cluster = dw.Bag.AppName
+	}
+	return fmt.Sprintf("%s. Cluster %s", DASHBOARD_OWNER_MARKER, cluster)
+}
+
+func (dw *DashboardWorker) tagDashboard(dashboard *m.Dashboard) {
+	tag := dw.ownerTag()
+	if dashboard.Description == nil || *dashboard.Description == "" {
+		dashboard.Description = &tag
+	} else if !strings.Contains(*dashboard.Description, tag) {
+		desc := fmt.Sprintf("%s\n%s", *dashboard.Description, tag)
+		dashboard.Description = &desc
+	}
+}
+
+//replaces the owner tag of the source cluster of an exported dashboard with the tag of this cluster.
+//The other fields of the export are kept as is
+func (dw *DashboardWorker) retagDashboard(data []byte) ([]byte, error) {
+	var obj map[string]interface{}
+	err := json.Unmarshal(data, &obj)
+	if err != nil {
+		return nil, err
+	}
+	desc, _ := obj["description"].(string)
+	desc = strings.TrimSpace(ownerTagPattern.ReplaceAllString(desc, ""))
+	dashboard := m.Dashboard{Description: &desc}
+	dw.tagDashboard(&dashboard)
+	obj["description"] = *dashboard.Description
+	return json.Marshal(obj)
+}
+
+func (dw *DashboardWorker) isOwnDashboard(dashboard *m.Dashboard) bool {
+	return dashboard.Description != nil && strings.Contains(*dashboard.Description, dw.ownerTag())
+}
+
+func (dw *DashboardWorker) exportDir() string {
+	return filepath.Dir(dw.Bag.DashboardTemplatePath) + DASHBOARD_EXPORT_DIR
+}
+
+//reconcileDashboards removes the agent dashboards that are not in the desired list
+//once they have been stale for longer than the grace period
+func (dw *DashboardWorker) reconcileDashboards(desired map[string]bool) {
+	if dw.Bag.DashboardCleanup == m.DASHBOARD_CLEANUP_NONE {
+		return
+	}
+	list, err := dw.loadDashboards()
+	if err != nil {
+		dw.Logger.WithField("error", err).Warn("Unable to check for stale dashboards")
+		return
+	}
+
+	lockStaleDashboards.Lock()
+	defer lockStaleDashboards.Unlock()
+
+	grace := time.Duration(dw.Bag.DashboardGracePeriodMin) * time.Minute
+	stale := make(map[float64]bool)
+	for i := range list {
+		d := list[i]
+		if desired[d.Name] || !dw.isOwnDashboard(&d) {
+			continue
+		}
+		stale[d.ID] = true
+		since, ok := staleDashboards[d.ID]
+		if !ok {
+			staleDashboards[d.ID] = time.Now()
+			dw.Logger.WithFields(log.Fields{"dashboard": d.Name, "gracePeriodMin": dw.Bag.DashboardGracePeriodMin}).Info("Dashboard is no longer generated. It will be removed after the grace period")
+			continue
+		}
+		if time.Since(since) < grace {
+			continue
+		}
+		if dw.Bag.DashboardCleanup == m.DASHBOARD_CLEANUP_ARCHIVE {
+			_, err = dw.exportDashboard(&d, filepath.Dir(dw.Bag.DashboardTemplatePath)+DASHBOARD_ARCHIVE_DIR)
+			if err != nil {
+				dw.Logger.WithFields(log.Fields{"dashboard": d.Name, "error": err}).Warn("Unable to archive stale dashboard. The dashboard is not deleted")
+				continue
+			}
+		}
+		err = dw.DeleteDashboard(int(d.ID))
+		if err != nil {
+			dw.Logger.WithFields(log.Fields{"dashboard": d.Name, "error": err}).Warn("Unable to delete stale dashboard")
+			continue
+		}
+		delete(staleDashboards, d.ID)
+		dw.Logger.WithFields(log.Fields{"dashboard": d.Name, "mode": dw.Bag.DashboardCleanup}).Info("Removed stale dashboard")
+	}
+
+	//dashboards that are generated again or were deleted by users
+	for id := range staleDashboards {
+		if !stale[id] {
+			delete(staleDashboards, id)
+		}
+	}
+}
+
+//exportDashboard saves the dashboard in the controller export format into the directory and returns the exported json
+func (dw *DashboardWorker) exportDashboard(dashboard *m.Dashboard, dir string) ([]byte, error) {
+	rc := app.NewRestClient(dw.Bag, dw.Logger)
+	data, err := rc.ExportDashboard(int(dashboard.ID))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to export dashboard %s. %v", dashboard.Name, err)
+	}
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create export directory %s. %v", dir, err)
+	}
+	fileName := filepath.Join(dir, unsafeFileChars.ReplaceAllString(dashboard.Name, "_")+".json")
+	err = ioutil.WriteFile(fileName, data, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to write exported dashboard %s. %v", fileName, err)
+	}
+	return data, nil
+}
+
+//DashboardExporter exports the agent dashboards and imports exported dashboards into the controller of the agent
+type DashboardExporter struct {
+	ConfManager *config.MutexConfigManager
+	Logger      *log.Logger
+}
+
+func NewDashboardExporter(cm *config.MutexConfigManager, l *log.Logger) *DashboardExporter {
+	return &DashboardExporter{ConfManager: cm, Logger: l}
+}
+
+//no metric lookups or saved searches are needed to move dashboards between controllers
+func (de *DashboardExporter) worker() *DashboardWorker {
+	return &DashboardWorker{Bag: (*de.ConfManager).Get(), Logger: de.Logger}
+}
+
+//ExportDashboards writes all agent dashboards into the export directory and returns them
+func (de *DashboardExporter) ExportDashboards() ([]json.RawMessage, error) {
+	dw := de.worker()
+	list, err := dw.loadDashboards()
+	if err != nil {
+		return nil, err
+	}
+	exported := []json.RawMessage{}
+	for i := range list {
+		d := list[i]
+		if !dw.isOwnDashboard(&d) {
+			continue
+		}
+		data, err := dw.exportDashboard(&d, dw.exportDir())
+		if err != nil {
+			return nil, err
+		}
+		exported = append(exported, json.RawMessage(data))
+	}
+	de.Logger.WithFields(log.Fields{"count": len(exported), "dir": dw.exportDir()}).Info("Exported agent dashboards")
+	return exported, nil
+}
+
+//ImportDashboards creates the dashboards in the controller of the agent. If no dashboards are passed, the files of the export directory are imported.
+//Dashboards with names that already exist in the controller are skipped
+func (de *DashboardExporter) ImportDashboards(dashboards []json.RawMessage) ([]string, []string, error) {
+	dw := de.worker()
+	files := []string{}
+	if len(dashboards) == 0 {
+		matches, err := filepath.Glob(filepath.Join(dw.exportDir(), "*.json"))
+		if err != nil {
+			return nil, nil, err
+		}
+		files = matches
+	} else {
+		tempDir, err := ioutil.TempDir("", "dashboards")
+		if err != nil {
+			return nil, nil, fmt.Errorf("Unable to create directory for dashboard import. %v", err)
+		}
+		defer os.RemoveAll(tempDir)
+		for i, d := range dashboards {
+			fileName := filepath.Join(tempDir, fmt.Sprintf("dashboard-%d.json", i))
+			err = ioutil.WriteFile(fileName, d, 0644)
+			if err != nil {
+				return nil, nil, fmt.Errorf("Unable to write dashboard for import. %v", err)
+			}
+			files = append(files, fileName)
+		}
+	}
+
+	list, err := dw.loadDashboards()
+	if err != nil {
+		return nil, nil, err
+	}
+	existing := make(map[string]bool)
+	for _, d := range list {
+		existing[d.Name] = true
+	}
+
+	imported := []string{}
+	skipped := []string{}
+	rc := app.NewRestClient(dw.Bag, dw.Logger)
+	for _, fileName := range files {
+		data, err := ioutil.ReadFile(fileName)
+		if err != nil {
+			return imported, skipped, fmt.Errorf("Unable to read dashboard file %s. %v", fileName, err)
+		}
+		var d m.Dashboard
+		err = json.Unmarshal(data, &d)
+		if err != nil || d.Name == "" {
+			return imported, skipped, fmt.Errorf("File %s is not an exported dashboard. %v", fileName, err)
+		}
+		if existing[d.Name] {
+			skipped = append(skipped, d.Name)
+			continue
+		}
+		data, err = dw.retagDashboard(data)
+		if err == nil {
+			err = ioutil.WriteFile(fileName, data, 0644)
+		}
+		if err != nil {
+			return imported, skipped, fmt.Errorf("Unable to tag dashboard %s. %v", d.Name, err)
+		}
+		_, err = rc.CreateDashboard(fileName)
+		if err != nil {
+			return imported, skipped, fmt.Errorf("Unable to import dashboard %s. %v", d.Name, err)
+		}
+		existing[d.Name] = true
+		imported = append(imported, d.Name)
+	}
+	de.Logger.WithFields(log.Fields{"imported": len(imported), "skipped": len(skipped)}).Info("Imported dashboards")
+	return imported, skipped, nil
+}
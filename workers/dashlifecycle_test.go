@@ -0,0 +1,168 @@
+package workers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/appdynamics/cluster-agent/config"
+	m "github.com/appdynamics/cluster-agent/models"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestRetagDashboard(t *testing.T) {
+	dw := &DashboardWorker{Bag: &m.AppDBag{AppName: "cluster", ClusterUID: "uid-2"}}
+
+	exported := `{"name": "cluster-shop", "description": "Shop overview\n` + DASHBOARD_OWNER_MARKER + `. Cluster uid-1", "widgets": []}`
+	data, err := dw.retagDashboard([]byte(exported))
+	if err != nil {
+		t.Fatalf("Unable to retag the dashboard: %v", err)
+	}
+	var d m.Dashboard
+	json.Unmarshal(data, &d)
+	if want := "Shop overview\n" + DASHBOARD_OWNER_MARKER + ". Cluster uid-2"; d.Description == nil || *d.Description != want {
+		t.Errorf("description = %v, want %q", d.Description, want)
+	}
+	if d.Name != "cluster-shop" || d.Widgets == nil {
+		t.Errorf("fields of the export were not kept: %s", data)
+	}
+	if !dw.isOwnDashboard(&d) {
+		t.Errorf("retagged dashboard is not owned by this cluster")
+	}
+
+	//dashboards that are not generated by an agent get the tag as well
+	data, _ = dw.retagDashboard([]byte(`{"name": "custom"}`))
+	json.Unmarshal(data, &d)
+	if *d.Description != DASHBOARD_OWNER_MARKER+". Cluster uid-2" {
+		t.Errorf("description of an untagged dashboard = %q", *d.Description)
+	}
+}
+
+func TestReconcileDashboards(t *testing.T) {
+	l := log.New()
+	l.Out = ioutil.Discard
+	bag := &m.AppDBag{AppName: "cluster", DashboardCleanup: m.DASHBOARD_CLEANUP_DELETE, DashboardGracePeriodMin: 0}
+	stub := newControllerStub(t, bag)
+	tag := DASHBOARD_OWNER_MARKER + ". Cluster cluster"
+	stub.responses["GET restui/dashboards/getAllDashboardsByType/false"] = `[
+		{"id": 5, "name": "cluster-Namespace-gone-SUMMARY", "description": "` + tag + `"},
+		{"id": 6, "name": "cluster-Namespace-shop-SUMMARY", "description": "` + tag + `"},
+		{"id": 7, "name": "team-dashboard", "description": "Made by hand"},
+		{"id": 8, "name": "other-Namespace-gone-SUMMARY", "description": "` + DASHBOARD_OWNER_MARKER + `. Cluster other"}]`
+	dw := &DashboardWorker{Bag: bag, Logger: l}
+	desired := map[string]bool{"cluster-Namespace-shop-SUMMARY": true}
+	lockStaleDashboards.Lock()
+	staleDashboards = make(map[float64]time.Time)
+	lockStaleDashboards.Unlock()
+
+	//the dashboard becomes stale. It is only removed after the grace period, on the next run
+	dw.reconcileDashboards(desired)
+	if requests := stub.takeRequests(); len(requests) != 1 {
+		t.Errorf("requests when the dashboard becomes stale = %v", requests)
+	}
+	if _, ok := staleDashboards[5]; !ok || len(staleDashboards) != 1 {
+		t.Errorf("stale dashboards = %v, want only the dashboard of this cluster", staleDashboards)
+	}
+
+	dw.reconcileDashboards(desired)
+	requests := stub.takeRequests()
+	if len(requests) != 2 || requests[1] != "POST restui/dashboards/deleteDashboards" {
+		t.Fatalf("requests after the grace period = %v", requests)
+	}
+	if body := stub.bodies["POST restui/dashboards/deleteDashboards"][0]; body != "[5]" {
+		t.Errorf("deleted dashboards = %s, want [5]", body)
+	}
+	if len(staleDashboards) != 0 {
+		t.Errorf("deleted dashboard is still tracked: %v", staleDashboards)
+	}
+
+	//the dashboard is generated again before the grace period ends
+	bag.DashboardGracePeriodMin = 60
+	dw.reconcileDashboards(desired)
+	desired["cluster-Namespace-gone-SUMMARY"] = true
+	dw.reconcileDashboards(desired)
+	if len(staleDashboards) != 0 {
+		t.Errorf("dashboard that is generated again is still stale: %v", staleDashboards)
+	}
+
+	bag.DashboardCleanup = m.DASHBOARD_CLEANUP_NONE
+	stub.takeRequests()
+	dw.reconcileDashboards(map[string]bool{})
+	if requests := stub.takeRequests(); len(requests) != 0 {
+		t.Errorf("requests with the cleanup disabled = %v", requests)
+	}
+}
+
+func TestImportDashboards(t *testing.T) {
+	l := log.New()
+	l.Out = ioutil.Discard
+	dir, err := ioutil.TempDir("", "dashlifecycle")
+	if err != nil {
+		t.Fatalf("Unable to create the template directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	bag := &m.AppDBag{AppName: "cluster", ClusterUID: "uid-2", DashboardTemplatePath: filepath.Join(dir, "cluster-template.json")}
+	stub := newControllerStub(t, bag)
+	stub.responses["GET restui/dashboards/getAllDashboardsByType/false"] = `[{"id": 3, "name": "cluster-shop"}]`
+	de := NewDashboardExporter(&config.MutexConfigManager{Conf: bag, Mutex: &sync.Mutex{}, Logger: l}, l)
+
+	source := DASHBOARD_OWNER_MARKER + ". Cluster uid-1"
+	dashboards := []json.RawMessage{
+		json.RawMessage(`{"name": "cluster-shop", "description": "` + source + `"}`),
+		json.RawMessage(`{"name": "cluster-Node-node-1", "description": "` + source + `"}`),
+	}
+	imported, skipped, err := de.ImportDashboards(dashboards)
+	if err != nil {
+		t.Fatalf("Unable to import the dashboards: %v", err)
+	}
+	if strings.Join(imported, ",") != "cluster-Node-node-1" || strings.Join(skipped, ",") != "cluster-shop" {
+		t.Errorf("imported %v, skipped %v", imported, skipped)
+	}
+	uploads := stub.bodies["POST CustomDashboardImportExportServlet"]
+	if len(uploads) != 1 {
+		t.Fatalf("uploaded %d dashboards, want 1", len(uploads))
+	}
+	if !strings.Contains(uploads[0], DASHBOARD_OWNER_MARKER+". Cluster uid-2") || strings.Contains(uploads[0], "uid-1") {
+		t.Errorf("uploaded dashboard is not retagged: %s", uploads[0])
+	}
+
+	_, _, err = de.ImportDashboards([]json.RawMessage{json.RawMessage(`{"widgets": []}`)})
+	if err == nil {
+		t.Errorf("dashboard without a name was imported")
+	}
+}
+
+func TestExportDashboards(t *testing.T) {
+	l := log.New()
+	l.Out = ioutil.Discard
+	dir, err := ioutil.TempDir("", "dashlifecycle")
+	if err != nil {
+		t.Fatalf("Unable to create the template directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	bag := &m.AppDBag{AppName: "cluster", DashboardTemplatePath: filepath.Join(dir, "cluster-template.json")}
+	stub := newControllerStub(t, bag)
+	stub.responses["GET restui/dashboards/getAllDashboardsByType/false"] = `[
+		{"id": 3, "name": "cluster-shop/api", "description": "` + DASHBOARD_OWNER_MARKER + `. Cluster cluster"},
+		{"id": 4, "name": "team-dashboard"}]`
+	stub.responses["GET CustomDashboardImportExportServlet"] = `{"name": "cluster-shop/api", "widgetTemplates": []}`
+	de := NewDashboardExporter(&config.MutexConfigManager{Conf: bag, Mutex: &sync.Mutex{}, Logger: l}, l)
+
+	exported, err := de.ExportDashboards()
+	if err != nil {
+		t.Fatalf("Unable to export the dashboards: %v", err)
+	}
+	if len(exported) != 1 || !strings.Contains(string(exported[0]), "widgetTemplates") {
+		t.Errorf("exported dashboards = %s", exported)
+	}
+	//unsafe characters of the name are replaced in the file name
+	data, err := ioutil.ReadFile(filepath.Join(dir, "export", "cluster-shop_api.json"))
+	if err != nil || string(data) != string(exported[0]) {
+		t.Errorf("exported file = %s, %v", data, err)
+	}
+}
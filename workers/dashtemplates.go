@@ -0,0 +1,168 @@
+package workers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"text/template"
+
+	m "github.com/appdynamics/cluster-agent/models"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+//DashboardTemplateData is passed to the Go templates of dashboards
+type DashboardTemplateData struct {
+	Name  string
+	Bag   *m.DashboardBag
+	Agent *m.AppDBag
+	Nodes []m.HeatNode //pods of the heat map, sorted by namespace, owner and name
+}
+
+//DashboardWidgetData is passed to the named templates of widgets
+type DashboardWidgetData struct {
+	DashboardTemplateData
+	Index  int
+	Metric string
+}
+
+//templateFor returns the Go template configured for the dashboard type or "" if the dashboard is generated from the json templates
+func (dw *DashboardWorker) templateFor(dashType m.DashboardType) string {
+	file, ok := dw.Bag.DashboardTemplates[string(dashType)]
+	if !ok || file == "" {
+		return ""
+	}
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(filepath.Dir(dw.Bag.DashboardTemplatePath), file)
+	}
+	return file
+}
+
+//metric path prefix of the dashboard scope
+func (dw *DashboardWorker) scopeMetricPath(bag *m.DashboardBag) string {
+	p := fmt.Sprintf(BASE_PATH, dw.Bag.TierName)
+	switch bag.Type {
+	case m.Tier:
+		p = fmt.Sprintf("%s%s%s%s%s%s%s%s%s", p, m.METRIC_PATH_NAMESPACES, m.METRIC_SEPARATOR, bag.Namespace, m.METRIC_SEPARATOR, m.METRIC_PATH_APPS, m.METRIC_SEPARATOR, bag.TierName, m.METRIC_SEPARATOR)
+	case m.Namespace:
+		p = fmt.Sprintf("%s%s%s%s%s", p, m.METRIC_PATH_NAMESPACES, m.METRIC_SEPARATOR, bag.Namespace, m.METRIC_SEPARATOR)
+	case m.Node:
+		p = fmt.Sprintf("%s%s%s%s%s", p, m.METRIC_PATH_NODES, m.METRIC_SEPARATOR, bag.NodeName, m.METRIC_SEPARATOR)
+	}
+	return p
+}
+
+func (dw *DashboardWorker) templateFuncs(bag *m.DashboardBag) template.FuncMap {
+	scopePath := dw.scopeMetricPath(bag)
+	return template.FuncMap{
+		//full path of the metric in the scope of the dashboard
+		"metric": func(name string) string {
+			return scopePath + name
+		},
+		//id of the metric in the scope of the dashboard. Fails if the metric is not registered yet
+		"metricID": func(name string) (float64, error) {
+			metricID, err := dw.AppdController.GetMetricID(dw.Bag.AppID, scopePath+name)
+			if err != nil {
+				return 0, fmt.Errorf("Cannot get metric ID for %s. %v", scopePath+name, err)
+			}
+			if metricID == 0 {
+				return 0, fmt.Errorf("Metrics are not fully registered with the controller %s. Delaying dashboard generation", scopePath+name)
+			}
+			return metricID, nil
+		},
+		//drill-down url of the saved search of the metric
+		"search": func(name string) string {
+			return dw.AdqlWorker.GetSearch(BASE_PATH + name)
+		},
+		"json": func(v interface{}) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+		"uuid": func() string {
+			return uuid.New().String()
+		},
+		"add": func(a, b int) int {
+			return a + b
+		},
+		"mul": func(a, b int) int {
+			return a * b
+		},
+		"list": func(items ...string) []string {
+			return items
+		},
+		"widget": func(data DashboardTemplateData, index int, metric string) DashboardWidgetData {
+			return DashboardWidgetData{DashboardTemplateData: data, Index: index, Metric: metric}
+		},
+	}
+}
+
+func (dw *DashboardWorker) renderDashboard(templateFile string, dashName string, bag *m.DashboardBag) (*m.Dashboard, error) {
+	t, err := template.New(filepath.Base(templateFile)).Funcs(dw.templateFuncs(bag)).ParseFiles(templateFile)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse dashboard template %s. %v", templateFile, err)
+	}
+
+	nodes, _, _ := bag.GetNodes()
+	data := DashboardTemplateData{Name: dashName, Bag: bag, Agent: dw.Bag, Nodes: nodes}
+	var buf bytes.Buffer
+	err = t.Execute(&buf, data)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to render dashboard template %s. %v", templateFile, err)
+	}
+
+	var dashboard m.Dashboard
+	err = json.Unmarshal(buf.Bytes(), &dashboard)
+	if err != nil {
+		return nil, fmt.Errorf("Dashboard template %s does not produce a valid dashboard. %v", templateFile, err)
+	}
+	return &dashboard, nil
+}
+
+//updateDashboardFromTemplate renders the Go template and replaces the widgets of the dashboard with the rendered ones
+func (dw *DashboardWorker) updateDashboardFromTemplate(templateFile string, dashName string, bag *m.DashboardBag) error {
+	dashboard, err := dw.renderDashboard(templateFile, dashName, bag)
+	if err != nil {
+		return err
+	}
+	dashboard.Name = dashName
+
+	existing, err := dw.loadDashboard(dashName)
+	if err != nil {
+		return fmt.Errorf("Unable to load dashboard %s. %v", dashName, err)
+	}
+	if existing == nil {
+		shell := *dashboard
+		shell.Widgets = []map[string]interface{}{}
+		existing, err = dw.createDashboard(&shell)
+		if err != nil {
+			return err
+		}
+	}
+	dashboard.ID = existing.ID
+	dashboard.Version = existing.Version
+
+	for _, widget := range dashboard.Widgets {
+		widget["dashboardId"] = dashboard.ID
+		widgetGuid, ok := widget["guid"].(string)
+		if !ok || widgetGuid == "" {
+			widgetGuid = uuid.New().String()
+			widget["guid"] = widgetGuid
+		}
+		if criterias, ok := widget["widgetsMetricMatchCriterias"].([]interface{}); ok {
+			for _, c := range criterias {
+				if mt, ok := c.(map[string]interface{}); ok {
+					mt["dashboardId"] = dashboard.ID
+					mt["widgetGuid"] = widgetGuid
+				}
+			}
+		}
+	}
+
+	_, err = dw.saveDashboard(dashboard)
+	if err != nil {
+		return err
+	}
+	dw.Logger.WithFields(log.Fields{"dashboard": dashName, "template": templateFile}).Info("Dashboard generated from template")
+	return nil
+}
@@ -0,0 +1,177 @@
+package workers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	m "github.com/appdynamics/cluster-agent/models"
+	log "github.com/sirupsen/logrus"
+)
+
+//template with two widgets built from a named template
+const TEST_DASHBOARD_TEMPLATE = `{{define "tile"}}{"type": "HEALTH_LIST", "title": {{json .Metric}}, "x": {{mul .Index 4}}, "y": {{add .Index 1}},
+ "widgetsMetricMatchCriterias": [{"metricMatchCriteria": {"metricExpression": {"metricPath": {{json (metric .Metric)}}}}}]}{{end}}
+{"name": {{json .Name}}, "description": "Pods of {{.Bag.Namespace}}", "widgets": [
+{{range $i, $name := list "PodCount" "PodRestarts"}}{{if $i}},{{end}}{{template "tile" (widget $ $i $name)}}{{end}},
+{"type": "TEXT", "guid": "text-1", "text": "{{.Agent.AppName}}"}]}
+`
+
+func newTestDashboardWorker(t *testing.T) (*DashboardWorker, *controllerStub, string) {
+	l := log.New()
+	l.Out = ioutil.Discard
+	dir, err := ioutil.TempDir("", "dashtemplates")
+	if err != nil {
+		t.Fatalf("Unable to create the template directory: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	file := filepath.Join(dir, "namespace.json.tmpl")
+	if err := ioutil.WriteFile(file, []byte(TEST_DASHBOARD_TEMPLATE), 0644); err != nil {
+		t.Fatalf("Unable to write the template: %v", err)
+	}
+
+	bag := &m.AppDBag{AppName: "cluster", TierName: "ClusterAgent", DashboardTemplatePath: filepath.Join(dir, "cluster-template.json"),
+		DashboardTemplates: map[string]string{"namespace": "namespace.json.tmpl", "node": "/etc/dashboards/node.json.tmpl", "tier": ""}}
+	stub := newControllerStub(t, bag)
+	stub.responses["GET restui/analyticsSavedSearches/getAllAnalyticsSavedSearches"] = `[]`
+	dw := NewDashboardWorker(bag, l, nil)
+	stub.takeRequests()
+	return &dw, stub, file
+}
+
+func TestTemplateFor(t *testing.T) {
+	dw, _, file := newTestDashboardWorker(t)
+
+	if got := dw.templateFor(m.Namespace); got != file {
+		t.Errorf("relative template = %q, want %q next to the json templates", got, file)
+	}
+	if got := dw.templateFor(m.Node); got != "/etc/dashboards/node.json.tmpl" {
+		t.Errorf("absolute template = %q", got)
+	}
+	if got := dw.templateFor(m.Tier); got != "" {
+		t.Errorf("empty template = %q, want the json templates", got)
+	}
+	if got := dw.templateFor(m.NodeHeat); got != "" {
+		t.Errorf("missing template = %q, want the json templates", got)
+	}
+}
+
+func TestScopeMetricPath(t *testing.T) {
+	dw, _, _ := newTestDashboardWorker(t)
+	base := "Application Infrastructure Performance|ClusterAgent|Custom Metrics|Cluster Stats|"
+
+	tier := m.NewDashboardBagTier("shop", "cart", []m.PodSchema{})
+	if got, want := dw.scopeMetricPath(&tier), base+"Namespaces|shop|Deployments|cart|"; got != want {
+		t.Errorf("tier scope = %q, want %q", got, want)
+	}
+	ns := m.NewDashboardBagNamespace("shop")
+	if got, want := dw.scopeMetricPath(&ns), base+"Namespaces|shop|"; got != want {
+		t.Errorf("namespace scope = %q, want %q", got, want)
+	}
+	node := m.DashboardBag{Type: m.Node, NodeName: "node-1"}
+	if got, want := dw.scopeMetricPath(&node), base+"Nodes|node-1|"; got != want {
+		t.Errorf("node scope = %q, want %q", got, want)
+	}
+	cluster := m.DashboardBag{}
+	if got := dw.scopeMetricPath(&cluster); got != base {
+		t.Errorf("cluster scope = %q, want %q", got, base)
+	}
+}
+
+func TestRenderDashboard(t *testing.T) {
+	dw, _, file := newTestDashboardWorker(t)
+	bag := m.NewDashboardBagNamespace("shop")
+
+	dashboard, err := dw.renderDashboard(file, "cluster-shop", &bag)
+	if err != nil {
+		t.Fatalf("Unable to render the dashboard: %v", err)
+	}
+	if dashboard.Name != "cluster-shop" || dashboard.Description == nil || *dashboard.Description != "Pods of shop" {
+		t.Errorf("rendered dashboard %q with description %v", dashboard.Name, dashboard.Description)
+	}
+	if len(dashboard.Widgets) != 3 {
+		t.Fatalf("rendered %d widgets, want 3", len(dashboard.Widgets))
+	}
+	second := dashboard.Widgets[1]
+	if second["title"] != "PodRestarts" || second["x"] != float64(4) || second["y"] != float64(2) {
+		t.Errorf("second widget = %v", second)
+	}
+	path := second["widgetsMetricMatchCriterias"].([]interface{})[0].(map[string]interface{})["metricMatchCriteria"].(map[string]interface{})["metricExpression"].(map[string]interface{})["metricPath"]
+	if want := "Application Infrastructure Performance|ClusterAgent|Custom Metrics|Cluster Stats|Namespaces|shop|PodRestarts"; path != want {
+		t.Errorf("metric path of the second widget = %v, want %s", path, want)
+	}
+	if text := dashboard.Widgets[2]["text"]; text != "cluster" {
+		t.Errorf("agent settings in the template: text = %v", text)
+	}
+
+	broken := filepath.Join(filepath.Dir(file), "broken.json.tmpl")
+	ioutil.WriteFile(broken, []byte(`{"name": {{json .Name}}, "widgets": [}`), 0644)
+	if _, err := dw.renderDashboard(broken, "cluster-shop", &bag); err == nil || !strings.Contains(err.Error(), "valid dashboard") {
+		t.Errorf("invalid json: error = %v", err)
+	}
+	if _, err := dw.renderDashboard(filepath.Join(filepath.Dir(file), "missing.json.tmpl"), "cluster-shop", &bag); err == nil {
+		t.Errorf("missing template rendered without error")
+	}
+}
+
+func TestUpdateDashboardFromTemplate(t *testing.T) {
+	dw, stub, file := newTestDashboardWorker(t)
+	bag := m.NewDashboardBagNamespace("shop")
+	stub.responses["GET restui/dashboards/getAllDashboardsByType/false"] = `[]`
+	stub.responses["POST restui/dashboards/createDashboard"] = `{"id": 42, "version": 1, "name": "cluster-shop"}`
+
+	//the dashboard does not exist. An empty one is created and the rendered widgets are saved into it
+	if err := dw.updateDashboardFromTemplate(file, "cluster-shop", &bag); err != nil {
+		t.Fatalf("Unable to generate the dashboard: %v", err)
+	}
+	want := []string{"GET restui/dashboards/getAllDashboardsByType/false", "POST restui/dashboards/createDashboard", "POST restui/dashboards/updateDashboard"}
+	if requests := stub.takeRequests(); strings.Join(requests, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("requests = %v, want %v", requests, want)
+	}
+	var shell m.Dashboard
+	json.Unmarshal([]byte(stub.bodies["POST restui/dashboards/createDashboard"][0]), &shell)
+	if len(shell.Widgets) != 0 || shell.Description == nil || !strings.Contains(*shell.Description, DASHBOARD_OWNER_MARKER) {
+		t.Errorf("created dashboard has %d widgets and description %v", len(shell.Widgets), shell.Description)
+	}
+
+	var saved m.Dashboard
+	json.Unmarshal([]byte(stub.bodies["POST restui/dashboards/updateDashboard"][0]), &saved)
+	if saved.ID != 42 || saved.Version != 1 || len(saved.Widgets) != 3 {
+		t.Fatalf("saved dashboard id %.0f, version %.0f with %d widgets", saved.ID, saved.Version, len(saved.Widgets))
+	}
+	first := saved.Widgets[0]
+	guid, _ := first["guid"].(string)
+	if guid == "" || first["dashboardId"] != float64(42) {
+		t.Errorf("first widget guid %q, dashboard %v", guid, first["dashboardId"])
+	}
+	criteria := first["widgetsMetricMatchCriterias"].([]interface{})[0].(map[string]interface{})
+	if criteria["widgetGuid"] != guid || criteria["dashboardId"] != float64(42) {
+		t.Errorf("criteria of the first widget are not linked to it: %v", criteria)
+	}
+	if saved.Widgets[2]["guid"] != "text-1" {
+		t.Errorf("guid of the template was replaced with %v", saved.Widgets[2]["guid"])
+	}
+
+	//the dashboard exists. Only the widgets are replaced
+	stub.responses["GET restui/dashboards/getAllDashboardsByType/false"] = `[{"id": 42, "version": 5, "name": "cluster-shop"}]`
+	if err := dw.updateDashboardFromTemplate(file, "cluster-shop", &bag); err != nil {
+		t.Fatalf("Unable to update the dashboard: %v", err)
+	}
+	want = []string{"GET restui/dashboards/getAllDashboardsByType/false", "POST restui/dashboards/updateDashboard"}
+	if requests := stub.takeRequests(); strings.Join(requests, "\n") != strings.Join(want, "\n") {
+		t.Errorf("requests for the existing dashboard = %v, want %v", requests, want)
+	}
+	json.Unmarshal([]byte(stub.bodies["POST restui/dashboards/updateDashboard"][1]), &saved)
+	if saved.Version != 5 {
+		t.Errorf("saved version = %.0f, want the version of the existing dashboard", saved.Version)
+	}
+
+	//failures of the controller are reported
+	stub.failing["POST restui/dashboards/updateDashboard"] = true
+	if err := dw.updateDashboardFromTemplate(file, "cluster-shop", &bag); err == nil {
+		t.Errorf("failed save is not reported")
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"sort"
 
 	"strings"
 
@@ -21,14 +22,23 @@ import (
 )
 
 const (
-	WIDGET_GAP      float64 = 10
-	FULL_CLUSTER    string  = "/cluster-template.json"
-	CLUSTER_WIDGETS string  = "/cluster-widgets.json"
-	DEPLOY_BASE     string  = "/base_deploy_template.json"
-	TIER_SUMMARY    string  = "/tier_stats_widget.json"
-	BACKGROUND      string  = "/background.json"
-	HEAT_MAP        string  = "/heatnodetemplate.json"
-	HEAT_WIDGET     string  = "/healthwidget.json"
+	WIDGET_GAP       float64 = 10
+	FULL_CLUSTER     string  = "/cluster-template.json"
+	CLUSTER_WIDGETS  string  = "/cluster-widgets.json"
+	DEPLOY_BASE      string  = "/base_deploy_template.json"
+	TIER_SUMMARY     string  = "/tier_stats_widget.json"
+	BACKGROUND       string  = "/background.json"
+	HEAT_MAP         string  = "/heatnodetemplate.json"
+	HEAT_WIDGET      string  = "/healthwidget.json"
+	WORKLOAD_SUMMARY string  = "/workload_stats_widget.json"
+	DAEMON_SUMMARY   string  = "/daemonset_stats_widget.json"
+	NS_SUMMARY       string  = "/namespace_stats_widget.json"
+	NODE_SUMMARY     string  = "/node_stats_widget.json"
+	TOP_CONSUMERS    int     = 5
+	HEAT_MAP_TOP     int     = 506 //top of the heat map on the cluster overview
+	NODE_HEAT_TOP    int     = 70  //top of the heat map on the node heat map dashboard
+
+	DASHBOARD_URL_TEMPLATE string = "%s#/location=CDASHBOARD_DETAIL&mode=MODE_DASHBOARD&dashboard=%.0f"
 )
 
 type DashboardWorker struct {
@@ -36,10 +46,11 @@ type DashboardWorker struct {
 	Logger         *log.Logger
 	AppdController *app.ControllerClient
 	AdqlWorker     AdqlSearchWorker
+	dashboardIDs   map[string]float64
 }
 
 func NewDashboardWorker(bag *m.AppDBag, l *log.Logger, appController *app.ControllerClient) DashboardWorker {
-	dw := DashboardWorker{bag, l, appController, NewAdqlSearchWorker(bag, l)}
+	dw := DashboardWorker{Bag: bag, Logger: l, AppdController: appController, AdqlWorker: NewAdqlSearchWorker(bag, l)}
 	return dw
 }
 
@@ -58,6 +69,7 @@ func (dw *DashboardWorker) ensureClusterDashboard() error {
 
 func (dw *DashboardWorker) createDashboard(dashboard *m.Dashboard) (*m.Dashboard, error) {
 	var dashObject *m.Dashboard = nil
+	dw.tagDashboard(dashboard)
 	data, err := json.Marshal(dashboard)
 	if err != nil {
 		return dashObject, fmt.Errorf("Unable to create dashboard %s. %v", dashboard.Name, err)
@@ -80,6 +92,7 @@ func (dw *DashboardWorker) createDashboard(dashboard *m.Dashboard) (*m.Dashboard
 
 func (dw *DashboardWorker) saveDashboard(dashboard *m.Dashboard) (*m.Dashboard, error) {
 	var dashObject *m.Dashboard = nil
+	dw.tagDashboard(dashboard)
 	data, err := json.Marshal(dashboard)
 	if err != nil {
 		return nil, fmt.Errorf("Unable to save dashboard %s. %v", dashboard.Name, err)
@@ -133,21 +146,42 @@ func (dw *DashboardWorker) loadDashboardByID(id float64) (*m.Dashboard, error) {
 	return &theDash, nil
 }
 
-func (dw *DashboardWorker) loadDashboard(dashName string) (*m.Dashboard, error) {
-	var theDash *m.Dashboard = nil
-
-	fmt.Println("Checking the dashboard...")
+func (dw *DashboardWorker) loadDashboards() ([]m.Dashboard, error) {
 	rc := app.NewRestClient(dw.Bag, dw.Logger)
 	data, err := rc.CallAppDController("restui/dashboards/getAllDashboardsByType/false", "GET", nil)
 	if err != nil {
 		fmt.Printf("Unable to get the list of dashboard. %v\n", err)
-		return theDash, err
+		return nil, err
 	}
 	var list []m.Dashboard
 	e := json.Unmarshal(data, &list)
 	if e != nil {
 		fmt.Printf("Unable to deserialize the list of dashboards. %v\n", e)
-		return theDash, e
+		return nil, e
+	}
+	return list, nil
+}
+
+//ids of the dashboards of the controller by name. The worker is built for each dashboard cycle, so the list is loaded once per cycle
+func (dw *DashboardWorker) getDashboardIDs() map[string]float64 {
+	if dw.dashboardIDs == nil {
+		dw.dashboardIDs = make(map[string]float64)
+		if list, err := dw.loadDashboards(); err == nil {
+			for _, d := range list {
+				dw.dashboardIDs[d.Name] = d.ID
+			}
+		}
+	}
+	return dw.dashboardIDs
+}
+
+func (dw *DashboardWorker) loadDashboard(dashName string) (*m.Dashboard, error) {
+	var theDash *m.Dashboard = nil
+
+	fmt.Println("Checking the dashboard...")
+	list, err := dw.loadDashboards()
+	if err != nil {
+		return theDash, err
 	}
 
 	for _, d := range list {
@@ -238,7 +272,7 @@ func (dw *DashboardWorker) createClusterDashboard(dashName string) error {
 }
 
 func (dw *DashboardWorker) createDashboardFromFile(dashboard *m.Dashboard, genPath string) (*m.Dashboard, error) {
-
+	dw.tagDashboard(dashboard)
 	fileForUpload, err := dw.saveTemplate(dashboard, genPath)
 	if err != nil {
 		return nil, fmt.Errorf("Unable to create dashboard. %v\n", err)
@@ -350,14 +384,17 @@ func (dw *DashboardWorker) validateTierDashboardBag(bag *m.DashboardBag) error {
 	if bag.ClusterAppID == 0 {
 		msg = "Missing Agent App ID."
 	}
-	if bag.AppID == 0 {
-		msg += " APM App ID is missing."
-	}
-	if bag.TierID == 0 {
-		msg += " APM Tier ID is missing."
-	}
-	if bag.NodeID == 0 {
-		msg += " APM Node ID is missing."
+	//APM entities are only required by the widgets of instrumented tiers
+	if dw.tierSummaryTemplate(bag) == TIER_SUMMARY {
+		if bag.AppID == 0 {
+			msg += " APM App ID is missing."
+		}
+		if bag.TierID == 0 {
+			msg += " APM Tier ID is missing."
+		}
+		if bag.NodeID == 0 {
+			msg += " APM Node ID is missing."
+		}
 	}
 	if msg != "" {
 		return fmt.Errorf("Dashboard validation failed. %s", msg)
@@ -378,7 +415,15 @@ func (dw *DashboardWorker) updateClusterOverview(bag *m.DashboardBag) error {
 		return fmt.Errorf("Cluster template exists, but cannot be loaded. %v", err)
 	}
 
-	dashName := fmt.Sprintf("Cluster-Overview-%s-%s", dw.Bag.AppName, dw.Bag.DashboardSuffix)
+	dashName := dw.dashboardName(bag)
+
+	if templateFile := dw.templateFor(bag.Type); templateFile != "" {
+		errTemplate := dw.updateDashboardFromTemplate(templateFile, dashName, bag)
+		if errTemplate == nil {
+			return nil
+		}
+		dw.Logger.WithField("error", errTemplate).Warn("Unable to generate cluster overview dashboard from template. Using built-in generator")
+	}
 
 	//	if dashboard != nil {
 	//		// if the dashboard was created earlier, make sure it still exists
@@ -485,7 +530,13 @@ func (dw *DashboardWorker) updateClusterOverview(bag *m.DashboardBag) error {
 	}
 
 	//add heat map
-	hotdash, heatErr := dw.addPodHeatMap(dashboard, bag)
+	var hotdash *m.Dashboard
+	var heatErr error
+	if dw.Bag.NodeHeatMap == m.NODE_HEAT_MAP_OVERVIEW && len(bag.NodeMap) > 0 {
+		hotdash, heatErr = dw.addNodeHeatMap(dashboard, bag, HEAT_MAP_TOP)
+	} else {
+		hotdash, heatErr = dw.addPodHeatMap(dashboard, bag)
+	}
 	if heatErr != nil {
 		dw.Logger.Errorf("Unable to add heatmap to the cluster dashboard. %v\n", heatErr)
 		return heatErr
@@ -705,6 +756,210 @@ func (dw *DashboardWorker) addPodHeatMap(dashboard *m.Dashboard, bag *m.Dashboar
 	return dashboard, nil
 }
 
+//addNodeHeatMap adds a tile per node colored by the worst of the node conditions, use and requests.
+//The tiles are placed on the background widgets 0 and 1 of the dashboard starting at top
+func (dw *DashboardWorker) addNodeHeatMap(dashboard *m.Dashboard, bag *m.DashboardBag, top int) (*m.Dashboard, error) {
+	backWidth := 1465
+	backTop := top + 11
+	startLine := top + 34
+	backHeight := 159
+	minSize := 24
+	leftMargin := 26
+	nodeMargin := minSize
+
+	currentX := leftMargin
+	currentY := startLine
+
+	width := minSize
+	height := minSize
+
+	nodeArray, roleNum := bag.GetNodeHeatNodes()
+	if len(nodeArray) == 0 {
+		return dashboard, nil
+	}
+
+	totalUnits := len(nodeArray) + 2*roleNum
+	availableArea := (backWidth - 2*nodeMargin) * (backHeight - (startLine - backTop))
+	areaPerNode := math.Round(float64(availableArea / totalUnits))
+	side := math.Sqrt(areaPerNode)
+	if side > float64(minSize+nodeMargin) {
+		nodeMargin = int(math.Round(side / 2))
+		width = nodeMargin
+		height = nodeMargin
+	}
+	roleGap := int(2 * width)
+
+	rightMargin := backWidth - nodeMargin
+	lastLine := backTop + backHeight - nodeMargin - height
+
+	backgroundWidet := dashboard.Widgets[1]
+	backgroundWidet["dashboardId"] = dashboard.ID
+	backgroundWidet["height"] = backHeight
+	backgroundWidet["width"] = backWidth
+	backgroundWidet["x"] = 14
+	backgroundWidet["y"] = top
+
+	dashBack := dashboard.Widgets[0]
+	dashBack["dashboardId"] = dashboard.ID
+	dashBack["height"] = top + 153
+	dashBack["width"] = 1479
+	dashBack["x"] = 10
+	dashBack["y"] = 13
+
+	widgetList, err, exists := dw.loadWidgetTemplate(HEAT_MAP)
+	if err != nil && exists {
+		return nil, fmt.Errorf("Heatmap template exists, but cannot be loaded. %v\n", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("Heatmap template does not exist, skipping node heat map. %v\n", err)
+	}
+	heatWidget := (*widgetList)[0]
+
+	//tiles drill down into the node dashboards when they exist
+	nodeDashboards := make(map[string]float64)
+	if len(dw.Bag.NodesToDashboard) > 0 || dw.Bag.NodesToDashboardSelector != "" {
+		nodeDashboards = dw.getDashboardIDs()
+	}
+
+	threshold := float64(dw.Bag.OverconsumptionThreshold)
+	oldRole := ""
+	first := true
+	for _, nh := range nodeArray {
+		dot, err := utils.CloneMap(heatWidget)
+		if err != nil {
+			return nil, fmt.Errorf("Heatmap template is invalid. %v\n", err)
+		}
+		dot["dashboardId"] = dashboard.ID
+		dot["guid"] = uuid.New().String()
+		dot["height"] = height
+		dot["width"] = width
+		dot["text"] = fmt.Sprintf("%d", nh.Pods)
+
+		state, reasons := nh.GetHeatState(threshold)
+		dot["description"] = fmt.Sprintf("%s (%s)\n%s", nh.Nodename, nh.Role, nh.GetStatsFormatted())
+		if len(reasons) > 0 {
+			dot["description"] = fmt.Sprintf("%s%s", dot["description"], strings.Join(reasons, "\n"))
+		}
+
+		colorCode := 34021 //blue
+		searchPath := fmt.Sprintf("%s%s", BASE_PATH, "PodRunning")
+		switch state {
+		case m.NODE_HEAT_UNAVAILABLE:
+			colorCode = 13369344 //red
+			searchPath = fmt.Sprintf("%s%s", BASE_PATH, "Evictions")
+		case m.NODE_HEAT_PRESSURE:
+			colorCode = 16605970 //orange
+			if nh.MemoryPressure {
+				searchPath = fmt.Sprintf("%s%s", BASE_PATH, "MemoryPressureNodes")
+			} else if nh.DiskPressure {
+				searchPath = fmt.Sprintf("%s%s", BASE_PATH, "DiskPressureNodes")
+			} else {
+				searchPath = fmt.Sprintf("%s%s", BASE_PATH, "PodPending")
+			}
+		case m.NODE_HEAT_BUSY:
+			colorCode = 10040319 //purple
+			searchPath = fmt.Sprintf("%s%s", BASE_PATH, "PodOverconsume")
+		}
+		dot["backgroundColor"] = colorCode
+
+		if nh.PodsNotRunning > 0 {
+			dot["borderEnabled"] = true
+			dot["borderThickness"] = 2
+			dot["borderColor"] = 16605970 //orange
+		}
+
+		if !first && oldRole != nh.Role {
+			currentX += roleGap
+		}
+		currentX, currentY = dw.validateDimensions(currentX, currentY, lastLine, height, nodeMargin, minSize, rightMargin, leftMargin, dashboard, &backgroundWidet, &dashBack)
+
+		dot["x"] = currentX
+		dot["y"] = currentY
+
+		oldRole = nh.Role
+		first = false
+
+		nodeDash := dw.dashboardName(&m.DashboardBag{Type: m.Node, NodeName: nh.Nodename})
+		if id, ok := nodeDashboards[nodeDash]; ok {
+			dot["drillDownUrl"] = fmt.Sprintf(DASHBOARD_URL_TEMPLATE, dw.Bag.RestAPIUrl, id)
+			dot["useMetricBrowserAsDrillDown"] = false
+		} else if searchUrl := dw.AdqlWorker.GetSearch(searchPath); searchUrl != "" {
+			dot["drillDownUrl"] = searchUrl
+			dot["useMetricBrowserAsDrillDown"] = false
+		}
+		dashboard.Widgets = append(dashboard.Widgets, dot)
+
+		currentX = currentX + minSize + nodeMargin
+		currentX, currentY = dw.validateDimensions(currentX, currentY, lastLine, height, nodeMargin, minSize, rightMargin, leftMargin, dashboard, &backgroundWidet, &dashBack)
+	}
+
+	return dashboard, nil
+}
+
+//updateNodeHeatDashboard builds the dashboard with the node heat map of the cluster
+func (dw *DashboardWorker) updateNodeHeatDashboard(bag *m.DashboardBag) error {
+	if bag.ClusterAppID == 0 || bag.ClusterTierID == 0 {
+		return fmt.Errorf("Dashboard cannot be created until the application is fully registered in the controller. AppID: %d TierID: %d", bag.ClusterAppID, bag.ClusterTierID)
+	}
+	dashName := dw.dashboardName(bag)
+	dashboard, err := dw.loadDashboard(dashName)
+	if err != nil {
+		return err
+	}
+	if dashboard == nil {
+		dashboard, err, _ = dw.loadDashboardTemplate(DEPLOY_BASE)
+		if err != nil {
+			return fmt.Errorf("Dashboard template cannot be loaded. %v", err)
+		}
+		dashboard.Name = dashName
+		dashboard, err = dw.createDashboard(dashboard)
+		if err != nil {
+			return err
+		}
+	}
+
+	widgetList, err, exists := dw.loadWidgetTemplate(BACKGROUND)
+	if err != nil && exists {
+		return fmt.Errorf("Background template exists, but cannot be loaded. %v", err)
+	}
+	if !exists {
+		return fmt.Errorf("Background template does not exist, skipping node heat map dashboard. %v", err)
+	}
+	dashBack := (*widgetList)[0]
+	heatBack, err := utils.CloneMap(dashBack)
+	if err != nil {
+		return fmt.Errorf("Background template is invalid. %v", err)
+	}
+	heatBack["guid"] = uuid.New().String()
+	heatBack["backgroundColor"] = 15395562
+	heatBack["backgroundColors"] = []int{15395562, 15395562}
+	title, err := utils.CloneMap(dashBack)
+	if err != nil {
+		return fmt.Errorf("Background template is invalid. %v", err)
+	}
+	title["guid"] = uuid.New().String()
+	title["dashboardId"] = dashboard.ID
+	title["x"] = 24
+	title["y"] = 20
+	title["width"] = 1451
+	title["height"] = 40
+	title["textAlign"] = "LEFT"
+	title["text"] = fmt.Sprintf("Nodes of %s. Blue: healthy, purple: use or requests above %d%s, orange: pressure or overcommitted requests, red: not ready or out of disk. The number is the count of pods, the orange border marks pods that are not running",
+		dw.Bag.AppName, dw.Bag.OverconsumptionThreshold, "%")
+
+	dashboard.Widgets = []map[string]interface{}{dashBack, heatBack}
+	updated, err := dw.addNodeHeatMap(dashboard, bag, NODE_HEAT_TOP)
+	if err != nil {
+		return err
+	}
+	updated.Widgets = append(updated.Widgets, title)
+	if height := float64(updated.Widgets[0]["height"].(int)) + 2*WIDGET_GAP; updated.Height < height {
+		updated.Height = height
+	}
+	_, err = dw.saveDashboard(updated)
+	return err
+}
+
 func (dw *DashboardWorker) validateDimensions(currentX, currentY, lastLine, height, nodeMargin, minSize, rightMargin, leftMargin int, dashboard *m.Dashboard, backgroundWidet *map[string]interface{}, dashBack *map[string]interface{}) (int, int) {
 	if currentX > rightMargin {
 		currentX = leftMargin
@@ -755,7 +1010,16 @@ func (dw *DashboardWorker) updateTierDashboard(bag *m.DashboardBag) error {
 	if err != nil && exists {
 		return fmt.Errorf("Deployment template for %s/%s exists, but cannot be loaded. %v", bag.Namespace, bag.TierName, err)
 	}
-	dashName := fmt.Sprintf("%s-%s-%s-%s", dw.Bag.AppName, bag.Namespace, bag.TierName, dw.Bag.DashboardSuffix)
+	dashName := dw.dashboardName(bag)
+
+	if templateFile := dw.templateFor(bag.Type); templateFile != "" {
+		errTemplate := dw.updateDashboardFromTemplate(templateFile, dashName, bag)
+		if errTemplate == nil {
+			return nil
+		}
+		dw.Logger.WithFields(log.Fields{"deployment": bag.TierName, "error": errTemplate}).Warn("Unable to generate deployment dashboard from template. Using built-in generator")
+	}
+
 	if !exists {
 		fmt.Printf("Checking dashboard for deployment %s/%s on the server\n", bag.Namespace, bag.TierName)
 		dashboard, err = dw.loadDashboard(dashName)
@@ -800,6 +1064,120 @@ func (dw *DashboardWorker) updateTierDashboard(bag *m.DashboardBag) error {
 	return errSaveDash
 }
 
+func (dw *DashboardWorker) dashboardName(bag *m.DashboardBag) string {
+	switch bag.Type {
+	case m.Cluster:
+		return fmt.Sprintf("Cluster-Overview-%s-%s", dw.Bag.AppName, dw.Bag.DashboardSuffix)
+	case m.NodeHeat:
+		return fmt.Sprintf("Cluster-Nodes-%s-%s", dw.Bag.AppName, dw.Bag.DashboardSuffix)
+	case m.Node:
+		return fmt.Sprintf("%s-Node-%s-%s", dw.Bag.AppName, bag.NodeName, dw.Bag.DashboardSuffix)
+	case m.Namespace:
+		return fmt.Sprintf("%s-Namespace-%s-%s", dw.Bag.AppName, bag.Namespace, dw.Bag.DashboardSuffix)
+	default:
+		return fmt.Sprintf("%s-%s-%s-%s", dw.Bag.AppName, bag.Namespace, bag.TierName, dw.Bag.DashboardSuffix)
+	}
+}
+
+//updateScopeDashboard builds the dashboard of a namespace or a node
+func (dw *DashboardWorker) updateScopeDashboard(bag *m.DashboardBag) error {
+	if bag.ClusterAppID == 0 || bag.ClusterTierID == 0 {
+		return fmt.Errorf("Dashboard cannot be created until the application is fully registered in the controller. AppID: %d TierID: %d", bag.ClusterAppID, bag.ClusterTierID)
+	}
+	dashName := dw.dashboardName(bag)
+
+	if templateFile := dw.templateFor(bag.Type); templateFile != "" {
+		errTemplate := dw.updateDashboardFromTemplate(templateFile, dashName, bag)
+		if errTemplate == nil {
+			return nil
+		}
+		dw.Logger.WithFields(log.Fields{"dashboard": dashName, "error": errTemplate}).Warn("Unable to generate dashboard from template. Using built-in generator")
+	}
+
+	dashboard, err := dw.loadDashboard(dashName)
+	if err != nil {
+		return err
+	}
+	if dashboard == nil {
+		dashboard, err, _ = dw.loadDashboardTemplate(DEPLOY_BASE)
+		if err != nil {
+			return fmt.Errorf("Dashboard template cannot be loaded. %v", err)
+		}
+		dashboard.Name = dashName
+		dashboard, err = dw.createDashboard(dashboard)
+		if err != nil {
+			return err
+		}
+	}
+
+	summary := NS_SUMMARY
+	if bag.Type == m.Node {
+		summary = NODE_SUMMARY
+	}
+	dashboard.Widgets = []map[string]interface{}{}
+	updated, err := dw.addSummaryWidgets(dashboard, bag, summary)
+	if err != nil {
+		return err
+	}
+	_, err = dw.saveDashboard(updated)
+	return err
+}
+
+func (dw *DashboardWorker) replaceTextPlaceholders(text string, bag *m.DashboardBag) string {
+	text = strings.Replace(text, "%NAMESPACE%", bag.Namespace, -1)
+	text = strings.Replace(text, "%NODE_NAME%", bag.NodeName, -1)
+	text = strings.Replace(text, "%APP_TIER_NAME%", bag.TierName, -1)
+	text = strings.Replace(text, "%OWNER_KIND%", bag.OwnerKind, -1)
+	if strings.Contains(text, "%NODE_DISTRIBUTION%") {
+		text = strings.Replace(text, "%NODE_DISTRIBUTION%", dw.nodeDistribution(bag), -1)
+	}
+	if strings.Contains(text, "%TOP_CONSUMERS%") {
+		var sb strings.Builder
+		for i, p := range bag.GetTopConsumers(TOP_CONSUMERS) {
+			name := p.Name
+			if bag.Type == m.Node {
+				name = fmt.Sprintf("%s/%s", p.Namespace, p.Name)
+			}
+			sb.WriteString(fmt.Sprintf("%d. %s  CPU: %dm  Memory: %dMi\n", i+1, name, p.CpuUse, p.MemUse/(1024*1024)))
+		}
+		text = strings.Replace(text, "%TOP_CONSUMERS%", sb.String(), -1)
+	}
+	return text
+}
+
+//pods of the workload per node
+func (dw *DashboardWorker) nodeDistribution(bag *m.DashboardBag) string {
+	type nodePods struct {
+		Pods     int
+		Running  int
+		Restarts int32
+	}
+	nodes := make(map[string]nodePods)
+	names := []string{}
+	for _, p := range bag.Pods {
+		n, ok := nodes[p.NodeName]
+		if !ok {
+			names = append(names, p.NodeName)
+		}
+		n.Pods++
+		if p.Phase == "Running" {
+			n.Running++
+		}
+		n.Restarts += p.PodRestarts
+		nodes[p.NodeName] = n
+	}
+	sort.Strings(names)
+	var sb strings.Builder
+	for _, name := range names {
+		n := nodes[name]
+		if name == "" {
+			name = "(not scheduled)"
+		}
+		sb.WriteString(fmt.Sprintf("%s: %d pods, %d running, %d restarts\n", name, n.Pods, n.Running, n.Restarts))
+	}
+	return sb.String()
+}
+
 func (dw *DashboardWorker) generateDeploymentDashboard(dashboard *m.Dashboard, bag *m.DashboardBag) (*m.Dashboard, error) {
 	//	dashboard.WidgetTemplates = []m.WidgetTemplate{}
 	fmt.Printf("generateDeploymentDashboard %s/%s\n", bag.Namespace, bag.TierName)
@@ -835,15 +1213,29 @@ func (dw *DashboardWorker) addBackground(dashboard *m.Dashboard, bag *m.Dashboar
 }
 
 func (dw *DashboardWorker) addDeploymentSummaryWidget(dashboard *m.Dashboard, bag *m.DashboardBag) (*m.Dashboard, error) {
-	widgetList, err, exists := dw.loadWidgetTemplate(TIER_SUMMARY)
-	fmt.Printf("Loaded tier summary  %v. %t. Num widgets: %d\n", err, exists, len(*widgetList))
+	return dw.addSummaryWidgets(dashboard, bag, dw.tierSummaryTemplate(bag))
+}
+
+//daemon sets get the per-node distribution of pods. Workloads that are not instrumented get the widgets without APM entities
+func (dw *DashboardWorker) tierSummaryTemplate(bag *m.DashboardBag) string {
+	if bag.OwnerKind == "DaemonSet" {
+		return DAEMON_SUMMARY
+	}
+	if bag.AppID > 0 && bag.TierID > 0 {
+		return TIER_SUMMARY
+	}
+	return WORKLOAD_SUMMARY
+}
+
+func (dw *DashboardWorker) addSummaryWidgets(dashboard *m.Dashboard, bag *m.DashboardBag, templateFile string) (*m.Dashboard, error) {
+	widgetList, err, exists := dw.loadWidgetTemplate(templateFile)
 	if err != nil && exists {
-		return nil, fmt.Errorf("Tier summary template exists, but cannot be loaded. %v\n", err)
+		return nil, fmt.Errorf("Summary template %s exists, but cannot be loaded. %v\n", templateFile, err)
 	}
 	if !exists {
-		return nil, fmt.Errorf("Tier summary template does not exist, skipping dashboard for deployment %s/%s. %v\n", bag.Namespace, bag.TierName, err)
+		return nil, fmt.Errorf("Summary template %s does not exist, skipping dashboard %s. %v\n", templateFile, dashboard.Name, err)
 	}
-	fmt.Printf("Adding tier summary to dash %s\n", dashboard.Name)
+	dw.Logger.Debugf("Adding summary %s to dash %s. Num widgets: %d", templateFile, dashboard.Name, len(*widgetList))
 	var maxY float64 = 0
 	for _, widget := range *widgetList {
 		widget["id"] = 0
@@ -860,6 +1252,10 @@ func (dw *DashboardWorker) addDeploymentSummaryWidget(dashboard *m.Dashboard, ba
 		if ok && widgetTitle != nil && strings.Contains(widgetTitle.(string), "%APP_TIER_NAME%") {
 			widget["title"] = strings.Replace(widgetTitle.(string), "%APP_TIER_NAME%", bag.TierName, 1)
 		}
+		widgetText, ok := widget["text"]
+		if ok && widgetText != nil {
+			widget["text"] = dw.replaceTextPlaceholders(widgetText.(string), bag)
+		}
 		appID, okApp := widget["applicationId"]
 		if okApp && appID != nil && strings.Contains(appID.(string), "%APP_ID%") {
 			widget["applicationId"] = bag.AppID
@@ -897,7 +1293,7 @@ func (dw *DashboardWorker) addDeploymentSummaryWidget(dashboard *m.Dashboard, ba
 	//adjust the background height
 	dashboard.Widgets[0]["height"] = dashboard.Height
 	fmt.Printf("Adjusted background dimensions %.0f x %.0f\n", dashboard.Widgets[0]["width"], dashboard.Widgets[0]["height"])
-	fmt.Printf("Added tier summary to to dash %s\n", dashboard.Name)
+	dw.Logger.Debugf("Added summary to dash %s", dashboard.Name)
 	return dashboard, nil
 }
 
@@ -935,6 +1331,14 @@ func (dw *DashboardWorker) updateMetricName(expTemplate map[string]interface{},
 		metricPath = fmt.Sprintf(rawPath, dw.Bag.TierName, dashBag.Namespace, dashBag.TierName)
 	}
 
+	if dashBag.Type == m.Namespace {
+		metricPath = fmt.Sprintf(rawPath, dw.Bag.TierName, dashBag.Namespace)
+	}
+
+	if dashBag.Type == m.Node {
+		metricPath = fmt.Sprintf(rawPath, dw.Bag.TierName, dashBag.NodeName)
+	}
+
 	metricID, err := dw.AppdController.GetMetricID(dw.Bag.AppID, metricPath)
 	if err != nil {
 		return fmt.Errorf("Cannot get metric ID for %s. %v\n", metricPath, err)
@@ -0,0 +1,224 @@
+package workers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	m "github.com/appdynamics/cluster-agent/models"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestAddToDashboardBag(t *testing.T) {
+	pw := &PodWorker{}
+	bag := &m.AppDBag{AppName: "cluster", AppID: 7, TierID: 8, NodeID: 9}
+	dash := make(map[string]m.DashboardBag)
+
+	api := m.PodSchema{Name: "api-1", Namespace: "shop", NodeName: "node-1"}
+	web := m.PodSchema{Name: "web-1", Namespace: "shop", NodeName: "node-2"}
+	pw.addToDashboardBag(dash, m.Namespace, "shop", &api, bag)
+	pw.addToDashboardBag(dash, m.Namespace, "shop", &web, bag)
+	pw.addToDashboardBag(dash, m.Node, "node-1", &api, bag)
+	//namespaces and nodes without pods
+	pw.addToDashboardBag(dash, m.Namespace, "empty", nil, bag)
+	pw.addToDashboardBag(dash, m.Node, "node-3", nil, bag)
+
+	if len(dash) != 4 {
+		t.Fatalf("dashboards = %d, want 4", len(dash))
+	}
+	ns := dash["namespace:shop"]
+	if ns.Type != m.Namespace || ns.Namespace != "shop" || len(ns.Pods) != 2 {
+		t.Errorf("namespace dashboard %s/%s with %d pods", ns.Type, ns.Namespace, len(ns.Pods))
+	}
+	if ns.ClusterName != "cluster" || ns.ClusterAppID != 7 || ns.ClusterTierID != 8 || ns.ClusterNodeID != 9 {
+		t.Errorf("namespace dashboard is not linked to the cluster application: %+v", ns)
+	}
+	node := dash["node:node-1"]
+	if node.Type != m.Node || node.NodeName != "node-1" || len(node.Pods) != 1 || node.Pods[0].Name != "api-1" {
+		t.Errorf("node dashboard %s/%s with pods %v", node.Type, node.NodeName, node.Pods)
+	}
+	if empty := dash["namespace:empty"]; empty.Pods == nil || len(empty.Pods) != 0 {
+		t.Errorf("namespace without pods has pods %v", empty.Pods)
+	}
+	if _, ok := dash["node:node-3"]; !ok {
+		t.Errorf("node without pods has no dashboard")
+	}
+}
+
+func TestScopeDashboardText(t *testing.T) {
+	dw, _, _ := newTestDashboardWorker(t)
+	dw.Bag.DashboardSuffix = "SUMMARY"
+	mi := int64(1024 * 1024)
+	pods := []m.PodSchema{
+		{Name: "idle", Namespace: "shop", CpuUse: 5, MemUse: 10 * mi},
+		{Name: "api", Namespace: "shop", CpuUse: 400, MemUse: 256 * mi},
+		{Name: "cache", Namespace: "shop", CpuUse: 400, MemUse: 512 * mi},
+		{Name: "db", Namespace: "data", CpuUse: 900, MemUse: 2048 * mi},
+		{Name: "web", Namespace: "shop", CpuUse: 50, MemUse: 64 * mi},
+		{Name: "worker", Namespace: "shop", CpuUse: 20, MemUse: 32 * mi},
+	}
+
+	ns := m.NewDashboardBagNamespace("shop")
+	ns.Pods = pods
+	if name := dw.dashboardName(&ns); name != "cluster-Namespace-shop-SUMMARY" {
+		t.Errorf("namespace dashboard name = %q", name)
+	}
+	text := dw.replaceTextPlaceholders("Namespace %NAMESPACE%\n%TOP_CONSUMERS%", &ns)
+	want := "Namespace shop\n" +
+		"1. db  CPU: 900m  Memory: 2048Mi\n" +
+		"2. cache  CPU: 400m  Memory: 512Mi\n" +
+		"3. api  CPU: 400m  Memory: 256Mi\n" +
+		"4. web  CPU: 50m  Memory: 64Mi\n" +
+		"5. worker  CPU: 20m  Memory: 32Mi\n"
+	if text != want {
+		t.Errorf("namespace text =\n%s\nwant\n%s", text, want)
+	}
+
+	node := m.NewDashboardBagNode("node-1")
+	node.Pods = pods[:2]
+	if name := dw.dashboardName(&node); name != "cluster-Node-node-1-SUMMARY" {
+		t.Errorf("node dashboard name = %q", name)
+	}
+	//pods of a node come from different namespaces
+	text = dw.replaceTextPlaceholders("Node %NODE_NAME%: %TOP_CONSUMERS%", &node)
+	if want := "Node node-1: 1. shop/api  CPU: 400m  Memory: 256Mi\n2. shop/idle  CPU: 5m  Memory: 10Mi\n"; text != want {
+		t.Errorf("node text = %q, want %q", text, want)
+	}
+}
+
+func TestUpdateScopeDashboard(t *testing.T) {
+	dw, stub, _ := newTestDashboardWorker(t)
+	dw.Bag.DashboardSuffix = "SUMMARY"
+	bag := m.NewDashboardBagNamespace("shop")
+
+	err := dw.updateScopeDashboard(&bag)
+	if err == nil || !strings.Contains(err.Error(), "fully registered") {
+		t.Errorf("dashboard of an unregistered application: error = %v", err)
+	}
+	if requests := stub.takeRequests(); len(requests) != 0 {
+		t.Errorf("requests before the application is registered = %v", requests)
+	}
+
+	//the namespace template of the worker is used
+	bag.ClusterAppID, bag.ClusterTierID = 7, 8
+	stub.responses["GET restui/dashboards/getAllDashboardsByType/false"] = `[{"id": 3, "version": 2, "name": "cluster-Namespace-shop-SUMMARY"}]`
+	if err := dw.updateScopeDashboard(&bag); err != nil {
+		t.Fatalf("Unable to update the namespace dashboard: %v", err)
+	}
+	saved := stub.bodies["POST restui/dashboards/updateDashboard"]
+	if len(saved) != 1 || !strings.Contains(saved[0], `"name":"cluster-Namespace-shop-SUMMARY"`) || !strings.Contains(saved[0], "Pods of shop") {
+		t.Errorf("saved namespace dashboard = %v", saved)
+	}
+}
+
+func TestTierSummaryTemplate(t *testing.T) {
+	dw, _, _ := newTestDashboardWorker(t)
+
+	instrumented := m.NewDashboardBagTier("shop", "api", []m.PodSchema{})
+	instrumented.OwnerKind, instrumented.ClusterAppID, instrumented.AppID, instrumented.TierID = "Deployment", 7, 11, 12
+	if summary := dw.tierSummaryTemplate(&instrumented); summary != TIER_SUMMARY {
+		t.Errorf("instrumented deployment summary = %s", summary)
+	}
+	//the node of the tier is not registered yet
+	if err := dw.validateTierDashboardBag(&instrumented); err == nil || !strings.Contains(err.Error(), "Node ID") {
+		t.Errorf("instrumented deployment without a node: error = %v", err)
+	}
+
+	workload := m.NewDashboardBagTier("shop", "worker", []m.PodSchema{})
+	workload.OwnerKind, workload.ClusterAppID = "StatefulSet", 7
+	if summary := dw.tierSummaryTemplate(&workload); summary != WORKLOAD_SUMMARY {
+		t.Errorf("stateful set summary = %s", summary)
+	}
+	if err := dw.validateTierDashboardBag(&workload); err != nil {
+		t.Errorf("workloads that are not instrumented do not need APM entities: %v", err)
+	}
+
+	daemons := m.NewDashboardBagTier("logging", "fluentd", []m.PodSchema{
+		{Name: "fluentd-b", NodeName: "node-2", Phase: "Running", PodRestarts: 1},
+		{Name: "fluentd-a", NodeName: "node-1", Phase: "Running", PodRestarts: 2},
+		{Name: "fluentd-c", NodeName: "node-2", Phase: "Pending", PodRestarts: 3},
+		{Name: "fluentd-d", Phase: "Pending"},
+	})
+	daemons.OwnerKind, daemons.AppID, daemons.TierID = "DaemonSet", 11, 12
+	if summary := dw.tierSummaryTemplate(&daemons); summary != DAEMON_SUMMARY {
+		t.Errorf("instrumented daemon set summary = %s", summary)
+	}
+	if err := dw.validateTierDashboardBag(&daemons); err == nil || strings.Contains(err.Error(), "APM") {
+		t.Errorf("daemon set without the agent application: error = %v", err)
+	}
+	text := dw.replaceTextPlaceholders("%OWNER_KIND% %APP_TIER_NAME%\n%NODE_DISTRIBUTION%", &daemons)
+	want := "DaemonSet fluentd\n" +
+		"(not scheduled): 1 pods, 0 running, 0 restarts\n" +
+		"node-1: 1 pods, 1 running, 2 restarts\n" +
+		"node-2: 2 pods, 1 running, 4 restarts\n"
+	if text != want {
+		t.Errorf("daemon set text =\n%s\nwant\n%s", text, want)
+	}
+}
+
+func TestUpdateNodeHeatDashboard(t *testing.T) {
+	l := log.New()
+	l.Out = ioutil.Discard
+	bag := &m.AppDBag{AppName: "cluster", TierName: "ClusterAgent", DashboardSuffix: "SUMMARY", DashboardTemplatePath: "../templates/cluster-template.json",
+		OverconsumptionThreshold: 80, NodesToDashboard: []string{"worker-1"}}
+	stub := newControllerStub(t, bag)
+	stub.responses["GET restui/analyticsSavedSearches/getAllAnalyticsSavedSearches"] = `[]`
+	stub.responses["GET restui/dashboards/getAllDashboardsByType/false"] = `[
+		{"id": 21, "version": 1, "name": "Cluster-Nodes-cluster-SUMMARY"},
+		{"id": 22, "name": "cluster-Node-worker-1-SUMMARY"}]`
+	dw := NewDashboardWorker(bag, l, nil)
+
+	nodeMap := map[string]m.NodeHeatNode{
+		"worker-1": {Nodename: "worker-1", Role: "worker", Ready: true, CpuCapacity: 1000, MemCapacity: 1000, CpuUse: 100, MemUse: 100, Pods: 3},
+		"worker-2": {Nodename: "worker-2", Role: "worker", Ready: true, CpuCapacity: 1000, MemCapacity: 1000, CpuUse: 900, MemUse: 100, Pods: 4, PodsNotRunning: 1},
+		"master-1": {Nodename: "master-1", Role: "master", Ready: false, Pods: 1},
+	}
+	heat := m.NewDashboardBagNodeHeat(nodeMap)
+	if err := dw.updateNodeHeatDashboard(&heat); err == nil {
+		t.Errorf("dashboard of an unregistered application was built")
+	}
+
+	heat.ClusterAppID, heat.ClusterTierID = 7, 8
+	if err := dw.updateNodeHeatDashboard(&heat); err != nil {
+		t.Fatalf("Unable to build the node heat map: %v", err)
+	}
+	listed := 0
+	for _, r := range stub.takeRequests() {
+		if r == "GET restui/dashboards/getAllDashboardsByType/false" {
+			listed++
+		}
+	}
+	//one lookup of the heat map dashboard and one of the node dashboards for all tiles
+	if listed != 2 {
+		t.Errorf("dashboard list loaded %d times, want 2", listed)
+	}
+
+	var saved m.Dashboard
+	if err := json.Unmarshal([]byte(stub.bodies["POST restui/dashboards/updateDashboard"][0]), &saved); err != nil {
+		t.Fatalf("Unable to read the saved dashboard: %v", err)
+	}
+	if saved.ID != 21 || saved.Name != "Cluster-Nodes-cluster-SUMMARY" {
+		t.Errorf("saved dashboard %.0f %s", saved.ID, saved.Name)
+	}
+	//backgrounds, three tiles sorted by role and name, and the title
+	if len(saved.Widgets) != 6 {
+		t.Fatalf("saved %d widgets, want 6", len(saved.Widgets))
+	}
+	master, worker1, worker2 := saved.Widgets[2], saved.Widgets[3], saved.Widgets[4]
+	if master["text"] != "1" || master["backgroundColor"] != float64(13369344) || !strings.Contains(master["description"].(string), "Not ready") {
+		t.Errorf("tile of the master that is not ready: %v, %v, %q", master["text"], master["backgroundColor"], master["description"])
+	}
+	if worker1["backgroundColor"] != float64(34021) || !strings.HasSuffix(worker1["drillDownUrl"].(string), "dashboard=22") {
+		t.Errorf("tile of the healthy worker: %v, drill-down %v", worker1["backgroundColor"], worker1["drillDownUrl"])
+	}
+	if worker2["backgroundColor"] != float64(10040319) || worker2["borderEnabled"] != true || worker2["text"] != "4" {
+		t.Errorf("tile of the busy worker: %v, border %v, text %v", worker2["backgroundColor"], worker2["borderEnabled"], worker2["text"])
+	}
+	if !(master["x"].(float64) < worker1["x"].(float64) && worker1["x"].(float64) < worker2["x"].(float64)) {
+		t.Errorf("tiles are not laid out left to right: %v, %v, %v", master["x"], worker1["x"], worker2["x"])
+	}
+	if title := saved.Widgets[5]["text"].(string); !strings.HasPrefix(title, "Nodes of cluster") || !strings.Contains(title, "above 80%") {
+		t.Errorf("title = %q", title)
+	}
+}
@@ -17,13 +17,13 @@ import (
 	instr "github.com/appdynamics/cluster-agent/instrumentation"
 	m "github.com/appdynamics/cluster-agent/models"
 	"github.com/appdynamics/cluster-agent/utils"
+	w "github.com/appdynamics/cluster-agent/watchers"
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/retry"
@@ -31,7 +31,7 @@ import (
 )
 
 type DeployWorker struct {
-	informer       cache.SharedIndexInformer
+	informer       *w.NamespacedInformer
 	Client         *kubernetes.Clientset
 	ConfigManager  *config.MutexConfigManager
 	SummaryMap     map[string]m.ClusterDeployMetrics
@@ -39,34 +39,29 @@ type DeployWorker struct {
 	AppdController *app.ControllerClient
 	PendingCache   []string
 	FailedCache    map[string]m.AttachStatus
+	Rollouts       map[string]m.RolloutSchema
+	RolloutQueue   []m.RolloutSchema
+	Changes        *ChangeRecorder
+	Spread         *SpreadChecker
+	SpreadCache    map[string]string //spread of the deployments in the last snapshots
 	Logger         *log.Logger
 }
 
-func NewDeployWorker(client *kubernetes.Clientset, cm *config.MutexConfigManager, controller *app.ControllerClient, l *log.Logger) DeployWorker {
+func NewDeployWorker(client *kubernetes.Clientset, cm *config.MutexConfigManager, im *w.InformerManager, controller *app.ControllerClient, changes *ChangeRecorder, l *log.Logger) DeployWorker {
 	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
 	dw := DeployWorker{Client: client, ConfigManager: cm, SummaryMap: make(map[string]m.ClusterDeployMetrics), WQ: queue,
-		AppdController: controller, PendingCache: []string{}, FailedCache: make(map[string]m.AttachStatus), Logger: l}
+		AppdController: controller, PendingCache: []string{}, FailedCache: make(map[string]m.AttachStatus),
+		Rollouts: make(map[string]m.RolloutSchema), RolloutQueue: []m.RolloutSchema{}, Changes: changes,
+		SpreadCache: make(map[string]string), Logger: l}
 	cm.SubscribeToInstrumentationUpdates(dw.uninstrument)
-	dw.initDeployInformer(client)
+	dw.initDeployInformer(im)
 	return dw
 }
 
-func (nw *DeployWorker) initDeployInformer(client *kubernetes.Clientset) cache.SharedIndexInformer {
-	i := cache.NewSharedIndexInformer(
-		&cache.ListWatch{
-			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
-				return client.AppsV1().Deployments(metav1.NamespaceAll).List(options)
-			},
-			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
-				return client.AppsV1().Deployments(metav1.NamespaceAll).Watch(options)
-			},
-		},
-		&appsv1.Deployment{},
-		0,
-		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
-	)
-
-	i.AddEventHandler(cache.ResourceEventHandlerFuncs{
+func (nw *DeployWorker) initDeployInformer(im *w.InformerManager) *w.NamespacedInformer {
+	i := im.NewInformer("deployments", func(factory informers.SharedInformerFactory, namespace string) cache.SharedIndexInformer {
+		return factory.Apps().V1().Deployments().Informer()
+	}, cache.ResourceEventHandlerFuncs{
 		AddFunc:    nw.onNewDeployment,
 		DeleteFunc: nw.onDeleteDeployment,
 		UpdateFunc: nw.onUpdateDeployment,
@@ -110,9 +105,18 @@ func (dw *DeployWorker) HasSynced() bool {
 }
 
 func (pw *DeployWorker) qualifies(p *appsv1.Deployment) bool {
-	return (len((*pw.ConfigManager).Get().NsToMonitor) == 0 ||
-		utils.StringInSlice(p.Namespace, (*pw.ConfigManager).Get().NsToMonitor)) &&
-		!utils.StringInSlice(p.Namespace, (*pw.ConfigManager).Get().NsToMonitorExclude)
+	return utils.NSQualifiesForMonitoring(p.Namespace, (*pw.ConfigManager).Get())
+}
+
+//re-queues the deployments of the namespace when the namespace selector may select or deselect the namespace
+func (dw *DeployWorker) NamespaceLabelsChanged(namespace string) {
+	for _, obj := range dw.informer.GetStore().List() {
+		deployObj := obj.(*appsv1.Deployment)
+		if deployObj.Namespace == namespace && dw.qualifies(deployObj) {
+			record, _ := dw.processObject(deployObj, nil)
+			dw.WQ.Add(&record)
+		}
+	}
 }
 
 func (dw *DeployWorker) onNewDeployment(obj interface{}) {
@@ -124,6 +128,7 @@ func (dw *DeployWorker) onNewDeployment(obj interface{}) {
 
 	deployRecord, _ := dw.processObject(deployObj, nil)
 	dw.WQ.Add(&deployRecord)
+	dw.trackRollout(deployObj, nil, true)
 
 	init, biq, agentRequests := dw.shouldUpdate(deployObj)
 	if init || biq {
@@ -140,6 +145,7 @@ func (dw *DeployWorker) onDeleteDeployment(obj interface{}) {
 	//clean caches
 	utils.RemoveFromSlice(utils.GetDeployKey(deployObj), dw.PendingCache)
 	delete(dw.FailedCache, utils.GetDeployKey(deployObj))
+	dw.clearRollout(deployObj.Namespace, deployObj.Name)
 }
 
 func (dw *DeployWorker) onUpdateDeployment(objOld interface{}, objNew interface{}) {
@@ -151,6 +157,9 @@ func (dw *DeployWorker) onUpdateDeployment(objOld interface{}, objNew interface{
 
 	deployRecord, _ := dw.processObject(deployObj, nil)
 	dw.WQ.Add(&deployRecord)
+	deployOldObj := objOld.(*appsv1.Deployment)
+	dw.trackRollout(deployObj, deployOldObj, false)
+	dw.Changes.RecordChange("Deployment", deployObj, &deployOldObj.Spec.Template, &deployObj.Spec.Template, deployOldObj.Spec.Replicas, deployObj.Spec.Replicas)
 
 	init, biq, agentRequests := dw.shouldUpdate(deployObj)
 	if init || biq {
@@ -197,6 +206,7 @@ func (pw *DeployWorker) startEventQueueWorker(stopCh <-chan struct{}) {
 func (pw *DeployWorker) flushQueue() {
 	bag := (*pw.ConfigManager).Get()
 	bth := pw.AppdController.StartBT("FlushDeploymentDataQueue")
+	pw.flushRollouts()
 	count := pw.WQ.Len()
 	if count > 0 {
 		pw.Logger.Infof("Flushing the queue of %d deployment records\n", count)
@@ -264,15 +274,25 @@ func (pw *DeployWorker) buildAppDMetrics() {
 	pw.SummaryMap = make(map[string]m.ClusterDeployMetrics)
 
 	count := 0
+	spreadCache := make(map[string]string)
 	for _, obj := range pw.informer.GetStore().List() {
 		deployObject := obj.(*appsv1.Deployment)
 		if !pw.qualifies(deployObject) {
 			continue
 		}
 		deploySchema, _ := pw.processObject(deployObject, nil)
+		//spread changes with pod scheduling, not with the deployment spec
+		spreadKey := utils.GetDeployKey(deployObject)
+		spread := deploySchema.ReplicaSpread + deploySchema.SinglePointOfFailure
+		if last, ok := pw.SpreadCache[spreadKey]; ok && last != spread {
+			pw.WQ.Add(&deploySchema)
+		}
+		spreadCache[spreadKey] = spread
+		pw.trackRollout(deployObject, nil, false)
 		pw.summarize(&deploySchema)
 		count++
 	}
+	pw.SpreadCache = spreadCache
 
 	if count == 0 {
 		bag := (*pw.ConfigManager).Get()
@@ -315,6 +335,22 @@ func (pw *DeployWorker) summarize(deployObject *m.DeploySchema) {
 	summary.DeployCollisionCount = summary.DeployCollisionCount + int64(deployObject.CollisionCount)
 	summaryNS.DeployCollisionCount = summaryNS.DeployCollisionCount + int64(deployObject.CollisionCount)
 
+	if rollout, ok := pw.getRollout(deployObject.Namespace, deployObject.Name); ok {
+		if rollout.IsInProgress() {
+			summary.RolloutsInProgress++
+			summaryNS.RolloutsInProgress++
+			if rollout.Stalled {
+				summary.RolloutsStalled++
+				summaryNS.RolloutsStalled++
+			}
+		}
+		//failures are counted in the metrics interval they happened in
+		if rollout.FailedSince(time.Now().Add(-time.Duration(bag.MetricsSyncInterval) * time.Second)) {
+			summary.RolloutsFailed++
+			summaryNS.RolloutsFailed++
+		}
+	}
+
 	pw.SummaryMap[m.ALL] = summary
 	pw.SummaryMap[deployObject.Namespace] = summaryNS
 }
@@ -386,6 +422,13 @@ func (pw *DeployWorker) processObject(d *appsv1.Deployment, old *appsv1.Deployme
 	}
 	deployObject.ReplicasReady = d.Status.ReadyReplicas
 
+	if pw.Spread != nil {
+		if spread, ok := pw.Spread.GetSpread(d.Namespace, "Deployment", d.Name); ok {
+			deployObject.ReplicaSpread = spread.GetSpreadFormatted()
+			deployObject.SinglePointOfFailure = strings.Join(spread.Issues, ",")
+		}
+	}
+
 	return deployObject, changed
 }
 
@@ -0,0 +1,207 @@
+package workers
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	app "github.com/appdynamics/cluster-agent/appd"
+	"github.com/appdynamics/cluster-agent/config"
+	instr "github.com/appdynamics/cluster-agent/instrumentation"
+	m "github.com/appdynamics/cluster-agent/models"
+	"github.com/appdynamics/cluster-agent/utils"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	EVENT_CUSTOM_EVENT_TYPE string        = "K8sEvent"
+	FORWARD_RATE_WINDOW     time.Duration = time.Minute
+	FORWARD_DEDUP_TTL       time.Duration = time.Hour
+)
+
+type forwardedCount struct {
+	Count int32
+	Seen  time.Time
+}
+
+type forwardWindow struct {
+	Start time.Time
+	Count int
+}
+
+type forwardedEvent struct {
+	AppName    string
+	TierName   string
+	Severity   string
+	Summary    string
+	Properties map[string]string
+}
+
+//EventForwarder posts events of pods and deployments as custom events to the AppD application and tier that own the object
+type EventForwarder struct {
+	ConfManager *config.MutexConfigManager
+	Client      *kubernetes.Clientset
+	PodsWorker  *PodWorker
+	Logger      *log.Logger
+	lock        *sync.Mutex
+	forwarded   map[string]forwardedCount
+	windows     map[string]forwardWindow
+	dropped     int
+	queue       []forwardedEvent
+}
+
+func NewEventForwarder(client *kubernetes.Clientset, cm *config.MutexConfigManager, podsWorker *PodWorker, l *log.Logger) *EventForwarder {
+	return &EventForwarder{ConfManager: cm, Client: client, PodsWorker: podsWorker, Logger: l, lock: &sync.Mutex{},
+		forwarded: make(map[string]forwardedCount), windows: make(map[string]forwardWindow), queue: []forwardedEvent{}}
+}
+
+func (ef *EventForwarder) Observe(stopCh <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+	bag := (*ef.ConfManager).Get()
+	ticker := time.NewTicker(time.Duration(bag.SnapshotSyncInterval) * time.Second)
+	for {
+		select {
+		case <-ticker.C:
+			ef.flushQueue()
+		case <-stopCh:
+			ticker.Stop()
+			return
+		}
+	}
+}
+
+//Forward queues the event if it matches the configured categories and its count increased since it was last forwarded
+func (ef *EventForwarder) Forward(e *v1.Event, eventObject *m.EventSchema) {
+	bag := (*ef.ConfManager).Get()
+	if len(bag.ForwardEventCategories) == 0 {
+		return
+	}
+	if !utils.StringInSlice(eventObject.Category, bag.ForwardEventCategories) && !utils.StringInSlice(eventObject.SubCategory, bag.ForwardEventCategories) &&
+		!utils.StringInSlice(eventObject.Reason, bag.ForwardEventCategories) {
+		return
+	}
+
+	uid := string(e.GetUID())
+	count := eventCount(e)
+	if !ef.isNewOccurrence(uid, count) {
+		return
+	}
+
+	namespace := e.InvolvedObject.Namespace
+	var appName, tierName string
+	switch e.InvolvedObject.Kind {
+	case "Pod":
+		appName, tierName = ef.podApp(bag, namespace, e.InvolvedObject.Name)
+	case "Deployment":
+		d, err := ef.Client.AppsV1().Deployments(namespace).Get(e.InvolvedObject.Name, metav1.GetOptions{})
+		if err != nil {
+			ef.Logger.WithFields(log.Fields{"deployment": e.InvolvedObject.Name, "error": err}).Debug("Unable to lookup deployment of the event")
+			return
+		}
+		appName, tierName = workloadApp(bag, d, &d.Spec.Template)
+	default:
+		return
+	}
+	if appName == "" {
+		return
+	}
+
+	ef.lock.Lock()
+	defer ef.lock.Unlock()
+
+	ef.forwarded[uid] = forwardedCount{Count: count, Seen: time.Now()}
+
+	if bag.ForwardEventsPerMinute > 0 {
+		window, ok := ef.windows[appName]
+		if !ok || time.Since(window.Start) > FORWARD_RATE_WINDOW {
+			window = forwardWindow{Start: time.Now(), Count: 0}
+		}
+		if window.Count >= bag.ForwardEventsPerMinute {
+			ef.dropped++
+			return
+		}
+		window.Count++
+		ef.windows[appName] = window
+	}
+
+	summary := fmt.Sprintf("%s %s/%s: %s", e.Reason, e.InvolvedObject.Kind, e.InvolvedObject.Name, e.Message)
+	if count > 1 {
+		summary = fmt.Sprintf("%s (x%d)", summary, count)
+	}
+	props := map[string]string{"namespace": namespace, "kind": e.InvolvedObject.Kind, "name": e.InvolvedObject.Name, "reason": e.Reason,
+		"count": fmt.Sprintf("%d", count), "category": eventObject.Category, "subCategory": eventObject.SubCategory}
+	ef.queue = append(ef.queue, forwardedEvent{AppName: appName, TierName: tierName, Severity: customEventSeverity(eventObject.Severity), Summary: summary, Properties: props})
+}
+
+//resyncs and updates which do not change the count of the event are not forwarded again
+func (ef *EventForwarder) isNewOccurrence(uid string, count int32) bool {
+	ef.lock.Lock()
+	defer ef.lock.Unlock()
+	last, ok := ef.forwarded[uid]
+	return !ok || count > last.Count
+}
+
+//the application of an instrumented pod is identified by the annotation set when the agent associated with the controller
+func (ef *EventForwarder) podApp(bag *m.AppDBag, namespace string, podName string) (string, string) {
+	if ef.PodsWorker == nil {
+		return "", ""
+	}
+	pod, owner, err := ef.PodsWorker.GetCachedPod(namespace, podName)
+	if err != nil || pod == nil {
+		return "", ""
+	}
+	appName := pod.Annotations[instr.APPD_APPID]
+	if appName == "" {
+		appName = pod.Labels[bag.AppDAppLabel]
+	}
+	tierName := pod.Labels[bag.AppDTierLabel]
+	if tierName == "" {
+		tierName = owner
+	}
+	return appName, tierName
+}
+
+func (ef *EventForwarder) flushQueue() {
+	ef.lock.Lock()
+	objList := ef.queue
+	dropped := ef.dropped
+	ef.queue = []forwardedEvent{}
+	ef.dropped = 0
+	for uid, f := range ef.forwarded {
+		if time.Since(f.Seen) > FORWARD_DEDUP_TTL {
+			delete(ef.forwarded, uid)
+		}
+	}
+	ef.lock.Unlock()
+
+	if dropped > 0 {
+		ef.Logger.Warnf("%d events were not forwarded to AppD applications due to the rate limit\n", dropped)
+	}
+	if len(objList) == 0 {
+		return
+	}
+
+	bag := (*ef.ConfManager).Get()
+	rc := app.NewRestClient(bag, ef.Logger)
+	ef.Logger.Infof("Forwarding %d events to AppD applications\n", len(objList))
+	for _, fe := range objList {
+		err := rc.PostCustomEvent(fe.AppName, fe.TierName, EVENT_CUSTOM_EVENT_TYPE, fe.Severity, fe.Summary, fe.Properties)
+		if err != nil {
+			ef.Logger.WithFields(log.Fields{"app": fe.AppName, "error": err}).Warn("Unable to forward event as custom event")
+		}
+	}
+}
+
+func customEventSeverity(severity string) string {
+	switch severity {
+	case m.EVENT_SEVERITY_ERROR:
+		return "ERROR"
+	case m.EVENT_SEVERITY_WARNING:
+		return "WARN"
+	default:
+		return "INFO"
+	}
+}
@@ -0,0 +1,96 @@
+package workers
+
+import (
+	"regexp"
+	"sync"
+
+	"github.com/appdynamics/cluster-agent/config"
+	m "github.com/appdynamics/cluster-agent/models"
+	"github.com/appdynamics/cluster-agent/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+type compiledEventRule struct {
+	Rule  m.EventRule
+	Regex *regexp.Regexp
+}
+
+func (cr *compiledEventRule) matches(eventSchema *m.EventSchema) bool {
+	if len(cr.Rule.Reasons) > 0 && !utils.StringInSlice(eventSchema.Reason, cr.Rule.Reasons) {
+		return false
+	}
+	if cr.Rule.Kind != "" && cr.Rule.Kind != eventSchema.ObjectKind {
+		return false
+	}
+	if cr.Rule.SourceComponent != "" && cr.Rule.SourceComponent != eventSchema.SourceComponent {
+		return false
+	}
+	if cr.Regex != nil && !cr.Regex.MatchString(eventSchema.Message) {
+		return false
+	}
+	return true
+}
+
+//EventCategorizer assigns categories to events using the rules from the config followed by the default rules.
+//Rules are recompiled when the config changes
+type EventCategorizer struct {
+	ConfManager *config.MutexConfigManager
+	Logger      *log.Logger
+	lock        *sync.RWMutex
+	rules       []compiledEventRule
+}
+
+func NewEventCategorizer(cm *config.MutexConfigManager, l *log.Logger) *EventCategorizer {
+	ec := EventCategorizer{ConfManager: cm, Logger: l, lock: &sync.RWMutex{}, rules: []compiledEventRule{}}
+	ec.reload()
+	cm.SubscribeToConfigUpdates(ec.reload)
+	return &ec
+}
+
+func (ec *EventCategorizer) reload() {
+	bag := (*ec.ConfManager).Get()
+	rules := []compiledEventRule{}
+	for _, rule := range append(append([]m.EventRule{}, bag.EventRules...), m.DefaultEventRules()...) {
+		cr := compiledEventRule{Rule: rule}
+		if rule.MessageRegex != "" {
+			regex, err := regexp.Compile(rule.MessageRegex)
+			if err != nil {
+				ec.Logger.WithFields(log.Fields{"regex": rule.MessageRegex, "error": err}).Error("Invalid message regex in event rule. Skipping the rule")
+				continue
+			}
+			cr.Regex = regex
+		}
+		if cr.Rule.Category == "" {
+			cr.Rule.Category = m.EVENT_CATEGORY_INFO
+		}
+		rules = append(rules, cr)
+	}
+	ec.lock.Lock()
+	defer ec.lock.Unlock()
+	ec.rules = rules
+	ec.Logger.WithField("count", len(bag.EventRules)).Debug("Event categorization rules loaded")
+}
+
+//returns category, subcategory and severity of the event
+func (ec *EventCategorizer) Categorize(eventSchema *m.EventSchema) (string, string, string) {
+	ec.lock.RLock()
+	defer ec.lock.RUnlock()
+	for _, cr := range ec.rules {
+		if cr.matches(eventSchema) {
+			severity := cr.Rule.Severity
+			if severity == "" {
+				severity = defaultEventSeverity(eventSchema)
+			}
+			return cr.Rule.Category, cr.Rule.SubCategory, severity
+		}
+	}
+	return m.EVENT_CATEGORY_INFO, "", defaultEventSeverity(eventSchema)
+}
+
+//severity of events without explicit severity follows the event type
+func defaultEventSeverity(eventSchema *m.EventSchema) string {
+	if eventSchema.Type == "Warning" {
+		return m.EVENT_SEVERITY_WARNING
+	}
+	return m.EVENT_SEVERITY_INFO
+}
@@ -0,0 +1,81 @@
+package workers
+
+import (
+	"io/ioutil"
+	"sync"
+	"testing"
+
+	"github.com/appdynamics/cluster-agent/config"
+	m "github.com/appdynamics/cluster-agent/models"
+	log "github.com/sirupsen/logrus"
+)
+
+func newTestCategorizer(rules ...m.EventRule) *EventCategorizer {
+	l := log.New()
+	l.Out = ioutil.Discard
+	cm := config.MutexConfigManager{Conf: &m.AppDBag{EventRules: rules}, Mutex: &sync.Mutex{}, Logger: l}
+	return NewEventCategorizer(&cm, l)
+}
+
+func TestCompiledEventRuleMatches(t *testing.T) {
+	event := m.EventSchema{Reason: "BackOff", ObjectKind: "Pod", SourceComponent: "kubelet", Message: "Back-off pulling image \"nginx:latest\""}
+	tests := []struct {
+		name    string
+		rule    m.EventRule
+		regex   string
+		matches bool
+	}{
+		{"empty rule", m.EventRule{}, "", true},
+		{"reason", m.EventRule{Reasons: []string{"Failed", "BackOff"}}, "", true},
+		{"other reason", m.EventRule{Reasons: []string{"Failed"}}, "", false},
+		{"kind", m.EventRule{Reasons: []string{"BackOff"}, Kind: "Pod"}, "", true},
+		{"other kind", m.EventRule{Reasons: []string{"BackOff"}, Kind: "Node"}, "", false},
+		{"source", m.EventRule{SourceComponent: "kubelet"}, "", true},
+		{"other source", m.EventRule{SourceComponent: "default-scheduler"}, "", false},
+		{"message", m.EventRule{Reasons: []string{"BackOff"}}, "pulling image", true},
+		{"other message", m.EventRule{Reasons: []string{"BackOff"}}, "restarting failed container", false},
+	}
+	for _, tt := range tests {
+		cr := newTestCategorizer(m.EventRule{Reasons: tt.rule.Reasons, Kind: tt.rule.Kind, SourceComponent: tt.rule.SourceComponent,
+			MessageRegex: tt.regex}).rules[0]
+		if matches := cr.matches(&event); matches != tt.matches {
+			t.Errorf("%s: matches = %t, want %t", tt.name, matches, tt.matches)
+		}
+	}
+}
+
+func TestCategorize(t *testing.T) {
+	ec := newTestCategorizer(
+		m.EventRule{Reasons: []string{"BackOff"}, MessageRegex: "pulling image", Category: m.EVENT_CATEGORY_ERROR, SubCategory: "registry"},
+		m.EventRule{Reasons: []string{"Scheduled"}, SubCategory: "scheduling"},
+		m.EventRule{Reasons: []string{"Pulled"}, MessageRegex: "(", Category: m.EVENT_CATEGORY_ERROR},
+		m.EventRule{Kind: "HorizontalPodAutoscaler", Category: m.EVENT_CATEGORY_INFO, SubCategory: "autoscaling", Severity: m.EVENT_SEVERITY_WARNING},
+	)
+	tests := []struct {
+		name        string
+		event       m.EventSchema
+		category    string
+		subCategory string
+		severity    string
+	}{
+		{"configured rule first", m.EventSchema{Reason: "BackOff", Type: "Warning", Message: "Back-off pulling image \"nginx\""},
+			m.EVENT_CATEGORY_ERROR, "registry", m.EVENT_SEVERITY_WARNING},
+		{"default rule", m.EventSchema{Reason: "BackOff", Type: "Warning", Message: "Back-off restarting failed container"},
+			m.EVENT_CATEGORY_ERROR, "pod", m.EVENT_SEVERITY_ERROR},
+		{"default category", m.EventSchema{Reason: "Scheduled", Type: "Normal"},
+			m.EVENT_CATEGORY_INFO, "scheduling", m.EVENT_SEVERITY_INFO},
+		{"invalid regex skipped", m.EventSchema{Reason: "Pulled", Type: "Normal", Message: "Successfully pulled image"},
+			m.EVENT_CATEGORY_INFO, "", m.EVENT_SEVERITY_INFO},
+		{"explicit severity", m.EventSchema{Reason: "SuccessfulRescale", ObjectKind: "HorizontalPodAutoscaler", Type: "Normal"},
+			m.EVENT_CATEGORY_INFO, "autoscaling", m.EVENT_SEVERITY_WARNING},
+		{"no matching rule", m.EventSchema{Reason: "Unknown", Type: "Warning"},
+			m.EVENT_CATEGORY_INFO, "", m.EVENT_SEVERITY_WARNING},
+	}
+	for _, tt := range tests {
+		category, subCategory, severity := ec.Categorize(&tt.event)
+		if category != tt.category || subCategory != tt.subCategory || severity != tt.severity {
+			t.Errorf("%s: Categorize = (%q, %q, %q), want (%q, %q, %q)", tt.name, category, subCategory, severity,
+				tt.category, tt.subCategory, tt.severity)
+		}
+	}
+}
@@ -3,7 +3,6 @@ package workers
 import (
 	"encoding/json"
 	"fmt"
-	"strings"
 	"sync"
 	"time"
 
@@ -12,14 +11,13 @@ import (
 	app "github.com/appdynamics/cluster-agent/appd"
 	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
 
 	m "github.com/appdynamics/cluster-agent/models"
 
 	"github.com/appdynamics/cluster-agent/config"
 	"github.com/appdynamics/cluster-agent/utils"
+	w "github.com/appdynamics/cluster-agent/watchers"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 )
@@ -28,50 +26,39 @@ var lockNSCache = sync.RWMutex{}
 var lockTierCache = sync.RWMutex{}
 
 type EventWorker struct {
-	informer       cache.SharedIndexInformer
+	informer       *w.NamespacedInformer
 	Client         *kubernetes.Clientset
 	ConfigManager  *config.MutexConfigManager
 	SummaryMap     map[string]m.ClusterEventMetrics
 	WQ             workqueue.RateLimitingInterface
 	AppdController *app.ControllerClient
 	PodsWorker     *PodWorker
+	Categorizer    *EventCategorizer
+	Forwarder      *EventForwarder
 	Logger         *log.Logger
 }
 
-func NewEventWorker(client *kubernetes.Clientset, cm *config.MutexConfigManager, appdController *app.ControllerClient, podsWorker *PodWorker, l *log.Logger) EventWorker {
+func NewEventWorker(client *kubernetes.Clientset, cm *config.MutexConfigManager, im *w.InformerManager, appdController *app.ControllerClient, podsWorker *PodWorker, l *log.Logger) EventWorker {
 	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
 	ew := EventWorker{Client: client, ConfigManager: cm,
-		AppdController: appdController, SummaryMap: make(map[string]m.ClusterEventMetrics), WQ: queue, PodsWorker: podsWorker, Logger: l}
-	ew.informer = ew.initInformer(client)
+		AppdController: appdController, SummaryMap: make(map[string]m.ClusterEventMetrics), WQ: queue, PodsWorker: podsWorker, Categorizer: NewEventCategorizer(cm, l),
+		Forwarder: NewEventForwarder(client, cm, podsWorker, l), Logger: l}
+	ew.informer = ew.initInformer(im)
 	return ew
 }
 
-func (ew *EventWorker) initInformer(client *kubernetes.Clientset) cache.SharedIndexInformer {
-	i := cache.NewSharedIndexInformer(
-		&cache.ListWatch{
-			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
-				return client.CoreV1().Events(metav1.NamespaceAll).List(options)
-			},
-			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
-				return client.CoreV1().Events(metav1.NamespaceAll).Watch(options)
-			},
-		},
-		&v1.Event{},
-		0,
-		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
-	)
-
-	i.AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc: ew.onNewEvent,
+func (ew *EventWorker) initInformer(im *w.InformerManager) *w.NamespacedInformer {
+	i := im.NewInformer("events", w.EventInformer, cache.ResourceEventHandlerFuncs{
+		AddFunc:    ew.onNewEvent,
+		UpdateFunc: ew.onUpdateEvent,
 	})
+
 	return i
 }
 
 func (pw *EventWorker) qualifies(p *v1.Event) bool {
 	bag := (*pw.ConfigManager).Get()
-	return (len(bag.NsToMonitor) == 0 ||
-		utils.StringInSlice(p.Namespace, bag.NsToMonitor)) &&
-		!utils.StringInSlice(p.Namespace, bag.NsToMonitorExclude)
+	return utils.NSQualifiesForMonitoring(p.Namespace, bag)
 }
 
 func (ew *EventWorker) onNewEvent(obj interface{}) {
@@ -82,6 +69,41 @@ func (ew *EventWorker) onNewEvent(obj interface{}) {
 	//	fmt.Printf("Received event: %s %s %s\n", eventObj.Namespace, eventObj.Message, eventObj.Reason)
 	eventRecord := ew.processObject(eventObj)
 	ew.WQ.Add(&eventRecord)
+	ew.Forwarder.Forward(eventObj, &eventRecord)
+}
+
+//repeated occurrences of the same event only increase its count
+func (ew *EventWorker) onUpdateEvent(objOld interface{}, objNew interface{}) {
+	eventObj := objNew.(*v1.Event)
+	eventOld := objOld.(*v1.Event)
+	if !ew.qualifies(eventObj) || !eventRepeated(eventOld, eventObj) {
+		return
+	}
+	eventRecord := ew.processObject(eventObj)
+	ew.Forwarder.Forward(eventObj, &eventRecord)
+}
+
+//true if the event occurred again since the old version.
+//Events recorded through events.k8s.io keep the count at 0 and count the repeats in the series
+func eventRepeated(old *v1.Event, e *v1.Event) bool {
+	if e.Count > old.Count {
+		return true
+	}
+	if e.Series == nil {
+		return false
+	}
+	if old.Series == nil {
+		return true
+	}
+	return e.Series.Count > old.Series.Count || e.Series.LastObservedTime.Time.After(old.Series.LastObservedTime.Time)
+}
+
+//number of occurrences of the event, either counted by the event itself or by its series
+func eventCount(e *v1.Event) int32 {
+	if e.Series != nil && e.Series.Count > e.Count {
+		return e.Series.Count
+	}
+	return e.Count
 }
 
 func (ew *EventWorker) Observe(stopCh <-chan struct{}, wg *sync.WaitGroup) {
@@ -100,6 +122,9 @@ func (ew *EventWorker) Observe(stopCh <-chan struct{}, wg *sync.WaitGroup) {
 	wg.Add(1)
 	go ew.startEventQueueWorker(stopCh)
 
+	wg.Add(1)
+	go ew.Forwarder.Observe(stopCh, wg)
+
 	<-stopCh
 }
 
@@ -278,7 +303,7 @@ func (ew *EventWorker) processObject(e *v1.Event) m.EventSchema {
 		eventObject.ClusterName = bag.AppName
 	}
 
-	eventObject.Count = e.Count
+	eventObject.Count = eventCount(e)
 	eventObject.CreationTimestamp = e.CreationTimestamp.Time
 	if e.DeletionTimestamp != nil {
 		eventObject.DeletionTimestamp = e.DeletionTimestamp.Time
@@ -302,20 +327,28 @@ func (ew *EventWorker) processObject(e *v1.Event) m.EventSchema {
 
 	eventObject.Reason = e.Reason
 	eventObject.Type = e.Type
-	eventObject.Count = e.Count
 	eventObject.ResourceVersion = e.GetResourceVersion()
 	eventObject.SelfLink = e.GetSelfLink()
 	eventObject.SourceComponent = e.Source.Component
 	eventObject.SourceHost = e.Source.Host
 
-	cat, sub := ew.GetEventCategory(&eventObject)
+	cat, sub, severity := ew.GetEventCategory(&eventObject)
 	eventObject.Category = cat
 	eventObject.SubCategory = sub
+	eventObject.Severity = severity
 
 	if ew.PodsWorker != nil && eventObject.ObjectKind == "Pod" && eventObject.Category == "error" {
 		ew.PodsWorker.OnPodErrorEvent(eventObject.ObjectName, eventObject)
 	}
 
+	if ew.PodsWorker != nil && eventObject.ObjectKind == "Pod" && (eventObject.Reason == "FailedScheduling" || eventObject.Reason == "Scheduled") {
+		ew.PodsWorker.OnPodSchedulingEvent(e.InvolvedObject.Namespace, eventObject.ObjectName, eventObject)
+	}
+
+	if ew.PodsWorker != nil && eventObject.ObjectKind == "Pod" && (eventObject.Reason == "Pulling" || eventObject.Reason == "Pulled") {
+		ew.PodsWorker.OnPodImagePullEvent(e.InvolvedObject.Namespace, eventObject.ObjectName, eventObject)
+	}
+
 	return eventObject
 }
 
@@ -499,188 +532,6 @@ func eventFromPod(podObj *v1.Pod, reason string, message string, eventType strin
 
 }
 
-func (ew *EventWorker) GetEventCategory(eventSchema *m.EventSchema) (string, string) {
-	msg := eventSchema.Message
-	cat := "info"
-	sub := ""
-	switch eventSchema.Reason {
-	case "Created":
-	case "Started":
-	case "Pulling":
-	case "Pulled":
-	case "NodeReady":
-	case "NodeSchedulable":
-	case "VolumeResizeSuccessful":
-	case "FileSystemResizeSuccessful":
-	case "AlreadyMountedVolume":
-	case "SuccessfulDetachVolume":
-	case "SuccessfulAttachVolume":
-	case "SuccessfulMountVolume":
-	case "SuccessfulUnMountVolume":
-	case "Rebooted":
-		break
-	case "NodeAllocatableEnforced":
-		cat = "error"
-		break
-	case "ScalingReplicaSet":
-		if strings.Contains(msg, "Scaled down") {
-			sub = "reduction"
-			cat = "error"
-		} else {
-			cat = "info"
-		}
-		break
-	case "Killing":
-		sub = "reduction"
-		cat = "error"
-		break
-	case "Failed":
-		if strings.Contains(msg, "ImagePullBackOff") || strings.Contains(msg, "image") {
-			sub = "image"
-		} else {
-			sub = "pod"
-		}
-		cat = "error"
-		break
-	case "Preempting":
-		cat = "error"
-		break
-	case "BackOff":
-		if strings.Contains(msg, "image") {
-			sub = "image"
-		}
-		if strings.Contains(msg, "container") {
-			sub = "pod"
-		}
-		cat = "error"
-		break
-	case "FailedCreate":
-		if strings.Contains(msg, "quota") {
-			sub = "quota"
-		} else {
-			sub = "pod"
-		}
-		cat = "error"
-		break
-	case "ExceededGracePeriod":
-	case "FailedKillPod":
-		sub = "pod"
-		cat = "error"
-		break
-	case "FailedCreatePodContainer":
-		sub = "pod"
-		cat = "error"
-		break
-	case "NetworkNotReady":
-	case "InspectFailed":
-		cat = "error"
-		break
-	case "ErrImageNeverPull":
-		sub = "image"
-		cat = "error"
-		break
-	case "NodeNotReady":
-	case "NodeNotSchedulable":
-	case "KubeletSetupFailed":
-		cat = "error"
-		break
-	case "FailedAttachVolume":
-		sub = "storage"
-		cat = "error"
-		break
-	case "FailedDetachVolume":
-		sub = "storage"
-		cat = "error"
-		break
-	case "FailedMount":
-		sub = "storage"
-		cat = "error"
-		break
-	case "FailedBinding":
-		sub = "storage"
-		cat = "error"
-		break
-	case "VolumeResizeFailed":
-		sub = "storage"
-		cat = "error"
-		break
-	case "FileSystemResizeFailed":
-		sub = "storage"
-		cat = "error"
-		break
-	case "FailedUnMount":
-		sub = "storage"
-		cat = "error"
-		break
-	case "FailedMapVolume":
-		sub = "storage"
-		cat = "error"
-		break
-	case "FailedUnmapDevice":
-		sub = "storage"
-		cat = "error"
-		break
-	case "HostPortConflict":
-	case "NodeSelectorMismatching":
-		cat = "error"
-		break
-	case "ContainerGCFailed":
-		sub = "pod"
-		cat = "error"
-		break
-	case "ImageGCFailed":
-		sub = "image"
-		cat = "error"
-		break
-	case "FailedNodeAllocatableEnforcement":
-		cat = "error"
-		break
-	case "UnsupportedMountOption":
-		sub = "storage"
-		cat = "error"
-		break
-	case "SandboxChanged":
-		sub = "pod"
-		cat = "error"
-		break
-	case "FailedCreatePodSandBox":
-		sub = "pod"
-		cat = "error"
-		break
-	case "FailedPodSandBoxStatus":
-		sub = "pod"
-		cat = "error"
-		break
-	case "InvalidDiskCapacity":
-		sub = "storage"
-		cat = "error"
-		break
-	case "FreeDiskSpaceFailed":
-		sub = "storage"
-		cat = "error"
-		break
-	case "Unhealthy":
-		sub = "pod"
-		cat = "error"
-		break
-	case "FailedSync":
-	case "FailedValidation":
-	case "FailedPostStartHook":
-	case "FailedPreStopHook":
-	case "UnfinishedPreStopHook":
-		cat = "error"
-		break
-	case "Evicted":
-		sub = "eviction"
-	case "InsufficientFreeCPU":
-	case "InsufficientFreeMemory":
-	case "FailedDaemonPod":
-	case "NodeHasDiskPressure":
-	case "EvictionThresholdMet":
-	case "ErrorReconciliationRetryTimeout":
-
-		cat = "error"
-		break
-	}
-	return cat, sub
+func (ew *EventWorker) GetEventCategory(eventSchema *m.EventSchema) (string, string, string) {
+	return ew.Categorizer.Categorize(eventSchema)
 }
@@ -0,0 +1,59 @@
+package workers
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestEventRepeatedSeries(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+	//events recorded through events.k8s.io keep the count at 0 while the series grows
+	series := func(count int32, observed time.Time) *v1.Event {
+		e := v1.Event{ObjectMeta: metav1.ObjectMeta{Name: "api-1.16f0", Namespace: "shop", UID: "e1"}, Reason: "BackOff",
+			InvolvedObject: v1.ObjectReference{Kind: "Pod", Name: "api-1", Namespace: "shop"}}
+		if count > 0 {
+			e.Series = &v1.EventSeries{Count: count, LastObservedTime: metav1.NewMicroTime(observed)}
+		}
+		return &e
+	}
+
+	first := series(0, start)
+	second := series(2, start.Add(time.Minute))
+	if !eventRepeated(first, second) {
+		t.Errorf("the start of the series is not a repeat")
+	}
+	third := series(3, start.Add(2*time.Minute))
+	if !eventRepeated(second, third) {
+		t.Errorf("the growing series count is not a repeat")
+	}
+	observed := series(3, start.Add(3*time.Minute))
+	if !eventRepeated(third, observed) {
+		t.Errorf("the later observation of the series is not a repeat")
+	}
+	if eventRepeated(observed, series(3, start.Add(3*time.Minute))) {
+		t.Errorf("an update of the same series is a repeat")
+	}
+	if eventRepeated(first, series(0, start)) {
+		t.Errorf("an update of the event without a count is a repeat")
+	}
+	if count := eventCount(third); count != 3 {
+		t.Errorf("eventCount of the series = %d, want 3", count)
+	}
+
+	//core/v1 events count the repeats themselves
+	legacy := series(0, start)
+	legacy.Count = 4
+	repeated := series(0, start)
+	repeated.Count = 5
+	if !eventRepeated(legacy, repeated) || eventRepeated(repeated, legacy) {
+		t.Errorf("eventRepeated does not follow the count of the event")
+	}
+
+	ew := EventWorker{ConfigManager: newTestCategorizer().ConfManager, Categorizer: newTestCategorizer()}
+	if record := ew.processObject(third); record.Count != 3 {
+		t.Errorf("processObject count of the series = %d, want 3", record.Count)
+	}
+}
@@ -0,0 +1,203 @@
+package workers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	app "github.com/appdynamics/cluster-agent/appd"
+	"github.com/appdynamics/cluster-agent/config"
+	m "github.com/appdynamics/cluster-agent/models"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	HEALTH_RULES_TEMPLATE string = "/health-rules.json"
+	HEALTH_RULES_API      string = "alerting/rest/v1/applications/%d/health-rules"
+)
+
+type healthRuleRef struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+//HealthRuleWorker provisions the health rules from the template on the cluster agent application
+//and keeps them in sync with the thresholds in the config
+type HealthRuleWorker struct {
+	ConfManager *config.MutexConfigManager
+	Logger      *log.Logger
+	lock        *sync.Mutex
+	applied     string
+}
+
+func NewHealthRuleWorker(cm *config.MutexConfigManager, l *log.Logger) *HealthRuleWorker {
+	hw := HealthRuleWorker{ConfManager: cm, Logger: l, lock: &sync.Mutex{}, applied: ""}
+	cm.SubscribeToConfigUpdates(hw.reconcile)
+	return &hw
+}
+
+//Observe retries the provisioning until the ID of the agent application is known and the controller accepts the rules
+func (hw *HealthRuleWorker) Observe(stopCh <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+	bag := (*hw.ConfManager).Get()
+	ticker := time.NewTicker(time.Duration(bag.SnapshotSyncInterval) * time.Second)
+	for {
+		select {
+		case <-ticker.C:
+			hw.reconcile()
+		case <-stopCh:
+			ticker.Stop()
+			return
+		}
+	}
+}
+
+func healthRuleThresholds(bag *m.AppDBag) map[string]int {
+	return map[string]int{"PodFailed": bag.HealthRulePodFailed, "EvictionThreats": bag.HealthRuleEvictionThreats,
+		"MissingDependencies": bag.HealthRuleMissingDeps, "NoConnectivity": bag.HealthRuleNoConnectivity, "PodOverconsume": bag.HealthRulePodOverconsume}
+}
+
+//reconcile creates the missing rules, updates the existing ones and deletes the rules whose threshold was set to 0.
+//Nothing is done if the thresholds did not change since the last successful run
+func (hw *HealthRuleWorker) reconcile() {
+	bag := (*hw.ConfManager).Get()
+	if !bag.HealthRulesEnabled {
+		return
+	}
+	if bag.AppID == 0 {
+		hw.Logger.Debug("Agent application ID is not known yet. Postponing health rule provisioning")
+		return
+	}
+
+	hw.lock.Lock()
+	defer hw.lock.Unlock()
+
+	fingerprint := fmt.Sprintf("%d|%s|%s|%v", bag.AppID, bag.AppName, bag.TierName, healthRuleThresholds(bag))
+	if fingerprint == hw.applied {
+		return
+	}
+
+	rules, disabled, err := hw.loadTemplates(bag)
+	if err != nil {
+		hw.Logger.WithField("error", err).Error("Unable to load health rule templates")
+		return
+	}
+
+	existing, err := hw.loadHealthRules(bag)
+	if err != nil {
+		hw.Logger.WithField("error", err).Error("Unable to load health rules of the agent application")
+		return
+	}
+
+	ok := true
+	for _, rule := range rules {
+		name, _ := rule["name"].(string)
+		err = hw.saveHealthRule(bag, rule, existing[name])
+		if err != nil {
+			hw.Logger.WithFields(log.Fields{"rule": name, "error": err}).Error("Unable to save health rule")
+			ok = false
+		}
+	}
+
+	for _, name := range disabled {
+		if id, exists := existing[name]; exists {
+			err = hw.deleteHealthRule(bag, id)
+			if err != nil {
+				hw.Logger.WithFields(log.Fields{"rule": name, "error": err}).Error("Unable to delete health rule")
+				ok = false
+			}
+		}
+	}
+
+	if ok {
+		hw.applied = fingerprint
+		hw.Logger.WithFields(log.Fields{"provisioned": len(rules), "disabled": len(disabled)}).Info("Health rules are in sync")
+	}
+}
+
+//returns the rules with the placeholders replaced and the names of the rules that are disabled in the config
+func (hw *HealthRuleWorker) loadTemplates(bag *m.AppDBag) ([]map[string]interface{}, []string, error) {
+	templateFile := filepath.Dir(bag.DashboardTemplatePath) + HEALTH_RULES_TEMPLATE
+	data, err := ioutil.ReadFile(templateFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Unable to read health rule template %s. %v", templateFile, err)
+	}
+
+	var templates []json.RawMessage
+	err = json.Unmarshal(data, &templates)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Unable to deserialize health rule template %s. %v", templateFile, err)
+	}
+
+	rules := []map[string]interface{}{}
+	disabled := []string{}
+	thresholds := healthRuleThresholds(bag)
+	for _, t := range templates {
+		s := strings.Replace(string(t), "%APP_NAME%", bag.AppName, -1)
+		s = strings.Replace(s, "%TIER_NAME%", bag.TierName, -1)
+		skip := false
+		for metric, val := range thresholds {
+			placeholder := fmt.Sprintf("\"%%THRESHOLD_%s%%\"", metric)
+			if strings.Contains(s, placeholder) {
+				skip = skip || val <= 0
+				s = strings.Replace(s, placeholder, strconv.Itoa(val), -1)
+			}
+		}
+		var rule map[string]interface{}
+		err = json.Unmarshal([]byte(s), &rule)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Unable to deserialize health rule. %v", err)
+		}
+		if skip {
+			name, _ := rule["name"].(string)
+			disabled = append(disabled, name)
+		} else {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, disabled, nil
+}
+
+func (hw *HealthRuleWorker) loadHealthRules(bag *m.AppDBag) (map[string]int, error) {
+	rc := app.NewRestClient(bag, hw.Logger)
+	data, err := rc.CallAppDController(fmt.Sprintf(HEALTH_RULES_API, bag.AppID), "GET", nil)
+	if err != nil {
+		return nil, err
+	}
+	var list []healthRuleRef
+	err = json.Unmarshal(data, &list)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to deserialize the list of health rules. %v", err)
+	}
+	existing := make(map[string]int)
+	for _, r := range list {
+		existing[r.Name] = r.ID
+	}
+	return existing, nil
+}
+
+//id 0 - new rule
+func (hw *HealthRuleWorker) saveHealthRule(bag *m.AppDBag, rule map[string]interface{}, id int) error {
+	data, err := json.Marshal(rule)
+	if err != nil {
+		return err
+	}
+	rc := app.NewRestClient(bag, hw.Logger)
+	if id > 0 {
+		_, err = rc.CallAppDController(fmt.Sprintf(HEALTH_RULES_API+"/%d", bag.AppID, id), "PUT", data)
+	} else {
+		_, err = rc.CallAppDController(fmt.Sprintf(HEALTH_RULES_API, bag.AppID), "POST", data)
+	}
+	return err
+}
+
+func (hw *HealthRuleWorker) deleteHealthRule(bag *m.AppDBag, id int) error {
+	rc := app.NewRestClient(bag, hw.Logger)
+	_, err := rc.CallAppDController(fmt.Sprintf(HEALTH_RULES_API+"/%d", bag.AppID, id), "DELETE", nil)
+	return err
+}
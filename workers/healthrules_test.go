@@ -0,0 +1,176 @@
+package workers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/appdynamics/cluster-agent/config"
+	m "github.com/appdynamics/cluster-agent/models"
+	log "github.com/sirupsen/logrus"
+)
+
+//controllerStub stands in for the REST API of the controller. It records the requests made after the login
+//with their bodies and answers them with the canned responses keyed by "METHOD path". Unknown requests get an empty object
+type controllerStub struct {
+	lock      sync.Mutex
+	requests  []string
+	bodies    map[string][]string
+	responses map[string]string
+	failing   map[string]bool
+}
+
+func newControllerStub(t *testing.T, bag *m.AppDBag) *controllerStub {
+	stub := &controllerStub{bodies: make(map[string][]string), responses: make(map[string]string), failing: make(map[string]bool)}
+	srv := httptest.NewServer(stub)
+	t.Cleanup(srv.Close)
+	bag.RestAPIUrl = srv.URL + "/controller/"
+	return stub
+}
+
+func (s *controllerStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, "/auth") {
+		http.SetCookie(w, &http.Cookie{Name: "X-CSRF-TOKEN", Value: "token"})
+		http.SetCookie(w, &http.Cookie{Name: "JSESSIONID", Value: "session"})
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	key := r.Method + " " + strings.TrimPrefix(r.URL.Path, "/controller/")
+	s.requests = append(s.requests, key)
+	body, _ := ioutil.ReadAll(r.Body)
+	s.bodies[key] = append(s.bodies[key], string(body))
+	if s.failing[key] {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if resp, ok := s.responses[key]; ok {
+		w.Write([]byte(resp))
+		return
+	}
+	w.Write([]byte("{}"))
+}
+
+//requests made since the last call, sorted
+func (s *controllerStub) takeRequests() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	list := s.requests
+	s.requests = []string{}
+	sort.Strings(list)
+	return list
+}
+
+func TestHealthRuleReconcile(t *testing.T) {
+	l := log.New()
+	l.Out = ioutil.Discard
+	bag := &m.AppDBag{AppName: "cluster", TierName: "ClusterAgent", DashboardTemplatePath: "../templates/cluster-template.json",
+		HealthRulesEnabled: true, HealthRulePodFailed: 3, HealthRuleEvictionThreats: 1, HealthRuleMissingDeps: 1, HealthRulePodOverconsume: 2}
+	stub := newControllerStub(t, bag)
+	stub.responses["GET alerting/rest/v1/applications/7/health-rules"] = `[{"id": 11, "name": "cluster - Failed pods"}, {"id": 12, "name": "cluster - No connectivity"}]`
+	cm := config.MutexConfigManager{Conf: bag, Mutex: &sync.Mutex{}, Logger: l}
+	hw := NewHealthRuleWorker(&cm, l)
+
+	//the application is not registered yet
+	hw.reconcile()
+	if requests := stub.takeRequests(); len(requests) != 0 {
+		t.Fatalf("requests before the application is known = %v", requests)
+	}
+
+	bag.AppID = 7
+	hw.reconcile()
+	want := []string{
+		"DELETE alerting/rest/v1/applications/7/health-rules/12",
+		"GET alerting/rest/v1/applications/7/health-rules",
+		"POST alerting/rest/v1/applications/7/health-rules",
+		"POST alerting/rest/v1/applications/7/health-rules",
+		"POST alerting/rest/v1/applications/7/health-rules",
+		"PUT alerting/rest/v1/applications/7/health-rules/11",
+	}
+	if requests := stub.takeRequests(); strings.Join(requests, "\n") != strings.Join(want, "\n") {
+		t.Errorf("requests of the first run = %v, want %v", requests, want)
+	}
+
+	var rule struct {
+		Name    string
+		Affects struct {
+			AffectedEntities struct {
+				AffectedTiers []string
+			}
+		}
+		EvalCriterias struct {
+			CriticalCriteria struct {
+				Conditions []struct {
+					EvalDetail struct {
+						MetricEvalDetail struct {
+							CompareValue int
+						}
+					}
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal([]byte(stub.bodies["PUT alerting/rest/v1/applications/7/health-rules/11"][0]), &rule); err != nil {
+		t.Fatalf("Unable to read the updated rule: %v", err)
+	}
+	if rule.Name != "cluster - Failed pods" || len(rule.Affects.AffectedEntities.AffectedTiers) != 1 || rule.Affects.AffectedEntities.AffectedTiers[0] != "ClusterAgent" {
+		t.Errorf("updated rule %q affects tiers %v", rule.Name, rule.Affects.AffectedEntities.AffectedTiers)
+	}
+	if conditions := rule.EvalCriterias.CriticalCriteria.Conditions; len(conditions) != 1 || conditions[0].EvalDetail.MetricEvalDetail.CompareValue != 3 {
+		t.Errorf("threshold of the updated rule = %+v, want 3", conditions)
+	}
+
+	//nothing changed
+	hw.reconcile()
+	if requests := stub.takeRequests(); len(requests) != 0 {
+		t.Errorf("requests without config changes = %v", requests)
+	}
+
+	//failed saves are retried on the next run
+	bag.HealthRulePodFailed = 5
+	stub.failing["PUT alerting/rest/v1/applications/7/health-rules/11"] = true
+	hw.reconcile()
+	if requests := stub.takeRequests(); len(requests) != len(want) {
+		t.Errorf("requests after the threshold change = %v", requests)
+	}
+	delete(stub.failing, "PUT alerting/rest/v1/applications/7/health-rules/11")
+	hw.reconcile()
+	if requests := stub.takeRequests(); len(requests) != len(want) {
+		t.Errorf("requests of the retry = %v", requests)
+	}
+	hw.reconcile()
+	if requests := stub.takeRequests(); len(requests) != 0 {
+		t.Errorf("requests after a successful retry = %v", requests)
+	}
+}
+
+func TestHealthRuleTemplates(t *testing.T) {
+	l := log.New()
+	l.Out = ioutil.Discard
+	hw := HealthRuleWorker{Logger: l}
+	bag := &m.AppDBag{AppName: "cluster", TierName: "ClusterAgent", DashboardTemplatePath: "../templates/cluster-template.json",
+		HealthRulePodFailed: 1, HealthRuleEvictionThreats: 1, HealthRuleMissingDeps: 1, HealthRuleNoConnectivity: 1, HealthRulePodOverconsume: 1}
+	rules, disabled, err := hw.loadTemplates(bag)
+	if err != nil {
+		t.Fatalf("loadTemplates error = %v", err)
+	}
+	if len(rules) != 5 || len(disabled) != 0 {
+		t.Errorf("loadTemplates = %d rules, %d disabled, want 5 and 0", len(rules), len(disabled))
+	}
+	for _, rule := range rules {
+		data, _ := json.Marshal(rule)
+		if strings.Contains(string(data), "%") {
+			t.Errorf("placeholders left in rule %s", data)
+		}
+	}
+
+	bag.DashboardTemplatePath = "../missing/cluster-template.json"
+	if _, _, err := hw.loadTemplates(bag); err == nil {
+		t.Errorf("loadTemplates without a template file succeeded")
+	}
+}
@@ -14,21 +14,19 @@ import (
 	"github.com/appdynamics/cluster-agent/config"
 	m "github.com/appdynamics/cluster-agent/models"
 	"github.com/appdynamics/cluster-agent/utils"
+	w "github.com/appdynamics/cluster-agent/watchers"
 
 	app "github.com/appdynamics/cluster-agent/appd"
 	batchTypes "k8s.io/api/batch/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
-	batch "k8s.io/client-go/kubernetes/typed/batch/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 )
 
 type JobsWorker struct {
-	informer       cache.SharedIndexInformer
+	informer       *w.NamespacedInformer
 	Client         *kubernetes.Clientset
 	ConfigManager  *config.MutexConfigManager
 	SummaryMap     map[string]m.ClusterJobMetrics
@@ -38,35 +36,17 @@ type JobsWorker struct {
 	Logger         *log.Logger
 }
 
-func NewJobsWorker(client *kubernetes.Clientset, cm *config.MutexConfigManager, controller *app.ControllerClient, config *rest.Config, l *log.Logger) JobsWorker {
+func NewJobsWorker(client *kubernetes.Clientset, cm *config.MutexConfigManager, im *w.InformerManager, controller *app.ControllerClient, config *rest.Config, l *log.Logger) JobsWorker {
 	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
 	pw := JobsWorker{Client: client, ConfigManager: cm, SummaryMap: make(map[string]m.ClusterJobMetrics), WQ: queue, AppdController: controller, K8sConfig: config, Logger: l}
-	pw.initJobInformer(client)
+	pw.initJobInformer(im)
 	return pw
 }
 
-func (nw *JobsWorker) initJobInformer(client *kubernetes.Clientset) cache.SharedIndexInformer {
-	batchClient, err := batch.NewForConfig(nw.K8sConfig)
-	if err != nil {
-		fmt.Printf("Issues when initializing Batch API client/ %v", err)
-		return nil
-	}
-
-	i := cache.NewSharedIndexInformer(
-		&cache.ListWatch{
-			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
-				return batchClient.Jobs(metav1.NamespaceAll).List(options)
-			},
-			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
-				return batchClient.Jobs(metav1.NamespaceAll).Watch(options)
-			},
-		},
-		&batchTypes.Job{},
-		0,
-		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
-	)
-
-	i.AddEventHandler(cache.ResourceEventHandlerFuncs{
+func (nw *JobsWorker) initJobInformer(im *w.InformerManager) *w.NamespacedInformer {
+	i := im.NewInformer("jobs", func(factory informers.SharedInformerFactory, namespace string) cache.SharedIndexInformer {
+		return factory.Batch().V1().Jobs().Informer()
+	}, cache.ResourceEventHandlerFuncs{
 		AddFunc:    nw.onNewJob,
 		DeleteFunc: nw.onDeleteJob,
 		UpdateFunc: nw.onUpdateJob,
@@ -78,9 +58,18 @@ func (nw *JobsWorker) initJobInformer(client *kubernetes.Clientset) cache.Shared
 
 func (pw *JobsWorker) qualifies(p *batchTypes.Job) bool {
 	bag := (*pw.ConfigManager).Get()
-	return (len(bag.NsToMonitor) == 0 ||
-		utils.StringInSlice(p.Namespace, bag.NsToMonitor)) &&
-		!utils.StringInSlice(p.Namespace, bag.NsToMonitorExclude)
+	return utils.NSQualifiesForMonitoring(p.Namespace, bag)
+}
+
+//re-queues the jobs of the namespace when the namespace selector may select or deselect the namespace
+func (nw *JobsWorker) NamespaceLabelsChanged(namespace string) {
+	for _, obj := range nw.informer.GetStore().List() {
+		jobObj := obj.(*batchTypes.Job)
+		if jobObj.Namespace == namespace && nw.qualifies(jobObj) {
+			record := nw.processObject(jobObj)
+			nw.WQ.Add(&record)
+		}
+	}
 }
 
 func (nw *JobsWorker) onNewJob(obj interface{}) {
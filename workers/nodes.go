@@ -12,54 +12,40 @@ import (
 	"github.com/appdynamics/cluster-agent/config"
 	m "github.com/appdynamics/cluster-agent/models"
 	"github.com/appdynamics/cluster-agent/utils"
+	w "github.com/appdynamics/cluster-agent/watchers"
 
 	app "github.com/appdynamics/cluster-agent/appd"
 	"k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 )
 
 type NodesWorker struct {
-	informer       cache.SharedIndexInformer
+	informer       *w.NamespacedInformer
 	Client         *kubernetes.Clientset
 	ConfigManager  *config.MutexConfigManager
 	SummaryMap     map[string]m.ClusterNodeMetrics
 	WQ             workqueue.RateLimitingInterface
 	AppdController *app.ControllerClient
 	CapacityMap    map[string]m.NodeSchema
+	Metrics        *w.MetricsCollector
+	Informers      *w.InformerManager
 	Logger         *log.Logger
 }
 
 var lockCapacityMap = sync.RWMutex{}
 
-func NewNodesWorker(client *kubernetes.Clientset, cm *config.MutexConfigManager, controller *app.ControllerClient, l *log.Logger) NodesWorker {
+func NewNodesWorker(client *kubernetes.Clientset, cm *config.MutexConfigManager, im *w.InformerManager, mc *w.MetricsCollector, controller *app.ControllerClient, l *log.Logger) NodesWorker {
 	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
 	pw := NodesWorker{Client: client, ConfigManager: cm, SummaryMap: make(map[string]m.ClusterNodeMetrics), WQ: queue, AppdController: controller,
-		CapacityMap: make(map[string]m.NodeSchema), Logger: l}
-	pw.initNodeInformer(client)
+		CapacityMap: make(map[string]m.NodeSchema), Metrics: mc, Informers: im, Logger: l}
+	pw.initNodeInformer(im)
 	return pw
 }
 
-func (nw *NodesWorker) initNodeInformer(client *kubernetes.Clientset) cache.SharedIndexInformer {
-	i := cache.NewSharedIndexInformer(
-		&cache.ListWatch{
-			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
-				return client.CoreV1().Nodes().List(options)
-			},
-			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
-				return client.CoreV1().Nodes().Watch(options)
-			},
-		},
-		&v1.Node{},
-		0,
-		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
-	)
-
-	i.AddEventHandler(cache.ResourceEventHandlerFuncs{
+func (nw *NodesWorker) initNodeInformer(im *w.InformerManager) *w.NamespacedInformer {
+	i := im.NewClusterInformer("nodes", w.NodeInformer, cache.ResourceEventHandlerFuncs{
 		AddFunc:    nw.onNewNode,
 		DeleteFunc: nw.onDeleteNode,
 		UpdateFunc: nw.onUpdateNode,
@@ -71,13 +57,15 @@ func (nw *NodesWorker) initNodeInformer(client *kubernetes.Clientset) cache.Shar
 
 func (pw *NodesWorker) qualifies(p *v1.Node) bool {
 	bag := (*pw.ConfigManager).Get()
-	return (len(bag.NodesToMonitor) == 0 ||
-		utils.StringInSlice(p.Name, bag.NodesToMonitor)) &&
-		!utils.StringInSlice(p.Name, bag.NodesToMonitorExclude)
+	return utils.NodeQualifiesForMonitoring(p.Name, bag)
 }
 
 func (nw *NodesWorker) onNewNode(obj interface{}) {
 	nodeObj := obj.(*v1.Node)
+	if utils.UpdateNodeLabels(nodeObj.Name, nodeObj.Labels) {
+		//pods seen before the node are re-evaluated
+		nw.notifyLabelsChanged(nodeObj)
+	}
 	if !nw.qualifies(nodeObj) {
 		return
 	}
@@ -89,6 +77,7 @@ func (nw *NodesWorker) onNewNode(obj interface{}) {
 
 func (nw *NodesWorker) onDeleteNode(obj interface{}) {
 	nodeObj := obj.(*v1.Node)
+	defer utils.DeleteNodeLabels(nodeObj.Name)
 	if !nw.qualifies(nodeObj) {
 		return
 	}
@@ -99,11 +88,16 @@ func (nw *NodesWorker) onDeleteNode(obj interface{}) {
 
 func (nw *NodesWorker) onUpdateNode(objOld interface{}, objNew interface{}) {
 	nodeObj := objNew.(*v1.Node)
+	//label changes may flip the node selector
+	labelsChanged := utils.UpdateNodeLabels(nodeObj.Name, nodeObj.Labels)
+	if labelsChanged {
+		nw.notifyLabelsChanged(nodeObj)
+	}
 	if !nw.qualifies(nodeObj) {
 		return
 	}
 	nodeOldObj := objOld.(*v1.Node)
-	if m.CompareNodeObjects(nodeObj, nodeOldObj) == true {
+	if labelsChanged || m.CompareNodeObjects(nodeObj, nodeOldObj) == true {
 		nodeRecord, changed := nw.processObject(nodeObj, nodeOldObj)
 
 		if changed {
@@ -113,6 +107,14 @@ func (nw *NodesWorker) onUpdateNode(objOld interface{}, objNew interface{}) {
 	}
 }
 
+//the workers subscribed to the informer manager re-queue the objects of the node
+func (nw *NodesWorker) notifyLabelsChanged(nodeObj *v1.Node) {
+	if (*nw.ConfigManager).Get().NodeSelector != "" && nw.Informers != nil {
+		nw.Logger.Debugf("Labels of node %s changed. Re-evaluating the node selector\n", nodeObj.Name)
+		nw.Informers.NodeLabelsChanged(nodeObj.Name)
+	}
+}
+
 func (pw NodesWorker) Observe(stopCh <-chan struct{}, wg *sync.WaitGroup) {
 	defer wg.Done()
 	defer pw.WQ.ShutDown()
@@ -240,6 +242,7 @@ func (pw *NodesWorker) getNextQueueItem() (*m.NodeSchema, bool) {
 func (pw *NodesWorker) buildAppDMetrics() {
 	bth := pw.AppdController.StartBT("PostNodeMetrics")
 	pw.SummaryMap = make(map[string]m.ClusterNodeMetrics)
+	pw.Metrics.Refresh()
 
 	count := 0
 	for _, obj := range pw.informer.GetStore().List() {
@@ -283,17 +286,21 @@ func (pw *NodesWorker) summarize(nodeObject *m.NodeSchema) {
 
 	if nodeObject.DiskPressure == "true" {
 		summary.DiskPressureNodes++
+		summaryNode.DiskPressureNodes = 1
 	}
 	if nodeObject.MemoryPressure == "true" {
 		summary.MemoryPressureNodes++
+		summaryNode.MemoryPressureNodes = 1
 	}
 	if nodeObject.OutOfDisk == "true" {
 		summary.OutOfDiskNodes++
+		summaryNode.OutOfDiskNodes = 1
 	}
 	if nodeObject.Ready == "true" {
 		summary.ReadyNodes++
+		summaryNode.ReadyNodes = 1
 	}
-	if nodeObject.Role == "master" {
+	if nodeObject.Role == m.NODE_ROLE_MASTER {
 		summary.Masters++
 	} else {
 		summary.Workers++
@@ -321,9 +328,51 @@ func (pw *NodesWorker) summarize(nodeObject *m.NodeSchema) {
 
 	pw.SummaryMap[m.ALL] = summary
 	pw.SummaryMap[nodeObject.NodeName] = summaryNode
+
+	//zone and node pool metrics
+	if nodeObject.Zone != "" {
+		pw.summarizeGroup(m.METRIC_PATH_ZONES, nodeObject.Zone, nodeObject)
+	}
+	pw.summarizeGroup(m.METRIC_PATH_POOLS, nodeObject.Pool, nodeObject)
 	pw.updateCapcityMap(*nodeObject)
 }
 
+//group keys cannot collide with node names, which do not contain slashes
+func (pw *NodesWorker) summarizeGroup(group string, name string, nodeObject *m.NodeSchema) {
+	bag := (*pw.ConfigManager).Get()
+	key := fmt.Sprintf("%s/%s", group, name)
+	summaryGroup, ok := pw.SummaryMap[key]
+	if !ok {
+		summaryGroup = m.NewClusterNodeGroupMetrics(bag, group, name)
+	}
+	if nodeObject.DiskPressure == "true" {
+		summaryGroup.DiskPressureNodes++
+	}
+	if nodeObject.MemoryPressure == "true" {
+		summaryGroup.MemoryPressureNodes++
+	}
+	if nodeObject.OutOfDisk == "true" {
+		summaryGroup.OutOfDiskNodes++
+	}
+	if nodeObject.Ready == "true" {
+		summaryGroup.ReadyNodes++
+	}
+	if nodeObject.Role == m.NODE_ROLE_MASTER {
+		summaryGroup.Masters++
+	} else {
+		summaryGroup.Workers++
+	}
+	summaryGroup.TaintsTotal += int64(nodeObject.TaintsNumber)
+	summaryGroup.CapacityCpu += nodeObject.CpuCapacity
+	summaryGroup.CapacityMemory += nodeObject.MemCapacity
+	summaryGroup.CapacityPods += nodeObject.PodCapacity
+	summaryGroup.AllocationsCpu += nodeObject.CpuAllocations
+	summaryGroup.AllocationsMemory += nodeObject.MemAllocations
+	summaryGroup.UseCpu += nodeObject.CpuUse
+	summaryGroup.UseMemory += nodeObject.MemUse
+	pw.SummaryMap[key] = summaryGroup
+}
+
 func (pw *NodesWorker) updateCapcityMap(node m.NodeSchema) {
 	lockCapacityMap.Lock()
 	defer lockCapacityMap.Unlock()
@@ -337,9 +386,34 @@ func (pw *NodesWorker) GetNodeData(nodeName string) *m.NodeSchema {
 	return &node
 }
 
+//GetKnownNodes returns the names of the monitored nodes currently in the cluster
+func (pw *NodesWorker) GetKnownNodes() []string {
+	nodes := []string{}
+	for _, obj := range pw.informer.GetStore().List() {
+		nodeObject := obj.(*v1.Node)
+		if pw.qualifies(nodeObject) {
+			nodes = append(nodes, nodeObject.Name)
+		}
+	}
+	return nodes
+}
+
+//GetNodeObjects returns the monitored nodes currently in the cluster
+func (pw *NodesWorker) GetNodeObjects() []*v1.Node {
+	nodes := []*v1.Node{}
+	for _, obj := range pw.informer.GetStore().List() {
+		nodeObject := obj.(*v1.Node)
+		if pw.qualifies(nodeObject) {
+			nodes = append(nodes, nodeObject)
+		}
+	}
+	return nodes
+}
+
 func (pw *NodesWorker) processObject(n *v1.Node, old *v1.Node) (m.NodeSchema, bool) {
 	changed := true
 
+	bag := (*pw.ConfigManager).Get()
 	nodeObject := m.NewNodeObj()
 	nodeObject.NodeName = n.Name
 	nodeObject.PodCIDR = n.Spec.PodCIDR
@@ -360,20 +434,27 @@ func (pw *NodesWorker) processObject(n *v1.Node, old *v1.Node) (m.NodeSchema, bo
 	nodeObject.Addresses = sb.String()
 	sb.Reset()
 
-	isMaster := false
 	for k, l := range n.Labels {
 		fmt.Fprintf(&sb, "%s:%s;", k, l)
-		if !isMaster && strings.Contains(k, "node-role.kubernetes.io/master") {
-			isMaster = true
-		}
 	}
 	nodeObject.Labels = sb.String()
 	sb.Reset()
-	if isMaster {
-		nodeObject.Role = "master"
+	if m.IsControlPlaneNode(n.Labels) {
+		nodeObject.Role = m.NODE_ROLE_MASTER
 	} else {
-		nodeObject.Role = "worker"
+		nodeObject.Role = m.NODE_ROLE_WORKER
+	}
+
+	//topology and cloud metadata
+	cloud, providerZone := m.ParseProviderID(n.Spec.ProviderID)
+	nodeObject.Cloud = cloud
+	nodeObject.Region = m.GetNodeRegion(n.Labels)
+	nodeObject.Zone = m.GetNodeZone(n.Labels)
+	if nodeObject.Zone == "" {
+		nodeObject.Zone = providerZone
 	}
+	nodeObject.InstanceType = m.GetNodeInstanceType(n.Labels)
+	nodeObject.Pool = m.GetNodePool(n.Labels, bag.NodePoolLabels)
 
 	for key, c := range n.Status.Capacity {
 		if key == "memory" {
@@ -439,7 +520,7 @@ func (pw *NodesWorker) processObject(n *v1.Node, old *v1.Node) (m.NodeSchema, bo
 	nodeObject.VolumesInUse = sb.String()
 	sb.Reset()
 
-	nodeMetricsObj := pw.GetNodeMetricsSingle(n.Name)
+	nodeMetricsObj := pw.Metrics.GetNodeMetrics(n.Name)
 	if nodeMetricsObj != nil {
 		usageObj := nodeMetricsObj.GetNodeUsage()
 
@@ -450,33 +531,6 @@ func (pw *NodesWorker) processObject(n *v1.Node, old *v1.Node) (m.NodeSchema, bo
 	return nodeObject, changed
 }
 
-func (pw NodesWorker) GetNodeMetricsSingle(podName string) *m.NodeMetricsObj {
-
-	metricsDone := make(chan *m.NodeMetricsObj)
-	go metricsWorkerSingleNode(metricsDone, pw.Client, podName)
-
-	metricsData := <-metricsDone
-	return metricsData
-}
-
-func metricsWorkerSingleNode(finished chan *m.NodeMetricsObj, client *kubernetes.Clientset, nodeName string) {
-	var path string = ""
-	var metricsObj m.NodeMetricsObj
-	if nodeName != "" {
-		path = fmt.Sprintf("apis/metrics.k8s.io/v1beta1/nodes/%s", nodeName)
-
-		data, err := client.RESTClient().Get().AbsPath(path).DoRaw()
-		if err != nil {
-			fmt.Printf("Issues when requesting metrics with path [%s]: %s\n", path, err.Error())
-		} else {
-			if err = json.Unmarshal(data, &metricsObj); err != nil {
-				fmt.Printf("Unmarshal issues. %v\n", err)
-			}
-		}
-	}
-	finished <- &metricsObj
-}
-
 func (pw NodesWorker) builAppDMetricsList() m.AppDMetricList {
 	ml := m.NewAppDMetricList()
 	var list []m.AppDMetric
@@ -25,12 +25,9 @@ import (
 	instr "github.com/appdynamics/cluster-agent/instrumentation"
 
 	"k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/tools/cache"
 
 	app "github.com/appdynamics/cluster-agent/appd"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
 
 	//	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apimachinery/pkg/labels"
@@ -39,11 +36,12 @@ import (
 )
 
 type PodWorker struct {
-	informer                cache.SharedIndexInformer
+	informer                *w.NamespacedInformer
 	Client                  *kubernetes.Clientset
 	ConfManager             *config.MutexConfigManager
 	Logger                  *log.Logger
 	SummaryMap              map[string]m.ClusterPodMetrics
+	GroupSummaryMap         map[string]m.ClusterPodGroupMetrics
 	AppSummaryMap           map[string]m.ClusterAppMetrics
 	ContainerSummaryMap     map[string]m.ClusterContainerMetrics
 	InstanceSummaryMap      map[string]m.ClusterInstanceMetrics
@@ -72,8 +70,15 @@ type PodWorker struct {
 	DelayDashboard          bool
 	PendingAssociationQueue map[string]m.AgentRetryRequest
 	EventMap                map[string][]m.EventSchema
+	PendingReasonMap        map[string][]string
+	ImagePullMap            map[string]imagePullWindow
 	NodesMonitor            *NodesWorker
+	Recommender             *Recommender
+	CostAllocator           *CostAllocator
+	CapacityAnalyzer        *CapacityAnalyzer
+	SpreadChecker           *SpreadChecker
 	ContainerCache          map[string]m.ContainerSchema
+	Metrics                 *w.MetricsCollector
 }
 
 var lockRQ = sync.RWMutex{}
@@ -84,6 +89,8 @@ var lockNS = sync.RWMutex{}
 var lockAssociationQueue = sync.RWMutex{}
 
 var lockEventLock = sync.RWMutex{}
+var lockPendingReasons = sync.RWMutex{}
+var lockImagePulls = sync.RWMutex{}
 
 var lockServices = sync.RWMutex{}
 var lockEPs = sync.RWMutex{}
@@ -93,46 +100,32 @@ var lockConfigs = sync.RWMutex{}
 var lockSecrets = sync.RWMutex{}
 var lockPVC = sync.RWMutex{}
 
-func NewPodWorker(client *kubernetes.Clientset, cm *config.MutexConfigManager, controller *app.ControllerClient, config *rest.Config, l *log.Logger, nw *NodesWorker) PodWorker {
+func NewPodWorker(client *kubernetes.Clientset, cm *config.MutexConfigManager, im *w.InformerManager, mc *w.MetricsCollector, controller *app.ControllerClient, config *rest.Config, l *log.Logger, nw *NodesWorker) PodWorker {
 	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
-	pw := PodWorker{Client: client, ConfManager: cm, Logger: l, SummaryMap: make(map[string]m.ClusterPodMetrics), AppSummaryMap: make(map[string]m.ClusterAppMetrics),
+	pw := PodWorker{Client: client, ConfManager: cm, Logger: l, SummaryMap: make(map[string]m.ClusterPodMetrics), GroupSummaryMap: make(map[string]m.ClusterPodGroupMetrics), AppSummaryMap: make(map[string]m.ClusterAppMetrics),
 		ContainerSummaryMap: make(map[string]m.ClusterContainerMetrics), InstanceSummaryMap: make(map[string]m.ClusterInstanceMetrics),
 		WQ: queue, AppdController: controller, K8sConfig: config, PendingCache: []string{}, FailedCache: make(map[string]m.AttachStatus),
 		ServiceCache: make(map[string]m.ServiceSchema), EndpointCache: make(map[string]v1.Endpoints),
-		OwnerMap: make(map[string]string), NamespaceMap: make(map[string]string), EventMap: make(map[string][]m.EventSchema),
+		OwnerMap: make(map[string]string), NamespaceMap: make(map[string]string), EventMap: make(map[string][]m.EventSchema), PendingReasonMap: make(map[string][]string), ImagePullMap: make(map[string]imagePullWindow),
 		RQCache: make(map[string]v1.ResourceQuota), PVCCache: make(map[string]v1.PersistentVolumeClaim), PendingAssociationQueue: make(map[string]m.AgentRetryRequest),
 		CMCache: make(map[string]v1.ConfigMap), SecretCache: make(map[string]v1.Secret), NSCache: make(map[string]m.NsSchema), DashboardCache: make(map[string]m.PodSchema),
-		ContainerCache: make(map[string]m.ContainerSchema)}
-	pw.initPodInformer(client)
-	pw.ServiceWatcher = w.NewServiceWatcher(client, cm, &pw.ServiceCache, pw, l, &lockServices)
-	pw.EndpointWatcher = w.NewEndpointWatcher(client, cm, &pw.EndpointCache, l, &lockEPs)
-	pw.PVCWatcher = w.NewPVCWatcher(client, cm, &pw.PVCCache, l, &lockPVC)
-	pw.RQWatcher = w.NewRQWatcher(client, cm, &pw.RQCache, pw.Logger, &lockRQ)
-	pw.CMWatcher = w.NewConfigWatcher(client, cm, &pw.CMCache, pw, l, &lockConfigs)
-	pw.SecretWatcher = w.NewSecretWathcer(client, cm, &pw.SecretCache, pw, l, &lockSecrets)
-	pw.NSWatcher = w.NewNSWatcher(client, cm, &pw.NSCache, l, &lockNS)
+		ContainerCache: make(map[string]m.ContainerSchema), Metrics: mc}
+	pw.initPodInformer(im)
+	pw.ServiceWatcher = w.NewServiceWatcher(client, cm, im, &pw.ServiceCache, pw, l, &lockServices)
+	pw.EndpointWatcher = w.NewEndpointWatcher(client, cm, im, &pw.EndpointCache, l, &lockEPs)
+	pw.PVCWatcher = w.NewPVCWatcher(client, cm, im, &pw.PVCCache, l, &lockPVC)
+	pw.RQWatcher = w.NewRQWatcher(client, cm, im, &pw.RQCache, pw.Logger, &lockRQ)
+	pw.CMWatcher = w.NewConfigWatcher(client, cm, im, &pw.CMCache, pw, l, &lockConfigs)
+	pw.SecretWatcher = w.NewSecretWathcer(client, cm, im, &pw.SecretCache, pw, l, &lockSecrets)
+	pw.NSWatcher = w.NewNSWatcher(client, cm, im, &pw.NSCache, pw, l, &lockNS)
 	pw.DelayDashboard = true
 	pw.NodesMonitor = nw
 
 	return pw
 }
 
-func (pw *PodWorker) initPodInformer(client *kubernetes.Clientset) cache.SharedIndexInformer {
-	i := cache.NewSharedIndexInformer(
-		&cache.ListWatch{
-			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
-				return client.CoreV1().Pods(metav1.NamespaceAll).List(options)
-			},
-			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
-				return client.CoreV1().Pods(metav1.NamespaceAll).Watch(options)
-			},
-		},
-		&v1.Pod{},
-		0,
-		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
-	)
-
-	i.AddEventHandler(cache.ResourceEventHandlerFuncs{
+func (pw *PodWorker) initPodInformer(im *w.InformerManager) *w.NamespacedInformer {
+	i := im.NewInformer("pods", w.PodInformer, cache.ResourceEventHandlerFuncs{
 		AddFunc:    pw.onNewPod,
 		DeleteFunc: pw.onDeletePod,
 		UpdateFunc: pw.onUpdatePod,
@@ -144,7 +137,9 @@ func (pw *PodWorker) initPodInformer(client *kubernetes.Clientset) cache.SharedI
 
 func (pw *PodWorker) qualifies(p *v1.Pod) bool {
 	bag := (*pw.ConfManager).Get()
-	return utils.NSQualifiesForMonitoring(p.Namespace, bag)
+	//pending pods are not bound to a node yet
+	return utils.NSQualifiesForMonitoring(p.Namespace, bag) &&
+		(p.Spec.NodeName == "" || utils.NodeQualifiesForMonitoring(p.Spec.NodeName, bag))
 }
 
 func (pw *PodWorker) onNewPod(obj interface{}) {
@@ -191,15 +186,31 @@ func (pw *PodWorker) GetCachedPod(namespace string, podName string) (*v1.Pod, st
 	return podObj, owner, err
 }
 
-func (pw *PodWorker) tryDashboardCache(podRecord *m.PodSchema) {
+func (pw *PodWorker) isSelectedForDashboard(podRecord *m.PodSchema) bool {
 	bag := (*pw.ConfManager).Get()
-	if utils.StringInSlice(podRecord.Owner, bag.DeploysToDashboard) {
+	return utils.StringInSlice(podRecord.Owner, bag.DeploysToDashboard) || utils.WorkloadMatchesSelectors(podRecord.OwnerKind, podRecord.Namespace, podRecord.Workload, bag.WorkloadsToDashboard)
+}
+
+func (pw *PodWorker) tryDashboardCache(podRecord *m.PodSchema) {
+	if pw.isSelectedForDashboard(podRecord) {
 		lockDashboards.Lock()
 		defer lockDashboards.Unlock()
 		pw.DashboardCache[utils.GetKey(podRecord.Namespace, podRecord.Owner)] = *podRecord
 	}
 }
 
+//pods of deployments are owned by replica sets with the pod-template-hash label
+func podWorkload(p *v1.Pod, owner string) (string, string) {
+	if len(p.OwnerReferences) == 0 {
+		return "Pod", owner
+	}
+	ref := p.OwnerReferences[0]
+	if hash, ok := p.Labels["pod-template-hash"]; ok && ref.Kind == "ReplicaSet" {
+		return "Deployment", strings.TrimSuffix(ref.Name, "-"+hash)
+	}
+	return ref.Kind, ref.Name
+}
+
 func (pw *PodWorker) shouldUpdateDashboard(podRecord *m.PodSchema) bool {
 	lockDashboards.RLock()
 	defer lockDashboards.RUnlock()
@@ -236,6 +247,7 @@ func (pw *PodWorker) startEventQueueWorker(stopCh <-chan struct{}) {
 
 func (pw *PodWorker) flushQueue() {
 	pw.updateServiceCache()
+	pw.Metrics.Refresh()
 	bag := (*pw.ConfManager).Get()
 	bth := pw.AppdController.StartBT("FlushPodDataQueue")
 	count := pw.WQ.Len()
@@ -392,6 +404,8 @@ func (pw *PodWorker) onDeletePod(obj interface{}) {
 	podRecord, _ := pw.processObject(podObj, nil)
 	pw.WQ.Add(&podRecord)
 	pw.clearContainerCache(&podRecord)
+	pw.clearPendingReasons(podRecord.Namespace, podRecord.Name)
+	pw.clearImagePulls(podRecord.Namespace, podRecord.Name)
 	if podRecord.NodeID > 0 {
 		//mark node as historial
 		pw.AppdController.MarkNodeHistorical(podRecord.NodeID)
@@ -517,8 +531,8 @@ func (pw PodWorker) Observe(stopCh <-chan struct{}, wg *sync.WaitGroup) {
 	go func() {
 		<-epTimer.C
 		pw.Logger.Infof("Starting Service End Point collection jobs...")
-		go pw.EndpointWatcher.WatchEndpoints()
-		go pw.ServiceWatcher.WatchServices()
+		go pw.EndpointWatcher.WatchEndpoints(stopCh)
+		go pw.ServiceWatcher.WatchServices(stopCh)
 	}()
 
 	// delay the start of the dependency object collection job (15 sec by default)
@@ -526,13 +540,13 @@ func (pw PodWorker) Observe(stopCh <-chan struct{}, wg *sync.WaitGroup) {
 	go func() {
 		<-dependencyTimer.C
 		pw.Logger.Infof("Starting Dependency Object collection jobs...")
-		go pw.RQWatcher.WatchResourceQuotas()
-		go pw.PVCWatcher.WatchPVC()
-		go pw.CMWatcher.WatchConfigs()
-		go pw.SecretWatcher.WatchSecrets()
+		go pw.RQWatcher.WatchResourceQuotas(stopCh)
+		go pw.PVCWatcher.WatchPVC(stopCh)
+		go pw.CMWatcher.WatchConfigs(stopCh)
+		go pw.SecretWatcher.WatchSecrets(stopCh)
 	}()
 
-	go pw.NSWatcher.WatchNamespaces()
+	go pw.NSWatcher.WatchNamespaces(stopCh)
 
 	go pw.startRetryQueueWorker(stopCh)
 
@@ -579,12 +593,67 @@ func (pw *PodWorker) eventQueueTicker(stop <-chan struct{}, ticker *time.Ticker)
 		}
 	}
 }
+
+//drops the state of the pods of the namespaces that are no longer monitored
+func (pw *PodWorker) purgeNamespaces(keep func(namespace string) bool) {
+	lockContainerCache.Lock()
+	for key, c := range pw.ContainerCache {
+		if !keep(c.Namespace) {
+			delete(pw.ContainerCache, key)
+		}
+	}
+	lockContainerCache.Unlock()
+
+	lockDashboards.Lock()
+	for key := range pw.DashboardCache {
+		if !keep(utils.KeyNamespace(key)) {
+			delete(pw.DashboardCache, key)
+		}
+	}
+	lockDashboards.Unlock()
+
+	lockEventLock.Lock()
+	for key := range pw.EventMap {
+		if !keep(utils.KeyNamespace(key)) {
+			delete(pw.EventMap, key)
+		}
+	}
+	lockEventLock.Unlock()
+
+	lockPendingReasons.Lock()
+	for key := range pw.PendingReasonMap {
+		if !keep(utils.KeyNamespace(key)) {
+			delete(pw.PendingReasonMap, key)
+		}
+	}
+	lockPendingReasons.Unlock()
+
+	lockImagePulls.Lock()
+	for key := range pw.ImagePullMap {
+		if !keep(utils.KeyNamespace(key)) {
+			delete(pw.ImagePullMap, key)
+		}
+	}
+	lockImagePulls.Unlock()
+}
+
+//re-queues the pods of the node when the node selector may select or deselect the node
+func (pw *PodWorker) NodeLabelsChanged(node string) {
+	for _, obj := range pw.informer.GetStore().List() {
+		podObject := obj.(*v1.Pod)
+		if podObject.Spec.NodeName == node && pw.qualifies(podObject) {
+			podSchema, _ := pw.processObject(podObject, nil)
+			pw.WQ.Add(&podSchema)
+		}
+	}
+}
+
 func (pw PodWorker) CacheUpdated(namespace string) {
 	pw.Logger.Debugf("CacheUpdated called for namespace %s\n", namespace)
 	//queue up pod records of the namespace to update
 	for _, obj := range pw.informer.GetStore().List() {
 		podObject := obj.(*v1.Pod)
-		if podObject.Namespace == namespace {
+		if podObject.Namespace == namespace && pw.qualifies(podObject) {
 			podSchema, _ := pw.processObject(podObject, nil)
 			pw.WQ.Add(&podSchema)
 		}
@@ -595,6 +664,7 @@ func (pw *PodWorker) buildAppDMetrics() {
 	bag := (*pw.ConfManager).Get()
 	bth := pw.AppdController.StartBT("PostPodMetrics")
 	pw.SummaryMap = make(map[string]m.ClusterPodMetrics)
+	pw.GroupSummaryMap = make(map[string]m.ClusterPodGroupMetrics)
 	pw.AppSummaryMap = make(map[string]m.ClusterAppMetrics)
 	pw.ContainerSummaryMap = make(map[string]m.ClusterContainerMetrics)
 	pw.InstanceSummaryMap = make(map[string]m.ClusterInstanceMetrics)
@@ -610,16 +680,32 @@ func (pw *PodWorker) buildAppDMetrics() {
 	}
 
 	dash := make(map[string]m.DashboardBag)
-	pw.Logger.Debugf("Deployments configured for dashboarding: %s \n", bag.DeploysToDashboard)
+	selected := make(map[string]m.DashboardBag)
+	pw.Logger.Debugf("Deployments configured for dashboarding: %s, workloads: %s \n", bag.DeploysToDashboard, bag.WorkloadsToDashboard)
 
 	var clusterBag *m.DashboardBag = nil
 	if !pw.DelayDashboard && bag.AppID > 0 && bag.TierID > 0 {
 		//create cluster dashboard bag
 		clusterBag = m.NewDashboardBagCluster()
+		//node tiles of the node heat map
+		if bag.NodeHeatMap != m.NODE_HEAT_MAP_NONE && pw.NodesMonitor != nil {
+			for _, node := range pw.NodesMonitor.GetKnownNodes() {
+				nodeInfo := pw.NodesMonitor.GetNodeData(node)
+				if nodeInfo.NodeName == "" {
+					continue
+				}
+				nodeHeat := m.NewNodeHeatNode(nodeInfo)
+				clusterBag.AddNodeHeat(&nodeHeat)
+			}
+		}
 	}
 	var count int = 0
 	for _, obj := range pw.informer.GetStore().List() {
 		podObject := obj.(*v1.Pod)
+		//pods of namespaces excluded from monitoring still hold resources on the nodes
+		if pw.CapacityAnalyzer != nil {
+			pw.CapacityAnalyzer.RecordPod(podObject)
+		}
 		if !pw.qualifies(podObject) {
 			continue
 		}
@@ -628,6 +714,15 @@ func (pw *PodWorker) buildAppDMetrics() {
 			pw.WQ.Add(&podSchema)
 		}
 		pw.summarize(&podSchema)
+		if pw.Recommender != nil {
+			pw.Recommender.RecordPod(&podSchema)
+		}
+		if pw.CostAllocator != nil {
+			pw.CostAllocator.RecordPod(&podSchema)
+		}
+		if pw.SpreadChecker != nil {
+			pw.SpreadChecker.RecordPod(podObject)
+		}
 		//endpoints
 		for _, ep := range epList {
 			ep.MatchPod(&podSchema)
@@ -638,8 +733,12 @@ func (pw *PodWorker) buildAppDMetrics() {
 			heatNode.Events = pw.GetPodEvents(&podSchema)
 			heatNode.Containers = pw.GetPodUtilization(&podSchema)
 			clusterBag.AddNode(&heatNode)
+			clusterBag.AddPodToNodeHeat(&podSchema)
 		}
 		count++
+		if pw.isSelectedForDashboard(&podSchema) {
+			selected[utils.GetKey(podSchema.Namespace, podSchema.Owner)] = m.NewDashboardBagTier(podSchema.Namespace, podSchema.Owner, nil)
+		}
 		//should build the dashboard
 		if pw.shouldUpdateDashboard(&podSchema) {
 			key := utils.GetKey(podSchema.Namespace, podSchema.Owner)
@@ -654,19 +753,52 @@ func (pw *PodWorker) buildAppDMetrics() {
 				dashBag.AppID = podSchema.AppID
 				dashBag.TierID = podSchema.TierID
 				dashBag.NodeID = podSchema.NodeID
+				dashBag.OwnerKind = podSchema.OwnerKind
 			}
 			dashBag.Pods = append(dashBag.Pods, podSchema)
 			dash[key] = dashBag
 		}
+		//namespace and node dashboards
+		if clusterBag != nil {
+			if utils.NSSelectedForDashboard(podSchema.Namespace, bag) {
+				pw.addToDashboardBag(dash, m.Namespace, podSchema.Namespace, &podSchema, bag)
+			}
+			if podSchema.NodeName != "" && utils.NodeSelectedForDashboard(podSchema.NodeName, bag) {
+				pw.addToDashboardBag(dash, m.Node, podSchema.NodeName, &podSchema, bag)
+			}
+		}
 	}
 
 	if count == 0 {
 		pw.SummaryMap[m.ALL] = m.NewClusterPodMetrics(bag, m.ALL, m.ALL)
 	}
+	pw.summarizeTopology()
+	if pw.Recommender != nil {
+		pw.Recommender.EndCycle()
+	}
+	var costMetrics []m.ClusterCostMetrics
+	if pw.CostAllocator != nil {
+		costMetrics = pw.CostAllocator.EndCycle(pw.NodesMonitor)
+	}
+	var analyzerMetrics []m.AppDMetricInterface
+	if pw.CapacityAnalyzer != nil {
+		analyzerMetrics = pw.CapacityAnalyzer.EndCycle(pw.NodesMonitor)
+	}
+	if pw.SpreadChecker != nil {
+		analyzerMetrics = append(analyzerMetrics, pw.SpreadChecker.EndCycle(pw.NodesMonitor)...)
+	}
 
 	pw.processNamespaces()
 
 	ml := pw.builAppDMetricsList()
+	for _, metricCost := range costMetrics {
+		objMap := metricCost.Unwrap()
+		pw.addMetricToList(*objMap, metricCost, &ml.Items)
+	}
+	for _, metricAnalyzer := range analyzerMetrics {
+		objMap := metricAnalyzer.Unwrap()
+		pw.addMetricToList(*objMap, metricAnalyzer, &ml.Items)
+	}
 
 	pw.postEPBatchRecords(&epList)
 
@@ -681,8 +813,29 @@ func (pw *PodWorker) buildAppDMetrics() {
 		clusterBag.ClusterTierID = bag.TierID
 		clusterBag.ClusterNodeID = bag.NodeID
 		dash[bag.AppName] = *clusterBag
+		if bag.NodeHeatMap == m.NODE_HEAT_MAP_DASHBOARD {
+			nodeHeatBag := m.NewDashboardBagNodeHeat(clusterBag.NodeMap)
+			nodeHeatBag.ClusterName = bag.AppName
+			nodeHeatBag.ClusterAppID = bag.AppID
+			nodeHeatBag.ClusterTierID = bag.TierID
+			nodeHeatBag.ClusterNodeID = bag.NodeID
+			dash[string(m.NodeHeat)] = nodeHeatBag
+		}
+		//selected namespaces and nodes without pods get dashboards too
+		for ns := range pw.NSWatcher.CloneMap() {
+			if utils.NSSelectedForDashboard(ns, bag) {
+				pw.addToDashboardBag(dash, m.Namespace, ns, nil, bag)
+			}
+		}
+		if pw.NodesMonitor != nil {
+			for _, node := range pw.NodesMonitor.GetKnownNodes() {
+				if utils.NodeSelectedForDashboard(node, bag) {
+					pw.addToDashboardBag(dash, m.Node, node, nil, bag)
+				}
+			}
+		}
 		pw.Logger.Debugf("Number of dashboards to be updated %d\n", len(dash))
-		go pw.buildDashboards(dash)
+		go pw.buildDashboards(dash, selected)
 	}
 }
 
@@ -733,6 +886,32 @@ func (pw *PodWorker) updateServiceCache() {
 	pw.ServiceWatcher.UpdateServiceCache()
 }
 
+//rolls up the pod counts of the nodes by zone and node pool
+func (pw *PodWorker) summarizeTopology() {
+	if pw.NodesMonitor == nil {
+		return
+	}
+	bag := (*pw.ConfManager).Get()
+	for _, summaryNode := range pw.SummaryMap {
+		if summaryNode.Nodename == "" || summaryNode.Nodename == m.ALL {
+			continue
+		}
+		node := pw.NodesMonitor.GetNodeData(summaryNode.Nodename)
+		for group, name := range map[string]string{m.METRIC_PATH_ZONES: node.Zone, m.METRIC_PATH_POOLS: node.Pool} {
+			if name == "" {
+				continue
+			}
+			key := fmt.Sprintf("%s/%s", group, name)
+			summaryGroup, ok := pw.GroupSummaryMap[key]
+			if !ok {
+				summaryGroup = m.NewClusterPodGroupMetrics(bag, group, name)
+			}
+			summaryGroup.AddCounts(&summaryNode)
+			pw.GroupSummaryMap[key] = summaryGroup
+		}
+	}
+}
+
 func (pw *PodWorker) summarize(podObject *m.PodSchema) {
 	bag := (*pw.ConfManager).Get()
 	//global metrics
@@ -918,6 +1097,57 @@ func (pw *PodWorker) summarize(podObject *m.PodSchema) {
 		break
 	}
 
+	if podObject.Phase == "Pending" {
+		if podObject.HasPendingReason(m.PENDING_REASON_INSUFFICIENT_CPU) {
+			summary.PendingInsufficientCpu++
+			summaryNS.PendingInsufficientCpu++
+			summaryNode.PendingInsufficientCpu++
+			summaryApp.PendingInsufficientCpu++
+		}
+		if podObject.HasPendingReason(m.PENDING_REASON_INSUFFICIENT_MEMORY) {
+			summary.PendingInsufficientMemory++
+			summaryNS.PendingInsufficientMemory++
+			summaryNode.PendingInsufficientMemory++
+			summaryApp.PendingInsufficientMemory++
+		}
+		if podObject.HasPendingReason(m.PENDING_REASON_TAINT) {
+			summary.PendingTaints++
+			summaryNS.PendingTaints++
+			summaryNode.PendingTaints++
+			summaryApp.PendingTaints++
+		}
+		if podObject.HasPendingReason(m.PENDING_REASON_NODE_AFFINITY) {
+			summary.PendingNodeAffinity++
+			summaryNS.PendingNodeAffinity++
+			summaryNode.PendingNodeAffinity++
+			summaryApp.PendingNodeAffinity++
+		}
+		if podObject.HasPendingReason(m.PENDING_REASON_UNBOUND_PVC) {
+			summary.PendingUnboundPVC++
+			summaryNS.PendingUnboundPVC++
+			summaryNode.PendingUnboundPVC++
+			summaryApp.PendingUnboundPVC++
+		}
+		if podObject.HasPendingReason(m.PENDING_REASON_TOO_MANY_PODS) {
+			summary.PendingTooManyPods++
+			summaryNS.PendingTooManyPods++
+			summaryNode.PendingTooManyPods++
+			summaryApp.PendingTooManyPods++
+		}
+		if podObject.HasPendingReason(m.PENDING_REASON_VOLUME_AFFINITY) {
+			summary.PendingVolumeAffinity++
+			summaryNS.PendingVolumeAffinity++
+			summaryNode.PendingVolumeAffinity++
+			summaryApp.PendingVolumeAffinity++
+		}
+		if podObject.HasPendingReason(m.PENDING_REASON_OTHER) {
+			summary.PendingOther++
+			summaryNS.PendingOther++
+			summaryNode.PendingOther++
+			summaryApp.PendingOther++
+		}
+	}
+
 	if podObject.Reason != "" && podObject.Reason == "Evicted" {
 		summary.Evictions++
 		summaryNS.Evictions++
@@ -946,11 +1176,44 @@ func (pw *PodWorker) summarize(podObject *m.PodSchema) {
 		summaryApp.UpTime = (summaryApp.UpTime + podObject.UpTimeMillis) / summaryApp.PodCount
 	}
 
+	//startup latencies are counted in the metrics interval the pod started in
+	latencySince := time.Now().Add(-time.Duration(bag.MetricsSyncInterval) * time.Second)
+	summary.AddStartupLatencies(podObject, latencySince)
+	summaryNS.AddStartupLatencies(podObject, latencySince)
+	summaryNode.AddStartupLatencies(podObject, latencySince)
+	summaryApp.AddStartupLatencies(podObject, latencySince)
+
 	summary.PodRestarts += int64(podObject.PodRestarts)
 	summaryNS.PodRestarts += int64(podObject.PodRestarts)
 	summaryNode.PodRestarts += int64(podObject.PodRestarts)
 	summaryApp.PodRestarts += int64(podObject.PodRestarts)
 
+	for _, containers := range []map[string]m.ContainerSchema{podObject.Containers, podObject.InitContainers} {
+		for _, c := range containers {
+			summary.AddSecurity(&c)
+			summaryNS.AddSecurity(&c)
+			summaryNode.AddSecurity(&c)
+			summaryApp.AddSecurity(&c)
+		}
+	}
+
+	//OOM kills are counted in the metrics interval they happened in
+	oomSince := time.Now().Add(-time.Duration(bag.MetricsSyncInterval) * time.Second)
+	for _, c := range podObject.Containers {
+		if c.OOMKilledSince(oomSince) {
+			summary.OOMKills++
+			summaryNS.OOMKills++
+			summaryNode.OOMKills++
+			summaryApp.OOMKills++
+		}
+		if c.IsCrashLooping() {
+			summary.CrashLoops++
+			summaryNS.CrashLoops++
+			summaryNode.CrashLoops++
+			summaryApp.CrashLoops++
+		}
+	}
+
 	summary.UseCpu += podObject.CpuUse
 	summary.UseMemory += podObject.MemUse
 
@@ -980,6 +1243,13 @@ func (pw *PodWorker) summarize(podObject *m.PodSchema) {
 		summaryApp.ConsumptionMem = (summaryApp.ConsumptionMem + int64(podObject.ConsumptionMem)) / summaryApp.PodCount
 	}
 
+	if bag.OverconsumptionThreshold > 0 && (podObject.ConsumptionCpu >= float64(bag.OverconsumptionThreshold) || podObject.ConsumptionMem >= float64(bag.OverconsumptionThreshold)) {
+		summary.PodOverconsume++
+		summaryNS.PodOverconsume++
+		summaryNode.PodOverconsume++
+		summaryApp.PodOverconsume++
+	}
+
 	//app summary for containers
 	if !podObject.IsEvicted {
 		for _, c := range podObject.Containers {
@@ -1103,6 +1373,7 @@ func (pw *PodWorker) processObject(p *v1.Pod, old *v1.Pod) (m.PodSchema, bool) {
 	if podObject.Owner == "" && len(p.OwnerReferences) > 0 {
 		podObject.Owner = p.OwnerReferences[0].Name
 	}
+	podObject.OwnerKind, podObject.Workload = podWorkload(p, podObject.Owner)
 
 	lockOwnerMap.Lock()
 	defer lockOwnerMap.Unlock()
@@ -1133,6 +1404,7 @@ func (pw *PodWorker) processObject(p *v1.Pod, old *v1.Pod) (m.PodSchema, bool) {
 		podObject.StartTime = p.Status.StartTime.Time
 		podObject.StartTimeMillis = podObject.StartTime.UnixNano() / 1000000
 	}
+	podObject.CreationTimeMillis = p.CreationTimestamp.UnixNano() / 1000000
 
 	if p.DeletionTimestamp != nil {
 		podObject.TerminationTime = &p.DeletionTimestamp.Time
@@ -1333,6 +1605,12 @@ func (pw *PodWorker) processObject(p *v1.Pod, old *v1.Pod) (m.PodSchema, bool) {
 
 	podObject.Phase = string(p.Status.Phase)
 
+	if podObject.Phase == "Pending" {
+		podObject.PendingReasons = strings.Join(pw.getPendingReasons(podObject.Namespace, podObject.Name), m.PENDING_REASONS_SEPARATOR)
+	} else {
+		pw.clearPendingReasons(podObject.Namespace, podObject.Name)
+	}
+
 	if !podObject.IsEvicted {
 		var lastCondition *v1.PodCondition = nil
 		maxTimeMillis := int64(0)
@@ -1346,11 +1624,16 @@ func (pw *PodWorker) processObject(p *v1.Pod, old *v1.Pod) (m.PodSchema, bool) {
 				switch cn.Type {
 				case v1.PodScheduled:
 					//					fmt.Printf("Pod %s Scheduled %s. Time: %s Probe: %s\n", podObject.Name, string(cn.Status), cn.LastTransitionTime.Time, cn.LastProbeTime.Time)
+					if cn.Status == v1.ConditionTrue {
+						podObject.ScheduledTimeMillis = last
+					}
 					break
 
 				case v1.PodReady:
 					//					fmt.Printf("Pod %s Ready %s. Time: %s Probe: %s\n", podObject.Name, string(cn.Status), cn.LastTransitionTime.Time, cn.LastProbeTime.Time)
-
+					if cn.Status == v1.ConditionTrue {
+						podObject.ReadyTimeMillis = last
+					}
 					break
 
 				case v1.PodReasonUnschedulable:
@@ -1359,6 +1642,9 @@ func (pw *PodWorker) processObject(p *v1.Pod, old *v1.Pod) (m.PodSchema, bool) {
 
 				case v1.PodInitialized:
 					//					fmt.Printf("Pod %s Initialized %s. Time: %s Probe: %s\n", podObject.Name, string(cn.Status), cn.LastTransitionTime.Time, cn.LastProbeTime.Time)
+					if cn.Status == v1.ConditionTrue {
+						podObject.InitializedTimeMillis = last
+					}
 					break
 
 				case v1.ContainersReady:
@@ -1418,6 +1704,12 @@ func (pw *PodWorker) processObject(p *v1.Pod, old *v1.Pod) (m.PodSchema, bool) {
 			i++
 		}
 
+		//startup latencies
+		pullWindow := pw.getImagePulls(podObject.Namespace, podObject.Name)
+		podObject.ImagePullStartMillis = pullWindow.StartMillis
+		podObject.ImagePullEndMillis = pullWindow.EndMillis
+		podObject.CalculateStartupLatencies()
+
 		//check PendingTime
 		if podObject.Phase != "Failed" && podObject.StartTimeMillis > 0 {
 			if podObject.RunningStartTimeMillis > 0 {
@@ -1453,7 +1745,7 @@ func (pw *PodWorker) processObject(p *v1.Pod, old *v1.Pod) (m.PodSchema, bool) {
 
 		//metrics
 		if utils.IsPodRunnnig(p) {
-			podMetricsObj := pw.GetPodMetricsSingle(p, p.Namespace, p.Name)
+			podMetricsObj := pw.Metrics.GetPodMetrics(p.Namespace, p.Name)
 			if podMetricsObj != nil {
 				usageObj := podMetricsObj.GetPodUsage()
 
@@ -1547,6 +1839,21 @@ func (pw PodWorker) updateConatainerStatus(podObject *m.PodSchema, containerObj
 		}
 	}
 
+	//the last termination explains restarts, including containers waiting in CrashLoopBackOff
+	lastTerm := st.LastTerminationState.Terminated
+	if lastTerm == nil {
+		lastTerm = st.State.Terminated
+	}
+	if lastTerm != nil && containerObj != nil {
+		containerObj.LastExitCode = lastTerm.ExitCode
+		containerObj.LastSignal = lastTerm.Signal
+		containerObj.LastTermReason = lastTerm.Reason
+		containerObj.LastFinishTime = &lastTerm.FinishedAt.Time
+		if st.RestartCount > 0 {
+			containerObj.RestartCause = m.ClassifyRestart(lastTerm.Reason, lastTerm.ExitCode, lastTerm.Signal)
+		}
+	}
+
 	podObject.Containers[containerObj.Name] = *containerObj
 
 	if bag.LogLines > 0 && (containerObj.Restarts > 2 || podObject.Phase == "Failed") {
@@ -1568,7 +1875,9 @@ func (pw PodWorker) updateConatainerStatus(podObject *m.PodSchema, containerObj
 	lockContainerCache.RUnlock()
 	if ok {
 		if lastSnapshot.Restarts != st.RestartCount ||
-			containerObj.LastTerminationTime == nil && st.LastTerminationState.Terminated != nil {
+			containerObj.LastTerminationTime == nil && st.LastTerminationState.Terminated != nil ||
+			lastSnapshot.WaitReason != containerObj.WaitReason ||
+			lastSnapshot.LastTermReason != containerObj.LastTermReason {
 			changed = true
 		}
 	} else {
@@ -1611,6 +1920,7 @@ func (pw PodWorker) processContainer(podObj *v1.Pod, podSchema *m.PodSchema, c v
 		podSchema.NumPrivileged++
 		containerObj.Privileged = 1
 	}
+	containerObj.EvaluateSecurity(podObj, &c)
 
 	if c.LivenessProbe == nil {
 		podSchema.LiveProbes++
@@ -1915,63 +2225,6 @@ func (pw *PodWorker) postLogRecords(objList *[]m.LogSchema) {
 	}
 }
 
-func (pw PodWorker) GetPodMetrics() *map[string]m.UsageStats {
-	metricsDonePods := make(chan *m.PodMetricsObjList)
-	go metricsWorkerPods(metricsDonePods, pw.Client, pw.Logger)
-
-	metricsDataPods := <-metricsDonePods
-	objMap := metricsDataPods.PrintPodList()
-	return &objMap
-}
-
-func (pw PodWorker) GetPodMetricsSingle(p *v1.Pod, namespace string, podName string) *m.PodMetricsObj {
-	if !utils.IsPodRunnnig(p) {
-		return nil
-	}
-	metricsDone := make(chan *m.PodMetricsObj)
-	go metricsWorkerSingle(metricsDone, pw.Client, namespace, podName, pw.Logger)
-
-	metricsData := <-metricsDone
-	return metricsData
-}
-
-func metricsWorkerPods(finished chan *m.PodMetricsObjList, client *kubernetes.Clientset, l *log.Logger) {
-	l.Debug("Metrics Worker Pods: Started")
-	var path string = "apis/metrics.k8s.io/v1beta1/pods"
-	var list m.PodMetricsObjList
-
-	data, err := client.RESTClient().Get().AbsPath(path).DoRaw()
-	if err != nil {
-		l.Errorf("Issues when requesting metrics with path [%s]: %s\n", path, err.Error())
-	} else {
-		if err = json.Unmarshal(data, &list); err != nil {
-			l.Errorf("Unmarshal issues. %v\n", err)
-		}
-		fmt.Println("Metrics Worker Pods: Finished. Metrics records: %d", len(list.Items))
-		fmt.Println(&list)
-	}
-	finished <- &list
-}
-
-func metricsWorkerSingle(finished chan *m.PodMetricsObj, client *kubernetes.Clientset, namespace string, podName string, l *log.Logger) {
-	var path string = ""
-	var metricsObj m.PodMetricsObj
-	if namespace != "" && podName != "" {
-		path = fmt.Sprintf("apis/metrics.k8s.io/v1beta1/namespaces/%s/pods/%s", namespace, podName)
-
-		data, err := client.RESTClient().Get().AbsPath(path).DoRaw()
-		if err != nil {
-			l.Errorf("Issues when requesting metrics with path [%s]: %s\n", path, err.Error())
-		} else {
-			if err = json.Unmarshal(data, &metricsObj); err != nil {
-				l.Errorf("Unmarshal issues when getting pods metrics. %v\n", err)
-			}
-		}
-	}
-
-	finished <- &metricsObj
-}
-
 func (pw PodWorker) builAppDMetricsList() m.AppDMetricList {
 	ml := m.NewAppDMetricList()
 	var list []m.AppDMetric
@@ -1987,6 +2240,10 @@ func (pw PodWorker) builAppDMetricsList() m.AppDMetricList {
 		quMap := quotaUsed.Unwrap()
 		pw.addMetricToList(*quMap, quotaUsed, &list)
 	}
+	for _, metricGroup := range pw.GroupSummaryMap {
+		objMap := metricGroup.Unwrap()
+		pw.addMetricToList(*objMap, metricGroup, &list)
+	}
 	for _, metricApp := range pw.AppSummaryMap {
 		objMap := metricApp.Unwrap()
 		pw.addMetricToList(*objMap, metricApp, &list)
@@ -2040,7 +2297,8 @@ func (pw PodWorker) addMetricToList(objMap map[string]interface{}, metric m.AppD
 }
 
 //dashboards
-func (pw *PodWorker) buildDashboards(dashData map[string]m.DashboardBag) {
+//selected - workloads configured for dashboarding, including the ones without recent changes
+func (pw *PodWorker) buildDashboards(dashData map[string]m.DashboardBag, selected map[string]m.DashboardBag) {
 	bag := (*pw.ConfManager).Get()
 	bth := pw.AppdController.StartBT("BuildDashboard")
 	//clear cache
@@ -2056,10 +2314,53 @@ func (pw *PodWorker) buildDashboards(dashData map[string]m.DashboardBag) {
 				pw.Logger.WithField("error", err).Error("Unable to build cluster overview dashboard")
 			}
 		}
+		if bag.Type == m.Namespace || bag.Type == m.Node {
+			err := dw.updateScopeDashboard(&bag)
+			if err != nil {
+				pw.Logger.WithFields(log.Fields{"type": bag.Type, "namespace": bag.Namespace, "node": bag.NodeName, "error": err}).Error("Unable to build dashboard")
+			}
+		}
+		if bag.Type == m.NodeHeat {
+			err := dw.updateNodeHeatDashboard(&bag)
+			if err != nil {
+				pw.Logger.WithField("error", err).Error("Unable to build node heat map dashboard")
+			}
+		}
+	}
+
+	//remove agent dashboards that are no longer generated
+	desired := make(map[string]bool)
+	for _, b := range dashData {
+		desired[dw.dashboardName(&b)] = true
 	}
+	for _, b := range selected {
+		desired[dw.dashboardName(&b)] = true
+	}
+	dw.reconcileDashboards(desired)
 	pw.AppdController.StopBT(bth)
 }
 
+//pod is nil if the namespace or node has no pods
+func (pw *PodWorker) addToDashboardBag(dash map[string]m.DashboardBag, dashType m.DashboardType, name string, podSchema *m.PodSchema, bag *m.AppDBag) {
+	key := fmt.Sprintf("%s:%s", dashType, name)
+	dashBag, ok := dash[key]
+	if !ok {
+		if dashType == m.Node {
+			dashBag = m.NewDashboardBagNode(name)
+		} else {
+			dashBag = m.NewDashboardBagNamespace(name)
+		}
+		dashBag.ClusterName = bag.AppName
+		dashBag.ClusterAppID = bag.AppID
+		dashBag.ClusterTierID = bag.TierID
+		dashBag.ClusterNodeID = bag.NodeID
+	}
+	if podSchema != nil {
+		dashBag.Pods = append(dashBag.Pods, *podSchema)
+	}
+	dash[key] = dashBag
+}
+
 func (pw *PodWorker) addToAssociationQueue(retryObj *m.AgentRetryRequest) {
 	lockAssociationQueue.Lock()
 	defer lockAssociationQueue.Unlock()
@@ -2174,6 +2475,94 @@ func (pw *PodWorker) OnPodErrorEvent(podName string, eventSchema m.EventSchema)
 	}
 }
 
+//OnPodSchedulingEvent records the reasons why the scheduler could not place the pod
+func (pw *PodWorker) OnPodSchedulingEvent(namespace string, podName string, eventSchema m.EventSchema) {
+	key := utils.GetKey(namespace, podName)
+	if eventSchema.Reason == "Scheduled" {
+		pw.clearPendingReasons(namespace, podName)
+	} else {
+		reasons := m.ParseSchedulingReasons(eventSchema.Message)
+		pw.Logger.WithFields(log.Fields{"pod": key, "reasons": reasons}).Debug("Pod cannot be scheduled")
+		lockPendingReasons.Lock()
+		pw.PendingReasonMap[key] = reasons
+		lockPendingReasons.Unlock()
+	}
+
+	//the event may arrive after the last snapshot of the pod was taken
+	if podObj, ok, err := pw.informer.GetStore().GetByKey(key); err == nil && ok {
+		podObject := podObj.(*v1.Pod)
+		if pw.qualifies(podObject) {
+			podSchema, _ := pw.processObject(podObject, nil)
+			pw.WQ.Add(&podSchema)
+		}
+	}
+}
+
+func (pw *PodWorker) getPendingReasons(namespace string, podName string) []string {
+	lockPendingReasons.RLock()
+	defer lockPendingReasons.RUnlock()
+	if reasons, ok := pw.PendingReasonMap[utils.GetKey(namespace, podName)]; ok {
+		return reasons
+	}
+	return []string{}
+}
+
+func (pw *PodWorker) clearPendingReasons(namespace string, podName string) {
+	key := utils.GetKey(namespace, podName)
+	lockPendingReasons.Lock()
+	defer lockPendingReasons.Unlock()
+	delete(pw.PendingReasonMap, key)
+}
+
+//time window in which the images of a pod were pulled
+type imagePullWindow struct {
+	StartMillis int64
+	EndMillis   int64
+}
+
+//OnPodImagePullEvent tracks the first Pulling and the last Pulled event of the pod
+func (pw *PodWorker) OnPodImagePullEvent(namespace string, podName string, eventSchema m.EventSchema) {
+	key := utils.GetKey(namespace, podName)
+	ts := eventSchema.LastTimestamp.UnixNano() / 1000000
+	if ts <= 0 {
+		return
+	}
+	lockImagePulls.Lock()
+	defer lockImagePulls.Unlock()
+	window := pw.ImagePullMap[key]
+	switch eventSchema.Reason {
+	case "Pulling":
+		if window.EndMillis > 0 && ts > window.EndMillis {
+			//new round of pulls, e.g. after a container restart
+			window = imagePullWindow{StartMillis: ts}
+		} else if window.StartMillis == 0 || ts < window.StartMillis {
+			window.StartMillis = ts
+		}
+	case "Pulled":
+		//images already present on the node are not pulled
+		if strings.Contains(eventSchema.Message, "already present") {
+			return
+		}
+		if ts > window.EndMillis {
+			window.EndMillis = ts
+		}
+	}
+	pw.ImagePullMap[key] = window
+}
+
+func (pw *PodWorker) getImagePulls(namespace string, podName string) imagePullWindow {
+	lockImagePulls.RLock()
+	defer lockImagePulls.RUnlock()
+	return pw.ImagePullMap[utils.GetKey(namespace, podName)]
+}
+
+func (pw *PodWorker) clearImagePulls(namespace string, podName string) {
+	key := utils.GetKey(namespace, podName)
+	lockImagePulls.Lock()
+	defer lockImagePulls.Unlock()
+	delete(pw.ImagePullMap, key)
+}
+
 func (pw *PodWorker) GetPodEvents(podSchema *m.PodSchema) []string {
 	lockEventLock.RLock()
 	defer lockEventLock.RUnlock()
@@ -2246,6 +2635,12 @@ func (pw *PodWorker) GetPodUtilization(podSchema *m.PodSchema) map[string]m.Util
 				memCeiling = c.MemLimit
 			}
 			cu.CheckStatus(float64(bag.OverconsumptionThreshold), float64(cpuCeiling), float64(memCeiling))
+			if pw.Recommender != nil {
+				if rec, ok := pw.Recommender.GetContainerRecommendation(podSchema.Namespace, podSchema.OwnerKind, podSchema.Workload, c.Name); ok && rec.Action != m.RECOMMENDATION_ACTION_KEEP {
+					cu.CpuGoal = float64(rec.RecCpuRequest)
+					cu.MemGoal = float64(rec.RecMemRequest)
+				}
+			}
 			mu[c.Name] = cu
 		}
 	}
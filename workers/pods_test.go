@@ -0,0 +1,93 @@
+package workers
+
+import (
+	"io/ioutil"
+	"sync"
+	"testing"
+
+	"github.com/appdynamics/cluster-agent/config"
+	m "github.com/appdynamics/cluster-agent/models"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestPodWorker(bag *m.AppDBag) *PodWorker {
+	l := log.New()
+	l.Out = ioutil.Discard
+	cm := config.MutexConfigManager{Conf: bag, Mutex: &sync.Mutex{}, Logger: l}
+	return &PodWorker{ConfManager: &cm, Logger: l, SummaryMap: make(map[string]m.ClusterPodMetrics), AppSummaryMap: make(map[string]m.ClusterAppMetrics),
+		ContainerSummaryMap: make(map[string]m.ClusterContainerMetrics), InstanceSummaryMap: make(map[string]m.ClusterInstanceMetrics),
+		ServiceCache: make(map[string]m.ServiceSchema), EndpointCache: make(map[string]v1.Endpoints), RQCache: make(map[string]v1.ResourceQuota)}
+}
+
+func TestSummarizePendingReasons(t *testing.T) {
+	pw := newTestPodWorker(&m.AppDBag{AppName: "cluster"})
+	pending := func(name string, reasons ...string) *m.PodSchema {
+		ps := m.PodSchema{Name: name, Namespace: "shop", Owner: "api", Phase: "Pending"}
+		for i, r := range reasons {
+			if i > 0 {
+				ps.PendingReasons += m.PENDING_REASONS_SEPARATOR
+			}
+			ps.PendingReasons += r
+		}
+		return &ps
+	}
+	pw.summarize(pending("api-1", m.ParseSchedulingReasons("0/3 nodes are available: 3 node(s) had volume node affinity conflict.")...))
+	pw.summarize(pending("api-2", m.PENDING_REASON_OTHER, m.PENDING_REASON_VOLUME_AFFINITY))
+	pw.summarize(pending("api-3", m.PENDING_REASON_INSUFFICIENT_CPU))
+
+	summary := pw.SummaryMap[m.ALL]
+	if summary.PendingVolumeAffinity != 2 || summary.PendingOther != 1 || summary.PendingInsufficientCpu != 1 {
+		t.Errorf("cluster pending = volume affinity %d, other %d, cpu %d, want 2, 1, 1",
+			summary.PendingVolumeAffinity, summary.PendingOther, summary.PendingInsufficientCpu)
+	}
+	if ns := pw.SummaryMap["shop"]; ns.PendingVolumeAffinity != 2 || ns.PendingOther != 1 {
+		t.Errorf("namespace pending = volume affinity %d, other %d, want 2, 1", ns.PendingVolumeAffinity, ns.PendingOther)
+	}
+	if app := pw.AppSummaryMap["api"]; app.PendingVolumeAffinity != 2 || app.PendingOther != 1 {
+		t.Errorf("tier pending = volume affinity %d, other %d, want 2, 1", app.PendingVolumeAffinity, app.PendingOther)
+	}
+}
+
+func TestPodWorkload(t *testing.T) {
+	pod := func(kind, name string, labels map[string]string) *v1.Pod {
+		p := &v1.Pod{}
+		p.Labels = labels
+		if kind != "" {
+			p.OwnerReferences = []metav1.OwnerReference{{Kind: kind, Name: name}}
+		}
+		return p
+	}
+	if kind, name := podWorkload(pod("ReplicaSet", "api-7d9f8b6c5", map[string]string{"pod-template-hash": "7d9f8b6c5"}), "api-7d9f8b6c5"); kind != "Deployment" || name != "api" {
+		t.Errorf("pod of a deployment: %s/%s", kind, name)
+	}
+	if kind, name := podWorkload(pod("ReplicaSet", "legacy", nil), "legacy"); kind != "ReplicaSet" || name != "legacy" {
+		t.Errorf("pod of a bare replica set: %s/%s", kind, name)
+	}
+	if kind, name := podWorkload(pod("DaemonSet", "fluentd", map[string]string{"controller-revision-hash": "5c7d"}), "fluentd"); kind != "DaemonSet" || name != "fluentd" {
+		t.Errorf("pod of a daemon set: %s/%s", kind, name)
+	}
+	if kind, name := podWorkload(pod("", "", nil), "debug"); kind != "Pod" || name != "debug" {
+		t.Errorf("standalone pod: %s/%s", kind, name)
+	}
+}
+
+func TestTryDashboardCacheWorkloads(t *testing.T) {
+	pw := newTestPodWorker(&m.AppDBag{DeploysToDashboard: []string{"legacy"}, WorkloadsToDashboard: []string{"DaemonSet/*/fluentd-*", "Deployment/shop/api"}})
+	pw.DashboardCache = make(map[string]m.PodSchema)
+
+	pw.tryDashboardCache(&m.PodSchema{Name: "fluentd-x1", Namespace: "logging", Owner: "fluentd-es", OwnerKind: "DaemonSet", Workload: "fluentd-es"})
+	pw.tryDashboardCache(&m.PodSchema{Name: "api-1", Namespace: "shop", Owner: "api-7d9f8b6c5", OwnerKind: "Deployment", Workload: "api"})
+	pw.tryDashboardCache(&m.PodSchema{Name: "api-2", Namespace: "staging", Owner: "api-5f6c", OwnerKind: "Deployment", Workload: "api"})
+	pw.tryDashboardCache(&m.PodSchema{Name: "legacy-1", Namespace: "shop", Owner: "legacy", OwnerKind: "Deployment", Workload: "legacy"})
+
+	for _, key := range []string{"logging/fluentd-es", "shop/api-7d9f8b6c5", "shop/legacy"} {
+		if _, ok := pw.DashboardCache[key]; !ok {
+			t.Errorf("%s is not cached for dashboarding", key)
+		}
+	}
+	if _, ok := pw.DashboardCache["staging/api-5f6c"]; ok || len(pw.DashboardCache) != 3 {
+		t.Errorf("dashboard cache = %v, want only the selected workloads", pw.DashboardCache)
+	}
+}
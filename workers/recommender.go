@@ -0,0 +1,368 @@
+package workers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	app "github.com/appdynamics/cluster-agent/appd"
+	"github.com/appdynamics/cluster-agent/config"
+	m "github.com/appdynamics/cluster-agent/models"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	RECOMMENDATION_MIN_CPU        int64   = 10               //millicores
+	RECOMMENDATION_MIN_MEM        int64   = 16 * 1024 * 1024 //bytes
+	RECOMMENDATION_TOLERANCE      float64 = 0.1              //changes within 10% of the current value are not recommended
+	RECOMMENDATION_RISK_THRESHOLD float64 = 0.9              //use above 90% of the limit
+)
+
+//Recommender keeps a rolling usage history of the containers of workloads
+//and computes the recommended requests and limits from the percentiles of the use
+type Recommender struct {
+	ConfManager     *config.MutexConfigManager
+	Logger          *log.Logger
+	lock            *sync.Mutex
+	history         map[string]*m.UsageHistory
+	replicas        map[string]int64 //workload -> running pods in the last metrics cycle
+	cycle           map[string]int64
+	recommendations []m.RecommendationSchema
+}
+
+func NewRecommender(cm *config.MutexConfigManager, l *log.Logger) *Recommender {
+	r := Recommender{ConfManager: cm, Logger: l, lock: &sync.Mutex{}, history: make(map[string]*m.UsageHistory),
+		replicas: make(map[string]int64), cycle: make(map[string]int64), recommendations: []m.RecommendationSchema{}}
+	r.loadHistory()
+	return &r
+}
+
+func (r *Recommender) Observe(stopCh <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+	bag := (*r.ConfManager).Get()
+	if bag.RecommendationIntervalMin <= 0 {
+		r.Logger.Info("Right-sizing recommendations are disabled")
+		return
+	}
+	ticker := time.NewTicker(time.Duration(bag.RecommendationIntervalMin) * time.Minute)
+	for {
+		select {
+		case <-ticker.C:
+			recs := r.Compute()
+			r.postRecommendations(recs)
+			r.saveHistory()
+		case <-stopCh:
+			ticker.Stop()
+			r.saveHistory()
+			return
+		}
+	}
+}
+
+func workloadKey(namespace string, kind string, workload string) string {
+	return fmt.Sprintf("%s/%s/%s", namespace, kind, workload)
+}
+
+func containerKey(namespace string, kind string, workload string, container string) string {
+	return fmt.Sprintf("%s/%s", workloadKey(namespace, kind, workload), container)
+}
+
+//RecordPod adds the current use of the containers of a running pod to the history of its workload
+func (r *Recommender) RecordPod(podSchema *m.PodSchema) {
+	if podSchema.GetState() != "Running" || podSchema.Workload == "" {
+		return
+	}
+	bag := (*r.ConfManager).Get()
+	if bag.RecommendationIntervalMin <= 0 {
+		return
+	}
+	slot := int64(bag.RecommendationSampleMin) * 60
+	if slot <= 0 {
+		slot = 300
+	}
+	now := time.Now()
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	recorded := false
+	for _, c := range podSchema.Containers {
+		if c.Init || (c.CpuUse <= 0 && c.MemUse <= 0) {
+			continue
+		}
+		key := containerKey(podSchema.Namespace, podSchema.OwnerKind, podSchema.Workload, c.Name)
+		h, ok := r.history[key]
+		if !ok {
+			h = &m.UsageHistory{Namespace: podSchema.Namespace, OwnerKind: podSchema.OwnerKind, Workload: podSchema.Workload, ContainerName: c.Name, Samples: []m.UsageSample{}}
+			r.history[key] = h
+		}
+		//the spec of the most recent pod wins
+		h.CpuRequest = c.CpuRequest
+		h.CpuLimit = c.CpuLimit
+		h.MemRequest = c.MemRequest
+		h.MemLimit = c.MemLimit
+		h.AddSample(now, slot, c.CpuUse, c.MemUse)
+		recorded = true
+	}
+	if recorded {
+		r.cycle[workloadKey(podSchema.Namespace, podSchema.OwnerKind, podSchema.Workload)]++
+	}
+}
+
+//EndCycle is called when all pods of the metrics cycle are recorded
+func (r *Recommender) EndCycle() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.replicas = r.cycle
+	r.cycle = make(map[string]int64)
+}
+
+//Compute prunes the history and recomputes the recommendations of the containers with enough history
+func (r *Recommender) Compute() []m.RecommendationSchema {
+	bag := (*r.ConfManager).Get()
+	window := time.Duration(bag.RecommendationWindowHours) * time.Hour
+	minHistory := time.Duration(bag.RecommendationMinHours) * time.Hour
+	now := time.Now()
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	recs := []m.RecommendationSchema{}
+	for key, h := range r.history {
+		h.Prune(now, window)
+		//containers and workloads that are gone
+		if len(h.Samples) == 0 || now.Unix()-h.LastSeen > int64(window.Seconds()) {
+			delete(r.history, key)
+			continue
+		}
+		if h.Duration() < minHistory {
+			continue
+		}
+		replicas := r.replicas[workloadKey(h.Namespace, h.OwnerKind, h.Workload)]
+		if replicas == 0 {
+			replicas = 1
+		}
+		rec := recommend(h, bag, replicas)
+		rec.Timestamp = now
+		recs = append(recs, rec)
+	}
+	sort.Slice(recs, func(i, j int) bool {
+		a := containerKey(recs[i].Namespace, recs[i].OwnerKind, recs[i].Workload, recs[i].ContainerName)
+		b := containerKey(recs[j].Namespace, recs[j].OwnerKind, recs[j].Workload, recs[j].ContainerName)
+		return a < b
+	})
+	r.recommendations = recs
+	r.Logger.WithFields(log.Fields{"containers": len(r.history), "recommendations": len(recs)}).Info("Computed right-sizing recommendations")
+	return recs
+}
+
+//GetRecommendations returns the last computed recommendations. Empty namespace returns all
+func (r *Recommender) GetRecommendations(namespace string) []m.RecommendationSchema {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	recs := []m.RecommendationSchema{}
+	for _, rec := range r.recommendations {
+		if namespace == "" || rec.Namespace == namespace {
+			recs = append(recs, rec)
+		}
+	}
+	return recs
+}
+
+//GetContainerRecommendation returns the last recommendation of the container of the workload
+func (r *Recommender) GetContainerRecommendation(namespace string, kind string, workload string, container string) (m.RecommendationSchema, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, rec := range r.recommendations {
+		if rec.Namespace == namespace && rec.OwnerKind == kind && rec.Workload == workload && rec.ContainerName == container {
+			return rec, true
+		}
+	}
+	return m.RecommendationSchema{}, false
+}
+
+//requests cover the 95th percentile of the use and limits the peak, both with the configured margin.
+//Cpu limits are only recommended for containers that have them, to avoid throttling
+func recommend(h *m.UsageHistory, bag *m.AppDBag, replicas int64) m.RecommendationSchema {
+	margin := 1 + float64(bag.RecommendationMargin)/100
+	rec := m.RecommendationSchema{ClusterName: bag.AppName, Namespace: h.Namespace, OwnerKind: h.OwnerKind, Workload: h.Workload, ContainerName: h.ContainerName,
+		Replicas: replicas, Samples: int64(len(h.Samples)), HistoryHours: math.Round(h.Duration().Hours()*10) / 10,
+		CpuRequest: h.CpuRequest, CpuLimit: h.CpuLimit, MemRequest: h.MemRequest, MemLimit: h.MemLimit}
+	rec.CpuP50, rec.MemP50 = h.Percentiles(50)
+	rec.CpuP95, rec.MemP95 = h.Percentiles(95)
+	rec.CpuMax, rec.MemMax = h.Percentiles(100)
+
+	rec.RecCpuRequest = withMargin(rec.CpuP95, margin, RECOMMENDATION_MIN_CPU)
+	rec.RecMemRequest = withMargin(rec.MemP95, margin, RECOMMENDATION_MIN_MEM)
+	rec.RecMemLimit = withMargin(rec.MemMax, margin, rec.RecMemRequest)
+	if h.CpuLimit > 0 {
+		rec.RecCpuLimit = withMargin(rec.CpuMax, margin, rec.RecCpuRequest)
+	}
+
+	rec.CpuSavings = (h.CpuRequest - rec.RecCpuRequest) * replicas
+	rec.MemSavings = (h.MemRequest - rec.RecMemRequest) * replicas
+
+	switch {
+	case h.MemLimit > 0 && float64(rec.MemMax) >= RECOMMENDATION_RISK_THRESHOLD*float64(h.MemLimit):
+		rec.Risk = m.RECOMMENDATION_RISK_OOM
+	case h.CpuLimit > 0 && float64(rec.CpuP95) >= RECOMMENDATION_RISK_THRESHOLD*float64(h.CpuLimit):
+		rec.Risk = m.RECOMMENDATION_RISK_THROTTLING
+	case h.CpuRequest == 0 || h.MemRequest == 0:
+		rec.Risk = m.RECOMMENDATION_RISK_NO_REQUESTS
+	case rec.CpuP95 > h.CpuRequest || rec.MemP95 > h.MemRequest:
+		rec.Risk = m.RECOMMENDATION_RISK_UNDER
+	default:
+		rec.Risk = m.RECOMMENDATION_RISK_NONE
+	}
+
+	cpuAction := compareRecommendation(h.CpuRequest, rec.RecCpuRequest)
+	memAction := compareRecommendation(h.MemRequest, rec.RecMemRequest)
+	if cpuAction == m.RECOMMENDATION_ACTION_INCREASE || memAction == m.RECOMMENDATION_ACTION_INCREASE {
+		rec.Action = m.RECOMMENDATION_ACTION_INCREASE
+	} else if cpuAction == m.RECOMMENDATION_ACTION_DECREASE || memAction == m.RECOMMENDATION_ACTION_DECREASE {
+		rec.Action = m.RECOMMENDATION_ACTION_DECREASE
+	} else {
+		rec.Action = m.RECOMMENDATION_ACTION_KEEP
+	}
+
+	changes := []string{}
+	if cpuAction != m.RECOMMENDATION_ACTION_KEEP {
+		changes = append(changes, fmt.Sprintf("cpu request %s -> %s", formatCpu(h.CpuRequest), formatCpu(rec.RecCpuRequest)))
+	}
+	if memAction != m.RECOMMENDATION_ACTION_KEEP {
+		changes = append(changes, fmt.Sprintf("memory request %s -> %s", formatMem(h.MemRequest), formatMem(rec.RecMemRequest)))
+	}
+	if compareRecommendation(h.MemLimit, rec.RecMemLimit) != m.RECOMMENDATION_ACTION_KEEP {
+		changes = append(changes, fmt.Sprintf("memory limit %s -> %s", formatMem(h.MemLimit), formatMem(rec.RecMemLimit)))
+	}
+	if h.CpuLimit > 0 && compareRecommendation(h.CpuLimit, rec.RecCpuLimit) != m.RECOMMENDATION_ACTION_KEEP {
+		changes = append(changes, fmt.Sprintf("cpu limit %s -> %s", formatCpu(h.CpuLimit), formatCpu(rec.RecCpuLimit)))
+	}
+	if len(changes) == 0 {
+		rec.Summary = fmt.Sprintf("%s/%s %s is right-sized", h.Namespace, h.Workload, h.ContainerName)
+	} else {
+		rec.Summary = fmt.Sprintf("%s/%s %s: %s. Risk: %s", h.Namespace, h.Workload, h.ContainerName, strings.Join(changes, ", "), rec.Risk)
+	}
+	return rec
+}
+
+func withMargin(val int64, margin float64, min int64) int64 {
+	v := int64(math.Ceil(float64(val) * margin))
+	if v < min {
+		return min
+	}
+	return v
+}
+
+func compareRecommendation(current int64, recommended int64) string {
+	if current <= 0 || float64(recommended) > float64(current)*(1+RECOMMENDATION_TOLERANCE) {
+		return m.RECOMMENDATION_ACTION_INCREASE
+	}
+	if float64(recommended) < float64(current)*(1-RECOMMENDATION_TOLERANCE) {
+		return m.RECOMMENDATION_ACTION_DECREASE
+	}
+	return m.RECOMMENDATION_ACTION_KEEP
+}
+
+func formatCpu(milli int64) string {
+	if milli <= 0 {
+		return "none"
+	}
+	return fmt.Sprintf("%dm", milli)
+}
+
+func formatMem(bytes int64) string {
+	if bytes <= 0 {
+		return "none"
+	}
+	return fmt.Sprintf("%dMi", int64(math.Ceil(float64(bytes)/(1024*1024))))
+}
+
+func (r *Recommender) postRecommendations(objList []m.RecommendationSchema) {
+	if len(objList) == 0 {
+		return
+	}
+	bag := (*r.ConfManager).Get()
+	rc := app.NewRestClient(bag, r.Logger)
+
+	schemaDefObj := m.NewRecommendationSchemaDefWrapper()
+	err := rc.EnsureSchema(bag.RecommendationSchemaName, &schemaDefObj)
+	if err != nil {
+		r.Logger.Errorf("Issues when ensuring %s schema. %v\n", bag.RecommendationSchemaName, err)
+		return
+	}
+	for len(objList) > 0 {
+		limit := len(objList)
+		if bag.EventAPILimit > 0 && limit > bag.EventAPILimit {
+			limit = bag.EventAPILimit
+		}
+		data, err := json.Marshal(objList[:limit])
+		if err != nil {
+			r.Logger.Errorf("Problems when serializing array of recommendation schemas. %v", err)
+		} else {
+			rc.PostAppDEvents(bag.RecommendationSchemaName, data)
+		}
+		objList = objList[limit:]
+	}
+}
+
+//the history survives agent restarts when RecommendationHistoryPath points to a volume
+func (r *Recommender) loadHistory() {
+	bag := (*r.ConfManager).Get()
+	if bag.RecommendationHistoryPath == "" {
+		return
+	}
+	data, err := ioutil.ReadFile(bag.RecommendationHistoryPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			r.Logger.WithFields(log.Fields{"path": bag.RecommendationHistoryPath, "error": err}).Warn("Unable to read usage history")
+		}
+		return
+	}
+	list := []m.UsageHistory{}
+	err = json.Unmarshal(data, &list)
+	if err != nil {
+		r.Logger.WithFields(log.Fields{"path": bag.RecommendationHistoryPath, "error": err}).Warn("Usage history is invalid. Starting with empty history")
+		return
+	}
+	for i := range list {
+		h := list[i]
+		r.history[containerKey(h.Namespace, h.OwnerKind, h.Workload, h.ContainerName)] = &h
+	}
+	r.Logger.WithField("containers", len(list)).Info("Loaded usage history")
+}
+
+func (r *Recommender) saveHistory() {
+	bag := (*r.ConfManager).Get()
+	if bag.RecommendationHistoryPath == "" {
+		return
+	}
+	r.lock.Lock()
+	list := make([]m.UsageHistory, 0, len(r.history))
+	for _, h := range r.history {
+		list = append(list, *h)
+	}
+	data, err := json.Marshal(list)
+	r.lock.Unlock()
+	if err != nil {
+		r.Logger.WithField("error", err).Warn("Unable to serialize usage history")
+		return
+	}
+	err = os.MkdirAll(filepath.Dir(bag.RecommendationHistoryPath), 0755)
+	if err == nil {
+		//write and rename so that a crash never leaves a partial file
+		tmp := bag.RecommendationHistoryPath + ".tmp"
+		err = ioutil.WriteFile(tmp, data, 0644)
+		if err == nil {
+			err = os.Rename(tmp, bag.RecommendationHistoryPath)
+		}
+	}
+	if err != nil {
+		r.Logger.WithFields(log.Fields{"path": bag.RecommendationHistoryPath, "error": err}).Warn("Unable to save usage history")
+	}
+}
@@ -0,0 +1,159 @@
+package workers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/appdynamics/cluster-agent/config"
+	m "github.com/appdynamics/cluster-agent/models"
+	log "github.com/sirupsen/logrus"
+)
+
+const MI int64 = 1024 * 1024
+
+//history of a container sampled every 5 minutes for the last hours
+func newTestUsageHistory(cpu []int64, mem []int64) *m.UsageHistory {
+	h := m.UsageHistory{Namespace: "shop", OwnerKind: "Deployment", Workload: "api", ContainerName: "app", LastSeen: time.Now().Unix()}
+	start := time.Now().Add(-time.Duration(len(cpu)) * 5 * time.Minute).Unix()
+	for i := range cpu {
+		h.Samples = append(h.Samples, m.UsageSample{Time: start + int64(i)*300, Cpu: cpu[i], Mem: mem[i]})
+	}
+	return &h
+}
+
+func TestRecommend(t *testing.T) {
+	bag := &m.AppDBag{AppName: "cluster", RecommendationMargin: 20}
+	cpu := []int64{}
+	mem := []int64{}
+	for i := int64(1); i <= 20; i++ {
+		cpu = append(cpu, i*10)
+		mem = append(mem, 200*MI)
+	}
+	mem[7] = 256 * MI
+
+	//over-provisioned workload with 3 replicas
+	h := newTestUsageHistory(cpu, mem)
+	h.CpuRequest, h.MemRequest, h.MemLimit = 1000, 1024*MI, 2048*MI
+	rec := recommend(h, bag, 3)
+	if rec.CpuP50 != 100 || rec.CpuP95 != 190 || rec.CpuMax != 200 || rec.MemP95 != 200*MI || rec.MemMax != 256*MI {
+		t.Errorf("percentiles: cpu %d/%d/%d, memory p95 %d max %d", rec.CpuP50, rec.CpuP95, rec.CpuMax, rec.MemP95, rec.MemMax)
+	}
+	if rec.RecCpuRequest != 228 || rec.RecMemRequest != 240*MI || rec.RecMemLimit != 322122548 || rec.RecCpuLimit != 0 {
+		t.Errorf("recommended cpu %d/%d, memory %d/%d", rec.RecCpuRequest, rec.RecCpuLimit, rec.RecMemRequest, rec.RecMemLimit)
+	}
+	if rec.CpuSavings != 2316 || rec.MemSavings != 2352*MI {
+		t.Errorf("savings of the workload: cpu %d, memory %d", rec.CpuSavings, rec.MemSavings)
+	}
+	if rec.Action != m.RECOMMENDATION_ACTION_DECREASE || rec.Risk != m.RECOMMENDATION_RISK_NONE {
+		t.Errorf("action %s, risk %s", rec.Action, rec.Risk)
+	}
+	if want := "shop/api app: cpu request 1000m -> 228m, memory request 1024Mi -> 240Mi, memory limit 2048Mi -> 308Mi. Risk: none"; rec.Summary != want {
+		t.Errorf("summary = %q, want %q", rec.Summary, want)
+	}
+
+	//use close to the memory limit
+	h = newTestUsageHistory([]int64{50, 50, 50}, []int64{120 * MI, 120 * MI, 120 * MI})
+	h.CpuRequest, h.CpuLimit, h.MemRequest, h.MemLimit = 50, 100, 100*MI, 128*MI
+	rec = recommend(h, bag, 1)
+	if rec.Risk != m.RECOMMENDATION_RISK_OOM || rec.Action != m.RECOMMENDATION_ACTION_INCREASE || rec.RecCpuLimit != 60 || rec.MemSavings != -44*MI {
+		t.Errorf("container close to the memory limit: risk %s, action %s, cpu limit %d, memory savings %d", rec.Risk, rec.Action, rec.RecCpuLimit, rec.MemSavings)
+	}
+
+	//use close to the cpu limit
+	h = newTestUsageHistory([]int64{95, 95, 95}, []int64{10 * MI, 10 * MI, 10 * MI})
+	h.CpuRequest, h.CpuLimit, h.MemRequest = 100, 100, 16*MI
+	if rec = recommend(h, bag, 1); rec.Risk != m.RECOMMENDATION_RISK_THROTTLING {
+		t.Errorf("container close to the cpu limit: risk %s", rec.Risk)
+	}
+
+	//containers without requests get at least the minimum requests
+	h = newTestUsageHistory([]int64{1, 2}, []int64{MI, MI})
+	rec = recommend(h, bag, 2)
+	if rec.Risk != m.RECOMMENDATION_RISK_NO_REQUESTS || rec.Action != m.RECOMMENDATION_ACTION_INCREASE ||
+		rec.RecCpuRequest != RECOMMENDATION_MIN_CPU || rec.RecMemRequest != RECOMMENDATION_MIN_MEM || rec.CpuSavings != -20 {
+		t.Errorf("container without requests: %+v", rec)
+	}
+
+	//changes within the tolerance are not recommended
+	h = newTestUsageHistory([]int64{100, 100}, []int64{100 * MI, 100 * MI})
+	h.CpuRequest, h.MemRequest, h.MemLimit = 125, 125*MI, 125*MI
+	if rec = recommend(h, bag, 1); rec.Action != m.RECOMMENDATION_ACTION_KEEP || rec.Summary != "shop/api app is right-sized" {
+		t.Errorf("right-sized container: action %s, summary %q", rec.Action, rec.Summary)
+	}
+}
+
+func TestRecommenderHistory(t *testing.T) {
+	l := log.New()
+	l.Out = ioutil.Discard
+	dir, err := ioutil.TempDir("", "recommender")
+	if err != nil {
+		t.Fatalf("Unable to create the history directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	bag := &m.AppDBag{AppName: "cluster", RecommendationIntervalMin: 60, RecommendationSampleMin: 5, RecommendationWindowHours: 24,
+		RecommendationMinHours: 1, RecommendationMargin: 15, RecommendationHistoryPath: filepath.Join(dir, "history", "usage.json")}
+	cm := &config.MutexConfigManager{Conf: bag, Mutex: &sync.Mutex{}, Logger: l}
+	r := NewRecommender(cm, l)
+
+	pod := func(name string, phase string, workload string) *m.PodSchema {
+		return &m.PodSchema{Name: name, Namespace: "shop", Phase: phase, OwnerKind: "Deployment", Workload: workload,
+			Containers: map[string]m.ContainerSchema{
+				"app":  {Name: "app", CpuUse: 120, MemUse: 64 * MI, CpuRequest: 500, MemRequest: 256 * MI},
+				"init": {Name: "init", Init: true, CpuUse: 900, MemUse: 900 * MI},
+				"idle": {Name: "idle"},
+			}}
+	}
+	r.RecordPod(pod("api-1", "Running", "api"))
+	r.RecordPod(pod("api-2", "Running", "api"))
+	r.RecordPod(pod("api-3", "Pending", "api"))
+	r.RecordPod(pod("debug", "Running", ""))
+	r.EndCycle()
+
+	if len(r.history) != 1 || r.replicas["shop/Deployment/api"] != 2 {
+		t.Fatalf("history of %d containers, %d replicas", len(r.history), r.replicas["shop/Deployment/api"])
+	}
+	h := r.history["shop/Deployment/api/app"]
+	if len(h.Samples) != 1 || h.Samples[0].Cpu != 120 || h.CpuRequest != 500 {
+		t.Errorf("history of the app container = %+v", h)
+	}
+
+	//the container needs an hour of history
+	if recs := r.Compute(); len(recs) != 0 {
+		t.Errorf("recommendations with a short history = %v", recs)
+	}
+
+	long := newTestUsageHistory([]int64{100, 120, 110, 130, 100, 90, 120, 110, 100, 120, 130, 100, 110}, make([]int64, 13))
+	long.CpuRequest, long.MemRequest = 500, 256*MI
+	r.history["shop/Deployment/api/app"] = long
+	gone := newTestUsageHistory([]int64{10, 10}, []int64{MI, MI})
+	gone.Workload, gone.LastSeen = "old", time.Now().Add(-48*time.Hour).Unix()
+	r.history["shop/Deployment/old/app"] = gone
+
+	recs := r.Compute()
+	if len(recs) != 1 || recs[0].Workload != "api" || recs[0].Replicas != 2 || recs[0].HistoryHours != 1 {
+		t.Fatalf("recommendations = %+v", recs)
+	}
+	if _, ok := r.history["shop/Deployment/old/app"]; ok {
+		t.Errorf("history of a workload that is gone is kept")
+	}
+	if got := r.GetRecommendations("shop"); len(got) != 1 {
+		t.Errorf("recommendations of the namespace = %d", len(got))
+	}
+	if got := r.GetRecommendations("payments"); len(got) != 0 {
+		t.Errorf("recommendations of another namespace = %d", len(got))
+	}
+	if rec, ok := r.GetContainerRecommendation("shop", "Deployment", "api", "app"); !ok || rec.RecCpuRequest != 150 {
+		t.Errorf("recommendation of the container: %t, cpu request %d", ok, rec.RecCpuRequest)
+	}
+
+	//the history survives a restart
+	r.saveHistory()
+	restarted := NewRecommender(cm, l)
+	if h, ok := restarted.history["shop/Deployment/api/app"]; !ok || len(h.Samples) != 13 || h.CpuRequest != 500 {
+		t.Errorf("history after a restart = %+v", restarted.history)
+	}
+}
@@ -0,0 +1,216 @@
+package workers
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
+
+	app "github.com/appdynamics/cluster-agent/appd"
+	m "github.com/appdynamics/cluster-agent/models"
+	"github.com/appdynamics/cluster-agent/utils"
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/api/core/v1"
+)
+
+const DEPLOYMENT_REVISION_ANNOTATION string = "deployment.kubernetes.io/revision"
+
+var lockRollouts = sync.RWMutex{}
+
+//trackRollout detects new rollouts of the deployment and follows them until they complete, fail or get superseded.
+//Every change of the rollout status is queued for the rollouts schema. added - the deployment is seen for the first time
+func (dw *DeployWorker) trackRollout(d *appsv1.Deployment, old *appsv1.Deployment, added bool) {
+	bag := (*dw.ConfigManager).Get()
+	key := utils.GetKey(d.Namespace, d.Name)
+	hash := templateHash(&d.Spec.Template)
+
+	lockRollouts.Lock()
+	defer lockRollouts.Unlock()
+
+	rollout, exists := dw.Rollouts[key]
+	start := false
+	oldImages := ""
+	if exists && rollout.TemplateHash != hash {
+		start = true
+		oldImages = rollout.NewImages
+	} else if !exists && old != nil && templateHash(&old.Spec.Template) != hash {
+		start = true
+		oldImages = templateImages(&old.Spec.Template)
+	} else if !exists && added && isRollingOut(d) {
+		//rollout in progress when the agent started. The actual start time is unknown
+		start = true
+	}
+
+	if start {
+		if exists && rollout.IsInProgress() {
+			rollout.Finish(m.ROLLOUT_STATUS_SUPERSEDED, "", "")
+			dw.RolloutQueue = append(dw.RolloutQueue, rollout)
+		}
+		rollout = m.NewRolloutObj()
+		rollout.Name = d.Name
+		rollout.Namespace = d.Namespace
+		rollout.ObjectUid = string(d.GetUID())
+		if d.ClusterName != "" {
+			rollout.ClusterName = d.ClusterName
+		} else {
+			rollout.ClusterName = bag.AppName
+		}
+		rollout.TemplateHash = hash
+		rollout.StartTime = time.Now()
+		rollout.OldImages = oldImages
+		rollout.NewImages = templateImages(&d.Spec.Template)
+		exists = true
+		dw.Logger.WithField("deployment", key).Info("Rollout started")
+	}
+
+	if !exists {
+		return
+	}
+
+	if !rollout.IsInProgress() && rollout.Status != m.ROLLOUT_STATUS_FAILED {
+		return
+	}
+
+	changed := start
+	desired := desiredReplicas(d)
+	rollout.Revision = d.Annotations[DEPLOYMENT_REVISION_ANNOTATION]
+	rollout.Replicas = desired
+	rollout.ReplicasUpdated = d.Status.UpdatedReplicas
+	rollout.ReplicasAvailable = d.Status.AvailableReplicas
+
+	//the status of the deployment is only meaningful once the controller observed the latest spec
+	if d.Status.ObservedGeneration >= d.Generation {
+		if isRolloutComplete(d) {
+			rollout.Finish(m.ROLLOUT_STATUS_COMPLETE, "", "")
+			changed = true
+			dw.Logger.WithFields(log.Fields{"deployment": key, "duration": rollout.Duration}).Info("Rollout complete")
+		} else if rollout.IsInProgress() {
+			for _, cond := range d.Status.Conditions {
+				if cond.Type == appsv1.DeploymentProgressing && cond.Reason == m.ROLLOUT_REASON_DEADLINE_EXCEEDED {
+					rollout.Finish(m.ROLLOUT_STATUS_FAILED, cond.Reason, cond.Message)
+					changed = true
+					dw.Logger.WithFields(log.Fields{"deployment": key, "message": cond.Message}).Warn("Rollout failed")
+					break
+				}
+			}
+		}
+	}
+
+	if rollout.IsInProgress() && !rollout.Stalled && bag.RolloutStallThreshold > 0 &&
+		time.Since(rollout.StartTime) > time.Duration(bag.RolloutStallThreshold)*time.Second && d.Status.AvailableReplicas < desired {
+		rollout.Stalled = true
+		rollout.Reason = m.ROLLOUT_REASON_STALLED
+		rollout.Message = fmt.Sprintf("%d of %d replicas available after %d sec", d.Status.AvailableReplicas, desired, bag.RolloutStallThreshold)
+		changed = true
+		dw.Logger.WithFields(log.Fields{"deployment": key, "message": rollout.Message}).Warn("Rollout stalled")
+	}
+
+	if changed {
+		dw.RolloutQueue = append(dw.RolloutQueue, rollout)
+	}
+	dw.Rollouts[key] = rollout
+}
+
+func (dw *DeployWorker) getRollout(namespace string, name string) (m.RolloutSchema, bool) {
+	lockRollouts.RLock()
+	defer lockRollouts.RUnlock()
+	rollout, ok := dw.Rollouts[utils.GetKey(namespace, name)]
+	return rollout, ok
+}
+
+func (dw *DeployWorker) clearRollout(namespace string, name string) {
+	lockRollouts.Lock()
+	defer lockRollouts.Unlock()
+	delete(dw.Rollouts, utils.GetKey(namespace, name))
+}
+
+//drops the rollouts of the namespaces that are no longer monitored
+func (dw *DeployWorker) purgeNamespaces(keep func(namespace string) bool) {
+	lockRollouts.Lock()
+	defer lockRollouts.Unlock()
+	for key := range dw.Rollouts {
+		if !keep(utils.KeyNamespace(key)) {
+			delete(dw.Rollouts, key)
+		}
+	}
+}
+
+func (dw *DeployWorker) flushRollouts() {
+	lockRollouts.Lock()
+	objList := dw.RolloutQueue
+	dw.RolloutQueue = []m.RolloutSchema{}
+	lockRollouts.Unlock()
+
+	if len(objList) == 0 {
+		return
+	}
+
+	bag := (*dw.ConfigManager).Get()
+	dw.Logger.Infof("Flushing %d rollout records\n", len(objList))
+	for len(objList) > 0 {
+		limit := len(objList)
+		if bag.EventAPILimit > 0 && limit > bag.EventAPILimit {
+			limit = bag.EventAPILimit
+		}
+		batch := objList[:limit]
+		dw.postRolloutRecords(&batch)
+		objList = objList[limit:]
+	}
+}
+
+func (dw *DeployWorker) postRolloutRecords(objList *[]m.RolloutSchema) {
+	bag := (*dw.ConfigManager).Get()
+	rc := app.NewRestClient(bag, dw.Logger)
+
+	schemaDefObj := m.NewRolloutSchemaDefWrapper()
+
+	err := rc.EnsureSchema(bag.RolloutSchemaName, &schemaDefObj)
+	if err != nil {
+		dw.Logger.Errorf("Issues when ensuring %s schema. %v\n", bag.RolloutSchemaName, err)
+	} else {
+		data, err := json.Marshal(objList)
+		if err != nil {
+			dw.Logger.Errorf("Problems when serializing array of rollout schemas. %v", err)
+		}
+		rc.PostAppDEvents(bag.RolloutSchemaName, data)
+	}
+}
+
+func desiredReplicas(d *appsv1.Deployment) int32 {
+	if d.Spec.Replicas != nil {
+		return *d.Spec.Replicas
+	}
+	return 1
+}
+
+func isRolloutComplete(d *appsv1.Deployment) bool {
+	desired := desiredReplicas(d)
+	return d.Status.UpdatedReplicas == desired && d.Status.Replicas == desired && d.Status.AvailableReplicas == desired
+}
+
+func isRollingOut(d *appsv1.Deployment) bool {
+	desired := desiredReplicas(d)
+	return d.Status.ObservedGeneration < d.Generation || d.Status.UpdatedReplicas < desired || d.Status.Replicas > d.Status.UpdatedReplicas
+}
+
+//fingerprint of the pod template. Any change of the template triggers a rollout
+func templateHash(template *v1.PodTemplateSpec) string {
+	data, err := json.Marshal(template)
+	if err != nil {
+		return ""
+	}
+	h := fnv.New32a()
+	h.Write(data)
+	return fmt.Sprintf("%x", h.Sum32())
+}
+
+func templateImages(template *v1.PodTemplateSpec) string {
+	var sb strings.Builder
+	for _, c := range template.Spec.Containers {
+		fmt.Fprintf(&sb, "%s:%s;", c.Name, c.Image)
+	}
+	return sb.String()
+}
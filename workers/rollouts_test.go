@@ -0,0 +1,194 @@
+package workers
+
+import (
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/appdynamics/cluster-agent/config"
+	m "github.com/appdynamics/cluster-agent/models"
+	"github.com/appdynamics/cluster-agent/utils"
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testDeployment(image string, replicas int32, status appsv1.DeploymentStatus) *appsv1.Deployment {
+	d := appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "shop", Generation: 2}}
+	d.Spec.Replicas = &replicas
+	d.Spec.Template.Spec.Containers = []v1.Container{{Name: "app", Image: image}}
+	d.Status = status
+	return &d
+}
+
+func TestTemplateHash(t *testing.T) {
+	a := testDeployment("app:1", 1, appsv1.DeploymentStatus{})
+	b := testDeployment("app:1", 3, appsv1.DeploymentStatus{Replicas: 3})
+	c := testDeployment("app:2", 1, appsv1.DeploymentStatus{})
+	if templateHash(&a.Spec.Template) != templateHash(&b.Spec.Template) {
+		t.Errorf("templateHash differs for the same template")
+	}
+	if templateHash(&a.Spec.Template) == templateHash(&c.Spec.Template) {
+		t.Errorf("templateHash is the same for different images")
+	}
+	c.Spec.Template.Spec.Containers[0].Image = "app:1"
+	c.Spec.Template.Labels = map[string]string{"version": "2"}
+	if templateHash(&a.Spec.Template) == templateHash(&c.Spec.Template) {
+		t.Errorf("templateHash is the same for different labels")
+	}
+}
+
+func TestRolloutState(t *testing.T) {
+	tests := []struct {
+		name       string
+		replicas   int32
+		status     appsv1.DeploymentStatus
+		complete   bool
+		rollingOut bool
+	}{
+		{"complete", 3, appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 3}, true, false},
+		{"not observed", 3, appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 3}, true, true},
+		{"updating", 3, appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 4, UpdatedReplicas: 1, AvailableReplicas: 3}, false, true},
+		{"old replicas terminating", 3, appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 4, UpdatedReplicas: 3, AvailableReplicas: 3}, false, true},
+		{"updated, not available", 3, appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 2}, false, false},
+		{"scaled to zero", 0, appsv1.DeploymentStatus{ObservedGeneration: 2}, true, false},
+	}
+	for _, tt := range tests {
+		d := testDeployment("app:1", tt.replicas, tt.status)
+		if complete := isRolloutComplete(d); complete != tt.complete {
+			t.Errorf("%s: isRolloutComplete = %t, want %t", tt.name, complete, tt.complete)
+		}
+		if rollingOut := isRollingOut(d); rollingOut != tt.rollingOut {
+			t.Errorf("%s: isRollingOut = %t, want %t", tt.name, rollingOut, tt.rollingOut)
+		}
+	}
+
+	d := testDeployment("app:1", 1, appsv1.DeploymentStatus{})
+	d.Spec.Replicas = nil
+	if desired := desiredReplicas(d); desired != 1 {
+		t.Errorf("desiredReplicas without replicas = %d, want 1", desired)
+	}
+}
+
+func TestTrackRollout(t *testing.T) {
+	l := log.New()
+	l.Out = ioutil.Discard
+	cm := config.MutexConfigManager{Conf: &m.AppDBag{AppName: "cluster"}, Mutex: &sync.Mutex{}, Logger: l}
+	dw := DeployWorker{ConfigManager: &cm, Rollouts: make(map[string]m.RolloutSchema), RolloutQueue: []m.RolloutSchema{}, Logger: l}
+
+	//steps of a single deployment. queued - the number of records queued by the step
+	steps := []struct {
+		name   string
+		image  string
+		status appsv1.DeploymentStatus
+		added  bool
+		queued int
+		state  string
+	}{
+		{"steady on startup", "app:1", appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2}, true, 0, ""},
+		{"new image", "app:2", appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 1, AvailableReplicas: 2}, false, 1, m.ROLLOUT_STATUS_IN_PROGRESS},
+		{"progress", "app:2", appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 2, AvailableReplicas: 2}, false, 0, m.ROLLOUT_STATUS_IN_PROGRESS},
+		{"superseded", "app:3", appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 1, AvailableReplicas: 2}, false, 2, m.ROLLOUT_STATUS_IN_PROGRESS},
+		{"failed", "app:3", appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 1, AvailableReplicas: 2,
+			Conditions: []appsv1.DeploymentCondition{{Type: appsv1.DeploymentProgressing, Reason: m.ROLLOUT_REASON_DEADLINE_EXCEEDED}}}, false, 1, m.ROLLOUT_STATUS_FAILED},
+		{"recovered", "app:3", appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2}, false, 1, m.ROLLOUT_STATUS_COMPLETE},
+		{"complete", "app:3", appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2}, false, 0, m.ROLLOUT_STATUS_COMPLETE},
+	}
+	var old *appsv1.Deployment
+	for _, step := range steps {
+		d := testDeployment(step.image, 2, step.status)
+		queued := len(dw.RolloutQueue)
+		dw.trackRollout(d, old, step.added)
+		if n := len(dw.RolloutQueue) - queued; n != step.queued {
+			t.Errorf("%s: queued %d records, want %d", step.name, n, step.queued)
+		}
+		rollout, ok := dw.getRollout("shop", "api")
+		if step.state == "" {
+			if ok {
+				t.Errorf("%s: unexpected rollout %s", step.name, rollout.Status)
+			}
+		} else if !ok || rollout.Status != step.state {
+			t.Errorf("%s: rollout status = %q, want %q", step.name, rollout.Status, step.state)
+		}
+		old = d
+	}
+	if superseded := dw.RolloutQueue[1]; superseded.Status != m.ROLLOUT_STATUS_SUPERSEDED || superseded.NewImages != "app:app:2;" {
+		t.Errorf("superseded rollout = %q of %q, want %q of %q", superseded.Status, superseded.NewImages, m.ROLLOUT_STATUS_SUPERSEDED, "app:app:2;")
+	}
+}
+
+func TestRolloutFailedSince(t *testing.T) {
+	since := time.Now().Add(-time.Minute)
+	before := since.Add(-time.Minute)
+	after := since.Add(30 * time.Second)
+	tests := []struct {
+		status string
+		end    *time.Time
+		failed bool
+	}{
+		{m.ROLLOUT_STATUS_FAILED, &after, true},
+		{m.ROLLOUT_STATUS_FAILED, &before, false},
+		{m.ROLLOUT_STATUS_FAILED, nil, false},
+		{m.ROLLOUT_STATUS_COMPLETE, &after, false},
+		{m.ROLLOUT_STATUS_IN_PROGRESS, nil, false},
+	}
+	for _, tt := range tests {
+		rs := m.RolloutSchema{Status: tt.status, EndTime: tt.end}
+		if failed := rs.FailedSince(since); failed != tt.failed {
+			t.Errorf("FailedSince of %s rollout ended at %v = %t, want %t", tt.status, tt.end, failed, tt.failed)
+		}
+	}
+}
+
+func TestTrackRolloutStalled(t *testing.T) {
+	l := log.New()
+	l.Out = ioutil.Discard
+	cm := config.MutexConfigManager{Conf: &m.AppDBag{AppName: "cluster", RolloutStallThreshold: 600}, Mutex: &sync.Mutex{}, Logger: l}
+	dw := DeployWorker{ConfigManager: &cm, Rollouts: make(map[string]m.RolloutSchema), RolloutQueue: []m.RolloutSchema{}, Logger: l}
+
+	old := testDeployment("app:1", 3, appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 3})
+	dw.trackRollout(old, nil, true)
+	partial := appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 1}
+	d := testDeployment("app:2", 3, partial)
+	dw.trackRollout(d, old, false)
+	if rollout, _ := dw.getRollout("shop", "api"); rollout.Stalled {
+		t.Fatalf("rollout flagged as stalled right after the start")
+	}
+
+	//move the start of the rollout past the threshold
+	rollout, _ := dw.getRollout("shop", "api")
+	rollout.StartTime = time.Now().Add(-11 * time.Minute)
+	dw.Rollouts[utils.GetKey("shop", "api")] = rollout
+
+	queued := len(dw.RolloutQueue)
+	dw.trackRollout(d, d, false)
+	rollout, _ = dw.getRollout("shop", "api")
+	if !rollout.Stalled || rollout.Reason != m.ROLLOUT_REASON_STALLED || rollout.Status != m.ROLLOUT_STATUS_IN_PROGRESS {
+		t.Errorf("rollout = stalled %t, reason %q, status %q, want a stalled rollout in progress", rollout.Stalled, rollout.Reason, rollout.Status)
+	}
+	if n := len(dw.RolloutQueue) - queued; n != 1 {
+		t.Errorf("stalled rollout queued %d records, want 1", n)
+	}
+
+	//the flag is raised once per rollout
+	queued = len(dw.RolloutQueue)
+	dw.trackRollout(d, d, false)
+	if n := len(dw.RolloutQueue) - queued; n != 0 {
+		t.Errorf("stalled rollout queued %d more records, want 0", n)
+	}
+
+	//0 disables the check
+	cm.Conf.RolloutStallThreshold = 0
+	dw.Rollouts = make(map[string]m.RolloutSchema)
+	dw.trackRollout(old, nil, true)
+	dw.trackRollout(d, old, false)
+	rollout, _ = dw.getRollout("shop", "api")
+	rollout.StartTime = time.Now().Add(-time.Hour)
+	dw.Rollouts[utils.GetKey("shop", "api")] = rollout
+	dw.trackRollout(d, d, false)
+	if rollout, _ = dw.getRollout("shop", "api"); rollout.Stalled {
+		t.Errorf("rollout flagged as stalled with the check disabled")
+	}
+}
@@ -13,11 +13,9 @@ import (
 
 	m "github.com/appdynamics/cluster-agent/models"
 	"github.com/appdynamics/cluster-agent/utils"
+	w "github.com/appdynamics/cluster-agent/watchers"
 	appsv1 "k8s.io/api/apps/v1"
 
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 
@@ -25,7 +23,7 @@ import (
 )
 
 type RsWorker struct {
-	informer       cache.SharedIndexInformer
+	informer       *w.NamespacedInformer
 	Client         *kubernetes.Clientset
 	ConfigManager  *config.MutexConfigManager
 	SummaryMap     map[string]m.ClusterRsMetrics
@@ -33,33 +31,20 @@ type RsWorker struct {
 	AppdController *app.ControllerClient
 	PendingCache   []string
 	FailedCache    map[string]m.AttachStatus
+	Changes        *ChangeRecorder
 	Logger         *log.Logger
 }
 
-func NewRsWorker(client *kubernetes.Clientset, cm *config.MutexConfigManager, controller *app.ControllerClient, l *log.Logger) RsWorker {
+func NewRsWorker(client *kubernetes.Clientset, cm *config.MutexConfigManager, im *w.InformerManager, controller *app.ControllerClient, changes *ChangeRecorder, l *log.Logger) RsWorker {
 	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
 	dw := RsWorker{Client: client, ConfigManager: cm, SummaryMap: make(map[string]m.ClusterRsMetrics), WQ: queue,
-		AppdController: controller, PendingCache: []string{}, FailedCache: make(map[string]m.AttachStatus), Logger: l}
-	dw.initRsInformer(client)
+		AppdController: controller, PendingCache: []string{}, FailedCache: make(map[string]m.AttachStatus), Changes: changes, Logger: l}
+	dw.initRsInformer(im)
 	return dw
 }
 
-func (nw *RsWorker) initRsInformer(client *kubernetes.Clientset) cache.SharedIndexInformer {
-	i := cache.NewSharedIndexInformer(
-		&cache.ListWatch{
-			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
-				return client.AppsV1().ReplicaSets(metav1.NamespaceAll).List(options)
-			},
-			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
-				return client.AppsV1().ReplicaSets(metav1.NamespaceAll).Watch(options)
-			},
-		},
-		&appsv1.ReplicaSet{},
-		0,
-		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
-	)
-
-	i.AddEventHandler(cache.ResourceEventHandlerFuncs{
+func (nw *RsWorker) initRsInformer(im *w.InformerManager) *w.NamespacedInformer {
+	i := im.NewInformer("replicasets", w.ReplicaSetInformer, cache.ResourceEventHandlerFuncs{
 		AddFunc:    nw.onNewReplicaSet,
 		DeleteFunc: nw.onDeleteReplicaSet,
 		UpdateFunc: nw.onUpdateReplicaSet,
@@ -85,9 +70,18 @@ func (dw *RsWorker) Observe(stopCh <-chan struct{}, wg *sync.WaitGroup) {
 }
 
 func (pw *RsWorker) qualifies(p *appsv1.ReplicaSet) bool {
-	return (len((*pw.ConfigManager).Get().NsToMonitor) == 0 ||
-		utils.StringInSlice(p.Namespace, (*pw.ConfigManager).Get().NsToMonitor)) &&
-		!utils.StringInSlice(p.Namespace, (*pw.ConfigManager).Get().NsToMonitorExclude)
+	return utils.NSQualifiesForMonitoring(p.Namespace, (*pw.ConfigManager).Get())
+}
+
+//re-queues the replica sets of the namespace when the namespace selector may select or deselect the namespace
+func (dw *RsWorker) NamespaceLabelsChanged(namespace string) {
+	for _, obj := range dw.informer.GetStore().List() {
+		rsObj := obj.(*appsv1.ReplicaSet)
+		if rsObj.Namespace == namespace && dw.qualifies(rsObj) {
+			record, _ := dw.processObject(rsObj, nil)
+			dw.WQ.Add(&record)
+		}
+	}
 }
 
 func (dw *RsWorker) onNewReplicaSet(obj interface{}) {
@@ -119,6 +113,22 @@ func (dw *RsWorker) onUpdateReplicaSet(objOld interface{}, objNew interface{}) {
 
 	RsRecord, _ := dw.processObject(RsObj, nil)
 	dw.WQ.Add(&RsRecord)
+
+	//changes of replica sets managed by deployments are recorded on the deployments.
+	//Templates of cached revisions may be stripped, which would be reported as changes
+	RsOldObj := objOld.(*appsv1.ReplicaSet)
+	if !isOwnedByDeployment(RsObj) && !w.IsTemplateStripped(RsOldObj) && !w.IsTemplateStripped(RsObj) {
+		dw.Changes.RecordChange("ReplicaSet", RsObj, &RsOldObj.Spec.Template, &RsObj.Spec.Template, RsOldObj.Spec.Replicas, RsObj.Spec.Replicas)
+	}
+}
+
+func isOwnedByDeployment(rs *appsv1.ReplicaSet) bool {
+	for _, ref := range rs.OwnerReferences {
+		if ref.Kind == "Deployment" {
+			return true
+		}
+	}
+	return false
 }
 
 func (pw *RsWorker) startMetricsWorker(stopCh <-chan struct{}) {
@@ -0,0 +1,119 @@
+package workers
+
+import (
+	"sync"
+
+	"github.com/appdynamics/cluster-agent/config"
+	m "github.com/appdynamics/cluster-agent/models"
+	"github.com/appdynamics/cluster-agent/utils"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+)
+
+//SpreadChecker computes the distribution of the replicas of Deployments and StatefulSets across nodes and zones
+//and flags the workloads that are single points of failure
+type SpreadChecker struct {
+	ConfManager *config.MutexConfigManager
+	Logger      *log.Logger
+	lock        *sync.RWMutex
+	cycle       map[string]*m.WorkloadSpread
+	spread      map[string]m.WorkloadSpread
+}
+
+func NewSpreadChecker(cm *config.MutexConfigManager, l *log.Logger) *SpreadChecker {
+	return &SpreadChecker{ConfManager: cm, Logger: l, lock: &sync.RWMutex{}, cycle: make(map[string]*m.WorkloadSpread), spread: make(map[string]m.WorkloadSpread)}
+}
+
+//RecordPod adds a replica to the distribution of its workload. Only ready replicas count towards nodes and zones
+func (sc *SpreadChecker) RecordPod(p *v1.Pod) {
+	if p.Status.Phase == v1.PodSucceeded || p.Status.Phase == v1.PodFailed || p.DeletionTimestamp != nil {
+		return
+	}
+	kind, workload := podWorkload(p, p.Name)
+	if kind != "Deployment" && kind != "StatefulSet" {
+		return
+	}
+	ready := false
+	for _, cond := range p.Status.Conditions {
+		if cond.Type == v1.PodReady {
+			ready = cond.Status == v1.ConditionTrue
+		}
+	}
+	constrained := len(p.Spec.TopologySpreadConstraints) > 0 || (p.Spec.Affinity != nil && p.Spec.Affinity.PodAntiAffinity != nil &&
+		(len(p.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution) > 0 || len(p.Spec.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution) > 0))
+
+	key := workloadKey(p.Namespace, kind, workload)
+	sc.lock.Lock()
+	defer sc.lock.Unlock()
+	spread, ok := sc.cycle[key]
+	if !ok {
+		s := m.NewWorkloadSpread(p.Namespace, kind, workload)
+		spread = &s
+		sc.cycle[key] = spread
+	}
+	spread.Replicas++
+	spread.HasConstraints = spread.HasConstraints || constrained
+	if ready && p.Spec.NodeName != "" {
+		spread.ReplicasReady++
+		spread.Nodes[p.Spec.NodeName]++
+		if zone := m.GetNodeZone(utils.GetNodeLabels(p.Spec.NodeName)); zone != "" {
+			spread.Zones[zone]++
+		}
+	}
+}
+
+//EndCycle evaluates the distribution of the workloads of the cycle and returns the spread metrics
+func (sc *SpreadChecker) EndCycle(nodes *NodesWorker) []m.AppDMetricInterface {
+	bag := (*sc.ConfManager).Get()
+	zones := make(map[string]bool)
+	if nodes != nil {
+		for _, n := range nodes.GetNodeObjects() {
+			if zone := m.GetNodeZone(n.Labels); zone != "" {
+				zones[zone] = true
+			}
+		}
+	}
+
+	sc.lock.Lock()
+	cycle := sc.cycle
+	sc.cycle = make(map[string]*m.WorkloadSpread)
+	sc.lock.Unlock()
+
+	spread := make(map[string]m.WorkloadSpread)
+	metrics := []m.AppDMetricInterface{}
+	summary := m.NewClusterSpofMetrics(bag, m.ALL)
+	summaryNS := make(map[string]*m.ClusterSpofMetrics)
+	for key, ws := range cycle {
+		ws.Evaluate(len(zones))
+		spread[key] = *ws
+		metrics = append(metrics, m.NewClusterSpreadMetrics(bag, ws))
+		summary.Add(ws)
+		ns, ok := summaryNS[ws.Namespace]
+		if !ok {
+			s := m.NewClusterSpofMetrics(bag, ws.Namespace)
+			ns = &s
+			summaryNS[ws.Namespace] = ns
+		}
+		ns.Add(ws)
+		if ws.IsSinglePointOfFailure() {
+			sc.Logger.WithFields(log.Fields{"namespace": ws.Namespace, "kind": ws.OwnerKind, "workload": ws.Workload, "issues": ws.Issues}).Debug("Workload is a single point of failure")
+		}
+	}
+	metrics = append(metrics, summary)
+	for _, ns := range summaryNS {
+		metrics = append(metrics, *ns)
+	}
+
+	sc.lock.Lock()
+	sc.spread = spread
+	sc.lock.Unlock()
+	return metrics
+}
+
+//GetSpread returns the distribution of the replicas of the workload from the last cycle
+func (sc *SpreadChecker) GetSpread(namespace string, kind string, workload string) (m.WorkloadSpread, bool) {
+	sc.lock.RLock()
+	defer sc.lock.RUnlock()
+	spread, ok := sc.spread[workloadKey(namespace, kind, workload)]
+	return spread, ok
+}
@@ -0,0 +1,94 @@
+package workers
+
+import (
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/appdynamics/cluster-agent/config"
+	m "github.com/appdynamics/cluster-agent/models"
+	"github.com/appdynamics/cluster-agent/utils"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+//replica of the deployment api or of the stateful set db on the node
+func newTestSpreadPod(name string, node string, ready bool) *v1.Pod {
+	p := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop"}, Spec: v1.PodSpec{NodeName: node}, Status: v1.PodStatus{Phase: v1.PodRunning}}
+	if strings.HasPrefix(name, "db-") {
+		p.OwnerReferences = []metav1.OwnerReference{{Kind: "StatefulSet", Name: "db"}}
+	} else {
+		p.Labels = map[string]string{"pod-template-hash": "5d4f"}
+		p.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "api-5d4f"}}
+	}
+	status := v1.ConditionFalse
+	if ready {
+		status = v1.ConditionTrue
+	}
+	p.Status.Conditions = []v1.PodCondition{{Type: v1.PodReady, Status: status}}
+	return p
+}
+
+func TestSpreadChecker(t *testing.T) {
+	l := log.New()
+	l.Out = ioutil.Discard
+	cm := &config.MutexConfigManager{Conf: &m.AppDBag{AppName: "cluster"}, Mutex: &sync.Mutex{}, Logger: l}
+	utils.UpdateNodeLabels("node-1", map[string]string{m.NODE_LABEL_ZONE: "zone-a"})
+	utils.UpdateNodeLabels("node-2", map[string]string{m.NODE_LABEL_ZONE_BETA: "zone-b"})
+	defer utils.DeleteNodeLabels("node-1")
+	defer utils.DeleteNodeLabels("node-2")
+	sc := NewSpreadChecker(cm, l)
+
+	//the ready replicas of api run on one node
+	sc.RecordPod(newTestSpreadPod("api-5d4f-a", "node-1", true))
+	sc.RecordPod(newTestSpreadPod("api-5d4f-b", "node-1", true))
+	sc.RecordPod(newTestSpreadPod("api-5d4f-c", "node-2", false))
+	constrained := newTestSpreadPod("db-0", "node-1", true)
+	constrained.Spec.TopologySpreadConstraints = []v1.TopologySpreadConstraint{{MaxSkew: 1, TopologyKey: m.NODE_LABEL_ZONE}}
+	sc.RecordPod(constrained)
+	sc.RecordPod(newTestSpreadPod("db-1", "node-2", true))
+	//completed pods, pods that are going away and standalone pods are not counted
+	done := newTestSpreadPod("api-5d4f-d", "node-2", false)
+	done.Status.Phase = v1.PodSucceeded
+	sc.RecordPod(done)
+	deleted := newTestSpreadPod("api-5d4f-e", "node-2", true)
+	deleted.DeletionTimestamp = &metav1.Time{}
+	sc.RecordPod(deleted)
+	sc.RecordPod(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "debug", Namespace: "shop"}, Spec: v1.PodSpec{NodeName: "node-2"}})
+
+	if _, ok := sc.GetSpread("shop", "Deployment", "api"); ok {
+		t.Errorf("spread is available before the end of the cycle")
+	}
+	metrics := sc.EndCycle(nil)
+	//2 workloads, the cluster and the namespace
+	if len(metrics) != 4 {
+		t.Fatalf("%d spread metrics, want 4", len(metrics))
+	}
+
+	api, ok := sc.GetSpread("shop", "Deployment", "api")
+	if !ok || api.Replicas != 3 || api.ReplicasReady != 2 || strings.Join(api.Issues, ",") != "singleNode,noSpreadConstraints" {
+		t.Errorf("spread of api = %+v", api)
+	}
+	if spread := api.GetSpreadFormatted(); spread != "nodes: node-1=2; zones: zone-a=2" {
+		t.Errorf("spread of api = %q", spread)
+	}
+	db, ok := sc.GetSpread("shop", "StatefulSet", "db")
+	if !ok || !db.HasConstraints || db.IsSinglePointOfFailure() || db.GetSpreadFormatted() != "nodes: node-1=1,node-2=1; zones: zone-a=1,zone-b=1" {
+		t.Errorf("spread of db = %+v", db)
+	}
+	for _, metric := range metrics {
+		if spof, ok := metric.(m.ClusterSpofMetrics); ok && (spof.SinglePointOfFailure != 1 || spof.SpofSingleNode != 1 || spof.SpofNoSpreadConstraints != 1) {
+			t.Errorf("single points of failure of %q = %+v", spof.Namespace, spof)
+		}
+	}
+
+	//the next cycle starts empty and replaces the spread of the last one
+	if metrics = sc.EndCycle(nil); len(metrics) != 1 {
+		t.Errorf("%d spread metrics of an empty cycle, want the cluster summary", len(metrics))
+	}
+	if _, ok := sc.GetSpread("shop", "Deployment", "api"); ok {
+		t.Errorf("spread of api is kept after a cycle without its pods")
+	}
+}